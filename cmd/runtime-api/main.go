@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,11 +13,13 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/alert"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/api"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/cleanup"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/config"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/k8s"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/logger"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/metrics"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/reaper"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/state"
 	muxtrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/gorilla/mux"
@@ -24,7 +28,34 @@ import (
 
 func isHealthCheck(r *http.Request) bool {
 	p := r.URL.Path
-	return p == "/health" || p == "/liveness" || p == "/readiness"
+	return p == "/health" || p == "/liveness" || p == "/readiness" || p == "/metrics"
+}
+
+// listenAndServe serves over TLS when TLS_CERT_FILE and TLS_KEY_FILE are set, and
+// plaintext otherwise
+func listenAndServe(server *http.Server, cfg *config.Config) error {
+	if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+		return server.ListenAndServe()
+	}
+	server.TLSConfig = serverTLSConfig()
+	logger.Info("Serving over TLS with certificate %s", cfg.TLSCertFile)
+	return server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+}
+
+// serverTLSConfig requires TLS 1.2 or newer and, for TLS 1.2, forward-secret AEAD cipher
+// suites only. TLS 1.3 suites are not configurable and are all considered secure.
+func serverTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
 }
 
 func main() {
@@ -33,6 +64,7 @@ func main() {
 
 	// Initialize logger with configured level
 	logger.Init(cfg.LogLevel)
+	logger.SetFormat(cfg.LogFormat)
 	logger.Info("Initializing OpenHands Kubernetes Runtime API")
 	logger.Debug("Log level set to: %s", cfg.LogLevel)
 
@@ -44,8 +76,17 @@ func main() {
 	}
 
 	// Validate required config
-	if cfg.APIKey == "" {
-		log.Fatal("API_KEY environment variable is required")
+	if len(cfg.APIKeys) == 0 {
+		log.Fatal("API_KEY or API_KEYS environment variable is required")
+	}
+	if _, err := k8s.ReadinessProbePort(cfg); err != nil {
+		log.Fatalf("Invalid SANDBOX_READINESS_PORT_ROLE: %v", err)
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		log.Fatal("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	for _, key := range cfg.DroppedIngressAnnotations {
+		logger.Warn("Ignoring SANDBOX_INGRESS_ANNOTATIONS key %s: not permitted by ALLOWED_INGRESS_ANNOTATION_KEYS", key)
 	}
 
 	// Initialize state manager
@@ -57,17 +98,41 @@ func main() {
 		log.Fatalf("Failed to create Kubernetes client: %v", err)
 	}
 
+	// A missing PriorityClass would make every sandbox pod fail admission
+	priorityCtx, priorityCancel := context.WithTimeout(context.Background(), cfg.K8sQueryTimeout)
+	err = k8sClient.ValidatePriorityClass(priorityCtx)
+	priorityCancel()
+	if errors.Is(err, k8s.ErrPriorityClassNotFound) {
+		log.Fatalf("Invalid SANDBOX_PRIORITY_CLASS_NAME: %v", err)
+	} else if err != nil {
+		logger.Warn("Could not verify SANDBOX_PRIORITY_CLASS_NAME %q: %v", cfg.SandboxPriorityClassName, err)
+	}
+
+	// Optionally prove we can create and delete sandboxes before serving traffic
+	if cfg.StartupSelfTest == "true" || cfg.StartupSelfTest == "warn" {
+		selfTestCtx, selfTestCancel := context.WithTimeout(context.Background(), cfg.K8sOperationTimeout)
+		err := k8sClient.SelfTest(selfTestCtx)
+		selfTestCancel()
+		if err != nil {
+			if cfg.StartupSelfTest == "true" {
+				log.Fatalf("Startup self-test failed: %v", err)
+			}
+			logger.Error("STARTUP SELF-TEST FAILED, sandbox creation is likely broken: %v", err)
+		}
+	}
+
 	// Pre-populate state by discovering all existing sandbox pods.
 	// This prevents sandboxes from appearing "lost" after a runtime API restart.
 	discoverCtx, discoverCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	discovered, err := k8sClient.DiscoverAllRuntimes(discoverCtx)
 	discoverCancel()
 	if err != nil {
-		logger.Info("Warning: failed to discover existing runtimes: %v", err)
+		logger.Warn("Failed to discover existing runtimes: %v", err)
 	} else {
 		for _, rt := range discovered {
 			stateMgr.AddRuntime(rt)
 		}
+		stateMgr.MarkReconciled()
 		logger.Info("Recovered %d existing sandbox(es) from Kubernetes", len(discovered))
 	}
 
@@ -96,6 +161,7 @@ func main() {
 						added++
 					}
 				}
+				stateMgr.MarkReconciled()
 				if added > 0 {
 					logger.Info("Reconcile: recovered %d sandbox(es)", added)
 				}
@@ -107,16 +173,26 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Shared so repeated failures of one runtime across cleanup and reaper count together
+	alertNotifier := alert.NewNotifier(cfg.AlertWebhookURL, cfg.CleanupErrorAlertThreshold)
+
+	// The reaper is built first so cleanup judges idleness by the same IDLE_SIGNAL
+	reaperInstance := reaper.NewReaper(stateMgr, k8sClient, cfg)
+	reaperInstance.SetAlertNotifier(alertNotifier)
+
 	cleanupSvc := cleanup.NewService(k8sClient, stateMgr, cfg)
+	cleanupSvc.SetAlertNotifier(alertNotifier)
+	cleanupSvc.SetActivityChecker(reaperInstance)
 	cleanupSvc.Start(ctx)
 	defer cleanupSvc.Stop()
 
+	// Start idle sandbox reaper
+	reaperInstance.Start()
+
 	// Initialize API handler
 	handler := api.NewHandler(k8sClient, stateMgr, cfg)
-
-	// Initialize and start idle sandbox reaper
-	reaperInstance := reaper.NewReaper(stateMgr, k8sClient, cfg)
-	reaperInstance.Start()
+	handler.SetStatsSources(cleanupSvc, reaperInstance)
+	handler.StartSweeper(ctx)
 
 	// Setup router — use muxtrace-instrumented router when Datadog is active.
 	// muxtrace.Router embeds *mux.Router and overrides ServeHTTP to trace requests.
@@ -144,7 +220,8 @@ func main() {
 	}
 	router.HandleFunc("/health", healthHandler).Methods("GET")
 	router.HandleFunc("/liveness", healthHandler).Methods("GET")
-	router.HandleFunc("/readiness", healthHandler).Methods("GET")
+	router.HandleFunc("/readiness", handler.Readiness).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
 
 	// Create a subrouter for authenticated routes
 	authRouter := router.PathPrefix("/").Subrouter()
@@ -153,16 +230,25 @@ func main() {
 
 	// Register authenticated routes
 	authRouter.HandleFunc("/start", handler.StartRuntime).Methods("POST")
+	authRouter.HandleFunc("/operations/{op_id}", handler.GetOperation).Methods("GET")
 	authRouter.HandleFunc("/stop", handler.StopRuntime).Methods("POST")
 	authRouter.HandleFunc("/pause", handler.PauseRuntime).Methods("POST")
 	authRouter.HandleFunc("/resume", handler.ResumeRuntime).Methods("POST")
 	authRouter.HandleFunc("/list", handler.ListRuntimes).Methods("GET")
 	authRouter.HandleFunc("/runtime/{runtime_id}", handler.GetRuntime).Methods("GET")
+	authRouter.HandleFunc("/runtime/{runtime_id}", handler.DeleteRuntime).Methods("DELETE")
+	authRouter.HandleFunc("/runtime/{runtime_id}/history", handler.GetRuntimeHistory).Methods("GET")
+	authRouter.HandleFunc("/runtime/{runtime_id}/logs", handler.GetRuntimeLogs).Methods("GET")
+	authRouter.HandleFunc("/runtime/{runtime_id}/events", handler.GetRuntimeEvents).Methods("GET")
+	authRouter.HandleFunc("/runtime/{runtime_id}/cordon", handler.CordonRuntime).Methods("POST")
+	authRouter.HandleFunc("/runtime/{runtime_id}/uncordon", handler.UncordonRuntime).Methods("POST")
 	authRouter.HandleFunc("/sessions/batch-conversations", handler.BatchGetConversations).Methods("POST")
 	authRouter.HandleFunc("/sessions/batch", handler.GetSessionsBatch).Methods("GET")
 	authRouter.HandleFunc("/sessions/{session_id}", handler.GetSession).Methods("GET")
 	authRouter.HandleFunc("/registry_prefix", handler.GetRegistryPrefix).Methods("GET")
 	authRouter.HandleFunc("/image_exists", handler.CheckImageExists).Methods("GET")
+	authRouter.HandleFunc("/stats", handler.GetStats).Methods("GET")
+	authRouter.HandleFunc("/admin/cleanup-stats", handler.GetStats).Methods("GET")
 
 	// Always register the sandbox proxy handler so that internal (in-cluster)
 	// traffic can reach sandboxes via http://openhands-runtime-api/sandbox/{id}/...
@@ -185,21 +271,20 @@ func main() {
 	logger.Info("Registry Prefix: %s", cfg.RegistryPrefix)
 	logger.Debug("Agent Server Port: %d", cfg.AgentServerPort)
 	logger.Debug("VSCode Port: %d", cfg.VSCodePort)
-	logger.Debug("Worker 1 Port: %d", cfg.Worker1Port)
-	logger.Debug("Worker 2 Port: %d", cfg.Worker2Port)
+	logger.Debug("Worker Ports: %v", cfg.WorkerPorts)
 
 	server := &http.Server{
 		Addr:         addr,
 		Handler:      serverHandler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 5 * time.Minute, // Must accommodate reverse proxy to sandbox pods (VSCode, long-running requests)
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  15 * time.Second, // Proxied sandbox requests clear their read and write deadlines (PROXY_* timeouts apply instead)
+		WriteTimeout: 5 * time.Minute,  // Accommodates long management calls, e.g. starts that wait for pod creation
+		IdleTimeout:  60 * time.Second, // Does not apply to hijacked (WebSocket) connections
 	}
 
 	// Run server in a goroutine so it doesn't block
 	go func() {
 		logger.Info("HTTP server starting...")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := listenAndServe(server, cfg); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
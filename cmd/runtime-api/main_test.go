@@ -1,13 +1,27 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/api"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/config"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/metrics"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/state"
 )
 
@@ -18,8 +32,7 @@ func setupTestRouter() *mux.Router {
 		APIKey:          "test-api-key",
 		Namespace:       "test",
 		BaseDomain:      "test.example.com",
-		Worker1Port:     12000,
-		Worker2Port:     12001,
+		WorkerPorts:     []int{12000, 12001},
 		AgentServerPort: 60000,
 		DefaultImage:    "test-image",
 	}
@@ -36,7 +49,8 @@ func setupTestRouter() *mux.Router {
 	}
 	router.HandleFunc("/health", healthHandler).Methods("GET")
 	router.HandleFunc("/liveness", healthHandler).Methods("GET")
-	router.HandleFunc("/readiness", healthHandler).Methods("GET")
+	router.HandleFunc("/readiness", handler.Readiness).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
 
 	// Create a subrouter for authenticated routes
 	authRouter := router.PathPrefix("/").Subrouter()
@@ -45,10 +59,18 @@ func setupTestRouter() *mux.Router {
 
 	// Register authenticated routes
 	authRouter.HandleFunc("/start", handler.StartRuntime).Methods("POST")
+	authRouter.HandleFunc("/operations/{op_id}", handler.GetOperation).Methods("GET")
 	authRouter.HandleFunc("/stop", handler.StopRuntime).Methods("POST")
 	authRouter.HandleFunc("/list", handler.ListRuntimes).Methods("GET")
 	authRouter.HandleFunc("/registry_prefix", handler.GetRegistryPrefix).Methods("GET")
 	authRouter.HandleFunc("/image_exists", handler.CheckImageExists).Methods("GET")
+	authRouter.HandleFunc("/stats", handler.GetStats).Methods("GET")
+	authRouter.HandleFunc("/admin/cleanup-stats", handler.GetStats).Methods("GET")
+	authRouter.HandleFunc("/runtime/{runtime_id}/history", handler.GetRuntimeHistory).Methods("GET")
+	authRouter.HandleFunc("/runtime/{runtime_id}/logs", handler.GetRuntimeLogs).Methods("GET")
+	authRouter.HandleFunc("/runtime/{runtime_id}/events", handler.GetRuntimeEvents).Methods("GET")
+	authRouter.HandleFunc("/runtime/{runtime_id}/cordon", handler.CordonRuntime).Methods("POST")
+	authRouter.HandleFunc("/runtime/{runtime_id}/uncordon", handler.UncordonRuntime).Methods("POST")
 
 	return router
 }
@@ -84,6 +106,21 @@ func TestHealthEndpointsNoAuth(t *testing.T) {
 	}
 }
 
+func TestMetricsEndpointNoAuth(t *testing.T) {
+	router := setupTestRouter()
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for /metrics without auth, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "# TYPE openhands_runtime_sandbox_starts_total counter") {
+		t.Errorf("Expected sandbox starts counter in /metrics output, got %q", rr.Body.String())
+	}
+}
+
 func TestAuthenticatedEndpointsRequireAuth(t *testing.T) {
 	router := setupTestRouter()
 
@@ -95,6 +132,12 @@ func TestAuthenticatedEndpointsRequireAuth(t *testing.T) {
 		{"List endpoint", "GET", "/list"},
 		{"Registry prefix endpoint", "GET", "/registry_prefix"},
 		{"Image exists endpoint", "GET", "/image_exists?image=test"},
+		{"Stats endpoint", "GET", "/stats"},
+		{"Cleanup stats endpoint", "GET", "/admin/cleanup-stats"},
+		{"Runtime history endpoint", "GET", "/runtime/abc123/history"},
+		{"Runtime logs endpoint", "GET", "/runtime/abc123/logs"},
+		{"Runtime events endpoint", "GET", "/runtime/abc123/events"},
+		{"Operation endpoint", "GET", "/operations/op123"},
 	}
 
 	for _, tt := range tests {
@@ -124,3 +167,98 @@ func TestAuthenticatedEndpointsRequireAuth(t *testing.T) {
 		})
 	}
 }
+
+// writeSelfSignedCert writes a self-signed certificate and key for 127.0.0.1 to dir and
+// returns their paths and the parsed certificate
+func writeSelfSignedCert(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "runtime-api-test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestListenAndServe_TLS(t *testing.T) {
+	certFile, keyFile, cert := writeSelfSignedCert(t, t.TempDir())
+
+	// Reserve a free port for the server to listen on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	server := &http.Server{Addr: addr, Handler: setupTestRouter(), ReadHeaderTimeout: 5 * time.Second}
+	served := make(chan error, 1)
+	go func() {
+		served <- listenAndServe(server, &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile})
+	}()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{
+		Timeout:   time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+	}
+	var resp *http.Response
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if resp, err = client.Get("https://" + addr + "/health"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 over HTTPS, got %d", resp.StatusCode)
+	}
+	if resp.TLS == nil || resp.TLS.Version < tls.VersionTLS12 {
+		t.Errorf("Expected a TLS 1.2+ connection, got %+v", resp.TLS)
+	}
+
+	// Clients limited to TLS 1.1 are refused
+	oldClient := &http.Client{
+		Timeout:   time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MaxVersion: tls.VersionTLS11}}, //nolint:gosec // G402: deliberately old to check it is rejected
+	}
+	if oldResp, err := oldClient.Get("https://" + addr + "/health"); err == nil {
+		oldResp.Body.Close()
+		t.Error("Expected a TLS 1.1 client to be rejected")
+	}
+
+	_ = server.Close()
+	if err := <-served; err != http.ErrServerClosed {
+		t.Errorf("Expected server to stop with ErrServerClosed, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/logger"
+)
+
+// Payload is the JSON body POSTed to the alert webhook
+type Payload struct {
+	Source              string    `json:"source"` // "cleanup" or "reaper"
+	RuntimeID           string    `json:"runtime_id"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Error               string    `json:"error"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
+// Notifier tracks consecutive teardown failures per runtime and POSTs one alert to a
+// webhook when a runtime's failures reach the threshold. Further failures of the same
+// runtime are suppressed until a successful teardown resolves the incident.
+// A nil Notifier, or one without a webhook URL or positive threshold, is a no-op.
+type Notifier struct {
+	webhookURL string
+	threshold  int
+	client     *http.Client
+
+	mu       sync.Mutex
+	failures map[string]int
+	alerted  map[string]bool
+}
+
+// NewNotifier creates a notifier that alerts after threshold consecutive failures
+func NewNotifier(webhookURL string, threshold int) *Notifier {
+	return &Notifier{
+		webhookURL: webhookURL,
+		threshold:  threshold,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		failures:   make(map[string]int),
+		alerted:    make(map[string]bool),
+	}
+}
+
+func (n *Notifier) enabled() bool {
+	return n != nil && n.webhookURL != "" && n.threshold > 0
+}
+
+// RecordFailure counts a failed teardown of runtimeID and sends an alert when the
+// consecutive failure count first reaches the threshold.
+func (n *Notifier) RecordFailure(source, runtimeID string, err error) {
+	if !n.enabled() {
+		return
+	}
+
+	n.mu.Lock()
+	n.failures[runtimeID]++
+	count := n.failures[runtimeID]
+	send := count >= n.threshold && !n.alerted[runtimeID]
+	if send {
+		n.alerted[runtimeID] = true
+	}
+	n.mu.Unlock()
+
+	if !send {
+		return
+	}
+
+	payload := Payload{
+		Source:              source,
+		RuntimeID:           runtimeID,
+		ConsecutiveFailures: count,
+		Timestamp:           time.Now().UTC(),
+	}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	if sendErr := n.send(payload); sendErr != nil {
+		logger.Error("Alert: Failed to send alert for runtime %s: %v", runtimeID, sendErr)
+		return
+	}
+	logger.Warn("Alert: Sent alert for runtime %s after %d consecutive %s failures", runtimeID, count, source)
+}
+
+// RecordSuccess resets the failure count for runtimeID, ending any open incident
+func (n *Notifier) RecordSuccess(runtimeID string) {
+	if !n.enabled() {
+		return
+	}
+	n.mu.Lock()
+	delete(n.failures, runtimeID)
+	delete(n.alerted, runtimeID)
+	n.mu.Unlock()
+}
+
+func (n *Notifier) send(payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal alert: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), n.client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post alert: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func newTestWebhook(t *testing.T) (*httptest.Server, func() []Payload) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("Failed to decode alert payload: %v", err)
+		}
+		mu.Lock()
+		received = append(received, p)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []Payload {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Payload(nil), received...)
+	}
+}
+
+func TestNotifier_OneAlertPerIncident(t *testing.T) {
+	server, received := newTestWebhook(t)
+	n := NewNotifier(server.URL, 3)
+	deleteErr := errors.New("finalizer stuck")
+
+	n.RecordFailure("cleanup", "runtime-1", deleteErr)
+	n.RecordFailure("reaper", "runtime-1", deleteErr)
+	if len(received()) != 0 {
+		t.Fatalf("Expected no alert below the threshold, got %d", len(received()))
+	}
+
+	n.RecordFailure("cleanup", "runtime-1", deleteErr)
+	alerts := received()
+	if len(alerts) != 1 {
+		t.Fatalf("Expected exactly 1 alert when crossing the threshold, got %d", len(alerts))
+	}
+	if alerts[0].RuntimeID != "runtime-1" || alerts[0].Source != "cleanup" ||
+		alerts[0].ConsecutiveFailures != 3 || alerts[0].Error != "finalizer stuck" {
+		t.Errorf("Unexpected alert payload: %+v", alerts[0])
+	}
+
+	// Further failures in the same incident are suppressed
+	for i := 0; i < 5; i++ {
+		n.RecordFailure("cleanup", "runtime-1", deleteErr)
+	}
+	if len(received()) != 1 {
+		t.Errorf("Expected repeated failures to be suppressed, got %d alerts", len(received()))
+	}
+
+	// Another runtime is tracked independently
+	for i := 0; i < 3; i++ {
+		n.RecordFailure("reaper", "runtime-2", deleteErr)
+	}
+	if len(received()) != 2 {
+		t.Errorf("Expected a separate alert for a different runtime, got %d alerts", len(received()))
+	}
+
+	// A success resolves the incident; a new run of failures alerts again
+	n.RecordSuccess("runtime-1")
+	for i := 0; i < 2; i++ {
+		n.RecordFailure("cleanup", "runtime-1", deleteErr)
+	}
+	if len(received()) != 2 {
+		t.Errorf("Expected failure count to reset after success, got %d alerts", len(received()))
+	}
+	n.RecordFailure("cleanup", "runtime-1", deleteErr)
+	if len(received()) != 3 {
+		t.Errorf("Expected a new alert for a new incident, got %d alerts", len(received()))
+	}
+}
+
+func TestNotifier_Disabled(t *testing.T) {
+	server, received := newTestWebhook(t)
+
+	var nilNotifier *Notifier
+	nilNotifier.RecordFailure("cleanup", "runtime-1", nil)
+	nilNotifier.RecordSuccess("runtime-1")
+
+	noThreshold := NewNotifier(server.URL, 0)
+	noWebhook := NewNotifier("", 1)
+	for i := 0; i < 5; i++ {
+		noThreshold.RecordFailure("cleanup", "runtime-1", nil)
+		noWebhook.RecordFailure("cleanup", "runtime-1", nil)
+	}
+
+	if len(received()) != 0 {
+		t.Errorf("Expected no alerts when disabled, got %d", len(received()))
+	}
+}
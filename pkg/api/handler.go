@@ -4,24 +4,40 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/cleanup"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/config"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/k8s"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/logger"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/metrics"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/reaper"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/state"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/types"
 	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // Handler handles HTTP requests
@@ -30,6 +46,30 @@ type Handler struct {
 	stateMgr     *state.StateManager
 	config       *config.Config
 	tracedClient *http.Client
+	cleanupSvc   *cleanup.Service
+	reaper       *reaper.Reaper
+	startLocks   *sessionLocks
+	imageLimiter *imageLimiter
+	operations   *operationStore
+
+	// readiness caches the Kubernetes API check behind GET /readiness
+	readiness readinessCache
+
+	// activeWebSockets counts upgraded connections currently proxied by ProxySandbox
+	activeWebSockets atomic.Int64
+
+	// sandboxDialContext, when set, replaces the dialer used by ProxySandbox (tests)
+	sandboxDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// proxyTransport is shared by all proxied requests so backend connections are reused;
+	// built on first use by sandboxTransport
+	proxyTransportOnce sync.Once
+	proxyTransport     http.RoundTripper
+
+	// Transports for runtimes with a proxy timeout override, keyed by the timeout, so
+	// runtimes sharing an override also share backend connections
+	proxyTransportsMu sync.Mutex
+	proxyTransports   map[time.Duration]http.RoundTripper
 }
 
 // NewHandler creates a new API handler
@@ -39,9 +79,24 @@ func NewHandler(k8sClient *k8s.Client, stateMgr *state.StateManager, cfg *config
 		stateMgr:     stateMgr,
 		config:       cfg,
 		tracedClient: httptrace.WrapClient(http.DefaultClient),
+		startLocks:   newSessionLocks(cfg.StartDedupTTL),
+		imageLimiter: newImageLimiter(cfg.MaxConcurrentCreatesPerImage),
+		operations:   newOperationStore(cfg.OperationTTL),
 	}
 }
 
+// StartSweeper periodically drops expired per-session /start bookkeeping until ctx is done
+func (h *Handler) StartSweeper(ctx context.Context) {
+	go h.startLocks.run(ctx)
+}
+
+// SetStatsSources sets the background services reported by the stats endpoint.
+// Either may be nil, in which case its section of the response is left zero-valued.
+func (h *Handler) SetStatsSources(cleanupSvc *cleanup.Service, reaperInstance *reaper.Reaper) {
+	h.cleanupSvc = cleanupSvc
+	h.reaper = reaperInstance
+}
+
 // pathIsSandboxProxy returns true if the request is for /sandbox/{runtime_id}/...
 // These requests are reverse-proxied to the sandbox pod. The sandbox validates
 // X-Session-API-Key; the runtime API does not require X-API-Key (management key)
@@ -64,38 +119,114 @@ func pathIsSandboxProxy(r *http.Request) bool {
 func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if pathIsSandboxProxy(r) {
-			logger.Debug("AuthMiddleware: Allowing /sandbox/... (auth by sandbox)")
+			logger.DebugCtx(r.Context(), "AuthMiddleware: Allowing /sandbox/... (auth by sandbox)")
 			next.ServeHTTP(w, r)
 			return
 		}
 		apiKey := r.Header.Get("X-API-Key")
-		logger.Debug("AuthMiddleware: Checking API key for %s %s", r.Method, r.URL.Path)
-		if apiKey == "" || apiKey != h.config.APIKey {
-			logger.Debug("AuthMiddleware: Invalid or missing API key")
-			respondError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing API key")
-			return
+		logger.DebugCtx(r.Context(), "AuthMiddleware: Checking API key for %s %s", r.Method, r.URL.Path)
+		if !h.validAPIKey(apiKey) {
+			if !matchesAnyKey(apiKey, h.config.ReadOnlyAPIKeys) {
+				logger.DebugCtx(r.Context(), "AuthMiddleware: Invalid or missing API key")
+				respondError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing API key")
+				return
+			}
+			if !isReadOnlyRequest(r) {
+				logger.DebugCtx(r.Context(), "AuthMiddleware: Read-only API key used for %s %s", r.Method, r.URL.Path)
+				respondError(w, http.StatusForbidden, "forbidden", "API key is read-only")
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), readOnlyCallerKey{}, true))
 		}
-		logger.Debug("AuthMiddleware: API key validated successfully")
+		logger.DebugCtx(r.Context(), "AuthMiddleware: API key validated successfully")
 		next.ServeHTTP(w, r)
 	})
 }
 
+// validAPIKey reports whether key matches any configured full-access management API key
+func (h *Handler) validAPIKey(key string) bool {
+	return matchesAnyKey(key, append([]string{h.config.APIKey}, h.config.APIKeys...))
+}
+
+// matchesAnyKey reports whether key matches one of keys. Every key is compared in
+// constant time so timing doesn't reveal which one is close.
+func matchesAnyKey(key string, keys []string) bool {
+	if key == "" {
+		return false
+	}
+	valid := false
+	for _, candidate := range keys {
+		if candidate != "" && subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// isReadOnlyRequest reports whether a management request only reads state, and so is
+// allowed with a READONLY_API_KEYS key: any GET or HEAD. The batch conversations lookup
+// is excluded: it calls into sandboxes with their session API keys, which read-only
+// callers must not be able to use.
+func isReadOnlyRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// readOnlyCallerKey marks a request context authenticated with a READONLY_API_KEYS key
+type readOnlyCallerKey struct{}
+
+// isReadOnlyCaller reports whether r was authenticated with a read-only API key. Such
+// callers can inspect sandboxes but never see their session API keys.
+func isReadOnlyCaller(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	readOnly, _ := r.Context().Value(readOnlyCallerKey{}).(bool)
+	return readOnly
+}
+
 // isSandboxHealthCheck returns true for proxied health-check paths (e.g. /sandbox/{id}/alive)
 // so they can be excluded from request logging to reduce noise.
 func isSandboxHealthCheck(path string) bool {
 	return strings.HasSuffix(path, "/alive")
 }
 
-// LoggingMiddleware logs requests
+// requestIDHeader carries the ID correlating runtime API and sandbox log lines for a request
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds caller-supplied request IDs; longer ones are replaced
+const maxRequestIDLength = 128
+
+// requestID returns the caller's X-Request-ID if it is usable, otherwise a new one
+func requestID(r *http.Request) string {
+	id := r.Header.Get(requestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength {
+		return generateID()
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return generateID()
+		}
+	}
+	return id
+}
+
+// LoggingMiddleware logs requests. Every request gets an X-Request-ID (the caller's, or a
+// generated one) that is echoed in the response, attached to log lines via the request
+// context and forwarded to the sandbox by ProxySandbox.
 func (h *Handler) LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestID(r)
+		r.Header.Set(requestIDHeader, id)
+		w.Header().Set(requestIDHeader, id)
+		r = r.WithContext(logger.WithRequestID(r.Context(), id))
+
 		if isSandboxHealthCheck(r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		start := time.Now()
-		logger.Info("Started %s %s", r.Method, r.URL.Path)
+		logger.InfoCtx(r.Context(), "Started %s %s", r.Method, r.URL.Path)
 
 		// Log request details in debug mode
 		// ⚠️ SECURITY WARNING: Debug mode logs complete request headers and bodies
@@ -103,16 +234,16 @@ func (h *Handler) LoggingMiddleware(next http.Handler) http.Handler {
 		// credentials, session tokens, and environment variables.
 		// Only enable debug mode in secure, controlled environments.
 		if logger.IsDebugEnabled() {
-			logger.Debug("Request Headers: %v", r.Header)
+			logger.DebugCtx(r.Context(), "Request Headers: %v", r.Header)
 			if r.Body != nil {
 				// Read body for logging, then restore it
 				bodyBytes, err := io.ReadAll(r.Body)
 				if err == nil {
-					logger.Debug("Request Body: %s", string(bodyBytes))
+					logger.DebugCtx(r.Context(), "Request Body: %s", string(bodyBytes))
 					// Restore body for handler
 					r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 				} else {
-					logger.Debug("Request Body: <unable to read: %v>", err)
+					logger.DebugCtx(r.Context(), "Request Body: <unable to read: %v>", err)
 					// On error, restore an empty body to prevent nil pointer issues
 					r.Body = io.NopCloser(bytes.NewReader([]byte{}))
 				}
@@ -120,46 +251,163 @@ func (h *Handler) LoggingMiddleware(next http.Handler) http.Handler {
 		}
 
 		next.ServeHTTP(w, r)
-		logger.Info("Completed %s %s in %v", r.Method, r.URL.Path, time.Since(start))
+		logger.InfoCtx(r.Context(), "Completed %s %s in %v", r.Method, r.URL.Path, time.Since(start))
 	})
 }
 
 // StartRuntime handles POST /start
+// With wait=true (or wait_for_ready in the body) it responds only once the new sandbox's
+// pod is ready, or with 504 after K8S_OPERATION_TIMEOUT while the sandbox keeps starting.
 func (h *Handler) StartRuntime(w http.ResponseWriter, r *http.Request) {
 	var req types.StartRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Debug("StartRuntime: Failed to decode request body: %v", err)
+		logger.DebugCtx(r.Context(), "StartRuntime: Failed to decode request body: %v", err)
 		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
 		return
 	}
 
-	logger.Debug("StartRuntime: Request decoded - SessionID: %s, Image: %s", req.SessionID, req.Image)
+	logger.DebugCtx(r.Context(), "StartRuntime: Request decoded - SessionID: %s, Image: %s", req.SessionID, req.Image)
 
 	// Validate required fields
 	if req.Image == "" {
-		logger.Debug("StartRuntime: Missing required field 'image'")
+		logger.DebugCtx(r.Context(), "StartRuntime: Missing required field 'image'")
 		respondError(w, http.StatusBadRequest, "invalid_request", "Image is required")
 		return
 	}
 	if req.SessionID == "" {
-		logger.Debug("StartRuntime: Missing required field 'session_id'")
+		logger.DebugCtx(r.Context(), "StartRuntime: Missing required field 'session_id'")
 		respondError(w, http.StatusBadRequest, "invalid_request", "Session ID is required")
 		return
 	}
+	if req.GPU != nil && req.GPU.Count < 0 {
+		logger.DebugCtx(r.Context(), "StartRuntime: Invalid GPU count %d", req.GPU.Count)
+		respondError(w, http.StatusBadRequest, "invalid_request", "GPU count must not be negative")
+		return
+	}
+	if req.GPU != nil && req.GPU.ResourceName != "" && !k8s.IsExtendedResourceName(req.GPU.ResourceName) {
+		logger.DebugCtx(r.Context(), "StartRuntime: Invalid GPU resource name %q", req.GPU.ResourceName)
+		respondError(w, http.StatusBadRequest, "invalid_request", "gpu.resource_name must be a domain-qualified extended resource such as nvidia.com/gpu")
+		return
+	}
+	if req.TTLSeconds < 0 {
+		logger.DebugCtx(r.Context(), "StartRuntime: Invalid ttl_seconds %d", req.TTLSeconds)
+		respondError(w, http.StatusBadRequest, "invalid_request", "ttl_seconds must not be negative")
+		return
+	}
+	if req.ProxyTimeoutSeconds < 0 {
+		logger.DebugCtx(r.Context(), "StartRuntime: Invalid proxy_timeout_seconds %d", req.ProxyTimeoutSeconds)
+		respondError(w, http.StatusBadRequest, "invalid_request", "proxy_timeout_seconds must not be negative")
+		return
+	}
+	if req.IdleTimeoutMinutes < 0 {
+		logger.DebugCtx(r.Context(), "StartRuntime: Invalid idle_timeout_minutes %d", req.IdleTimeoutMinutes)
+		respondError(w, http.StatusBadRequest, "invalid_request", "idle_timeout_minutes must not be negative")
+		return
+	}
+	if err := validateSchedulingHint(req.SchedulingHint); err != nil {
+		logger.DebugCtx(r.Context(), "StartRuntime: Invalid scheduling_hint: %v", err)
+		respondError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Invalid scheduling_hint: %v", err))
+		return
+	}
+	if err := validateEnvironment(req.Environment); err != nil {
+		logger.DebugCtx(r.Context(), "StartRuntime: Invalid environment: %v", err)
+		respondError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Invalid environment: %v", err))
+		return
+	}
+	if err := validatePodMetadata(req.PodLabels, req.PodAnnotations); err != nil {
+		logger.DebugCtx(r.Context(), "StartRuntime: Invalid pod metadata: %v", err)
+		respondError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Invalid pod metadata: %v", err))
+		return
+	}
+	if err := h.validateVolumes(req.Volumes); err != nil {
+		logger.DebugCtx(r.Context(), "StartRuntime: Invalid volumes: %v", err)
+		respondError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Invalid volumes: %v", err))
+		return
+	}
+	if req.InitContainer != nil {
+		if err := h.validateInitContainer(req.InitContainer); err != nil {
+			logger.DebugCtx(r.Context(), "StartRuntime: Invalid init_container: %v", err)
+			if errors.Is(err, errInitContainerImageNotAllowed) {
+				respondError(w, http.StatusForbidden, "init_container_not_allowed", err.Error())
+			} else {
+				respondError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Invalid init_container: %v", err))
+			}
+			return
+		}
+	}
+	if err := validateResourceQuantities(&req); err != nil {
+		logger.DebugCtx(r.Context(), "StartRuntime: Invalid resource quantity: %v", err)
+		respondError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Invalid resources: %v", err))
+		return
+	}
+	if req.OwnWorkspacePVC && req.WorkspacePVCName == "" {
+		logger.DebugCtx(r.Context(), "StartRuntime: own_workspace_pvc set without workspace_pvc_name")
+		respondError(w, http.StatusBadRequest, "invalid_request", "own_workspace_pvc requires workspace_pvc_name")
+		return
+	}
+	if req.WorkspacePVCName != "" {
+		if errs := validation.IsDNS1123Subdomain(req.WorkspacePVCName); len(errs) > 0 {
+			logger.DebugCtx(r.Context(), "StartRuntime: Invalid workspace_pvc_name %q: %v", req.WorkspacePVCName, errs)
+			respondError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Invalid workspace_pvc_name: %s", strings.Join(errs, "; ")))
+			return
+		}
+	}
+
+	// Serialize starts for the same session so concurrent requests can't create duplicate sandboxes
+	unlock := h.startLocks.lock(req.SessionID)
+	defer unlock()
 
 	// Check if runtime already exists for this session
 	if existingRuntime, err := h.stateMgr.GetRuntimeBySessionID(req.SessionID); err == nil {
 		// Runtime exists, return it
-		logger.Debug("StartRuntime: Found existing runtime for session %s: %s", req.SessionID, existingRuntime.RuntimeID)
-		response := h.buildRuntimeResponse(existingRuntime)
+		logger.DebugCtx(r.Context(), "StartRuntime: Found existing runtime for session %s: %s", req.SessionID, existingRuntime.RuntimeID)
+		response := h.buildRuntimeResponse(r, existingRuntime)
 		respondJSON(w, http.StatusOK, response)
 		return
 	}
 
+	// The session may still have a live pod in the cluster (e.g. state was lost, or the
+	// runtime was created by another replica); adopt it rather than creating a duplicate.
+	if h.config.ReuseExistingSandboxes && h.k8sClient != nil {
+		discoverCtx, discoverCancel := context.WithTimeout(r.Context(), h.config.K8sQueryTimeout)
+		discovered, err := h.k8sClient.DiscoverRuntimeBySessionID(discoverCtx, req.SessionID)
+		discoverCancel()
+		if err != nil {
+			logger.DebugCtx(r.Context(), "StartRuntime: Failed to discover existing sandbox for session %s: %v", req.SessionID, err)
+		} else if discovered != nil {
+			logger.InfoCtx(r.Context(), "StartRuntime: Adopting existing sandbox %s for session %s", discovered.RuntimeID, req.SessionID)
+			h.stateMgr.AddRuntime(discovered)
+			response := h.buildRuntimeResponse(r, discovered)
+			respondJSON(w, http.StatusOK, response)
+			return
+		}
+	}
+
+	// A referenced workspace PVC must exist and not be held by another sandbox
+	if req.WorkspacePVCName != "" {
+		pvcCtx, pvcCancel := context.WithTimeout(r.Context(), h.config.K8sQueryTimeout)
+		err := h.k8sClient.ValidateWorkspacePVC(pvcCtx, req.WorkspacePVCName)
+		pvcCancel()
+		switch {
+		case errors.Is(err, k8s.ErrWorkspacePVCNotFound):
+			logger.DebugCtx(r.Context(), "StartRuntime: %v", err)
+			respondError(w, http.StatusBadRequest, "workspace_pvc_not_found", fmt.Sprintf("Workspace PVC %q does not exist", req.WorkspacePVCName))
+			return
+		case errors.Is(err, k8s.ErrWorkspacePVCInUse):
+			logger.DebugCtx(r.Context(), "StartRuntime: %v", err)
+			respondError(w, http.StatusConflict, "workspace_pvc_in_use", err.Error())
+			return
+		case err != nil:
+			logger.ErrorCtx(r.Context(), "StartRuntime: Failed to validate workspace PVC %s: %v", req.WorkspacePVCName, err)
+			respondError(w, http.StatusInternalServerError, "workspace_pvc_check_failed", fmt.Sprintf("Failed to validate workspace PVC: %v", err))
+			return
+		}
+	}
+
 	// Generate runtime ID and session API key
 	runtimeID := generateID()
 	sessionAPIKey := generateSessionAPIKey()
-	logger.Debug("StartRuntime: Generated RuntimeID: %s, SessionID: %s", runtimeID, req.SessionID)
+	logger.DebugCtx(r.Context(), "StartRuntime: Generated RuntimeID: %s, SessionID: %s", runtimeID, req.SessionID)
 
 	// Session ID for hostnames must be lowercase (RFC 1123 subdomain); keep original for lookups
 	sessionIDForHost := strings.ToLower(req.SessionID)
@@ -176,254 +424,1068 @@ func (h *Handler) StartRuntime(w http.ResponseWriter, r *http.Request) {
 		IngressName:      fmt.Sprintf("runtime-%s", runtimeID),
 		CreatedAt:        time.Now(),
 		LastActivityTime: time.Now(),
-		WorkHosts: map[string]int{
-			fmt.Sprintf("https://work-1-%s.%s", sessionIDForHost, h.config.BaseDomain): h.config.Worker1Port,
-			fmt.Sprintf("https://work-2-%s.%s", sessionIDForHost, h.config.BaseDomain): h.config.Worker2Port,
-		},
+		TTL:              time.Duration(req.TTLSeconds) * time.Second,
+		IdleTimeout:      h.idleTimeoutOverride(req.IdleTimeoutMinutes),
+		ProxyTimeout:     h.proxyTimeoutOverride(req.ProxyTimeoutSeconds),
+		WorkHosts:        k8s.WorkHosts(h.config, req.SessionID),
+		WorkspacePVCName: req.WorkspacePVCName,
+		Protected:        req.Protected,
+	}
+	runtimeInfo.CPURequestMillis, runtimeInfo.MemoryRequestBytes = k8s.RequestedResources(h.config, &req)
+	original := req
+	runtimeInfo.StartRequest = &original
+	if span, ok := tracer.SpanFromContext(r.Context()); ok {
+		runtimeInfo.StartTraceID, runtimeInfo.StartSpanID = span.Context().TraceID(), span.Context().SpanID()
 	}
 
-	logger.Debug("StartRuntime: Runtime info created - URL: %s, PodName: %s", runtimeInfo.URL, runtimeInfo.PodName)
+	logger.DebugCtx(r.Context(), "StartRuntime: Runtime info created - URL: %s, PodName: %s", runtimeInfo.URL, runtimeInfo.PodName)
 
-	// Add to state
-	h.stateMgr.AddRuntime(runtimeInfo)
-	logger.Debug("StartRuntime: Added runtime to state manager")
+	// Add to state, unless that would exceed MAX_SANDBOXES, MAX_TOTAL_CPU or MAX_TOTAL_MEMORY
+	switch err := h.stateMgr.AddRuntimeWithinLimits(runtimeInfo, h.admissionLimits()); {
+	case errors.Is(err, state.ErrRuntimeLimitReached):
+		logger.WarnCtx(r.Context(), "StartRuntime: Rejecting sandbox for session %s: limit of %d sandboxes reached", req.SessionID, h.config.MaxSandboxes)
+		respondError(w, http.StatusTooManyRequests, "sandbox_limit_reached", fmt.Sprintf("Sandbox limit of %d reached; stop an existing sandbox and retry", h.config.MaxSandboxes))
+		return
+	case errors.Is(err, state.ErrResourceCeilingReached):
+		cpuMillis, memoryBytes := h.stateMgr.CommittedResources()
+		logger.WarnCtx(r.Context(), "StartRuntime: Rejecting sandbox for session %s: requesting %dm CPU / %dMi memory on top of %dm / %dMi committed would exceed the resource ceiling",
+			req.SessionID, runtimeInfo.CPURequestMillis, runtimeInfo.MemoryRequestBytes>>20, cpuMillis, memoryBytes>>20)
+		respondError(w, http.StatusTooManyRequests, "resource_ceiling", "Sandbox would exceed the configured CPU or memory ceiling; stop an existing sandbox and retry")
+		return
+	}
+	logger.DebugCtx(r.Context(), "StartRuntime: Added runtime to state manager")
+
+	// In async mode the pending runtime is returned as an operation right away and the
+	// sandbox is created in the background
+	if req.Async || r.URL.Query().Get("async") == "true" {
+		op := h.operations.create(runtimeID)
+		logger.InfoCtx(r.Context(), "StartRuntime: Creating runtime %s for session %s asynchronously (operation %s)", runtimeID, req.SessionID, op.OperationID)
+		// Detached from the request's cancellation but keeping its request ID and trace
+		go h.createRuntimeAsync(r.Clone(context.WithoutCancel(r.Context())), op.OperationID, req, runtimeInfo)
+		w.Header().Set("Location", "/operations/"+op.OperationID)
+		respondJSON(w, http.StatusAccepted, op)
+		return
+	}
 
 	// Create sandbox in Kubernetes with operation timeout
 	ctx, cancel := context.WithTimeout(r.Context(), h.config.K8sOperationTimeout)
 	defer cancel()
-	logger.Debug("StartRuntime: Creating sandbox in Kubernetes...")
-	if err := h.k8sClient.CreateSandbox(ctx, &req, runtimeInfo); err != nil {
-		// Remove from state on failure
-		_ = h.stateMgr.DeleteRuntime(runtimeID)
-		logger.Info("Failed to create sandbox: %v", err)
-		respondError(w, http.StatusInternalServerError, "sandbox_creation_failed", fmt.Sprintf("Failed to create sandbox: %v", err))
+	created, startErr := h.createRuntime(ctx, &req, runtimeInfo)
+	if startErr != nil {
+		respondError(w, startErr.status, startErr.code, startErr.message)
+		return
+	}
+	if created != runtimeInfo {
+		respondJSON(w, http.StatusOK, h.buildRuntimeResponse(r, created))
 		return
 	}
 
-	logger.Debug("StartRuntime: Sandbox created successfully")
+	if req.WaitForReady || r.URL.Query().Get("wait") == "true" {
+		logger.DebugCtx(r.Context(), "StartRuntime: Waiting for pod %s to become ready", runtimeInfo.PodName)
+		if err := h.k8sClient.WaitForPodReady(r.Context(), runtimeInfo.PodName, h.config.K8sOperationTimeout); err != nil {
+			// The sandbox is left running; the caller can poll GET /runtime/{id} for readiness
+			if errors.Is(err, k8s.ErrPodReadyTimeout) {
+				logger.WarnCtx(r.Context(), "StartRuntime: Runtime %s not ready after %s", runtimeID, h.config.K8sOperationTimeout)
+				respondError(w, http.StatusGatewayTimeout, "runtime_not_ready",
+					fmt.Sprintf("Runtime %s was created but is not ready yet; poll GET /runtime/%s", runtimeID, runtimeID))
+				return
+			}
+			logger.ErrorCtx(r.Context(), "StartRuntime: Runtime %s failed to become ready: %v", runtimeID, err)
+			respondError(w, http.StatusInternalServerError, "runtime_not_ready",
+				fmt.Sprintf("Runtime %s was created but failed to become ready: %v", runtimeID, err))
+			return
+		}
+		runtimeInfo.PodStatus = types.PodStatusReady
+		_ = h.stateMgr.UpdateRuntime(runtimeInfo)
+	}
+
+	// Build and return response
+	response := h.buildRuntimeResponse(r, runtimeInfo)
+	logger.DebugCtx(r.Context(), "StartRuntime: Returning response for runtime %s", runtimeID)
+	respondJSON(w, http.StatusOK, response)
+}
+
+// startError is a /start failure after the runtime was added to state, with the HTTP
+// status and error code to report it as
+type startError struct {
+	status  int
+	code    string
+	message string
+}
+
+// createRuntime creates the Kubernetes resources for a runtime already added to state as
+// pending, and marks it running. If a sandbox is already left over for the session
+// (e.g. from a start interrupted by a crash), that runtime is adopted and returned
+// instead. On failure the runtime is removed from state.
+func (h *Handler) createRuntime(ctx context.Context, req *types.StartRequest, runtimeInfo *state.RuntimeInfo) (*state.RuntimeInfo, *startError) {
+	// Queue behind other creations of the same image so a cold image isn't pulled by every node at once
+	release, err := h.imageLimiter.acquire(ctx, req.Image)
+	if err != nil {
+		_ = h.stateMgr.DeleteRuntime(runtimeInfo.RuntimeID)
+		metrics.SandboxCreateFailures.Inc("throttled", metrics.ImageBucket(req.Image))
+		logger.WarnCtx(ctx, "StartRuntime: Timed out waiting for a creation slot for image %s: %v", req.Image, err)
+		return nil, &startError{http.StatusServiceUnavailable, "image_create_throttled", fmt.Sprintf("Timed out waiting to create sandbox for image %s", req.Image)}
+	}
+
+	logger.DebugCtx(ctx, "StartRuntime: Creating sandbox in Kubernetes...")
+	if err := h.k8sClient.CreateSandbox(ctx, req, runtimeInfo); err != nil {
+		release()
+		// Remove from state on failure
+		_ = h.stateMgr.DeleteRuntime(runtimeInfo.RuntimeID)
+
+		var exists *k8s.SandboxExistsError
+		if errors.As(err, &exists) {
+			logger.InfoCtx(ctx, "StartRuntime: Adopting existing sandbox %s for session %s", exists.Runtime.RuntimeID, req.SessionID)
+			h.stateMgr.AddRuntime(exists.Runtime)
+			return exists.Runtime, nil
+		}
+
+		metrics.SandboxCreateFailures.Inc(createFailureReason(err), metrics.ImageBucket(req.Image))
+		logger.ErrorCtx(ctx, "Failed to create sandbox: %v", err)
+		return nil, &startError{http.StatusInternalServerError, "sandbox_creation_failed", fmt.Sprintf("Failed to create sandbox: %v", err)}
+	}
+
+	logger.DebugCtx(ctx, "StartRuntime: Sandbox created successfully")
+	metrics.SandboxStarts.Inc(metrics.ImageBucket(req.Image))
+	h.holdImageSlot(ctx, runtimeInfo, release)
 
 	// Update status to running
 	runtimeInfo.Status = types.StatusRunning
 	_ = h.stateMgr.UpdateRuntime(runtimeInfo)
-	logger.Debug("StartRuntime: Updated runtime status to running")
+	_ = h.stateMgr.RecordEvent(runtimeInfo.RuntimeID, types.RuntimeEventCreated, "image "+req.Image)
+	logger.DebugCtx(ctx, "StartRuntime: Updated runtime status to running")
+	return runtimeInfo, nil
+}
 
-	// Build and return response
-	response := h.buildRuntimeResponse(runtimeInfo)
-	logger.Debug("StartRuntime: Returning response for runtime %s", runtimeID)
-	respondJSON(w, http.StatusOK, response)
+// holdImageSlot keeps a created runtime's per-image creation slot until its pod has
+// pulled the image and started (or failed, or was deleted), bounded by
+// IMAGE_PULL_SLOT_TIMEOUT. Creating the API objects takes milliseconds; the pull the
+// limit exists for only begins once the pod is scheduled. Teardown releases it early.
+func (h *Handler) holdImageSlot(ctx context.Context, runtimeInfo *state.RuntimeInfo, release func()) {
+	if !h.imageLimiter.enabled() {
+		return
+	}
+	h.imageLimiter.hold(runtimeInfo.RuntimeID, release)
+	runtimeID, podName := runtimeInfo.RuntimeID, runtimeInfo.PodName
+	go func() {
+		defer h.imageLimiter.releaseHeld(runtimeID)
+		if err := h.k8sClient.WaitForPodStarted(context.WithoutCancel(ctx), podName, h.config.ImagePullSlotTimeout); err != nil {
+			logger.WarnCtx(ctx, "StartRuntime: Releasing image creation slot for runtime %s: %v", runtimeID, err)
+		}
+	}()
+}
+
+// createRuntimeAsync runs createRuntime for an async /start and records the outcome in
+// the operation store for GET /operations/{op_id}. origin is a copy of the /start request,
+// whose context and host are used after the handler has returned.
+func (h *Handler) createRuntimeAsync(origin *http.Request, operationID string, req types.StartRequest, runtimeInfo *state.RuntimeInfo) {
+	ctx, cancel := context.WithTimeout(origin.Context(), h.config.K8sOperationTimeout)
+	defer cancel()
+
+	created, startErr := h.createRuntime(ctx, &req, runtimeInfo)
+	if startErr != nil {
+		logger.WarnCtx(ctx, "StartRuntime: Operation %s failed: %s", operationID, startErr.message)
+		h.operations.fail(operationID, startErr.code, startErr.message)
+		return
+	}
+	response := h.buildRuntimeResponse(origin, created)
+	h.operations.succeed(operationID, &response)
+	logger.InfoCtx(ctx, "StartRuntime: Operation %s created runtime %s", operationID, created.RuntimeID)
+}
+
+// GetOperation handles GET /operations/{op_id}, reporting whether an async /start is still
+// pending, succeeded (with the runtime) or failed (with the error)
+func (h *Handler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	operationID := mux.Vars(r)["op_id"]
+
+	op, ok := h.operations.get(operationID)
+	if !ok {
+		respondError(w, http.StatusNotFound, "operation_not_found", "Operation not found or expired")
+		return
+	}
+	respondJSON(w, http.StatusOK, op)
 }
 
 // StopRuntime handles POST /stop
 func (h *Handler) StopRuntime(w http.ResponseWriter, r *http.Request) {
 	var req types.StopRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Debug("StopRuntime: Failed to decode request body: %v", err)
+		logger.DebugCtx(r.Context(), "StopRuntime: Failed to decode request body: %v", err)
 		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
 		return
 	}
 
-	logger.Debug("StopRuntime: Request decoded - RuntimeID: %s", req.RuntimeID)
+	logger.DebugCtx(r.Context(), "StopRuntime: Request decoded - RuntimeID: %s", req.RuntimeID)
 
 	runtimeInfo, err := h.stateMgr.GetRuntimeByID(req.RuntimeID)
 	if err != nil {
-		logger.Debug("StopRuntime: Runtime not found: %s", req.RuntimeID)
+		logger.DebugCtx(r.Context(), "StopRuntime: Runtime not found: %s", req.RuntimeID)
 		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
 		return
 	}
 
-	logger.Debug("StopRuntime: Deleting sandbox for runtime %s (Pod: %s)", req.RuntimeID, runtimeInfo.PodName)
+	if err := h.teardownRuntime(r.Context(), runtimeInfo); err != nil {
+		logger.ErrorCtx(r.Context(), "Failed to delete sandbox: %v", err)
+		respondError(w, http.StatusInternalServerError, "sandbox_deletion_failed", fmt.Sprintf("Failed to delete sandbox: %v", err))
+		return
+	}
+
+	response := h.buildRuntimeResponse(r, runtimeInfo)
+	respondJSON(w, http.StatusOK, response)
+}
 
-	ctx, cancel := context.WithTimeout(r.Context(), h.config.K8sOperationTimeout)
-	defer cancel()
-	if err := h.k8sClient.DeleteSandbox(ctx, runtimeInfo); err != nil {
-		logger.Info("Failed to delete sandbox: %v", err)
+// DeleteRuntime handles DELETE /runtime/{runtime_id}
+func (h *Handler) DeleteRuntime(w http.ResponseWriter, r *http.Request) {
+	runtimeID := mux.Vars(r)["runtime_id"]
+
+	runtimeInfo, err := h.stateMgr.GetRuntimeByID(runtimeID)
+	if err != nil {
+		logger.DebugCtx(r.Context(), "DeleteRuntime: Runtime not found: %s", runtimeID)
+		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
+		return
+	}
+
+	if err := h.teardownRuntime(r.Context(), runtimeInfo); err != nil {
+		logger.ErrorCtx(r.Context(), "Failed to delete sandbox: %v", err)
 		respondError(w, http.StatusInternalServerError, "sandbox_deletion_failed", fmt.Sprintf("Failed to delete sandbox: %v", err))
 		return
 	}
 
-	logger.Debug("StopRuntime: Sandbox deleted successfully")
+	response := h.buildRuntimeResponse(r, runtimeInfo)
+	respondJSON(w, http.StatusOK, response)
+}
+
+// teardownRuntime deletes the runtime's Kubernetes resources, marks it stopped and
+// removes it from state. Shared by POST /stop and DELETE /runtime/{runtime_id}.
+func (h *Handler) teardownRuntime(ctx context.Context, runtimeInfo *state.RuntimeInfo) error {
+	unlock := h.stateMgr.LockRuntime(runtimeInfo.RuntimeID)
+	defer unlock()
+	live, err := h.stateMgr.GetRuntimeByID(runtimeInfo.RuntimeID)
+	if err != nil {
+		// The reaper or cleanup tore it down while we waited; the stop has happened
+		logger.DebugCtx(ctx, "teardownRuntime: Runtime %s already torn down", runtimeInfo.RuntimeID)
+		runtimeInfo.Status = types.StatusStopped
+		return nil
+	}
+	previous := live.Status
+	if !h.stateMgr.TransitionStatus(runtimeInfo.RuntimeID, previous, types.StatusStopping) {
+		logger.DebugCtx(ctx, "teardownRuntime: Runtime %s is already being torn down", runtimeInfo.RuntimeID)
+		runtimeInfo.Status = types.StatusStopped
+		return nil
+	}
+
+	logger.DebugCtx(ctx, "teardownRuntime: Deleting sandbox for runtime %s (Pod: %s)", runtimeInfo.RuntimeID, runtimeInfo.PodName)
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.K8sOperationTimeout)
+	defer cancel()
+	if err := h.k8sClient.DeleteSandbox(ctx, runtimeInfo); err != nil {
+		h.stateMgr.TransitionStatus(runtimeInfo.RuntimeID, types.StatusStopping, previous)
+		return err
+	}
+
+	logger.DebugCtx(ctx, "teardownRuntime: Sandbox deleted successfully")
+	h.imageLimiter.releaseHeld(runtimeInfo.RuntimeID)
 
 	// Update status
 	runtimeInfo.Status = types.StatusStopped
 	_ = h.stateMgr.UpdateRuntime(runtimeInfo)
+	_ = h.stateMgr.RecordEvent(runtimeInfo.RuntimeID, types.RuntimeEventStopped, "")
 
 	// Remove from state
-	_ = h.stateMgr.DeleteRuntime(req.RuntimeID)
-	logger.Debug("StopRuntime: Removed runtime from state")
-
-	response := h.buildRuntimeResponse(runtimeInfo)
-	respondJSON(w, http.StatusOK, response)
+	_ = h.stateMgr.DeleteRuntime(runtimeInfo.RuntimeID)
+	h.stateMgr.Tombstone(runtimeInfo)
+	metrics.SandboxStops.Inc()
+	logger.DebugCtx(ctx, "teardownRuntime: Removed runtime %s from state", runtimeInfo.RuntimeID)
+	return nil
 }
 
 // PauseRuntime handles POST /pause
 func (h *Handler) PauseRuntime(w http.ResponseWriter, r *http.Request) {
 	var req types.PauseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Debug("PauseRuntime: Failed to decode request body: %v", err)
+		logger.DebugCtx(r.Context(), "PauseRuntime: Failed to decode request body: %v", err)
+		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	logger.DebugCtx(r.Context(), "PauseRuntime: Request decoded - RuntimeID: %s", req.RuntimeID)
+
+	runtimeInfo, err := h.stateMgr.GetRuntimeByID(req.RuntimeID)
+	if err != nil {
+		logger.DebugCtx(r.Context(), "PauseRuntime: Runtime not found: %s", req.RuntimeID)
+		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
+		return
+	}
+
+	logger.DebugCtx(r.Context(), "PauseRuntime: Scaling pod to zero for runtime %s (Pod: %s)", req.RuntimeID, runtimeInfo.PodName)
+
+	// For pause, we delete the pod but keep the state
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.K8sOperationTimeout)
+	defer cancel()
+	if err := h.k8sClient.ScalePodToZero(ctx, runtimeInfo.PodName); err != nil {
+		logger.ErrorCtx(r.Context(), "Failed to pause runtime: %v", err)
+		_ = h.stateMgr.RecordEvent(req.RuntimeID, types.RuntimeEventError, fmt.Sprintf("pause failed: %v", err))
+		respondError(w, http.StatusInternalServerError, "pause_failed", fmt.Sprintf("Failed to pause runtime: %v", err))
+		return
+	}
+
+	logger.DebugCtx(r.Context(), "PauseRuntime: Pod scaled to zero successfully")
+
+	// Update status
+	runtimeInfo.Status = types.StatusPaused
+	runtimeInfo.PodStatus = types.PodStatusNotFound
+	_ = h.stateMgr.UpdateRuntime(runtimeInfo)
+	_ = h.stateMgr.RecordEvent(req.RuntimeID, types.RuntimeEventPaused, "")
+	logger.DebugCtx(r.Context(), "PauseRuntime: Updated runtime status to paused")
+
+	response := h.buildRuntimeResponse(r, runtimeInfo)
+	respondJSON(w, http.StatusOK, response)
+}
+
+// ResumeRuntime handles POST /resume
+func (h *Handler) ResumeRuntime(w http.ResponseWriter, r *http.Request) {
+	var req types.ResumeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.DebugCtx(r.Context(), "ResumeRuntime: Failed to decode request body: %v", err)
 		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
 		return
 	}
 
-	logger.Debug("PauseRuntime: Request decoded - RuntimeID: %s", req.RuntimeID)
+	logger.DebugCtx(r.Context(), "ResumeRuntime: Request decoded - RuntimeID: %s", req.RuntimeID)
+
+	runtimeInfo, err := h.stateMgr.GetRuntimeByID(req.RuntimeID)
+	if err != nil {
+		logger.DebugCtx(r.Context(), "ResumeRuntime: Runtime not found: %s", req.RuntimeID)
+		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
+		return
+	}
+
+	// Serialize with /start and resume-on-access for the same session so only one of
+	// them recreates the pod; the status is checked under the lock
+	unlock := h.startLocks.lock(runtimeInfo.SessionID)
+	defer unlock()
+
+	// Read under the state lock: a resume-on-access may have just marked it running
+	status, err := h.stateMgr.GetStatus(runtimeInfo.RuntimeID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
+		return
+	}
+
+	// Already running: no-op (e.g. WebSocket recovery calls resume for running sandboxes)
+	if status == types.StatusRunning {
+		logger.DebugCtx(r.Context(), "ResumeRuntime: Runtime %s already running, no-op", req.RuntimeID)
+		response := h.buildRuntimeResponse(r, runtimeInfo)
+		respondJSON(w, http.StatusOK, response)
+		return
+	}
+
+	if status != types.StatusPaused {
+		logger.DebugCtx(r.Context(), "ResumeRuntime: Runtime %s is not paused (status: %s)", req.RuntimeID, status)
+		respondError(w, http.StatusBadRequest, "invalid_state", "Runtime is not paused")
+		return
+	}
+
+	if err := h.resumeRuntime(r.Context(), runtimeInfo); err != nil {
+		logger.ErrorCtx(r.Context(), "Failed to resume runtime: %v", err)
+		respondError(w, http.StatusInternalServerError, "resume_failed", fmt.Sprintf("Failed to resume runtime: %v", err))
+		return
+	}
+
+	response := h.buildRuntimeResponse(r, runtimeInfo)
+	respondJSON(w, http.StatusOK, response)
+}
+
+// resumeRuntime recreates a paused runtime's pod and marks it running.
+// Shared by POST /resume and ProxySandbox's resume-on-access; callers hold the session's
+// start lock and have checked the runtime is paused.
+func (h *Handler) resumeRuntime(ctx context.Context, runtimeInfo *state.RuntimeInfo) error {
+	logger.DebugCtx(ctx, "resumeRuntime: Recreating pod for runtime %s", runtimeInfo.RuntimeID)
+
+	startReq := h.resumeRequest(runtimeInfo)
+	ctx, cancel := context.WithTimeout(ctx, h.config.K8sOperationTimeout)
+	defer cancel()
+	if err := h.k8sClient.RecreatePod(ctx, startReq, runtimeInfo); err != nil {
+		_ = h.stateMgr.RecordEvent(runtimeInfo.RuntimeID, types.RuntimeEventError, fmt.Sprintf("resume failed: %v", err))
+		return err
+	}
+
+	logger.DebugCtx(ctx, "resumeRuntime: Pod recreated successfully")
+
+	// Update status under the state lock, since proxied requests read it without the
+	// session lock. Committed totals follow the pod that now exists, which differs from the
+	// one that was paused when a discovered runtime is recreated from defaults.
+	cpuMillis, memoryBytes := k8s.RequestedResources(h.config, startReq)
+	if !h.stateMgr.MarkResumed(runtimeInfo.RuntimeID, cpuMillis, memoryBytes) {
+		return fmt.Errorf("runtime %s is no longer paused", runtimeInfo.RuntimeID)
+	}
+	_ = h.stateMgr.RecordEvent(runtimeInfo.RuntimeID, types.RuntimeEventResumed, "")
+	logger.DebugCtx(ctx, "resumeRuntime: Updated runtime status to running")
+	return nil
+}
+
+// resumeRequest returns the request a paused runtime's pod is recreated from: its original
+// /start request minus the init container, which only runs on first start, or defaults
+// for runtimes discovered from the cluster, whose request was never seen.
+func (h *Handler) resumeRequest(runtimeInfo *state.RuntimeInfo) *types.StartRequest {
+	if runtimeInfo.StartRequest != nil {
+		req := *runtimeInfo.StartRequest
+		req.InitContainer = nil
+		return &req
+	}
+	return &types.StartRequest{
+		Image:      h.config.DefaultImage,
+		Command:    types.FlexibleCommand{"/usr/local/bin/openhands-agent-server", "--port", fmt.Sprintf("%d", h.config.AgentServerPort)},
+		WorkingDir: "/openhands/code/",
+		SessionID:  runtimeInfo.SessionID,
+
+		WorkspacePVCName: runtimeInfo.WorkspacePVCName,
+	}
+}
+
+// sandboxPending reports whether a runtime is still starting: it has no pod yet, or its
+// pod is pending. The stored pod status is only refreshed by status calls, so a pending
+// one is re-checked against Kubernetes before being trusted.
+func (h *Handler) sandboxPending(runtimeInfo *state.RuntimeInfo) bool {
+	if runtimeInfo.Status == types.StatusPending {
+		return true
+	}
+	if runtimeInfo.PodStatus != types.PodStatusPending || h.k8sClient == nil {
+		return false
+	}
+	h.updateRuntimeStatusFromK8s(runtimeInfo)
+	return runtimeInfo.PodStatus == types.PodStatusPending
+}
+
+// respondSandboxStarting writes a 503 sandbox_starting response with the remaining wait
+// estimated from how long the sandbox has been starting against the expected startup time
+func respondSandboxStarting(w http.ResponseWriter, runtimeInfo *state.RuntimeInfo, expected time.Duration) {
+	const minWait = 5 * time.Second
+	wait := max(expected-time.Since(runtimeInfo.CreatedAt), minWait)
+	seconds := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	respondJSON(w, http.StatusServiceUnavailable, types.SandboxStartingResponse{
+		ErrorResponse: types.ErrorResponse{
+			Error:   "sandbox_starting",
+			Message: "Sandbox is starting",
+		},
+		EstimatedWaitSeconds: seconds,
+	})
+}
+
+// resumeForProxy resumes a paused runtime on access and waits for its pod to become
+// ready, so a user opening a paused sandbox's URL gets the sandbox rather than a 502.
+// It writes the error response and returns false if the runtime can't be served yet.
+func (h *Handler) resumeForProxy(w http.ResponseWriter, r *http.Request, runtimeInfo *state.RuntimeInfo) bool {
+	// Serialize with /start, /resume and other proxied requests for the same session so
+	// only one of them recreates the pod. The lock is released before waiting for
+	// readiness so concurrent requests wait in parallel rather than one after another.
+	// The status is re-checked under the lock: another request may have resumed it.
+	unlock := h.startLocks.lock(runtimeInfo.SessionID)
+	if status, _ := h.stateMgr.GetStatus(runtimeInfo.RuntimeID); status == types.StatusPaused {
+		logger.InfoCtx(r.Context(), "ProxySandbox: Resuming paused runtime %s on access", runtimeInfo.RuntimeID)
+		if err := h.resumeRuntime(r.Context(), runtimeInfo); err != nil {
+			unlock()
+			logger.ErrorCtx(r.Context(), "ProxySandbox: Failed to resume runtime %s: %v", runtimeInfo.RuntimeID, err)
+			respondError(w, http.StatusInternalServerError, "resume_failed", fmt.Sprintf("Failed to resume runtime: %v", err))
+			return false
+		}
+	}
+	unlock()
+
+	if err := h.k8sClient.WaitForPodReady(r.Context(), runtimeInfo.PodName, h.config.ProxyResumeTimeout); err != nil {
+		logger.WarnCtx(r.Context(), "ProxySandbox: Runtime %s not ready after resume: %v", runtimeInfo.RuntimeID, err)
+		// The pod keeps starting in the background; a retry shortly after will usually succeed
+		w.Header().Set("Retry-After", "10")
+		respondError(w, http.StatusServiceUnavailable, "runtime_resuming", "Runtime is resuming, retry shortly")
+		return false
+	}
+	return true
+}
+
+// ListRuntimes handles GET /list
+// Optional query parameters: status (running|paused|pending), limit and offset.
+// Runtimes are ordered by creation time so pages are stable between calls.
+func (h *Handler) ListRuntimes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	statusFilter := types.RuntimeStatus(query.Get("status"))
+	switch statusFilter {
+	case "", types.StatusRunning, types.StatusPaused, types.StatusPending:
+	default:
+		respondError(w, http.StatusBadRequest, "invalid_request", "status must be one of running, paused, pending")
+		return
+	}
+	limit, err := parseNonNegativeQueryInt(query.Get("limit"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "limit must be a non-negative integer")
+		return
+	}
+	offset, err := parseNonNegativeQueryInt(query.Get("offset"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "offset must be a non-negative integer")
+		return
+	}
+
+	logger.DebugCtx(r.Context(), "ListRuntimes: Fetching runtimes (status=%q, limit=%d, offset=%d)", statusFilter, limit, offset)
+	all := h.stateMgr.ListRuntimes()
+
+	// Filter before fetching pod statuses so we only query what we return
+	runtimes := make([]*state.RuntimeInfo, 0, len(all))
+	for _, runtime := range all {
+		if statusFilter == "" || runtime.Status == statusFilter {
+			runtimes = append(runtimes, runtime)
+		}
+	}
+	sort.Slice(runtimes, func(i, j int) bool {
+		if !runtimes[i].CreatedAt.Equal(runtimes[j].CreatedAt) {
+			return runtimes[i].CreatedAt.Before(runtimes[j].CreatedAt)
+		}
+		return runtimes[i].RuntimeID < runtimes[j].RuntimeID
+	})
+
+	total := len(runtimes)
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	runtimes = runtimes[offset:end]
+	logger.DebugCtx(r.Context(), "ListRuntimes: Returning %d of %d runtimes", len(runtimes), total)
+
+	// Batch-fetch pod statuses for this page in a single K8s API call. If that fails the
+	// stored statuses are returned flagged as stale.
+	stale := false
+	var statuses map[string]*k8s.PodStatusInfo
+	if h.k8sClient != nil && len(runtimes) > 0 {
+		podNames := make([]string, 0, len(runtimes))
+		for _, runtime := range runtimes {
+			podNames = append(podNames, runtime.PodName)
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), h.config.K8sQueryTimeout)
+		defer cancel()
+		var err error
+		if statuses, err = h.k8sClient.GetPodStatuses(ctx, podNames); err == nil {
+			for _, runtime := range runtimes {
+				if statusInfo, ok := statuses[runtime.PodName]; ok {
+					h.recordPodStatusEvents(runtime, statusInfo)
+					runtime.PodStatus = statusInfo.Status
+					runtime.RestartCount = statusInfo.RestartCount
+					runtime.RestartReasons = statusInfo.RestartReasons
+					runtime.Restarts = statusInfo.Restarts
+					runtime.LastTerminationReason = statusInfo.LastTerminationReason
+					runtime.LastTerminationExitCode = statusInfo.LastTerminationExitCode
+					_ = h.stateMgr.UpdateRuntime(runtime)
+				}
+			}
+		} else {
+			logger.DebugCtx(r.Context(), "ListRuntimes: Failed to batch-fetch pod statuses: %v", err)
+			stale = true
+		}
+	}
+
+	// verbose=true adds the raw pod phase, conditions and container states for operators
+	verbose := r.URL.Query().Get("verbose") == "true"
+	responses := make([]types.RuntimeResponse, 0, len(runtimes))
+	for _, runtime := range runtimes {
+		response := h.buildRuntimeResponse(r, runtime)
+		response.StatusStale = stale
+		if statusInfo, ok := statuses[runtime.PodName]; verbose && ok {
+			response.PodDetail = statusInfo.Detail
+		}
+		responses = append(responses, response)
+	}
+
+	resp := types.ListResponse{Runtimes: responses, Total: total}
+	if end < total {
+		resp.NextOffset = end
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// validateSchedulingHint checks that a scheduling hint names a valid zone and/or node label
+func validateSchedulingHint(hint *types.SchedulingHint) error {
+	if hint == nil {
+		return nil
+	}
+	if hint.Zone == "" && hint.NodeLabel == "" {
+		return fmt.Errorf("zone or node_label is required")
+	}
+	if hint.Zone != "" {
+		if errs := validation.IsValidLabelValue(hint.Zone); len(errs) > 0 {
+			return fmt.Errorf("zone %q: %s", hint.Zone, strings.Join(errs, "; "))
+		}
+	}
+	if hint.NodeLabel != "" {
+		key, value, ok := strings.Cut(hint.NodeLabel, "=")
+		if !ok {
+			return fmt.Errorf("node_label %q must be key=value", hint.NodeLabel)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("node_label key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("node_label value %q: %s", value, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// createFailureReason classifies a CreateSandbox error into a small fixed set of values
+// for the create failures metric
+func createFailureReason(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err):
+		return "timeout"
+	case apierrors.IsTooManyRequests(err):
+		return "throttled"
+	case apierrors.IsForbidden(err):
+		// Includes ResourceQuota rejections
+		return "forbidden"
+	case apierrors.IsInvalid(err):
+		return "invalid"
+	case apierrors.IsAlreadyExists(err):
+		return "already_exists"
+	default:
+		return "other"
+	}
+}
+
+// validateEnvironment checks that every environment key is a name Kubernetes accepts
+// for a container env var, so a bad key fails the request instead of the pod create
+func validateEnvironment(env map[string]string) error {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if errs := validation.IsEnvVarName(key); len(errs) > 0 {
+			return fmt.Errorf("key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// validatePodMetadata checks that custom pod labels and annotations use valid, unreserved
+// keys and that label values are valid, so a bad entry fails the request instead of the
+// pod create
+func validatePodMetadata(labels, annotations map[string]string) error {
+	if err := validateMetadataMap("pod_labels", labels, true); err != nil {
+		return err
+	}
+	return validateMetadataMap("pod_annotations", annotations, false)
+}
+
+// validateMetadataMap validates the keys of one label or annotation map, and its values
+// too when they are label values
+func validateMetadataMap(field string, m map[string]string, labelValues bool) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if k8s.IsReservedPodMetadataKey(key) {
+			return fmt.Errorf("%s key %q is reserved", field, key)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("%s key %q: %s", field, key, strings.Join(errs, "; "))
+		}
+		if labelValues {
+			if errs := validation.IsValidLabelValue(m[key]); len(errs) > 0 {
+				return fmt.Errorf("%s value %q: %s", field, m[key], strings.Join(errs, "; "))
+			}
+		}
+	}
+	return nil
+}
+
+// validateVolumes checks requested ConfigMap/Secret volumes: valid unique names, exactly
+// one source, and absolute mount paths that don't overlap each other, the workspace or
+// the CA certificate mount
+func (h *Handler) validateVolumes(volumes []types.VolumeRequest) error {
+	reserved := []string{path.Clean(h.config.WorkspaceMountPath)}
+	if h.config.CACertSecretName != "" {
+		reserved = append(reserved, k8s.CACertMountPath)
+	}
+	names := make(map[string]bool, len(volumes))
+	var mounts []string
+	for _, v := range volumes {
+		if errs := validation.IsDNS1123Label(v.Name); len(errs) > 0 {
+			return fmt.Errorf("name %q: %s", v.Name, strings.Join(errs, "; "))
+		}
+		if k8s.IsReservedVolumeName(v.Name) {
+			return fmt.Errorf("name %q is reserved", v.Name)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("duplicate name %q", v.Name)
+		}
+		names[v.Name] = true
+
+		if (v.ConfigMap == "") == (v.Secret == "") {
+			return fmt.Errorf("volume %q must set exactly one of config_map and secret", v.Name)
+		}
+		source := v.ConfigMap + v.Secret
+		if errs := validation.IsDNS1123Subdomain(source); len(errs) > 0 {
+			return fmt.Errorf("volume %q source %q: %s", v.Name, source, strings.Join(errs, "; "))
+		}
+
+		if !path.IsAbs(v.MountPath) {
+			return fmt.Errorf("volume %q mount_path must be absolute", v.Name)
+		}
+		mountPath := path.Clean(v.MountPath)
+		for _, other := range reserved {
+			if pathsOverlap(mountPath, other) {
+				return fmt.Errorf("volume %q mount_path %q collides with %q", v.Name, v.MountPath, other)
+			}
+		}
+		for _, other := range mounts {
+			if mountPath == other {
+				return fmt.Errorf("volume %q mount_path %q is already used", v.Name, v.MountPath)
+			}
+		}
+		mounts = append(mounts, mountPath)
+
+		if v.SubPath != "" && (path.IsAbs(v.SubPath) || strings.Contains("/"+v.SubPath+"/", "/../")) {
+			return fmt.Errorf("volume %q sub_path must be relative and must not contain '..'", v.Name)
+		}
+	}
+	return nil
+}
+
+// errInitContainerImageNotAllowed is returned for init container images outside
+// INIT_CONTAINER_ALLOWED_REGISTRIES
+var errInitContainerImageNotAllowed = errors.New("init container image is not from an allowed registry")
+
+// validateInitContainer checks that an init container names an image from an allowed
+// registry and sets only valid env var names
+func (h *Handler) validateInitContainer(init *types.InitContainerRequest) error {
+	if strings.TrimSpace(init.Image) == "" {
+		return fmt.Errorf("image is required")
+	}
+	if !imageFromRegistries(init.Image, h.config.InitContainerRegistries) {
+		return fmt.Errorf("%w: %s", errInitContainerImageNotAllowed, init.Image)
+	}
+	return validateEnvironment(init.Environment)
+}
+
+// imageFromRegistries reports whether image is under one of registries. Entries match
+// whole path segments, so "ghcr.io/org" admits "ghcr.io/org/tool" but not "ghcr.io/org2/tool";
+// images must be fully qualified to match.
+func imageFromRegistries(image string, registries []string) bool {
+	for _, registry := range registries {
+		if registry = strings.TrimSuffix(registry, "/"); registry != "" && strings.HasPrefix(image, registry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// pathsOverlap reports whether two cleaned absolute paths are equal or one contains the other
+func pathsOverlap(a, b string) bool {
+	return a == b || strings.HasPrefix(a, strings.TrimSuffix(b, "/")+"/") || strings.HasPrefix(b, strings.TrimSuffix(a, "/")+"/")
+}
+
+// validateResourceQuantities checks that explicit CPU/memory values parse as non-negative
+// Kubernetes quantities and that no explicit request exceeds its explicit limit
+func validateResourceQuantities(req *types.StartRequest) error {
+	pairs := []struct {
+		name, request, limit string
+	}{
+		{"cpu", req.CPURequest, req.CPULimit},
+		{"memory", req.MemoryRequest, req.MemoryLimit},
+	}
+	for _, p := range pairs {
+		request, err := parseQuantity(p.name+"_request", p.request)
+		if err != nil {
+			return err
+		}
+		limit, err := parseQuantity(p.name+"_limit", p.limit)
+		if err != nil {
+			return err
+		}
+		if request != nil && limit != nil && request.Cmp(*limit) > 0 {
+			return fmt.Errorf("%s_request %q exceeds %s_limit %q", p.name, p.request, p.name, p.limit)
+		}
+	}
+	return nil
+}
+
+// parseQuantity parses an optional resource quantity field; empty values return nil
+func parseQuantity(field, value string) (*resource.Quantity, error) {
+	if value == "" {
+		return nil, nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %v", field, value, err)
+	}
+	if q.Sign() < 0 {
+		return nil, fmt.Errorf("%s %q must not be negative", field, value)
+	}
+	return &q, nil
+}
+
+// admissionLimits returns the MAX_SANDBOXES, MAX_TOTAL_CPU and MAX_TOTAL_MEMORY limits for
+// new sandboxes. The quantities are validated when the config is loaded.
+func (h *Handler) admissionLimits() state.Limits {
+	limits := state.Limits{MaxRuntimes: h.config.MaxSandboxes}
+	if h.config.MaxTotalCPU != "" {
+		q := resource.MustParse(h.config.MaxTotalCPU)
+		limits.MaxCPUMillis = q.MilliValue()
+	}
+	if h.config.MaxTotalMemory != "" {
+		q := resource.MustParse(h.config.MaxTotalMemory)
+		limits.MaxMemoryBytes = q.Value()
+	}
+	return limits
+}
+
+// idleTimeoutOverride converts a start request's idle_timeout_minutes into a per-sandbox
+// idle timeout, capped at MaxIdleTimeoutMinutes so callers can't create immortal sandboxes.
+// Zero means the reaper's global timeout applies.
+func (h *Handler) idleTimeoutOverride(minutes int) time.Duration {
+	if minutes <= 0 {
+		return 0
+	}
+	if limit := h.config.MaxIdleTimeoutMinutes; limit > 0 && minutes > limit {
+		logger.Debug("StartRuntime: Capping idle_timeout_minutes %d at %d", minutes, limit)
+		minutes = limit
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// proxyTimeoutOverride converts a start request's proxy_timeout_seconds to a duration,
+// capped at MAX_PROXY_TIMEOUT (0 = use PROXY_RESPONSE_HEADER_TIMEOUT)
+func (h *Handler) proxyTimeoutOverride(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	timeout := time.Duration(seconds) * time.Second
+	if limit := h.config.MaxProxyTimeout; limit > 0 && timeout > limit {
+		logger.Debug("StartRuntime: Capping proxy_timeout_seconds %d at %s", seconds, limit)
+		timeout = limit
+	}
+	return timeout
+}
+
+// parseNonNegativeQueryInt parses an optional non-negative integer query value (empty = 0)
+func parseNonNegativeQueryInt(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid value %q", v)
+	}
+	return n, nil
+}
+
+// GetRuntime handles GET /runtime/{runtime_id}
+func (h *Handler) GetRuntime(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	runtimeID := vars["runtime_id"]
+	logger.DebugCtx(r.Context(), "GetRuntime: Fetching runtime %s", runtimeID)
 
-	runtimeInfo, err := h.stateMgr.GetRuntimeByID(req.RuntimeID)
+	runtimeInfo, err := h.stateMgr.GetRuntimeByID(runtimeID)
 	if err != nil {
-		logger.Debug("PauseRuntime: Runtime not found: %s", req.RuntimeID)
+		if h.config.UseGoneForStopped && h.stateMgr.IsTombstoned(runtimeID) {
+			logger.DebugCtx(r.Context(), "GetRuntime: Runtime was stopped: %s", runtimeID)
+			respondError(w, http.StatusGone, "runtime_gone", "Runtime has been stopped")
+			return
+		}
+		logger.DebugCtx(r.Context(), "GetRuntime: Runtime not found: %s", runtimeID)
 		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
 		return
 	}
 
-	logger.Debug("PauseRuntime: Scaling pod to zero for runtime %s (Pod: %s)", req.RuntimeID, runtimeInfo.PodName)
-
-	// For pause, we delete the pod but keep the state
-	ctx, cancel := context.WithTimeout(r.Context(), h.config.K8sOperationTimeout)
-	defer cancel()
-	if err := h.k8sClient.ScalePodToZero(ctx, runtimeInfo.PodName); err != nil {
-		logger.Info("Failed to pause runtime: %v", err)
-		respondError(w, http.StatusInternalServerError, "pause_failed", fmt.Sprintf("Failed to pause runtime: %v", err))
-		return
-	}
+	// Update pod status from Kubernetes, falling back to the last-known status
+	refreshed := h.updateRuntimeStatusFromK8s(runtimeInfo)
 
-	logger.Debug("PauseRuntime: Pod scaled to zero successfully")
+	response := h.buildRuntimeResponse(r, runtimeInfo)
+	response.StatusStale = !refreshed
+	respondJSON(w, http.StatusOK, response)
+}
 
-	// Update status
-	runtimeInfo.Status = types.StatusPaused
-	runtimeInfo.PodStatus = types.PodStatusNotFound
-	_ = h.stateMgr.UpdateRuntime(runtimeInfo)
-	logger.Debug("PauseRuntime: Updated runtime status to paused")
+// CordonRuntime handles POST /runtime/{runtime_id}/cordon. A cordoned sandbox keeps
+// running and stays manageable, but ProxySandbox refuses its traffic with 503.
+func (h *Handler) CordonRuntime(w http.ResponseWriter, r *http.Request) {
+	h.setCordoned(w, r, true)
+}
 
-	response := h.buildRuntimeResponse(runtimeInfo)
-	respondJSON(w, http.StatusOK, response)
+// UncordonRuntime handles POST /runtime/{runtime_id}/uncordon, restoring proxying
+func (h *Handler) UncordonRuntime(w http.ResponseWriter, r *http.Request) {
+	h.setCordoned(w, r, false)
 }
 
-// ResumeRuntime handles POST /resume
-func (h *Handler) ResumeRuntime(w http.ResponseWriter, r *http.Request) {
-	var req types.ResumeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Debug("ResumeRuntime: Failed to decode request body: %v", err)
-		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+func (h *Handler) setCordoned(w http.ResponseWriter, r *http.Request, cordoned bool) {
+	runtimeID := mux.Vars(r)["runtime_id"]
+	if err := h.stateMgr.SetCordoned(runtimeID, cordoned); err != nil {
+		logger.DebugCtx(r.Context(), "setCordoned: Runtime not found: %s", runtimeID)
+		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
 		return
 	}
+	logger.InfoCtx(r.Context(), "setCordoned: Runtime %s cordoned=%t", runtimeID, cordoned)
 
-	logger.Debug("ResumeRuntime: Request decoded - RuntimeID: %s", req.RuntimeID)
-
-	runtimeInfo, err := h.stateMgr.GetRuntimeByID(req.RuntimeID)
+	runtimeInfo, err := h.stateMgr.GetRuntimeByID(runtimeID)
 	if err != nil {
-		logger.Debug("ResumeRuntime: Runtime not found: %s", req.RuntimeID)
+		// Stopped between the update and this lookup
 		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
 		return
 	}
+	respondJSON(w, http.StatusOK, h.buildRuntimeResponse(r, runtimeInfo))
+}
 
-	// Already running: no-op (e.g. WebSocket recovery calls resume for running sandboxes)
-	if runtimeInfo.Status == types.StatusRunning {
-		logger.Debug("ResumeRuntime: Runtime %s already running, no-op", req.RuntimeID)
-		response := h.buildRuntimeResponse(runtimeInfo)
-		respondJSON(w, http.StatusOK, response)
-		return
-	}
+// GetRuntimeHistory handles GET /runtime/{runtime_id}/history, returning the runtime's
+// lifecycle events newest first. Supports limit and offset like GET /list; stopped
+// runtimes' history stays available for as long as they are tombstoned.
+func (h *Handler) GetRuntimeHistory(w http.ResponseWriter, r *http.Request) {
+	runtimeID := mux.Vars(r)["runtime_id"]
 
-	if runtimeInfo.Status != types.StatusPaused {
-		logger.Debug("ResumeRuntime: Runtime %s is not paused (status: %s)", req.RuntimeID, runtimeInfo.Status)
-		respondError(w, http.StatusBadRequest, "invalid_state", "Runtime is not paused")
+	query := r.URL.Query()
+	limit, err := parseNonNegativeQueryInt(query.Get("limit"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "limit must be a non-negative integer")
 		return
 	}
-
-	logger.Debug("ResumeRuntime: Recreating pod for runtime %s", req.RuntimeID)
-
-	// Recreate the pod
-	// TODO(technical-debt): Store original image, command, and environment in RuntimeInfo
-	// so we can recreate the pod exactly as it was. For now, using defaults.
-	startReq := &types.StartRequest{
-		Image:      h.config.DefaultImage, // This should be stored in RuntimeInfo in production
-		Command:    types.FlexibleCommand{"/usr/local/bin/openhands-agent-server", "--port", fmt.Sprintf("%d", h.config.AgentServerPort)},
-		WorkingDir: "/openhands/code/",
-		SessionID:  runtimeInfo.SessionID,
+	offset, err := parseNonNegativeQueryInt(query.Get("offset"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "offset must be a non-negative integer")
+		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), h.config.K8sOperationTimeout)
-	defer cancel()
-	if err := h.k8sClient.RecreatePod(ctx, startReq, runtimeInfo); err != nil {
-		logger.Info("Failed to resume runtime: %v", err)
-		respondError(w, http.StatusInternalServerError, "resume_failed", fmt.Sprintf("Failed to resume runtime: %v", err))
+	events, err := h.stateMgr.History(runtimeID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
 		return
 	}
 
-	logger.Debug("ResumeRuntime: Pod recreated successfully")
-
-	// Update status
-	runtimeInfo.Status = types.StatusRunning
-	runtimeInfo.PodStatus = types.PodStatusPending
-	_ = h.stateMgr.UpdateRuntime(runtimeInfo)
-	logger.Debug("ResumeRuntime: Updated runtime status to running")
+	total := len(events)
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
 
-	response := h.buildRuntimeResponse(runtimeInfo)
-	respondJSON(w, http.StatusOK, response)
+	resp := types.HistoryResponse{RuntimeID: runtimeID, Events: events[offset:end], Total: total}
+	if end < total {
+		resp.NextOffset = end
+	}
+	respondJSON(w, http.StatusOK, resp)
 }
 
-// ListRuntimes handles GET /list
-func (h *Handler) ListRuntimes(w http.ResponseWriter, r *http.Request) {
-	logger.Debug("ListRuntimes: Fetching all runtimes")
-	runtimes := h.stateMgr.ListRuntimes()
-	logger.Debug("ListRuntimes: Found %d runtimes", len(runtimes))
+// GetRuntimeEvents handles GET /runtime/{runtime_id}/events, listing the Kubernetes Events
+// for the sandbox pod so users can see why it is stuck (e.g. FailedScheduling) without
+// cluster access
+func (h *Handler) GetRuntimeEvents(w http.ResponseWriter, r *http.Request) {
+	runtimeID := mux.Vars(r)["runtime_id"]
 
-	// Batch-fetch all pod statuses in a single K8s API call.
-	if h.k8sClient != nil {
-		podNames := make([]string, 0, len(runtimes))
-		for _, runtime := range runtimes {
-			podNames = append(podNames, runtime.PodName)
-		}
-		ctx, cancel := context.WithTimeout(r.Context(), h.config.K8sQueryTimeout)
-		defer cancel()
-		if statuses, err := h.k8sClient.GetPodStatuses(ctx, podNames); err == nil {
-			for _, runtime := range runtimes {
-				if statusInfo, ok := statuses[runtime.PodName]; ok {
-					runtime.PodStatus = statusInfo.Status
-					runtime.RestartCount = statusInfo.RestartCount
-					runtime.RestartReasons = statusInfo.RestartReasons
-					runtime.LastTerminationReason = statusInfo.LastTerminationReason
-					runtime.LastTerminationExitCode = statusInfo.LastTerminationExitCode
-					_ = h.stateMgr.UpdateRuntime(runtime)
-				}
-			}
-		} else {
-			logger.Debug("ListRuntimes: Failed to batch-fetch pod statuses: %v", err)
-		}
+	runtimeInfo, err := h.stateMgr.GetRuntimeByID(runtimeID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
+		return
 	}
-
-	responses := make([]types.RuntimeResponse, 0, len(runtimes))
-	for _, runtime := range runtimes {
-		responses = append(responses, h.buildRuntimeResponse(runtime))
+	if h.k8sClient == nil {
+		respondError(w, http.StatusServiceUnavailable, "events_unavailable", "Kubernetes client not configured")
+		return
 	}
 
-	logger.Debug("ListRuntimes: Returning %d runtime responses", len(responses))
-	respondJSON(w, http.StatusOK, types.ListResponse{Runtimes: responses})
+	events, err := h.k8sClient.ListPodEvents(r.Context(), runtimeInfo.PodName)
+	if err != nil {
+		logger.ErrorCtx(r.Context(), "GetRuntimeEvents: Failed to list events for runtime %s: %v", runtimeID, err)
+		respondError(w, http.StatusInternalServerError, "events_failed", "Failed to list pod events")
+		return
+	}
+	respondJSON(w, http.StatusOK, types.PodEventsResponse{
+		RuntimeID: runtimeID,
+		PodName:   runtimeInfo.PodName,
+		Events:    events,
+	})
 }
 
-// GetRuntime handles GET /runtime/{runtime_id}
-func (h *Handler) GetRuntime(w http.ResponseWriter, r *http.Request) {
+// GetRuntimeLogs handles GET /runtime/{runtime_id}/logs, streaming the agent container's
+// logs. Supports ?follow=true, ?tail=N and ?previous=true; the stream is cancelled when
+// the client disconnects.
+func (h *Handler) GetRuntimeLogs(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	runtimeID := vars["runtime_id"]
-	logger.Debug("GetRuntime: Fetching runtime %s", runtimeID)
 
 	runtimeInfo, err := h.stateMgr.GetRuntimeByID(runtimeID)
 	if err != nil {
-		logger.Debug("GetRuntime: Runtime not found: %s", runtimeID)
 		respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
 		return
 	}
 
-	// Update pod status from Kubernetes
-	h.updateRuntimeStatusFromK8s(runtimeInfo)
+	query := r.URL.Query()
+	tail, err := parseNonNegativeQueryInt(query.Get("tail"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("tail: %v", err))
+		return
+	}
+	opts := k8s.LogOptions{
+		Follow:    query.Get("follow") == "true",
+		TailLines: int64(tail),
+		Previous:  query.Get("previous") == "true",
+	}
 
-	response := h.buildRuntimeResponse(runtimeInfo)
-	respondJSON(w, http.StatusOK, response)
+	if h.k8sClient == nil {
+		respondError(w, http.StatusServiceUnavailable, "logs_unavailable", "Kubernetes client not configured")
+		return
+	}
+	stream, err := h.k8sClient.StreamPodLogs(r.Context(), runtimeInfo.PodName, opts)
+	if err != nil {
+		switch {
+		case apierrors.IsNotFound(err):
+			respondError(w, http.StatusNotFound, "pod_not_found", "Sandbox pod not found")
+		case apierrors.IsBadRequest(err):
+			// e.g. previous=true when the container has not restarted
+			respondError(w, http.StatusBadRequest, "logs_unavailable", err.Error())
+		default:
+			logger.ErrorCtx(r.Context(), "GetRuntimeLogs: Failed to stream logs for runtime %s: %v", runtimeID, err)
+			respondError(w, http.StatusInternalServerError, "logs_failed", "Failed to stream logs")
+		}
+		return
+	}
+	defer stream.Close()
+
+	rc := http.NewResponseController(w)
+	if opts.Follow {
+		// A followed stream outlives the server's WriteTimeout
+		_ = rc.SetWriteDeadline(time.Time{})
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	// No Content-Length and a flush per read makes the response chunked
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := stream.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return
+			}
+			_ = rc.Flush()
+		}
+		if readErr != nil {
+			if readErr != io.EOF && r.Context().Err() == nil {
+				logger.DebugCtx(r.Context(), "GetRuntimeLogs: Log stream for runtime %s ended: %v", runtimeID, readErr)
+			}
+			return
+		}
+	}
 }
 
 // GetSession handles GET /sessions/{session_id}
 func (h *Handler) GetSession(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	sessionID := vars["session_id"]
-	logger.Debug("GetSession: Fetching session %s", sessionID)
+	logger.DebugCtx(r.Context(), "GetSession: Fetching session %s", sessionID)
 
 	runtimeInfo, err := h.stateMgr.GetRuntimeBySessionID(sessionID)
 	if err != nil {
@@ -432,25 +1494,28 @@ func (h *Handler) GetSession(w http.ResponseWriter, r *http.Request) {
 			ctx, cancel := context.WithTimeout(r.Context(), h.config.K8sQueryTimeout)
 			defer cancel()
 			if discovered, discoverErr := h.k8sClient.DiscoverRuntimeBySessionID(ctx, sessionID); discoverErr == nil && discovered != nil {
-				logger.Info("GetSession: Recovered session %s from Kubernetes (state was lost)", sessionID)
+				logger.InfoCtx(r.Context(), "GetSession: Recovered session %s from Kubernetes (state was lost)", sessionID)
 				h.stateMgr.AddRuntime(discovered)
 				runtimeInfo = discovered
-			} else {
-				logger.Debug("GetSession: Session not found: %s", sessionID)
-				respondError(w, http.StatusNotFound, "session_not_found", "Session not found")
+			}
+		}
+		if runtimeInfo == nil {
+			if h.config.UseGoneForStopped && h.stateMgr.IsSessionTombstoned(sessionID) {
+				logger.DebugCtx(r.Context(), "GetSession: Session runtime was stopped: %s", sessionID)
+				respondError(w, http.StatusGone, "session_gone", "Session runtime has been stopped")
 				return
 			}
-		} else {
-			logger.Debug("GetSession: Session not found: %s", sessionID)
+			logger.DebugCtx(r.Context(), "GetSession: Session not found: %s", sessionID)
 			respondError(w, http.StatusNotFound, "session_not_found", "Session not found")
 			return
 		}
 	}
 
-	// Update pod status from Kubernetes
-	h.updateRuntimeStatusFromK8s(runtimeInfo)
+	// Update pod status from Kubernetes, falling back to the last-known status
+	refreshed := h.updateRuntimeStatusFromK8s(runtimeInfo)
 
-	response := h.buildRuntimeResponse(runtimeInfo)
+	response := h.buildRuntimeResponse(r, runtimeInfo)
+	response.StatusStale = !refreshed
 	respondJSON(w, http.StatusOK, response)
 }
 
@@ -469,7 +1534,7 @@ func (h *Handler) GetSessionsBatch(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "invalid_request", "ids parameter is required")
 		return
 	}
-	logger.Debug("GetSessionsBatch: Fetching %d sessions", len(sessionIDs))
+	logger.DebugCtx(r.Context(), "GetSessionsBatch: Fetching %d sessions", len(sessionIDs))
 
 	// Build runtimes list, discovering from Kubernetes for any not in state
 	ctx, cancel := context.WithTimeout(r.Context(), h.config.K8sQueryTimeout)
@@ -483,7 +1548,7 @@ func (h *Handler) GetSessionsBatch(w http.ResponseWriter, r *http.Request) {
 			runtimesBySession[sessionID] = runtime
 		} else if h.k8sClient != nil {
 			if discovered, discoverErr := h.k8sClient.DiscoverRuntimeBySessionID(ctx, sessionID); discoverErr == nil && discovered != nil {
-				logger.Info("GetSessionsBatch: Recovered session %s from Kubernetes (state was lost)", sessionID)
+				logger.InfoCtx(r.Context(), "GetSessionsBatch: Recovered session %s from Kubernetes (state was lost)", sessionID)
 				h.stateMgr.AddRuntime(discovered)
 				runtimesBySession[sessionID] = discovered
 			}
@@ -491,6 +1556,7 @@ func (h *Handler) GetSessionsBatch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Collect all pod names and fetch their statuses in a single K8s API call.
+	stale := false
 	if h.k8sClient != nil {
 		podNames := make([]string, 0, len(runtimesBySession))
 		for _, runtime := range runtimesBySession {
@@ -499,12 +1565,17 @@ func (h *Handler) GetSessionsBatch(w http.ResponseWriter, r *http.Request) {
 		if statuses, err := h.k8sClient.GetPodStatuses(ctx, podNames); err == nil {
 			for _, runtime := range runtimesBySession {
 				if statusInfo, ok := statuses[runtime.PodName]; ok {
+					h.recordPodStatusEvents(runtime, statusInfo)
 					runtime.PodStatus = statusInfo.Status
 					runtime.RestartCount = statusInfo.RestartCount
 					runtime.RestartReasons = statusInfo.RestartReasons
+					runtime.Restarts = statusInfo.Restarts
 					_ = h.stateMgr.UpdateRuntime(runtime)
 				}
 			}
+		} else {
+			logger.DebugCtx(r.Context(), "GetSessionsBatch: Failed to batch-fetch pod statuses: %v", err)
+			stale = true
 		}
 	}
 
@@ -514,10 +1585,12 @@ func (h *Handler) GetSessionsBatch(w http.ResponseWriter, r *http.Request) {
 		if !ok {
 			continue
 		}
-		responses = append(responses, h.buildRuntimeResponse(runtime))
+		response := h.buildRuntimeResponse(r, runtime)
+		response.StatusStale = stale
+		responses = append(responses, response)
 	}
 
-	logger.Debug("GetSessionsBatch: Returning %d runtime responses", len(responses))
+	logger.DebugCtx(r.Context(), "GetSessionsBatch: Returning %d runtime responses", len(responses))
 	// Return a plain JSON array – the OpenHands app server iterates over the
 	// response directly as a list of runtime objects.
 	respondJSON(w, http.StatusOK, responses)
@@ -529,18 +1602,18 @@ func (h *Handler) GetSessionsBatch(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) BatchGetConversations(w http.ResponseWriter, r *http.Request) {
 	var req types.BatchConversationsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Debug("BatchGetConversations: Failed to decode request body: %v", err)
+		logger.DebugCtx(r.Context(), "BatchGetConversations: Failed to decode request body: %v", err)
 		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
 		return
 	}
 
 	if len(req.Sandboxes) == 0 {
-		logger.Debug("BatchGetConversations: Empty sandboxes map")
+		logger.DebugCtx(r.Context(), "BatchGetConversations: Empty sandboxes map")
 		respondJSON(w, http.StatusOK, map[string]json.RawMessage{})
 		return
 	}
 
-	logger.Debug("BatchGetConversations: Fetching conversations for %d sandboxes", len(req.Sandboxes))
+	logger.DebugCtx(r.Context(), "BatchGetConversations: Fetching conversations for %d sandboxes", len(req.Sandboxes))
 
 	// Fan out requests concurrently
 	type result struct {
@@ -562,7 +1635,7 @@ func (h *Handler) BatchGetConversations(w http.ResponseWriter, r *http.Request)
 				// Try by session ID
 				runtimeInfo, err = h.stateMgr.GetRuntimeBySessionID(sb.SessionID)
 				if err != nil {
-					logger.Debug("BatchGetConversations: Runtime not found for %s (session %s)", rtID, sb.SessionID)
+					logger.DebugCtx(r.Context(), "BatchGetConversations: Runtime not found for %s (session %s)", rtID, sb.SessionID)
 					resultsCh <- result{runtimeID: rtID, data: json.RawMessage("[]")}
 					return
 				}
@@ -575,7 +1648,7 @@ func (h *Handler) BatchGetConversations(w http.ResponseWriter, r *http.Request)
 
 			resp, err := h.fetchConversations(ctx, runtimeInfo.ServiceName, ids, runtimeInfo.SessionAPIKey)
 			if err != nil {
-				logger.Debug("BatchGetConversations: Request failed for %s: %v", rtID, err)
+				logger.DebugCtx(r.Context(), "BatchGetConversations: Request failed for %s: %v", rtID, err)
 				resultsCh <- result{runtimeID: rtID, data: json.RawMessage("[]")}
 				return
 			}
@@ -583,13 +1656,13 @@ func (h *Handler) BatchGetConversations(w http.ResponseWriter, r *http.Request)
 
 			body, err := io.ReadAll(resp.Body)
 			if err != nil {
-				logger.Debug("BatchGetConversations: Failed to read response for %s: %v", rtID, err)
+				logger.DebugCtx(r.Context(), "BatchGetConversations: Failed to read response for %s: %v", rtID, err)
 				resultsCh <- result{runtimeID: rtID, data: json.RawMessage("[]")}
 				return
 			}
 
 			if resp.StatusCode != http.StatusOK {
-				logger.Debug("BatchGetConversations: Non-200 status for %s: %d", rtID, resp.StatusCode)
+				logger.DebugCtx(r.Context(), "BatchGetConversations: Non-200 status for %s: %d", rtID, resp.StatusCode)
 				resultsCh <- result{runtimeID: rtID, data: json.RawMessage("[]")}
 				return
 			}
@@ -611,7 +1684,7 @@ func (h *Handler) BatchGetConversations(w http.ResponseWriter, r *http.Request)
 		response[res.runtimeID] = res.data
 	}
 
-	logger.Debug("BatchGetConversations: Returning results for %d sandboxes", len(response))
+	logger.DebugCtx(r.Context(), "BatchGetConversations: Returning results for %d sandboxes", len(response))
 	respondJSON(w, http.StatusOK, response)
 }
 
@@ -619,8 +1692,7 @@ func (h *Handler) BatchGetConversations(w http.ResponseWriter, r *http.Request)
 // The service name is an internal K8s service created by the runtime API, and the namespace
 // comes from config — both are trusted, not user-supplied.
 func (h *Handler) fetchConversations(ctx context.Context, serviceName, ids, sessionAPIKey string) (*http.Response, error) {
-	inClusterURL := fmt.Sprintf("http://%s.%s.svc.cluster.local:%d/api/conversations?ids=%s",
-		serviceName, h.config.Namespace, h.config.AgentServerPort, url.QueryEscape(ids))
+	inClusterURL := h.serviceURL(serviceName, h.config.AgentServerPort) + "/api/conversations?ids=" + url.QueryEscape(ids)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, inClusterURL, nil)
 	if err != nil {
@@ -628,7 +1700,7 @@ func (h *Handler) fetchConversations(ctx context.Context, serviceName, ids, sess
 	}
 	req.Header.Set("X-Session-API-Key", sessionAPIKey)
 
-	logger.Debug("fetchConversations: GET %s", inClusterURL)
+	logger.DebugCtx(ctx, "fetchConversations: GET %s", inClusterURL)
 	return h.tracedClient.Do(req) //nolint:gosec // G704: URL built from trusted in-cluster service name and config namespace
 }
 
@@ -643,12 +1715,12 @@ func (h *Handler) GetRegistryPrefix(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) CheckImageExists(w http.ResponseWriter, r *http.Request) {
 	image := r.URL.Query().Get("image")
 	if image == "" {
-		logger.Debug("CheckImageExists: Missing 'image' parameter")
+		logger.DebugCtx(r.Context(), "CheckImageExists: Missing 'image' parameter")
 		respondError(w, http.StatusBadRequest, "invalid_request", "image parameter is required")
 		return
 	}
 
-	logger.Debug("CheckImageExists: Checking image %s", image)
+	logger.DebugCtx(r.Context(), "CheckImageExists: Checking image %s", image)
 	// For MVP, we'll assume all images exist
 	// In production, this should actually check the registry
 	respondJSON(w, http.StatusOK, types.ImageExistsResponse{
@@ -656,8 +1728,67 @@ func (h *Handler) CheckImageExists(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// buildRuntimeResponse builds a RuntimeResponse from RuntimeInfo
-func (h *Handler) buildRuntimeResponse(info *state.RuntimeInfo) types.RuntimeResponse {
+// GetStats handles GET /stats and its alias GET /admin/cleanup-stats
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	resp := types.StatsResponse{
+		RuntimeCount:      h.stateMgr.Count(),
+		ActiveWebSockets:  h.activeWebSockets.Load(),
+		LastReconcileTime: timePtr(h.stateMgr.LastReconcileTime()),
+		Cleanup: types.CleanupStatsResponse{
+			Enabled:           h.config.CleanupEnabled,
+			DryRun:            h.config.CleanupDryRun,
+			LastCleanupErrors: []string{},
+		},
+		Reaper: types.ReaperStatsResponse{
+			LastReapErrors: []string{},
+		},
+	}
+
+	if h.cleanupSvc != nil {
+		stats := h.cleanupSvc.GetStats()
+		resp.Cleanup.LastRunTime = timePtr(stats.LastRunTime)
+		resp.Cleanup.TotalRunCount = stats.TotalRunCount
+		resp.Cleanup.TotalCleaned = stats.TotalCleaned
+		resp.Cleanup.FailedCleaned = stats.FailedCleaned
+		resp.Cleanup.IdleCleaned = stats.IdleCleaned
+		resp.Cleanup.WouldClean = stats.WouldClean
+		resp.Cleanup.Repaired = stats.Repaired
+		resp.Cleanup.OrphansDeleted = stats.OrphansDeleted
+		if stats.LastCleanupErrors != nil {
+			resp.Cleanup.LastCleanupErrors = stats.LastCleanupErrors
+		}
+	}
+
+	if h.reaper != nil {
+		stats := h.reaper.GetStats()
+		resp.Reaper.LastRunTime = timePtr(stats.LastRunTime)
+		resp.Reaper.TotalRunCount = stats.TotalRunCount
+		resp.Reaper.TotalReaped = stats.TotalReaped
+		resp.Reaper.IdleReaped = stats.IdleReaped
+		resp.Reaper.TTLReaped = stats.TTLReaped
+		resp.Reaper.DryRun = h.config.ReaperDryRun
+		resp.Reaper.WouldReap = stats.WouldReap
+		resp.Reaper.Pruned = stats.Pruned
+		if stats.LastReapErrors != nil {
+			resp.Reaper.LastReapErrors = stats.LastReapErrors
+		}
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// timePtr returns nil for a zero time so unset timestamps are omitted from JSON
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// buildRuntimeResponse builds a RuntimeResponse from RuntimeInfo. r is the request being
+// answered, used for proxy URLs under PROXY_BASE_FROM_REQUEST and to omit the session API
+// key for read-only callers; it may be nil.
+func (h *Handler) buildRuntimeResponse(r *http.Request, info *state.RuntimeInfo) types.RuntimeResponse {
 	resp := types.RuntimeResponse{
 		RuntimeID:               info.RuntimeID,
 		SessionID:               info.SessionID,
@@ -668,9 +1799,14 @@ func (h *Handler) buildRuntimeResponse(info *state.RuntimeInfo) types.RuntimeRes
 		WorkHosts:               info.WorkHosts,
 		RestartCount:            info.RestartCount,
 		RestartReasons:          info.RestartReasons,
+		Restarts:                info.Restarts,
+		Cordoned:                info.Cordoned,
 		LastTerminationReason:   info.LastTerminationReason,
 		LastTerminationExitCode: info.LastTerminationExitCode,
 	}
+	if isReadOnlyCaller(r) {
+		resp.SessionAPIKey = ""
+	}
 	if h.config.DirectRouting {
 		// Path-based direct routing: traffic goes ingress → pod, bypassing the proxy.
 		// URLs use the same /sandbox/{runtime_id} format so the frontend is unaffected.
@@ -678,24 +1814,117 @@ func (h *Handler) buildRuntimeResponse(info *state.RuntimeInfo) types.RuntimeRes
 		resp.URL = fmt.Sprintf("%s/sandbox/%s", base, info.RuntimeID)
 		resp.VSCodeURL = fmt.Sprintf("%s/sandbox/%s/vscode", base, info.RuntimeID)
 	} else if h.config.ProxyBaseURL != "" {
-		base := strings.TrimSuffix(h.config.ProxyBaseURL, "/")
+		base := h.proxyBaseURL(r)
 		resp.URL = fmt.Sprintf("%s/sandbox/%s", base, info.RuntimeID)
 		resp.VSCodeURL = fmt.Sprintf("%s/sandbox/%s/vscode", base, info.RuntimeID)
 	}
+	if info.ServiceName != "" {
+		resp.InternalURL = h.serviceURL(info.ServiceName, h.config.AgentServerPort)
+	}
 	return resp
 }
 
-// updateRuntimeStatusFromK8s updates runtime info with latest pod status from Kubernetes
-func (h *Handler) updateRuntimeStatusFromK8s(runtimeInfo *state.RuntimeInfo) {
+// proxyBaseURL returns the base of proxied sandbox URLs: PROXY_BASE_URL, or with
+// PROXY_BASE_FROM_REQUEST the same URL on the host the request arrived on
+// (X-Forwarded-Host, then Host) if PROXY_ALLOWED_HOSTS permits it, so one runtime API
+// can serve several hostnames. The scheme follows X-Forwarded-Proto when it is http or https.
+func (h *Handler) proxyBaseURL(r *http.Request) string {
+	base := strings.TrimSuffix(h.config.ProxyBaseURL, "/")
+	if !h.config.ProxyBaseFromRequest || r == nil {
+		return base
+	}
+	host := lastForwardedValue(r.Header.Get("X-Forwarded-Host"))
+	if host == "" {
+		host = r.Host
+	}
+	// Reject anything that is not a bare host[:port], e.g. a smuggled path or userinfo, or
+	// that is not in PROXY_ALLOWED_HOSTS
+	parsed, err := url.Parse("https://" + host)
+	if err != nil || parsed.Host != host || parsed.User != nil || !h.proxyHostAllowed(parsed.Hostname()) {
+		return base
+	}
+	configured, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	if proto := strings.ToLower(lastForwardedValue(r.Header.Get("X-Forwarded-Proto"))); proto == "http" || proto == "https" {
+		configured.Scheme = proto
+	}
+	configured.Host = host
+	return configured.String()
+}
+
+// lastForwardedValue returns the last entry of a comma-separated X-Forwarded-* header,
+// which is the one appended by the proxy in front of the API. Earlier entries come from
+// the client or proxies further out and can be spoofed.
+func lastForwardedValue(header string) string {
+	if i := strings.LastIndex(header, ","); i >= 0 {
+		header = header[i+1:]
+	}
+	return strings.TrimSpace(header)
+}
+
+// proxyHostAllowed reports whether PROXY_ALLOWED_HOSTS lets proxy URLs be built on host
+func (h *Handler) proxyHostAllowed(host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, allowed := range h.config.ProxyAllowedHosts {
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") {
+			if len(host) > len(suffix) && strings.HasSuffix(strings.ToLower(host), strings.ToLower(suffix)) {
+				return true
+			}
+		} else if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceURL returns the in-cluster base URL of a sandbox service port
+func (h *Handler) serviceURL(serviceName string, port int) string {
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", serviceName, h.config.Namespace, port)
+}
+
+// updateRuntimeStatusFromK8s updates runtime info with latest pod status from Kubernetes.
+// It returns false, leaving the last-known status in place, when the query fails or
+// exceeds K8S_QUERY_TIMEOUT.
+func (h *Handler) updateRuntimeStatusFromK8s(runtimeInfo *state.RuntimeInfo) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), h.config.K8sQueryTimeout)
 	defer cancel()
-	if statusInfo, err := h.k8sClient.GetPodStatus(ctx, runtimeInfo.PodName); err == nil {
-		runtimeInfo.PodStatus = statusInfo.Status
-		runtimeInfo.RestartCount = statusInfo.RestartCount
-		runtimeInfo.RestartReasons = statusInfo.RestartReasons
-		runtimeInfo.LastTerminationReason = statusInfo.LastTerminationReason
-		runtimeInfo.LastTerminationExitCode = statusInfo.LastTerminationExitCode
-		_ = h.stateMgr.UpdateRuntime(runtimeInfo)
+	statusInfo, err := h.k8sClient.GetPodStatus(ctx, runtimeInfo.PodName)
+	if err != nil {
+		logger.Debug("updateRuntimeStatusFromK8s: Using last-known status for runtime %s: %v", runtimeInfo.RuntimeID, err)
+		return false
+	}
+	h.recordPodStatusEvents(runtimeInfo, statusInfo)
+	runtimeInfo.PodStatus = statusInfo.Status
+	runtimeInfo.RestartCount = statusInfo.RestartCount
+	runtimeInfo.RestartReasons = statusInfo.RestartReasons
+	runtimeInfo.Restarts = statusInfo.Restarts
+	runtimeInfo.LastTerminationReason = statusInfo.LastTerminationReason
+	runtimeInfo.LastTerminationExitCode = statusInfo.LastTerminationExitCode
+	_ = h.stateMgr.UpdateRuntime(runtimeInfo)
+	return true
+}
+
+// recordPodStatusEvents adds history events for changes between the runtime's stored pod
+// status and a fresh one. Call it before copying statusInfo into runtimeInfo.
+func (h *Handler) recordPodStatusEvents(runtimeInfo *state.RuntimeInfo, statusInfo *k8s.PodStatusInfo) {
+	if statusInfo.Status != runtimeInfo.PodStatus {
+		switch statusInfo.Status {
+		case types.PodStatusReady:
+			_ = h.stateMgr.RecordEvent(runtimeInfo.RuntimeID, types.RuntimeEventReady, "")
+		case types.PodStatusFailed, types.PodStatusCrashLoopBackOff:
+			_ = h.stateMgr.RecordEvent(runtimeInfo.RuntimeID, types.RuntimeEventError, "pod status "+string(statusInfo.Status))
+		}
+	}
+	if statusInfo.RestartCount > runtimeInfo.RestartCount {
+		message := fmt.Sprintf("restart count %d", statusInfo.RestartCount)
+		if statusInfo.LastTerminationReason != "" {
+			message += ", last termination " + statusInfo.LastTerminationReason
+		}
+		_ = h.stateMgr.RecordEvent(runtimeInfo.RuntimeID, types.RuntimeEventRestarted, message)
 	}
 }
 
@@ -726,7 +1955,18 @@ func (h *Handler) ProxySandbox(w http.ResponseWriter, r *http.Request) {
 	// backendRawPath preserves percent-encoding from the original request
 	var backendRawPath string
 	var backendPort int
-	if len(parts) == 2 && (parts[1] == "vscode" || strings.HasPrefix(parts[1], "vscode/")) {
+	isVSCode := len(parts) == 2 && (parts[1] == "vscode" || strings.HasPrefix(parts[1], "vscode/"))
+	if isVSCode && parts[1] == "vscode" {
+		// VSCode's workbench loads assets by relative URL, which only resolve under
+		// /vscode/ when the page itself was loaded with the trailing slash
+		location := path + "/"
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, location, http.StatusPermanentRedirect)
+		return
+	}
+	if isVSCode {
 		backendPort = h.config.VSCodePort
 		// Forward the complete path to the VSCode backend. openvscode-server is started
 		// with --server-base-path /sandbox/{runtime_id}/vscode, so it expects to receive
@@ -736,10 +1976,13 @@ func (h *Handler) ProxySandbox(w http.ResponseWriter, r *http.Request) {
 		backendRawPath = path
 	} else {
 		backendPort = h.config.AgentServerPort
-		if len(parts) == 2 {
+		// /sandbox/{id} and /sandbox/{id}/ both map to the agent server's root
+		backendRawPath = "/"
+		if len(parts) == 2 && parts[1] != "" {
 			backendRawPath = "/" + parts[1]
-		} else {
-			backendRawPath = "/"
+			if h.config.ProxyTrailingSlash == "normalize" {
+				backendRawPath = "/" + strings.TrimRight(parts[1], "/")
+			}
 		}
 	}
 
@@ -750,45 +1993,81 @@ func (h *Handler) ProxySandbox(w http.ResponseWriter, r *http.Request) {
 			ctx, cancel := context.WithTimeout(r.Context(), h.config.K8sQueryTimeout)
 			defer cancel()
 			if discovered, discoverErr := h.k8sClient.DiscoverRuntimeByRuntimeID(ctx, runtimeID); discoverErr == nil && discovered != nil {
-				logger.Info("ProxySandbox: Recovered runtime %s from Kubernetes (state was lost)", runtimeID)
+				logger.InfoCtx(r.Context(), "ProxySandbox: Recovered runtime %s from Kubernetes (state was lost)", runtimeID)
 				h.stateMgr.AddRuntime(discovered)
 				runtimeInfo = discovered
 			} else {
-				logger.Debug("ProxySandbox: Runtime not found: %s", runtimeID)
+				logger.DebugCtx(r.Context(), "ProxySandbox: Runtime not found: %s", runtimeID)
 				respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
 				return
 			}
 		} else {
-			logger.Debug("ProxySandbox: Runtime not found: %s", runtimeID)
+			logger.DebugCtx(r.Context(), "ProxySandbox: Runtime not found: %s", runtimeID)
 			respondError(w, http.StatusNotFound, "runtime_not_found", "Runtime not found")
 			return
 		}
 	}
 
+	if runtimeInfo.Cordoned {
+		logger.DebugCtx(r.Context(), "ProxySandbox: Runtime %s is cordoned", runtimeID)
+		respondError(w, http.StatusServiceUnavailable, "sandbox_cordoned", "Sandbox is cordoned")
+		return
+	}
+
+	// Optionally reject bad session keys here rather than relying on the sandbox alone.
+	// VSCode authenticates with its own connection token, so it is not checked.
+	if h.config.ProxyValidateSessionKey && !isVSCode && !validSessionKey(r, runtimeInfo.SessionAPIKey) {
+		logger.WarnCtx(r.Context(), "ProxySandbox: Invalid or missing session API key for runtime %s", runtimeID)
+		respondError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing session API key")
+		return
+	}
+
+	// A pending sandbox has nothing listening yet; tell the client it is starting rather
+	// than letting the request fail with a connection error
+	if h.config.SandboxExpectedStartup > 0 && h.sandboxPending(runtimeInfo) {
+		logger.DebugCtx(r.Context(), "ProxySandbox: Runtime %s is still starting", runtimeID)
+		respondSandboxStarting(w, runtimeInfo, h.config.SandboxExpectedStartup)
+		return
+	}
+
+	// Each upgraded connection holds a goroutine pair and two sockets until it closes
+	if isUpgradeRequest(r) {
+		if !h.acquireWebSocket() {
+			logger.WarnCtx(r.Context(), "ProxySandbox: Rejecting WebSocket for runtime %s: limit of %d reached", runtimeID, h.config.MaxProxyWebSockets)
+			respondError(w, http.StatusServiceUnavailable, "too_many_websockets", "Too many concurrent WebSocket connections")
+			return
+		}
+		defer h.activeWebSockets.Add(-1)
+	}
+
+	// A paused sandbox has no pod behind its service; bring it back before proxying
+	if status, _ := h.stateMgr.GetStatus(runtimeID); status == types.StatusPaused && h.k8sClient != nil {
+		if !h.resumeForProxy(w, r, runtimeInfo) {
+			return
+		}
+	}
+
 	// Update last activity time for this sandbox
 	_ = h.stateMgr.UpdateLastActivity(runtimeID)
 
 	// Build backend URL with the raw (percent-encoded) path preserved.
 	// We construct scheme+host separately and set the path via RawPath so that
 	// url.Parse does not decode percent-encoded characters (e.g. %2F → /).
-	backendBase := fmt.Sprintf("http://%s.%s.svc.cluster.local:%d",
-		runtimeInfo.ServiceName, h.config.Namespace, backendPort)
+	backendBase := h.serviceURL(runtimeInfo.ServiceName, backendPort)
 	target, err := url.Parse(backendBase)
 	if err != nil {
-		logger.Debug("ProxySandbox: Invalid backend URL: %v", err)
+		logger.ErrorCtx(r.Context(), "ProxySandbox: Invalid backend URL: %v", err)
 		respondError(w, http.StatusInternalServerError, "proxy_error", "Invalid backend URL")
 		return
 	}
 
 	proxy := httputil.NewSingleHostReverseProxy(target) //nolint:gosec // G704: target is built from trusted pod IP, not user input
-	// Use a transport with ResponseHeaderTimeout to prevent hanging when backend pods
-	// never respond (e.g. pod not yet ready, crashed). The default transport has no such
-	// timeout, which caused 742+ second hangs observed in Datadog.
-	// Set to 300s to accommodate slow conversation creation (agent-server does heavy init:
-	// git clones, skill loading, MCP server startup) which can exceed 120s.
-	proxyTransport := http.DefaultTransport.(*http.Transport).Clone()
-	proxyTransport.ResponseHeaderTimeout = 300 * time.Second
-	proxy.Transport = httptrace.WrapRoundTripper(proxyTransport)
+	proxy.Transport = h.sandboxTransportFor(runtimeInfo)
+	// Flush immediately so streamed responses (SSE, chunked logs) reach the client as they
+	// are written. WebSocket upgrades are handled by ReverseProxy itself: it forwards the
+	// Connection/Upgrade headers and, on 101, hijacks the client connection (which clears
+	// the server's read/write/idle deadlines) and copies frames in both directions.
+	proxy.FlushInterval = -1
 	proxy.Director = func(req *http.Request) {
 		req.URL.Scheme = target.Scheme
 		req.URL.Host = target.Host
@@ -801,6 +2080,10 @@ func (h *Handler) ProxySandbox(w http.ResponseWriter, r *http.Request) {
 		if req.Header == nil {
 			req.Header = make(http.Header)
 		}
+		// Forward the request ID so sandbox log lines can be correlated with ours
+		if id := logger.RequestIDFromContext(r.Context()); id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
 		// Forward session API key so sandbox can validate
 		if v := r.Header.Get("X-Session-API-Key"); v != "" {
 			req.Header.Set("X-Session-API-Key", v)
@@ -819,10 +2102,181 @@ func (h *Handler) ProxySandbox(w http.ResponseWriter, r *http.Request) {
 
 	// Rewrite Set-Cookie and Location headers to use the correct path for the proxy
 	proxy.ModifyResponse = h.createProxyResponseRewriter(runtimeID, backendPort)
+	retried := false
+	var startupDeadline time.Time
+	startupBackoff := proxyStartupInitialBackoff
+	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
+		// A freshly started sandbox refuses connections until its agent server listens.
+		// Wait for it (retrying body-less requests) rather than surfacing a raw 502.
+		if isConnRefused(err) && h.config.ProxyStartupGrace > 0 && h.k8sClient != nil && h.sandboxStarting(r.Context(), runtimeInfo) {
+			if startupDeadline.IsZero() {
+				startupDeadline = time.Now().Add(h.config.ProxyStartupGrace)
+			}
+			if (r.Body == nil || r.Body == http.NoBody) && time.Now().Add(startupBackoff).Before(startupDeadline) {
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(startupBackoff):
+				}
+				startupBackoff = min(startupBackoff*2, proxyStartupMaxBackoff)
+				proxy.ServeHTTP(rw, r) //nolint:gosec // G704: proxy target is a trusted internal pod address
+				return
+			}
+			logger.WarnCtx(r.Context(), "ProxySandbox: Runtime %s is not accepting connections yet: %v", runtimeID, err)
+			rw.Header().Set("Retry-After", "5")
+			respondError(rw, http.StatusServiceUnavailable, "sandbox_not_ready", "Sandbox is starting and not accepting connections yet")
+			return
+		}
+		// A Service that no longer resolves fails before anything is sent, so a body-less
+		// request can safely be retried once the Service is back
+		if !retried && h.config.ProxySelfHealService && h.k8sClient != nil && isDNSError(err) &&
+			(r.Body == nil || r.Body == http.NoBody) {
+			retried = true
+			if h.healMissingService(r.Context(), runtimeInfo) {
+				proxy.ServeHTTP(rw, r) //nolint:gosec // G704: proxy target is a trusted internal pod address
+				return
+			}
+		}
+		logger.ErrorCtx(r.Context(), "ProxySandbox: Error proxying %s %s to runtime %s: %v", req.Method, req.URL.Path, runtimeID, err)
+		rw.WriteHeader(http.StatusBadGateway)
+	}
+
+	// The server's read and write timeouts are sized for management calls; they would cut
+	// off large uploads and long-running or streamed responses. Proxied requests are bounded
+	// by the transport's timeouts and the client's connection instead.
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	// Count traffic on upgraded (WebSocket) connections as activity for as long as they
+	// stay open, so long-lived interactive sessions aren't reaped mid-use
+	w = &activityResponseWriter{ResponseWriter: w, touch: h.activityToucher(runtimeID)}
 
 	proxy.ServeHTTP(w, r) //nolint:gosec // G704: proxy target is a trusted internal pod address
 }
 
+// Backoff between proxy retries while a sandbox is starting, for PROXY_STARTUP_GRACE
+const (
+	proxyStartupInitialBackoff = 250 * time.Millisecond
+	proxyStartupMaxBackoff     = 2 * time.Second
+)
+
+// isConnRefused reports whether a proxy error is a refused connection, as seen while the
+// sandbox's agent server is not yet listening
+func isConnRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// sandboxStarting reports whether the sandbox's pod is pending or running, i.e. worth
+// waiting for, as opposed to failed or gone
+func (h *Handler) sandboxStarting(ctx context.Context, runtimeInfo *state.RuntimeInfo) bool {
+	ctx, cancel := context.WithTimeout(ctx, h.config.K8sQueryTimeout)
+	defer cancel()
+
+	podStatus, err := h.k8sClient.GetPodStatus(ctx, runtimeInfo.PodName)
+	if err != nil {
+		return false
+	}
+	switch podStatus.Status {
+	case types.PodStatusPending, types.PodStatusRunning, types.PodStatusReady:
+		return true
+	default:
+		return false
+	}
+}
+
+// sandboxTransport returns the transport shared by proxied requests. ResponseHeaderTimeout
+// (PROXY_RESPONSE_HEADER_TIMEOUT) stops requests hanging on backends that never respond,
+// e.g. a pod that isn't ready or has crashed; without it we saw 742+ second hangs. It
+// defaults to 300s because conversation creation (git clones, skill loading, MCP server
+// startup) can exceed 120s.
+func (h *Handler) sandboxTransport() http.RoundTripper {
+	h.proxyTransportOnce.Do(func() {
+		h.proxyTransport = h.newSandboxTransport(h.config.ProxyResponseHeaderTimeout)
+	})
+	return h.proxyTransport
+}
+
+// sandboxTransportFor returns the transport for a runtime's proxied requests, honouring
+// its proxy_timeout_seconds override
+func (h *Handler) sandboxTransportFor(runtimeInfo *state.RuntimeInfo) http.RoundTripper {
+	timeout := runtimeInfo.ProxyTimeout
+	if timeout <= 0 || timeout == h.config.ProxyResponseHeaderTimeout {
+		return h.sandboxTransport()
+	}
+	h.proxyTransportsMu.Lock()
+	defer h.proxyTransportsMu.Unlock()
+	if transport, ok := h.proxyTransports[timeout]; ok {
+		return transport
+	}
+	if h.proxyTransports == nil {
+		h.proxyTransports = make(map[time.Duration]http.RoundTripper)
+	}
+	transport := h.newSandboxTransport(timeout)
+	h.proxyTransports[timeout] = transport
+	return transport
+}
+
+func (h *Handler) newSandboxTransport(responseHeaderTimeout time.Duration) http.RoundTripper {
+	dialer := &net.Dialer{Timeout: h.config.ProxyDialTimeout, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if h.sandboxDialContext != nil {
+			return h.sandboxDialContext(ctx, network, addr)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+	transport.IdleConnTimeout = h.config.ProxyIdleConnTimeout
+	// Go keeps only 2 idle connections per host by default, so concurrent requests to a
+	// busy sandbox would otherwise keep redialing it
+	if h.config.ProxyMaxIdleConns > 0 {
+		transport.MaxIdleConns = h.config.ProxyMaxIdleConns
+	}
+	if h.config.ProxyMaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = h.config.ProxyMaxIdleConnsPerHost
+	}
+	return httptrace.WrapRoundTripper(transport)
+}
+
+// isDNSError reports whether a proxy error is a failed name lookup
+func isDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// healMissingService recreates the Service of a sandbox whose pod is still running, for
+// PROXY_SELF_HEAL_SERVICE. Returns true if the Service exists afterwards.
+func (h *Handler) healMissingService(ctx context.Context, runtimeInfo *state.RuntimeInfo) bool {
+	ctx, cancel := context.WithTimeout(ctx, h.config.K8sOperationTimeout)
+	defer cancel()
+
+	podStatus, err := h.k8sClient.GetPodStatus(ctx, runtimeInfo.PodName)
+	if err != nil || (podStatus.Status != types.PodStatusRunning && podStatus.Status != types.PodStatusReady) {
+		logger.DebugCtx(ctx, "ProxySandbox: Not recreating service for runtime %s: pod is not running", runtimeInfo.RuntimeID)
+		return false
+	}
+	if err := h.k8sClient.EnsureService(ctx, runtimeInfo); err != nil {
+		logger.ErrorCtx(ctx, "ProxySandbox: Failed to recreate service %s for runtime %s: %v", runtimeInfo.ServiceName, runtimeInfo.RuntimeID, err)
+		return false
+	}
+	logger.WarnCtx(ctx, "ProxySandbox: Recreated missing service %s for runtime %s", runtimeInfo.ServiceName, runtimeInfo.RuntimeID)
+	return true
+}
+
+// validSessionKey reports whether the request presents the runtime's session API key, in
+// the X-Session-API-Key header or (for WebSocket clients) the session_api_key query parameter
+func validSessionKey(r *http.Request, expected string) bool {
+	presented := r.Header.Get("X-Session-API-Key")
+	if presented == "" {
+		presented = r.URL.Query().Get("session_api_key")
+	}
+	if presented == "" || expected == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(expected)) == 1
+}
+
 // createProxyResponseRewriter creates a response modifier that rewrites Set-Cookie and Location headers
 // to use the correct proxy path format (/sandbox/{runtime_id}/...).
 func (h *Handler) createProxyResponseRewriter(runtimeID string, backendPort int) func(*http.Response) error {
@@ -859,6 +2313,12 @@ func (h *Handler) createProxyResponseRewriter(runtimeID string, backendPort int)
 			}
 		}
 
+		// Server-Sent Events are flushed as they arrive (FlushInterval); also tell an
+		// nginx ingress in front of us not to buffer them
+		if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+			resp.Header.Set("X-Accel-Buffering", "no")
+		}
+
 		return nil
 	}
 }
@@ -907,7 +2367,7 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		logger.Info("Error encoding JSON response: %v", err)
+		logger.Error("Error encoding JSON response: %v", err)
 	}
 }
 
@@ -919,7 +2379,7 @@ func respondError(w http.ResponseWriter, status int, errorType, message string)
 		Error:   errorType,
 		Message: message,
 	}); err != nil {
-		logger.Info("Error encoding error response: %v", err)
+		logger.Error("Error encoding error response: %v", err)
 	}
 }
 
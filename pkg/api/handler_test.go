@@ -1,18 +1,43 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/cleanup"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/config"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/k8s"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/logger"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/metrics"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/reaper"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/state"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/types"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func setupTestHandler() (*Handler, *state.StateManager) {
@@ -21,8 +46,7 @@ func setupTestHandler() (*Handler, *state.StateManager) {
 		APIKey:          "test-api-key",
 		Namespace:       "test",
 		BaseDomain:      "test.example.com",
-		Worker1Port:     12000,
-		Worker2Port:     12001,
+		WorkerPorts:     []int{12000, 12001},
 		AgentServerPort: 60000,
 		VSCodePort:      60001,
 		DefaultImage:    "test-image",
@@ -35,6 +59,8 @@ func setupTestHandler() (*Handler, *state.StateManager) {
 		stateMgr:     stateMgr,
 		config:       cfg,
 		tracedClient: http.DefaultClient,
+		startLocks:   newSessionLocks(time.Minute),
+		operations:   newOperationStore(time.Hour),
 	}
 
 	return handler, stateMgr
@@ -278,6 +304,71 @@ func TestGetRuntime(t *testing.T) {
 	// which is skipped for now
 }
 
+func TestRuntimeStatus_StaleOnQueryTimeout(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.K8sQueryTimeout = 50 * time.Millisecond
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runtime-runtime-123", Namespace: "test", Labels: map[string]string{"app": "openhands-runtime"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	})
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID: "runtime-123",
+		SessionID: "session-456",
+		Status:    types.StatusRunning,
+		PodStatus: types.PodStatusPending,
+		PodName:   "runtime-runtime-123",
+	})
+
+	var hang atomic.Bool
+	hangUntilTimeout := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if !hang.Load() {
+			return false, nil, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+		return true, nil, context.DeadlineExceeded
+	}
+	clientset.PrependReactor("get", "pods", hangUntilTimeout)
+	clientset.PrependReactor("list", "pods", hangUntilTimeout)
+
+	getRuntime := func() types.RuntimeResponse {
+		req := httptest.NewRequest("GET", "/runtime/runtime-123", nil)
+		req = mux.SetURLVars(req, map[string]string{"runtime_id": "runtime-123"})
+		rr := httptest.NewRecorder()
+		handler.GetRuntime(rr, req)
+		var resp types.RuntimeResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+	listRuntimes := func() types.ListResponse {
+		rr := httptest.NewRecorder()
+		handler.ListRuntimes(rr, httptest.NewRequest("GET", "/list", nil))
+		var resp types.ListResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	hang.Store(true)
+	if resp := getRuntime(); !resp.StatusStale || resp.PodStatus != types.PodStatusPending {
+		t.Errorf("Expected stale last-known pending status on timeout, got stale=%v pod_status=%s", resp.StatusStale, resp.PodStatus)
+	}
+	if resp := listRuntimes(); len(resp.Runtimes) != 1 || !resp.Runtimes[0].StatusStale {
+		t.Errorf("Expected list entry flagged stale on timeout, got %+v", resp.Runtimes)
+	}
+
+	hang.Store(false)
+	if resp := getRuntime(); resp.StatusStale || resp.PodStatus != types.PodStatusRunning {
+		t.Errorf("Expected fresh running status, got stale=%v pod_status=%s", resp.StatusStale, resp.PodStatus)
+	}
+	if resp := listRuntimes(); len(resp.Runtimes) != 1 || resp.Runtimes[0].StatusStale {
+		t.Errorf("Expected fresh list entry, got %+v", resp.Runtimes)
+	}
+}
+
 func TestGetSession(t *testing.T) {
 	handler, stateMgr := setupTestHandler()
 
@@ -324,6 +415,54 @@ func TestGetSessionsBatch(t *testing.T) {
 	// Note: Testing with valid IDs would require k8s client mock
 }
 
+func TestStartRuntime_NegativeGPUCount(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+
+	reqBody := types.StartRequest{
+		Image:     "test-image",
+		SessionID: "session-gpu",
+		GPU:       &types.GPURequest{Count: -1},
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/start", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.StartRuntime(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+	if len(stateMgr.ListRuntimes()) != 0 {
+		t.Error("Expected no runtime to be added to state")
+	}
+}
+
+func TestStartRuntime_InvalidGPUResourceName(t *testing.T) {
+	for _, name := range []string{"cpu", "memory", "ephemeral-storage", "kubernetes.io/gpu", "requests.nvidia.com/gpu"} {
+		t.Run(name, func(t *testing.T) {
+			handler, stateMgr := setupTestHandler()
+
+			reqBody := types.StartRequest{
+				Image:     "test-image",
+				SessionID: "session-gpu",
+				GPU:       &types.GPURequest{Count: 1, ResourceName: name},
+			}
+			body, _ := json.Marshal(reqBody)
+			req := httptest.NewRequest("POST", "/start", bytes.NewReader(body))
+			rr := httptest.NewRecorder()
+
+			handler.StartRuntime(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", rr.Code)
+			}
+			if len(stateMgr.ListRuntimes()) != 0 {
+				t.Error("Expected no runtime to be added to state")
+			}
+		})
+	}
+}
+
 func TestStopRuntime(t *testing.T) {
 	handler, stateMgr := setupTestHandler()
 
@@ -356,6 +495,33 @@ func TestStopRuntime(t *testing.T) {
 			t.Errorf("Expected status 400, got %d", rr.Code)
 		}
 	})
+
+	t.Run("Stop keeps caller workspace PVC", func(t *testing.T) {
+		handler.config.DeletePVCOnStop = true
+		clientset := fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "caller-pvc", Namespace: "test"},
+		})
+		handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+		stateMgr.AddRuntime(&state.RuntimeInfo{
+			RuntimeID:        "runtime-pvc",
+			SessionID:        "session-pvc",
+			PodName:          "runtime-runtime-pvc",
+			WorkspacePVCName: "caller-pvc",
+		})
+
+		body, _ := json.Marshal(types.StopRequest{RuntimeID: "runtime-pvc"})
+		req := httptest.NewRequest("POST", "/stop", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+
+		handler.StopRuntime(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if _, err := clientset.CoreV1().PersistentVolumeClaims("test").Get(context.Background(), "caller-pvc", metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected workspace_pvc_name PVC to survive /stop, got %v", err)
+		}
+	})
 }
 
 func TestGenerateID(t *testing.T) {
@@ -399,7 +565,7 @@ func TestBuildRuntimeResponse_WithoutProxy(t *testing.T) {
 	})
 
 	info, _ := stateMgr.GetRuntimeByID("rt-123")
-	resp := handler.buildRuntimeResponse(info)
+	resp := handler.buildRuntimeResponse(nil, info)
 
 	if resp.URL != "https://sess-456.test.example.com" {
 		t.Errorf("Expected URL from RuntimeInfo, got %q", resp.URL)
@@ -424,7 +590,7 @@ func TestBuildRuntimeResponse_WithProxy(t *testing.T) {
 	})
 
 	info, _ := stateMgr.GetRuntimeByID("rt-abc")
-	resp := handler.buildRuntimeResponse(info)
+	resp := handler.buildRuntimeResponse(nil, info)
 
 	expectedURL := "https://runtime-api.example.com/sandbox/rt-abc"
 	if resp.URL != expectedURL {
@@ -436,6 +602,48 @@ func TestBuildRuntimeResponse_WithProxy(t *testing.T) {
 	}
 }
 
+func TestBuildRuntimeResponse_InternalURL(t *testing.T) {
+	tests := []struct {
+		name         string
+		proxyBaseURL string
+		expectedURL  string
+	}{
+		{"Subdomain routing", "", "https://sess-xyz.test.example.com"},
+		{"Proxy mode", "https://runtime-api.example.com", "https://runtime-api.example.com/sandbox/rt-abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := setupTestHandler()
+			handler.config.ProxyBaseURL = tt.proxyBaseURL
+
+			resp := handler.buildRuntimeResponse(nil, &state.RuntimeInfo{
+				RuntimeID:   "rt-abc",
+				SessionID:   "sess-xyz",
+				URL:         "https://sess-xyz.test.example.com",
+				Status:      types.StatusRunning,
+				ServiceName: "runtime-rt-abc",
+			})
+
+			if resp.URL != tt.expectedURL {
+				t.Errorf("Expected external URL %q, got %q", tt.expectedURL, resp.URL)
+			}
+			expectedInternal := "http://runtime-rt-abc.test.svc.cluster.local:60000"
+			if resp.InternalURL != expectedInternal {
+				t.Errorf("Expected internal URL %q, got %q", expectedInternal, resp.InternalURL)
+			}
+		})
+	}
+
+	t.Run("Omitted without a service", func(t *testing.T) {
+		handler, _ := setupTestHandler()
+		resp := handler.buildRuntimeResponse(nil, &state.RuntimeInfo{RuntimeID: "rt-abc"})
+		if resp.InternalURL != "" {
+			t.Errorf("Expected no internal URL without a service name, got %q", resp.InternalURL)
+		}
+	})
+}
+
 func TestBuildRuntimeResponse_WithProxyBaseURLTrailingSlash(t *testing.T) {
 	handler, stateMgr := setupTestHandler()
 	handler.config.ProxyBaseURL = "https://runtime-api.example.com/"
@@ -450,7 +658,7 @@ func TestBuildRuntimeResponse_WithProxyBaseURLTrailingSlash(t *testing.T) {
 	})
 
 	info, _ := stateMgr.GetRuntimeByID("rt-1")
-	resp := handler.buildRuntimeResponse(info)
+	resp := handler.buildRuntimeResponse(nil, info)
 
 	// buildRuntimeResponse uses TrimSuffix on ProxyBaseURL
 	if resp.URL != "https://runtime-api.example.com/sandbox/rt-1" {
@@ -458,6 +666,74 @@ func TestBuildRuntimeResponse_WithProxyBaseURLTrailingSlash(t *testing.T) {
 	}
 }
 
+func TestBuildRuntimeResponse_ProxyBaseFromRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		fromRequest bool
+		host        string
+		headers     map[string]string
+		expectedURL string
+	}{
+		{"Disabled uses configured base", false, "other.example.com", nil, "https://runtime-api.example.com/api/sandbox/rt-1"},
+		{"Request host", true, "other.example.com", nil, "https://other.example.com/api/sandbox/rt-1"},
+		{"Request host with port", true, "other.example.com:8443", nil, "https://other.example.com:8443/api/sandbox/rt-1"},
+		{"Forwarded host wins", true, "runtime-api.svc", map[string]string{"X-Forwarded-Host": "edge.example.org"}, "https://edge.example.org/api/sandbox/rt-1"},
+		{"Last forwarded hop wins", true, "runtime-api.svc", map[string]string{"X-Forwarded-Host": "evil.example.com, edge.example.org"}, "https://edge.example.org/api/sandbox/rt-1"},
+		{"Wildcard entry", true, "b.tenants.example.com", nil, "https://b.tenants.example.com/api/sandbox/rt-1"},
+		{"Wildcard does not match its apex", true, "tenants.example.com", nil, "https://runtime-api.example.com/api/sandbox/rt-1"},
+		{"Host not allowed falls back", true, "evil.example.com", nil, "https://runtime-api.example.com/api/sandbox/rt-1"},
+		{"Forwarded host not allowed falls back", true, "other.example.com", map[string]string{"X-Forwarded-Host": "evil.example.com"}, "https://runtime-api.example.com/api/sandbox/rt-1"},
+		{"Forwarded proto", true, "other.example.com", map[string]string{"X-Forwarded-Proto": "http"}, "http://other.example.com/api/sandbox/rt-1"},
+		{"Unknown proto ignored", true, "other.example.com", map[string]string{"X-Forwarded-Proto": "gopher"}, "https://other.example.com/api/sandbox/rt-1"},
+		{"Host with path falls back", true, "other.example.com", map[string]string{"X-Forwarded-Host": "evil.example.com/steal"}, "https://runtime-api.example.com/api/sandbox/rt-1"},
+		{"Host with userinfo falls back", true, "other.example.com", map[string]string{"X-Forwarded-Host": "user@evil.example.com"}, "https://runtime-api.example.com/api/sandbox/rt-1"},
+		{"Empty host falls back", true, "", nil, "https://runtime-api.example.com/api/sandbox/rt-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := setupTestHandler()
+			handler.config.ProxyBaseURL = "https://runtime-api.example.com/api"
+			handler.config.ProxyBaseFromRequest = tt.fromRequest
+			handler.config.ProxyAllowedHosts = []string{"other.example.com", "edge.example.org", "*.tenants.example.com"}
+			req := httptest.NewRequest("GET", "/runtime/rt-1", nil)
+			req.Host = tt.host
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			resp := handler.buildRuntimeResponse(req, &state.RuntimeInfo{RuntimeID: "rt-1"})
+			if resp.URL != tt.expectedURL {
+				t.Errorf("Expected URL %q, got %q", tt.expectedURL, resp.URL)
+			}
+			if resp.VSCodeURL != tt.expectedURL+"/vscode" {
+				t.Errorf("Expected VSCode URL %q, got %q", tt.expectedURL+"/vscode", resp.VSCodeURL)
+			}
+		})
+	}
+
+	t.Run("GET /runtime reflects the request host", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.ProxyBaseURL = "https://runtime-api.example.com"
+		handler.config.ProxyBaseFromRequest = true
+		handler.config.ProxyAllowedHosts = []string{"tenant-b.example.com"}
+		handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+		stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "rt-1", SessionID: "s1", Status: types.StatusRunning, PodName: "runtime-rt-1"})
+
+		req := httptest.NewRequest("GET", "/runtime/rt-1", nil)
+		req.Host = "tenant-b.example.com"
+		req = mux.SetURLVars(req, map[string]string{"runtime_id": "rt-1"})
+		rr := httptest.NewRecorder()
+		handler.GetRuntime(rr, req)
+
+		var resp types.RuntimeResponse
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.URL != "https://tenant-b.example.com/sandbox/rt-1" {
+			t.Errorf("Expected URL on the request host, got %q (status %d)", resp.URL, rr.Code)
+		}
+	})
+}
+
 func TestBuildRuntimeResponse_WithDirectRouting(t *testing.T) {
 	handler, stateMgr := setupTestHandler()
 	handler.config.DirectRouting = true
@@ -474,7 +750,7 @@ func TestBuildRuntimeResponse_WithDirectRouting(t *testing.T) {
 	})
 
 	info, _ := stateMgr.GetRuntimeByID("rt-direct")
-	resp := handler.buildRuntimeResponse(info)
+	resp := handler.buildRuntimeResponse(nil, info)
 
 	expectedURL := "https://runtime-api.example.com/sandbox/rt-direct"
 	if resp.URL != expectedURL {
@@ -500,7 +776,7 @@ func TestBuildRuntimeResponse_DirectRoutingTakesPrecedenceOverProxy(t *testing.T
 	})
 
 	info, _ := stateMgr.GetRuntimeByID("rt-both")
-	resp := handler.buildRuntimeResponse(info)
+	resp := handler.buildRuntimeResponse(nil, info)
 
 	// DirectRouting takes precedence — URL must use BaseDomain, not ProxyBaseURL
 	if resp.URL != "https://runtime-api.example.com/sandbox/rt-both" {
@@ -508,426 +784,1164 @@ func TestBuildRuntimeResponse_DirectRoutingTakesPrecedenceOverProxy(t *testing.T
 	}
 }
 
-func TestProxySandbox_NotFound(t *testing.T) {
-	handler, stateMgr := setupTestHandler()
-	stateMgr.AddRuntime(&state.RuntimeInfo{
-		RuntimeID:   "rt-1",
-		SessionID:   "s1",
-		ServiceName: "runtime-rt-1",
+func TestAuthMiddleware_MultipleKeys(t *testing.T) {
+	handler, _ := setupTestHandler()
+	handler.config.APIKey = ""
+	handler.config.APIKeys = []string{"new-key", "old-key"}
+
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+	}{
+		{"New key accepted", "new-key", http.StatusOK},
+		{"Old key accepted during rotation", "old-key", http.StatusOK},
+		{"Unknown key rejected", "retired-key", http.StatusUnauthorized},
+		{"Prefix of a key rejected", "new", http.StatusUnauthorized},
+		{"Missing key rejected", "", http.StatusUnauthorized},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
 	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/list", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rr := httptest.NewRecorder()
+			handler.AuthMiddleware(next).ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
 
-	t.Run("Path without sandbox prefix", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/other/rt-1/alive", nil)
-		req.URL.Path = "/other/rt-1/alive"
-		rr := httptest.NewRecorder()
-		handler.ProxySandbox(rr, req)
-		if rr.Code != http.StatusNotFound {
-			t.Errorf("Expected 404, got %d", rr.Code)
-		}
-	})
+func TestAuthMiddleware_SingleKeyAlongsideList(t *testing.T) {
+	handler, _ := setupTestHandler()
+	handler.config.APIKey = "legacy-key"
+	handler.config.APIKeys = []string{"new-key"}
 
-	t.Run("Unknown runtime ID", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/sandbox/nonexistent-id/alive", nil)
-		req.URL.Path = "/sandbox/nonexistent-id/alive"
-		rr := httptest.NewRecorder()
-		handler.ProxySandbox(rr, req)
-		if rr.Code != http.StatusNotFound {
-			t.Errorf("Expected 404 for unknown runtime, got %d", rr.Code)
-		}
-		var errResp types.ErrorResponse
-		_ = json.NewDecoder(rr.Body).Decode(&errResp)
-		if errResp.Error != "runtime_not_found" {
-			t.Errorf("Expected error runtime_not_found, got %q", errResp.Error)
-		}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
 	})
-
-	t.Run("Empty path after sandbox", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/sandbox/", nil)
-		req.URL.Path = "/sandbox/"
+	for _, key := range []string{"legacy-key", "new-key"} {
+		req := httptest.NewRequest("GET", "/list", nil)
+		req.Header.Set("X-API-Key", key)
 		rr := httptest.NewRecorder()
-		handler.ProxySandbox(rr, req)
-		if rr.Code != http.StatusNotFound {
-			t.Errorf("Expected 404 for empty path, got %d", rr.Code)
+		handler.AuthMiddleware(next).ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected key %q to be accepted, got %d", key, rr.Code)
 		}
-	})
+	}
 }
 
-func TestBatchGetConversations_InvalidBody(t *testing.T) {
+func TestAuthMiddleware_ReadOnlyKeys(t *testing.T) {
 	handler, _ := setupTestHandler()
+	handler.config.ReadOnlyAPIKeys = []string{"dashboard-key"}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		key        string
+		wantStatus int
+	}{
+		{"Read-only key can list", "GET", "/list", "dashboard-key", http.StatusOK},
+		{"Read-only key can fetch stats", "GET", "/stats", "dashboard-key", http.StatusOK},
+		{"Read-only key cannot batch fetch conversations", "POST", "/sessions/batch-conversations", "dashboard-key", http.StatusForbidden},
+		{"Read-only key cannot start", "POST", "/start", "dashboard-key", http.StatusForbidden},
+		{"Read-only key cannot delete", "DELETE", "/runtime/abc", "dashboard-key", http.StatusForbidden},
+		{"Full key can start", "POST", "/start", "test-api-key", http.StatusOK},
+		{"Unknown key rejected", "GET", "/list", "other-key", http.StatusUnauthorized},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-API-Key", tt.key)
+			rr := httptest.NewRecorder()
+			handler.AuthMiddleware(next).ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
 
-	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader([]byte("not json")))
-	rr := httptest.NewRecorder()
-
-	handler.BatchGetConversations(rr, req)
+func TestAuthMiddleware_ReadOnlyKeysOmitSessionAPIKey(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.ReadOnlyAPIKeys = []string{"dashboard-key"}
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:     "runtime-123",
+		SessionID:     "session-456",
+		Status:        types.StatusRunning,
+		PodName:       "runtime-runtime-123",
+		SessionAPIKey: "secret-session-key",
+	})
 
-	if rr.Code != http.StatusBadRequest {
-		t.Errorf("Expected status 400, got %d", rr.Code)
+	router := mux.NewRouter()
+	router.Use(handler.AuthMiddleware)
+	router.HandleFunc("/list", handler.ListRuntimes).Methods("GET")
+	router.HandleFunc("/runtime/{runtime_id}", handler.GetRuntime).Methods("GET")
+
+	tests := []struct {
+		name    string
+		path    string
+		key     string
+		wantKey bool
+	}{
+		{"Read-only key list", "/list", "dashboard-key", false},
+		{"Read-only key runtime", "/runtime/runtime-123", "dashboard-key", false},
+		{"Full key list", "/list", "test-api-key", true},
+		{"Full key runtime", "/runtime/runtime-123", "test-api-key", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("X-API-Key", tt.key)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			body := rr.Body.String()
+			if got := strings.Contains(body, "session_api_key"); got != tt.wantKey {
+				t.Errorf("Expected session_api_key present=%v, got body %s", tt.wantKey, body)
+			}
+			if !tt.wantKey && strings.Contains(body, "secret-session-key") {
+				t.Errorf("Expected read-only response to omit the session key, got %s", body)
+			}
+		})
 	}
+}
 
-	var errResp types.ErrorResponse
-	if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
-		t.Fatalf("Failed to decode error response: %v", err)
-	}
-	if errResp.Error != "invalid_request" {
-		t.Errorf("Expected error 'invalid_request', got %q", errResp.Error)
+func TestLoggingMiddleware_RequestID(t *testing.T) {
+	handler, _ := setupTestHandler()
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"Caller ID is kept", "caller-id-1", true},
+		{"Missing ID is generated", "", false},
+		{"Overlong ID is replaced", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"ID with spaces is replaced", "bad id", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = logger.RequestIDFromContext(r.Context())
+			})
+			req := httptest.NewRequest("GET", "/list", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestIDHeader, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.LoggingMiddleware(next).ServeHTTP(rr, req)
+
+			echoed := rr.Header().Get(requestIDHeader)
+			if echoed == "" || echoed != seen {
+				t.Errorf("Expected response header %q to match context request ID %q", echoed, seen)
+			}
+			if (echoed == tt.incoming) != tt.keep {
+				t.Errorf("Expected caller ID kept=%v, got %q", tt.keep, echoed)
+			}
+		})
 	}
 }
 
-func TestBatchGetConversations_EmptySandboxes(t *testing.T) {
-	handler, _ := setupTestHandler()
+func TestProxySandbox_ForwardsRequestID(t *testing.T) {
+	forwarded := make(chan string, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded <- r.Header.Get(requestIDHeader)
+	}))
+	defer backend.Close()
 
-	reqBody := types.BatchConversationsRequest{
-		Sandboxes: map[string]types.BatchConversationSandbox{},
-	}
-	body, _ := json.Marshal(reqBody)
-	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
-	rr := httptest.NewRecorder()
+	handler, stateMgr := setupTestHandler()
+	routeSandboxesTo(handler, backend)
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:   "rt-1",
+		SessionID:   "s1",
+		Status:      types.StatusRunning,
+		ServiceName: "runtime-rt-1",
+	})
 
-	handler.BatchGetConversations(rr, req)
+	req := httptest.NewRequest("GET", "/sandbox/rt-1/api/conversations", nil)
+	rr := httptest.NewRecorder()
+	handler.LoggingMiddleware(http.HandlerFunc(handler.ProxySandbox)).ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", rr.Code)
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
 	}
-
-	var resp map[string]json.RawMessage
-	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
-		t.Fatalf("Failed to decode response: %v", err)
+	id := rr.Header().Get(requestIDHeader)
+	if got := <-forwarded; got == "" || got != id {
+		t.Errorf("Expected sandbox to receive request ID %q, got %q", id, got)
 	}
-	if len(resp) != 0 {
-		t.Errorf("Expected empty response, got %d entries", len(resp))
+}
+
+func TestProxySandbox_ValidateSessionKey(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name       string
+		validate   bool
+		path       string
+		header     string
+		wantStatus int
+	}{
+		{"Disabled forwards without key", false, "/sandbox/rt-1/api/conversations", "", http.StatusOK},
+		{"Valid header key", true, "/sandbox/rt-1/api/conversations", "sandbox-key", http.StatusOK},
+		{"Valid query key", true, "/sandbox/rt-1/sockets/events?session_api_key=sandbox-key", "", http.StatusOK},
+		{"Wrong key", true, "/sandbox/rt-1/api/conversations", "other-key", http.StatusUnauthorized},
+		{"Missing key", true, "/sandbox/rt-1/api/conversations", "", http.StatusUnauthorized},
+		{"VSCode uses its own token", true, "/sandbox/rt-1/vscode/", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, stateMgr := setupTestHandler()
+			handler.config.ProxyValidateSessionKey = tt.validate
+			routeSandboxesTo(handler, backend)
+			stateMgr.AddRuntime(&state.RuntimeInfo{
+				RuntimeID:     "rt-1",
+				SessionID:     "s1",
+				SessionAPIKey: "sandbox-key",
+				Status:        types.StatusRunning,
+				ServiceName:   "runtime-rt-1",
+			})
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("X-Session-API-Key", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ProxySandbox(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
 	}
 }
 
-func TestBatchGetConversations_RuntimeNotFound(t *testing.T) {
-	handler, _ := setupTestHandler()
+func TestCordonRuntime(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
 
-	reqBody := types.BatchConversationsRequest{
-		Sandboxes: map[string]types.BatchConversationSandbox{
-			"nonexistent-runtime": {
-				SessionID:       "nonexistent-session",
-				ConversationIDs: []string{"conv1"},
-			},
-		},
-	}
-	body, _ := json.Marshal(reqBody)
-	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
-	rr := httptest.NewRecorder()
+	handler, stateMgr := setupTestHandler()
+	handler.config.K8sQueryTimeout = 5 * time.Second
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+	routeSandboxesTo(handler, backend)
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:   "rt-1",
+		SessionID:   "s1",
+		Status:      types.StatusRunning,
+		PodName:     "runtime-rt-1",
+		ServiceName: "runtime-rt-1",
+	})
 
-	handler.BatchGetConversations(rr, req)
+	runtimeRequest := func(method, path string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req = mux.SetURLVars(req, map[string]string{"runtime_id": "rt-1"})
+		rr := httptest.NewRecorder()
+		h(rr, req)
+		return rr
+	}
+	proxyStatus := func() int {
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, httptest.NewRequest("GET", "/sandbox/rt-1/api/conversations", nil))
+		return rr.Code
+	}
 
+	rr := runtimeRequest("POST", "/runtime/rt-1/cordon", handler.CordonRuntime)
 	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", rr.Code)
+		t.Fatalf("Expected cordon to return 200, got %d: %s", rr.Code, rr.Body.String())
 	}
-
-	var resp map[string]json.RawMessage
+	var resp types.RuntimeResponse
 	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
+	if !resp.Cordoned {
+		t.Error("Expected response to report the runtime as cordoned")
+	}
 
-	// Should return empty array for unfound runtime
-	data, ok := resp["nonexistent-runtime"]
-	if !ok {
-		t.Fatal("Expected key 'nonexistent-runtime' in response")
+	if code := proxyStatus(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected cordoned runtime's proxy to return 503, got %d", code)
 	}
-	if string(data) != "[]" {
-		t.Errorf("Expected empty array for unfound runtime, got %s", string(data))
+	if rr := runtimeRequest("GET", "/runtime/rt-1", handler.GetRuntime); rr.Code != http.StatusOK {
+		t.Errorf("Expected GetRuntime to keep working while cordoned, got %d", rr.Code)
+	}
+
+	if rr := runtimeRequest("POST", "/runtime/rt-1/uncordon", handler.UncordonRuntime); rr.Code != http.StatusOK {
+		t.Fatalf("Expected uncordon to return 200, got %d", rr.Code)
+	}
+	if code := proxyStatus(); code != http.StatusOK {
+		t.Errorf("Expected proxying to resume after uncordon, got %d", code)
+	}
+
+	req := httptest.NewRequest("POST", "/runtime/missing/cordon", nil)
+	req = mux.SetURLVars(req, map[string]string{"runtime_id": "missing"})
+	rr = httptest.NewRecorder()
+	handler.CordonRuntime(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown runtime, got %d", rr.Code)
 	}
 }
 
-func TestBatchGetConversations_WithMockAgentServer(t *testing.T) {
-	// Start a mock agent-server that returns conversation data
-	mockConversations := `[{"id":"conv1","status":"running"},{"id":"conv2","status":"idle"}]`
-	var capturedAPIKey string
-	var capturedIDs string
-	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		capturedAPIKey = r.Header.Get("X-Session-API-Key")
-		capturedIDs = r.URL.Query().Get("ids")
-		w.Header().Set("Content-Type", "application/json")
+func TestProxySandbox_SelfHealService(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, mockConversations)
 	}))
-	defer mockServer.Close()
+	defer backend.Close()
+	backendAddr := backend.Listener.Addr().String()
+
+	setup := func(selfHeal bool) (*Handler, *fake.Clientset) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.ProxySelfHealService = selfHeal
+		handler.config.K8sOperationTimeout = 5 * time.Second
+		// The pod is running, but its Service was deleted
+		clientset := fake.NewSimpleClientset(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "runtime-rt-1", Namespace: "test"},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "openhands-agent", Ready: true}},
+			},
+		})
+		handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+		// Service addresses only resolve while the Service object exists
+		handler.sandboxDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if _, err := clientset.CoreV1().Services("test").Get(ctx, "runtime-rt-1", metav1.GetOptions{}); err != nil {
+				return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}}
+			}
+			return (&net.Dialer{}).DialContext(ctx, network, backendAddr)
+		}
+		stateMgr.AddRuntime(&state.RuntimeInfo{
+			RuntimeID:   "rt-1",
+			SessionID:   "s1",
+			Status:      types.StatusRunning,
+			PodName:     "runtime-rt-1",
+			ServiceName: "runtime-rt-1",
+		})
+		return handler, clientset
+	}
+	proxy := func(handler *Handler) int {
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, httptest.NewRequest("GET", "/sandbox/rt-1/api/conversations", nil))
+		return rr.Code
+	}
 
-	// In-cluster DNS won't work in tests, so we use a custom HTTP transport that
-	// redirects the in-cluster URL to our mock server.
-	handler, stateMgr := setupTestHandler()
+	t.Run("Disabled returns 502", func(t *testing.T) {
+		handler, clientset := setup(false)
+		if code := proxy(handler); code != http.StatusBadGateway {
+			t.Errorf("Expected 502 without self-heal, got %d", code)
+		}
+		if _, err := clientset.CoreV1().Services("test").Get(context.Background(), "runtime-rt-1", metav1.GetOptions{}); err == nil {
+			t.Error("Expected the service not to be recreated when self-heal is disabled")
+		}
+	})
 
-	originalTransport := http.DefaultTransport
-	http.DefaultTransport = &mockTransport{
-		mockServerURL: mockServer.URL,
-		inner:         originalTransport,
-	}
-	defer func() { http.DefaultTransport = originalTransport }()
+	t.Run("Enabled recreates the service and retries", func(t *testing.T) {
+		handler, clientset := setup(true)
+		if code := proxy(handler); code != http.StatusOK {
+			t.Errorf("Expected the proxy to self-heal and return 200, got %d", code)
+		}
+		if _, err := clientset.CoreV1().Services("test").Get(context.Background(), "runtime-rt-1", metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected the service to be recreated: %v", err)
+		}
+	})
+}
 
-	// Add a runtime with known service name
-	stateMgr.AddRuntime(&state.RuntimeInfo{
-		RuntimeID:     "rt-100",
-		SessionID:     "sess-100",
-		ServiceName:   "runtime-rt-100",
-		SessionAPIKey: "test-session-key-abc",
-		Status:        types.StatusRunning,
-		PodStatus:     types.PodStatusReady,
-		PodName:       "pod-100",
+func TestProxySandbox_PendingReturnsStarting(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	setup := func(status types.RuntimeStatus, podStatus types.PodStatus, podPhase corev1.PodPhase) *Handler {
+		handler, stateMgr := setupTestHandler()
+		handler.config.SandboxExpectedStartup = 60 * time.Second
+		handler.config.K8sQueryTimeout = 5 * time.Second
+		handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "runtime-rt-1", Namespace: "test"},
+			Status:     corev1.PodStatus{Phase: podPhase},
+		}), handler.config)
+		routeSandboxesTo(handler, backend)
+		stateMgr.AddRuntime(&state.RuntimeInfo{
+			RuntimeID:   "rt-1",
+			SessionID:   "s1",
+			Status:      status,
+			PodStatus:   podStatus,
+			PodName:     "runtime-rt-1",
+			ServiceName: "runtime-rt-1",
+			CreatedAt:   time.Now().Add(-20 * time.Second),
+		})
+		return handler
+	}
+	proxy := func(handler *Handler) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, httptest.NewRequest("GET", "/sandbox/rt-1/alive", nil))
+		return rr
+	}
+
+	t.Run("Pending runtime returns starting", func(t *testing.T) {
+		rr := proxy(setup(types.StatusPending, types.PodStatusPending, corev1.PodPending))
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected 503 for a pending runtime, got %d", rr.Code)
+		}
+		var resp types.SandboxStartingResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.Error != "sandbox_starting" {
+			t.Errorf("Expected sandbox_starting error, got %q", resp.Error)
+		}
+		// 60s expected startup, 20s in
+		if resp.EstimatedWaitSeconds < 39 || resp.EstimatedWaitSeconds > 40 {
+			t.Errorf("Expected an estimated wait of about 40s, got %d", resp.EstimatedWaitSeconds)
+		}
+		if got := rr.Header().Get("Retry-After"); got != strconv.Itoa(resp.EstimatedWaitSeconds) {
+			t.Errorf("Expected Retry-After to match the estimate, got %q", got)
+		}
 	})
 
-	reqBody := types.BatchConversationsRequest{
-		Sandboxes: map[string]types.BatchConversationSandbox{
-			"rt-100": {
-				SessionID:       "sess-100",
-				ConversationIDs: []string{"conv1", "conv2"},
-			},
-		},
-	}
-	body, _ := json.Marshal(reqBody)
-	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
-	rr := httptest.NewRecorder()
+	t.Run("Pending pod returns starting", func(t *testing.T) {
+		if rr := proxy(setup(types.StatusRunning, types.PodStatusPending, corev1.PodPending)); rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 while the pod is pending, got %d", rr.Code)
+		}
+	})
 
-	handler.BatchGetConversations(rr, req)
+	t.Run("Stale pending pod status is refreshed", func(t *testing.T) {
+		if rr := proxy(setup(types.StatusRunning, types.PodStatusPending, corev1.PodRunning)); rr.Code != http.StatusOK {
+			t.Errorf("Expected the request to be proxied once the pod is running, got %d", rr.Code)
+		}
+	})
+}
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d; body: %s", rr.Code, rr.Body.String())
+func TestProxySandbox_StartupGrace(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	backendAddr := backend.Listener.Addr().String()
+
+	// setup returns a handler whose sandbox refuses the first refusals dials, as an agent
+	// server that isn't listening yet would
+	setup := func(phase corev1.PodPhase, grace time.Duration, refusals int32) (*Handler, *atomic.Int32) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.ProxyStartupGrace = grace
+		handler.config.K8sQueryTimeout = 5 * time.Second
+		handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "runtime-rt-1", Namespace: "test"},
+			Status:     corev1.PodStatus{Phase: phase},
+		}), handler.config)
+		dials := &atomic.Int32{}
+		handler.sandboxDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if dials.Add(1) <= refusals {
+				return nil, &net.OpError{Op: "dial", Net: network, Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+			}
+			return (&net.Dialer{}).DialContext(ctx, network, backendAddr)
+		}
+		stateMgr.AddRuntime(&state.RuntimeInfo{
+			RuntimeID:   "rt-1",
+			SessionID:   "s1",
+			Status:      types.StatusRunning,
+			PodName:     "runtime-rt-1",
+			ServiceName: "runtime-rt-1",
+		})
+		return handler, dials
 	}
-
-	// Verify the session API key was forwarded
-	if capturedAPIKey != "test-session-key-abc" {
-		t.Errorf("Expected X-Session-API-Key 'test-session-key-abc', got %q", capturedAPIKey)
+	proxy := func(handler *Handler) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, httptest.NewRequest("GET", "/sandbox/rt-1/alive", nil))
+		return rr
 	}
 
-	// Verify the conversation IDs were passed
-	if capturedIDs != "conv1,conv2" {
-		t.Errorf("Expected ids query param 'conv1,conv2', got %q", capturedIDs)
-	}
+	t.Run("Retries until the agent server listens", func(t *testing.T) {
+		handler, dials := setup(corev1.PodPending, 5*time.Second, 2)
+		if rr := proxy(handler); rr.Code != http.StatusOK {
+			t.Errorf("Expected 200 once the sandbox accepts connections, got %d", rr.Code)
+		}
+		if dials.Load() != 3 {
+			t.Errorf("Expected 3 dial attempts, got %d", dials.Load())
+		}
+	})
 
-	// Verify the response contains the pass-through JSON
-	var resp map[string]json.RawMessage
-	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
-		t.Fatalf("Failed to decode response: %v", err)
-	}
+	t.Run("Returns 503 after the grace period", func(t *testing.T) {
+		handler, _ := setup(corev1.PodRunning, 300*time.Millisecond, 100)
+		rr := proxy(handler)
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected 503 after the grace period, got %d", rr.Code)
+		}
+		var resp types.ErrorResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil || resp.Error != "sandbox_not_ready" {
+			t.Errorf("Expected sandbox_not_ready JSON error, got %q (%v)", resp.Error, err)
+		}
+	})
 
-	data, ok := resp["rt-100"]
-	if !ok {
-		t.Fatal("Expected key 'rt-100' in response")
-	}
+	t.Run("Does not wait for a failed pod", func(t *testing.T) {
+		handler, dials := setup(corev1.PodFailed, 5*time.Second, 100)
+		if rr := proxy(handler); rr.Code != http.StatusBadGateway {
+			t.Errorf("Expected 502 for a failed pod, got %d", rr.Code)
+		}
+		if dials.Load() != 1 {
+			t.Errorf("Expected no retries for a failed pod, got %d dials", dials.Load())
+		}
+	})
 
-	// Verify the raw JSON was passed through
-	if string(data) != mockConversations {
-		t.Errorf("Expected pass-through JSON %q, got %q", mockConversations, string(data))
-	}
+	t.Run("Disabled returns 502", func(t *testing.T) {
+		handler, dials := setup(corev1.PodPending, 0, 100)
+		if rr := proxy(handler); rr.Code != http.StatusBadGateway {
+			t.Errorf("Expected 502 with the grace period disabled, got %d", rr.Code)
+		}
+		if dials.Load() != 1 {
+			t.Errorf("Expected no retries when disabled, got %d dials", dials.Load())
+		}
+	})
 }
 
-func TestBatchGetConversations_MultipleSandboxes(t *testing.T) {
-	// Create two mock servers to simulate different agent-server pods
-	mockServer1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprint(w, `[{"id":"conv1","status":"running"}]`)
-	}))
-	defer mockServer1.Close()
-
-	mockServer2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprint(w, `[{"id":"conv3","status":"idle"}]`)
+func TestProxySandbox_OutlivesServerTimeouts(t *testing.T) {
+	// Backend that reads a slow upload, then streams its response slowly
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		for i := 0; i < 3; i++ {
+			_, _ = fmt.Fprintf(w, "chunk %d\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(150 * time.Millisecond)
+		}
+		_, _ = fmt.Fprintf(w, "received %d\n", n)
 	}))
-	defer mockServer2.Close()
+	defer backend.Close()
 
 	handler, stateMgr := setupTestHandler()
-
-	// Redirect all in-cluster calls to mockServer1 for simplicity
-	// (both runtimes will hit the same mock, but we test concurrency)
-	originalTransport := http.DefaultTransport
-	http.DefaultTransport = &mockTransport{
-		mockServerURL: mockServer1.URL,
-		inner:         originalTransport,
+	routeSandboxesTo(handler, backend)
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "rt-1", SessionID: "s1", Status: types.StatusRunning, ServiceName: "runtime-rt-1"})
+
+	front := httptest.NewUnstartedServer(http.HandlerFunc(handler.ProxySandbox))
+	front.Config.ReadTimeout = 200 * time.Millisecond
+	front.Config.WriteTimeout = 200 * time.Millisecond
+	front.Start()
+	defer front.Close()
+
+	body, upload := io.Pipe()
+	go func() {
+		for i := 0; i < 3; i++ {
+			_, _ = upload.Write([]byte("0123456789"))
+			time.Sleep(150 * time.Millisecond)
+		}
+		_ = upload.Close()
+	}()
+	resp, err := http.Post(front.URL+"/sandbox/rt-1/api/file/upload", "application/octet-stream", body)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
 	}
-	defer func() { http.DefaultTransport = originalTransport }()
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Response was cut off after %q: %v", got, err)
+	}
+	if want := "chunk 0\nchunk 1\nchunk 2\nreceived 30\n"; string(got) != want {
+		t.Errorf("Expected full streamed response %q, got %q", want, got)
+	}
+}
 
-	stateMgr.AddRuntime(&state.RuntimeInfo{
-		RuntimeID:     "rt-a",
-		SessionID:     "sess-a",
-		ServiceName:   "runtime-rt-a",
-		SessionAPIKey: "key-a",
-		Status:        types.StatusRunning,
-		PodName:       "pod-a",
-	})
-	stateMgr.AddRuntime(&state.RuntimeInfo{
-		RuntimeID:     "rt-b",
-		SessionID:     "sess-b",
-		ServiceName:   "runtime-rt-b",
-		SessionAPIKey: "key-b",
-		Status:        types.StatusRunning,
-		PodName:       "pod-b",
-	})
+func TestProxySandbox_ServerSentEvents(t *testing.T) {
+	firstReceived := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		_, _ = fmt.Fprint(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		// Hold the second event until the client has seen the first, so a buffering
+		// proxy would stall here
+		select {
+		case <-firstReceived:
+		case <-time.After(2 * time.Second):
+			_, _ = fmt.Fprint(w, "data: buffered\n\n")
+			return
+		}
+		// Outlast the server's write timeout
+		time.Sleep(300 * time.Millisecond)
+		_, _ = fmt.Fprint(w, "data: second\n\n")
+	}))
+	defer backend.Close()
 
-	reqBody := types.BatchConversationsRequest{
-		Sandboxes: map[string]types.BatchConversationSandbox{
-			"rt-a": {
-				SessionID:       "sess-a",
-				ConversationIDs: []string{"conv1"},
-			},
-			"rt-b": {
-				SessionID:       "sess-b",
-				ConversationIDs: []string{"conv3"},
-			},
-		},
-	}
-	body, _ := json.Marshal(reqBody)
-	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
-	rr := httptest.NewRecorder()
+	handler, stateMgr := setupTestHandler()
+	routeSandboxesTo(handler, backend)
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "rt-1", SessionID: "s1", Status: types.StatusRunning, ServiceName: "runtime-rt-1"})
 
-	handler.BatchGetConversations(rr, req)
+	front := httptest.NewUnstartedServer(http.HandlerFunc(handler.ProxySandbox))
+	front.Config.WriteTimeout = 200 * time.Millisecond
+	front.Start()
+	defer front.Close()
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d; body: %s", rr.Code, rr.Body.String())
+	resp, err := http.Get(front.URL + "/sandbox/rt-1/events")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
 	}
-
-	var resp map[string]json.RawMessage
-	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
-		t.Fatalf("Failed to decode response: %v", err)
+	defer resp.Body.Close()
+	if got := resp.Header.Get("X-Accel-Buffering"); got != "no" {
+		t.Errorf("Expected X-Accel-Buffering: no, got %q", got)
 	}
 
-	if len(resp) != 2 {
-		t.Errorf("Expected 2 entries, got %d", len(resp))
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "data: first\n" {
+		t.Fatalf("Expected the first event before the stream ends, got %q (%v)", line, err)
 	}
+	close(firstReceived)
 
-	if _, ok := resp["rt-a"]; !ok {
-		t.Error("Expected key 'rt-a' in response")
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Stream was cut off after %q: %v", rest, err)
 	}
-	if _, ok := resp["rt-b"]; !ok {
-		t.Error("Expected key 'rt-b' in response")
+	if want := "\ndata: second\n\n"; string(rest) != want {
+		t.Errorf("Expected second event %q, got %q", want, rest)
 	}
 }
 
-func TestBatchGetConversations_LookupBySessionID(t *testing.T) {
-	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprint(w, `[{"id":"conv1"}]`)
+func TestProxySandbox_ResponseHeaderTimeout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
 	}))
-	defer mockServer.Close()
+	defer backend.Close()
 
 	handler, stateMgr := setupTestHandler()
+	handler.config.ProxyResponseHeaderTimeout = 100 * time.Millisecond
+	routeSandboxesTo(handler, backend)
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "rt-1", SessionID: "s1", Status: types.StatusRunning, ServiceName: "runtime-rt-1"})
 
-	originalTransport := http.DefaultTransport
-	http.DefaultTransport = &mockTransport{
-		mockServerURL: mockServer.URL,
-		inner:         originalTransport,
+	started := time.Now()
+	rr := httptest.NewRecorder()
+	handler.ProxySandbox(rr, httptest.NewRequest("GET", "/sandbox/rt-1/api/slow", nil))
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 when the sandbox doesn't respond in time, got %d", rr.Code)
 	}
-	defer func() { http.DefaultTransport = originalTransport }()
-
-	// Runtime with a different runtime ID than what the request uses
-	stateMgr.AddRuntime(&state.RuntimeInfo{
-		RuntimeID:     "actual-rt-id",
-		SessionID:     "sess-xyz",
-		ServiceName:   "runtime-actual-rt-id",
-		SessionAPIKey: "key-xyz",
-		Status:        types.StatusRunning,
-		PodName:       "pod-xyz",
-	})
-
-	// Request uses a runtime ID that doesn't exist, but provides the correct session ID
-	reqBody := types.BatchConversationsRequest{
-		Sandboxes: map[string]types.BatchConversationSandbox{
-			"unknown-rt-id": {
-				SessionID:       "sess-xyz",
-				ConversationIDs: []string{"conv1"},
-			},
-		},
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Expected the response header timeout to end the request, took %s", elapsed)
 	}
-	body, _ := json.Marshal(reqBody)
-	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
-	rr := httptest.NewRecorder()
+}
 
-	handler.BatchGetConversations(rr, req)
+func TestProxySandbox_ProxyTimeoutOverride(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(300 * time.Millisecond):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", rr.Code)
+	handler, stateMgr := setupTestHandler()
+	handler.config.ProxyResponseHeaderTimeout = 100 * time.Millisecond
+	handler.config.MaxProxyTimeout = time.Minute
+	routeSandboxesTo(handler, backend)
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "rt-short", SessionID: "s1", Status: types.StatusRunning, ServiceName: "runtime-rt-short"})
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "rt-long", SessionID: "s2", Status: types.StatusRunning, ServiceName: "runtime-rt-long",
+		ProxyTimeout: handler.proxyTimeoutOverride(5)})
+
+	proxy := func(runtimeID string) int {
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, httptest.NewRequest("GET", "/sandbox/"+runtimeID+"/api/build", nil))
+		return rr.Code
 	}
-
-	var resp map[string]json.RawMessage
-	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
-		t.Fatalf("Failed to decode response: %v", err)
+	if code := proxy("rt-long"); code != http.StatusOK {
+		t.Errorf("Expected the extended override to allow the slow response, got %d", code)
+	}
+	if code := proxy("rt-short"); code != http.StatusBadGateway {
+		t.Errorf("Expected the default timeout to still bound other runtimes, got %d", code)
+	}
+	if code := proxy("rt-long"); code != http.StatusOK {
+		t.Errorf("Expected the override transport to be reused, got %d", code)
 	}
 
-	// Should return data under the requested key (unknown-rt-id), not the actual runtime ID
-	data, ok := resp["unknown-rt-id"]
-	if !ok {
-		t.Fatal("Expected key 'unknown-rt-id' in response")
+	if got := handler.proxyTimeoutOverride(7200); got != time.Minute {
+		t.Errorf("Expected proxy_timeout_seconds to be capped at MAX_PROXY_TIMEOUT, got %s", got)
 	}
-	if string(data) != `[{"id":"conv1"}]` {
-		t.Errorf("Expected pass-through JSON, got %s", string(data))
+	if got := handler.proxyTimeoutOverride(0); got != 0 {
+		t.Errorf("Expected no override for 0, got %s", got)
 	}
 }
 
-func TestBatchGetConversations_AgentServerError(t *testing.T) {
-	// Mock server that returns 500
-	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, `{"error":"internal"}`)
+func TestProxySandbox_TrailingSlashes(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.EscapedPath())
 	}))
-	defer mockServer.Close()
-
-	handler, stateMgr := setupTestHandler()
-
-	originalTransport := http.DefaultTransport
-	http.DefaultTransport = &mockTransport{
-		mockServerURL: mockServer.URL,
-		inner:         originalTransport,
+	defer backend.Close()
+
+	tests := []struct {
+		name         string
+		mode         string
+		path         string
+		wantStatus   int
+		wantBackend  string
+		wantLocation string
+	}{
+		{"Root without slash", "preserve", "/sandbox/rt-1", http.StatusOK, "/", ""},
+		{"Root with slash", "preserve", "/sandbox/rt-1/", http.StatusOK, "/", ""},
+		{"Subpath slash preserved", "preserve", "/sandbox/rt-1/api/conversations/", http.StatusOK, "/api/conversations/", ""},
+		{"Subpath slash normalized", "normalize", "/sandbox/rt-1/api/conversations/", http.StatusOK, "/api/conversations", ""},
+		{"Root with slash normalized", "normalize", "/sandbox/rt-1/", http.StatusOK, "/", ""},
+		{"VSCode without slash redirects", "preserve", "/sandbox/rt-1/vscode?tkn=abc&folder=/workspace", http.StatusPermanentRedirect, "", "/sandbox/rt-1/vscode/?tkn=abc&folder=/workspace"},
+		{"VSCode with slash", "preserve", "/sandbox/rt-1/vscode/", http.StatusOK, "/sandbox/rt-1/vscode/", ""},
+		{"VSCode asset slash kept when normalizing", "normalize", "/sandbox/rt-1/vscode/static/", http.StatusOK, "/sandbox/rt-1/vscode/static/", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, stateMgr := setupTestHandler()
+			handler.config.ProxyTrailingSlash = tt.mode
+			routeSandboxesTo(handler, backend)
+			stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "rt-1", SessionID: "s1", Status: types.StatusRunning, ServiceName: "runtime-rt-1"})
+
+			rr := httptest.NewRecorder()
+			handler.ProxySandbox(rr, httptest.NewRequest("GET", tt.path, nil))
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantBackend != "" && rr.Body.String() != tt.wantBackend {
+				t.Errorf("Expected backend path %q, got %q", tt.wantBackend, rr.Body.String())
+			}
+			if got := rr.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Expected Location %q, got %q", tt.wantLocation, got)
+			}
+		})
 	}
-	defer func() { http.DefaultTransport = originalTransport }()
+}
 
+func TestProxySandbox_NotFound(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
 	stateMgr.AddRuntime(&state.RuntimeInfo{
-		RuntimeID:     "rt-err",
-		SessionID:     "sess-err",
-		ServiceName:   "runtime-rt-err",
-		SessionAPIKey: "key-err",
-		Status:        types.StatusRunning,
-		PodName:       "pod-err",
+		RuntimeID:   "rt-1",
+		SessionID:   "s1",
+		ServiceName: "runtime-rt-1",
 	})
 
-	reqBody := types.BatchConversationsRequest{
-		Sandboxes: map[string]types.BatchConversationSandbox{
-			"rt-err": {
-				SessionID:       "sess-err",
-				ConversationIDs: []string{"conv1"},
-			},
-		},
-	}
-	body, _ := json.Marshal(reqBody)
-	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
-	rr := httptest.NewRecorder()
+	t.Run("Path without sandbox prefix", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/other/rt-1/alive", nil)
+		req.URL.Path = "/other/rt-1/alive"
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Unknown runtime ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/sandbox/nonexistent-id/alive", nil)
+		req.URL.Path = "/sandbox/nonexistent-id/alive"
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for unknown runtime, got %d", rr.Code)
+		}
+		var errResp types.ErrorResponse
+		_ = json.NewDecoder(rr.Body).Decode(&errResp)
+		if errResp.Error != "runtime_not_found" {
+			t.Errorf("Expected error runtime_not_found, got %q", errResp.Error)
+		}
+	})
+
+	t.Run("Empty path after sandbox", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/sandbox/", nil)
+		req.URL.Path = "/sandbox/"
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for empty path, got %d", rr.Code)
+		}
+	})
+}
+
+func TestProxySandbox_ResumesPausedRuntime(t *testing.T) {
+	pausedRuntime := func(stateMgr *state.StateManager) *state.RuntimeInfo {
+		info := &state.RuntimeInfo{
+			RuntimeID:   "rt-1",
+			SessionID:   "s1",
+			Status:      types.StatusPaused,
+			PodName:     "runtime-rt-1",
+			ServiceName: "runtime-rt-1",
+		}
+		stateMgr.AddRuntime(info)
+		return info
+	}
+	proxyRequest := func(handler *Handler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/sandbox/rt-1/alive", nil)
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, req)
+		return rr
+	}
+
+	t.Run("Paused runtime transitions to running", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.K8sOperationTimeout = 10 * time.Second
+		handler.config.ProxyResumeTimeout = 10 * time.Second
+		clientset := fake.NewSimpleClientset()
+		// Recreated pods come up ready immediately
+		clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+			pod.Status.Phase = corev1.PodRunning
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "openhands-agent", Ready: true}}
+			return false, nil, nil
+		})
+		handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+		info := pausedRuntime(stateMgr)
+
+		rr := proxyRequest(handler)
+		if rr.Code == http.StatusServiceUnavailable || rr.Code == http.StatusInternalServerError {
+			t.Fatalf("Expected request to be proxied after resume, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if info.Status != types.StatusRunning {
+			t.Errorf("Expected runtime status running, got %s", info.Status)
+		}
+		if _, err := clientset.CoreV1().Pods("test").Get(context.Background(), "runtime-rt-1", metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected pod to be recreated: %v", err)
+		}
+	})
+
+	t.Run("Not ready in time returns 503", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.K8sOperationTimeout = 10 * time.Second
+		handler.config.ProxyResumeTimeout = 50 * time.Millisecond
+		handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+		pausedRuntime(stateMgr)
+
+		rr := proxyRequest(handler)
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected status 503, got %d", rr.Code)
+		}
+		if rr.Header().Get("Retry-After") == "" {
+			t.Error("Expected Retry-After header on 503")
+		}
+	})
+
+	t.Run("Concurrent requests wait for readiness in parallel", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.K8sOperationTimeout = 10 * time.Second
+		handler.config.ProxyResumeTimeout = 300 * time.Millisecond
+		clientset := fake.NewSimpleClientset()
+		var creates atomic.Int32
+		clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			creates.Add(1)
+			return false, nil, nil
+		})
+		handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+		pausedRuntime(stateMgr)
+
+		// Hold the session lock so every request sees the runtime paused and queues on it
+		unlock := handler.startLocks.lock("s1")
+		const requests = 4
+		codes := make(chan int, requests)
+		for i := 0; i < requests; i++ {
+			go func() { codes <- proxyRequest(handler).Code }()
+		}
+		time.Sleep(50 * time.Millisecond)
+		start := time.Now()
+		unlock()
+		for i := 0; i < requests; i++ {
+			if code := <-codes; code != http.StatusServiceUnavailable {
+				t.Errorf("Expected status 503 while the pod is not ready, got %d", code)
+			}
+		}
+		// Waits queued behind the session lock would take requests × ProxyResumeTimeout
+		if elapsed := time.Since(start); elapsed >= 2*handler.config.ProxyResumeTimeout {
+			t.Errorf("Expected readiness waits to overlap, took %s for %d requests", elapsed, requests)
+		}
+		if got := creates.Load(); got != 1 {
+			t.Errorf("Expected the pod to be recreated once, got %d creates", got)
+		}
+	})
+}
+
+func TestResumeRuntime_RacingProxyResumeRecreatesOnce(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.K8sOperationTimeout = 10 * time.Second
+	handler.config.ProxyResumeTimeout = 50 * time.Millisecond
+	clientset := fake.NewSimpleClientset()
+	var creates atomic.Int32
+	// Slow creates widen the window in which an unserialized resume would recreate the pod twice
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		creates.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return false, nil, nil
+	})
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:   "rt-1",
+		SessionID:   "s1",
+		Status:      types.StatusPaused,
+		PodName:     "runtime-rt-1",
+		ServiceName: "runtime-rt-1",
+	})
+
+	proxyDone := make(chan int, 1)
+	go func() {
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, httptest.NewRequest("GET", "/sandbox/rt-1/alive", nil))
+		proxyDone <- rr.Code
+	}()
+
+	body, _ := json.Marshal(types.ResumeRequest{RuntimeID: "rt-1"})
+	rr := httptest.NewRecorder()
+	handler.ResumeRuntime(rr, httptest.NewRequest("POST", "/resume", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected /resume to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if code := <-proxyDone; code == http.StatusInternalServerError {
+		t.Errorf("Expected proxied request not to fail the resume, got %d", code)
+	}
+	if got := creates.Load(); got != 1 {
+		t.Errorf("Expected the pod to be recreated once, got %d creates", got)
+	}
+}
+
+func TestResumeRuntime_ResetsRestartHistory(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.K8sOperationTimeout = 10 * time.Second
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+	info := &state.RuntimeInfo{
+		RuntimeID:               "rt-1",
+		SessionID:               "s1",
+		Status:                  types.StatusPaused,
+		PodName:                 "runtime-rt-1",
+		ServiceName:             "runtime-rt-1",
+		RestartCount:            7,
+		RestartReasons:          []string{"OOMKilled"},
+		Restarts:                []types.RestartRecord{{Container: "openhands-agent", Reason: "OOMKilled", Count: 7, ExitCode: 137}},
+		LastTerminationReason:   "OOMKilled",
+		LastTerminationExitCode: 137,
+	}
+	stateMgr.AddRuntime(info)
+
+	body, _ := json.Marshal(types.ResumeRequest{RuntimeID: "rt-1"})
+	req := httptest.NewRequest("POST", "/resume", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.ResumeRuntime(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp types.RuntimeResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.RestartCount != 0 || len(resp.RestartReasons) != 0 || len(resp.Restarts) != 0 {
+		t.Errorf("Expected no restarts right after resume, got count %d, reasons %v, restarts %v",
+			resp.RestartCount, resp.RestartReasons, resp.Restarts)
+	}
+	if resp.LastTerminationReason != "" || resp.LastTerminationExitCode != 0 {
+		t.Errorf("Expected last termination to be cleared, got %q (exit %d)", resp.LastTerminationReason, resp.LastTerminationExitCode)
+	}
+	if info.RestartCount != 0 {
+		t.Errorf("Expected stored restart count to be reset, got %d", info.RestartCount)
+	}
+}
+
+func TestIdleTimeoutOverride(t *testing.T) {
+	handler, _ := setupTestHandler()
+	handler.config.MaxIdleTimeoutMinutes = 24 * 60
+
+	tests := []struct {
+		name     string
+		minutes  int
+		expected time.Duration
+	}{
+		{"Unset uses global timeout", 0, 0},
+		{"Within max", 30, 30 * time.Minute},
+		{"At max", 24 * 60, 24 * time.Hour},
+		{"Clamped to max", 30 * 24 * 60, 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := handler.idleTimeoutOverride(tt.minutes); got != tt.expected {
+				t.Errorf("idleTimeoutOverride(%d) = %v, want %v", tt.minutes, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStopRuntime_ConcurrentWithReaper(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.K8sOperationTimeout = 10 * time.Second
+	handler.config.IdleTimeoutHours = 1
+	handler.config.ReaperCheckInterval = time.Millisecond
+
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runtime-rt-1", Namespace: "test"},
+	})
+	var podDeletes atomic.Int32
+	reapStarted := make(chan struct{})
+	releaseReap := make(chan struct{})
+	// The first teardown (the reaper's) blocks mid-delete so the stop overlaps it
+	clientset.PrependReactor("delete", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if podDeletes.Add(1) == 1 {
+			close(reapStarted)
+			<-releaseReap
+		}
+		return false, nil, nil
+	})
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:        "rt-1",
+		SessionID:        "s1",
+		Status:           types.StatusRunning,
+		PodName:          "runtime-rt-1",
+		ServiceName:      "runtime-rt-1",
+		IngressName:      "runtime-rt-1",
+		CreatedAt:        time.Now().Add(-3 * time.Hour),
+		LastActivityTime: time.Now().Add(-2 * time.Hour),
+	})
+
+	reaperInstance := reaper.NewReaper(stateMgr, handler.k8sClient, handler.config)
+	reaperInstance.Start()
+	defer reaperInstance.Stop()
+	<-reapStarted
+
+	body, _ := json.Marshal(types.StopRequest{RuntimeID: "rt-1"})
+	rr := httptest.NewRecorder()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		handler.StopRuntime(rr, httptest.NewRequest("POST", "/stop", bytes.NewReader(body)))
+	}()
+	// Give the stop time to find the runtime and queue behind the reaper
+	time.Sleep(50 * time.Millisecond)
+	close(releaseReap)
+	<-stopped
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected stop to succeed while racing the reaper, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp types.RuntimeResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Status != types.StatusStopped {
+		t.Errorf("Expected stopped status, got %s", resp.Status)
+	}
+	if n := podDeletes.Load(); n != 1 {
+		t.Errorf("Expected a single teardown, got %d pod deletions", n)
+	}
+	if _, err := stateMgr.GetRuntimeByID("rt-1"); err == nil {
+		t.Error("Expected runtime to be removed from state")
+	}
+}
+
+func TestBatchGetConversations_InvalidBody(t *testing.T) {
+	handler, _ := setupTestHandler()
+
+	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader([]byte("not json")))
+	rr := httptest.NewRecorder()
+
+	handler.BatchGetConversations(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+
+	var errResp types.ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if errResp.Error != "invalid_request" {
+		t.Errorf("Expected error 'invalid_request', got %q", errResp.Error)
+	}
+}
+
+func TestBatchGetConversations_EmptySandboxes(t *testing.T) {
+	handler, _ := setupTestHandler()
+
+	reqBody := types.BatchConversationsRequest{
+		Sandboxes: map[string]types.BatchConversationSandbox{},
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
 
 	handler.BatchGetConversations(rr, req)
 
 	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status 200 (batch doesn't fail on individual errors), got %d", rr.Code)
+		t.Errorf("Expected status 200, got %d", rr.Code)
 	}
 
 	var resp map[string]json.RawMessage
 	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
+	if len(resp) != 0 {
+		t.Errorf("Expected empty response, got %d entries", len(resp))
+	}
+}
 
-	// Should return empty array for the failed sandbox
-	data, ok := resp["rt-err"]
+func TestBatchGetConversations_RuntimeNotFound(t *testing.T) {
+	handler, _ := setupTestHandler()
+
+	reqBody := types.BatchConversationsRequest{
+		Sandboxes: map[string]types.BatchConversationSandbox{
+			"nonexistent-runtime": {
+				SessionID:       "nonexistent-session",
+				ConversationIDs: []string{"conv1"},
+			},
+		},
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.BatchGetConversations(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// Should return empty array for unfound runtime
+	data, ok := resp["nonexistent-runtime"]
 	if !ok {
-		t.Fatal("Expected key 'rt-err' in response")
+		t.Fatal("Expected key 'nonexistent-runtime' in response")
 	}
 	if string(data) != "[]" {
-		t.Errorf("Expected empty array for failed sandbox, got %s", string(data))
+		t.Errorf("Expected empty array for unfound runtime, got %s", string(data))
 	}
 }
 
-func TestBatchGetConversations_MixedResults(t *testing.T) {
-	// One sandbox succeeds, one fails (not found), one has agent-server error
+func TestBatchGetConversations_WithMockAgentServer(t *testing.T) {
+	// Start a mock agent-server that returns conversation data
+	mockConversations := `[{"id":"conv1","status":"running"},{"id":"conv2","status":"idle"}]`
+	var capturedAPIKey string
+	var capturedIDs string
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ids := r.URL.Query().Get("ids")
-		if strings.Contains(ids, "conv-fail") {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+		capturedAPIKey = r.Header.Get("X-Session-API-Key")
+		capturedIDs = r.URL.Query().Get("ids")
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprint(w, `[{"id":"conv-ok"}]`)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, mockConversations)
 	}))
 	defer mockServer.Close()
 
+	// In-cluster DNS won't work in tests, so we use a custom HTTP transport that
+	// redirects the in-cluster URL to our mock server.
 	handler, stateMgr := setupTestHandler()
 
 	originalTransport := http.DefaultTransport
@@ -937,36 +1951,22 @@ func TestBatchGetConversations_MixedResults(t *testing.T) {
 	}
 	defer func() { http.DefaultTransport = originalTransport }()
 
+	// Add a runtime with known service name
 	stateMgr.AddRuntime(&state.RuntimeInfo{
-		RuntimeID:     "rt-ok",
-		SessionID:     "sess-ok",
-		ServiceName:   "runtime-rt-ok",
-		SessionAPIKey: "key-ok",
-		Status:        types.StatusRunning,
-		PodName:       "pod-ok",
-	})
-	stateMgr.AddRuntime(&state.RuntimeInfo{
-		RuntimeID:     "rt-fail",
-		SessionID:     "sess-fail",
-		ServiceName:   "runtime-rt-fail",
-		SessionAPIKey: "key-fail",
+		RuntimeID:     "rt-100",
+		SessionID:     "sess-100",
+		ServiceName:   "runtime-rt-100",
+		SessionAPIKey: "test-session-key-abc",
 		Status:        types.StatusRunning,
-		PodName:       "pod-fail",
+		PodStatus:     types.PodStatusReady,
+		PodName:       "pod-100",
 	})
 
 	reqBody := types.BatchConversationsRequest{
 		Sandboxes: map[string]types.BatchConversationSandbox{
-			"rt-ok": {
-				SessionID:       "sess-ok",
-				ConversationIDs: []string{"conv-ok"},
-			},
-			"rt-fail": {
-				SessionID:       "sess-fail",
-				ConversationIDs: []string{"conv-fail"},
-			},
-			"rt-notfound": {
-				SessionID:       "sess-notfound",
-				ConversationIDs: []string{"conv-x"},
+			"rt-100": {
+				SessionID:       "sess-100",
+				ConversationIDs: []string{"conv1", "conv2"},
 			},
 		},
 	}
@@ -977,53 +1977,2004 @@ func TestBatchGetConversations_MixedResults(t *testing.T) {
 	handler.BatchGetConversations(rr, req)
 
 	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", rr.Code)
+		t.Errorf("Expected status 200, got %d; body: %s", rr.Code, rr.Body.String())
+	}
+
+	// Verify the session API key was forwarded
+	if capturedAPIKey != "test-session-key-abc" {
+		t.Errorf("Expected X-Session-API-Key 'test-session-key-abc', got %q", capturedAPIKey)
+	}
+
+	// Verify the conversation IDs were passed
+	if capturedIDs != "conv1,conv2" {
+		t.Errorf("Expected ids query param 'conv1,conv2', got %q", capturedIDs)
 	}
 
+	// Verify the response contains the pass-through JSON
 	var resp map[string]json.RawMessage
 	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(resp) != 3 {
-		t.Errorf("Expected 3 entries, got %d", len(resp))
+	data, ok := resp["rt-100"]
+	if !ok {
+		t.Fatal("Expected key 'rt-100' in response")
 	}
 
-	// rt-ok should have conversation data
-	if string(resp["rt-ok"]) != `[{"id":"conv-ok"}]` {
-		t.Errorf("Expected conversation data for rt-ok, got %s", string(resp["rt-ok"]))
+	// Verify the raw JSON was passed through
+	if string(data) != mockConversations {
+		t.Errorf("Expected pass-through JSON %q, got %q", mockConversations, string(data))
 	}
+}
 
-	// rt-fail should have empty array (agent-server error)
+func TestBatchGetConversations_MultipleSandboxes(t *testing.T) {
+	// Create two mock servers to simulate different agent-server pods
+	mockServer1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"id":"conv1","status":"running"}]`)
+	}))
+	defer mockServer1.Close()
+
+	mockServer2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"id":"conv3","status":"idle"}]`)
+	}))
+	defer mockServer2.Close()
+
+	handler, stateMgr := setupTestHandler()
+
+	// Redirect all in-cluster calls to mockServer1 for simplicity
+	// (both runtimes will hit the same mock, but we test concurrency)
+	originalTransport := http.DefaultTransport
+	http.DefaultTransport = &mockTransport{
+		mockServerURL: mockServer1.URL,
+		inner:         originalTransport,
+	}
+	defer func() { http.DefaultTransport = originalTransport }()
+
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:     "rt-a",
+		SessionID:     "sess-a",
+		ServiceName:   "runtime-rt-a",
+		SessionAPIKey: "key-a",
+		Status:        types.StatusRunning,
+		PodName:       "pod-a",
+	})
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:     "rt-b",
+		SessionID:     "sess-b",
+		ServiceName:   "runtime-rt-b",
+		SessionAPIKey: "key-b",
+		Status:        types.StatusRunning,
+		PodName:       "pod-b",
+	})
+
+	reqBody := types.BatchConversationsRequest{
+		Sandboxes: map[string]types.BatchConversationSandbox{
+			"rt-a": {
+				SessionID:       "sess-a",
+				ConversationIDs: []string{"conv1"},
+			},
+			"rt-b": {
+				SessionID:       "sess-b",
+				ConversationIDs: []string{"conv3"},
+			},
+		},
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.BatchGetConversations(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d; body: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(resp) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(resp))
+	}
+
+	if _, ok := resp["rt-a"]; !ok {
+		t.Error("Expected key 'rt-a' in response")
+	}
+	if _, ok := resp["rt-b"]; !ok {
+		t.Error("Expected key 'rt-b' in response")
+	}
+}
+
+func TestBatchGetConversations_LookupBySessionID(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"id":"conv1"}]`)
+	}))
+	defer mockServer.Close()
+
+	handler, stateMgr := setupTestHandler()
+
+	originalTransport := http.DefaultTransport
+	http.DefaultTransport = &mockTransport{
+		mockServerURL: mockServer.URL,
+		inner:         originalTransport,
+	}
+	defer func() { http.DefaultTransport = originalTransport }()
+
+	// Runtime with a different runtime ID than what the request uses
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:     "actual-rt-id",
+		SessionID:     "sess-xyz",
+		ServiceName:   "runtime-actual-rt-id",
+		SessionAPIKey: "key-xyz",
+		Status:        types.StatusRunning,
+		PodName:       "pod-xyz",
+	})
+
+	// Request uses a runtime ID that doesn't exist, but provides the correct session ID
+	reqBody := types.BatchConversationsRequest{
+		Sandboxes: map[string]types.BatchConversationSandbox{
+			"unknown-rt-id": {
+				SessionID:       "sess-xyz",
+				ConversationIDs: []string{"conv1"},
+			},
+		},
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.BatchGetConversations(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// Should return data under the requested key (unknown-rt-id), not the actual runtime ID
+	data, ok := resp["unknown-rt-id"]
+	if !ok {
+		t.Fatal("Expected key 'unknown-rt-id' in response")
+	}
+	if string(data) != `[{"id":"conv1"}]` {
+		t.Errorf("Expected pass-through JSON, got %s", string(data))
+	}
+}
+
+func TestBatchGetConversations_AgentServerError(t *testing.T) {
+	// Mock server that returns 500
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"error":"internal"}`)
+	}))
+	defer mockServer.Close()
+
+	handler, stateMgr := setupTestHandler()
+
+	originalTransport := http.DefaultTransport
+	http.DefaultTransport = &mockTransport{
+		mockServerURL: mockServer.URL,
+		inner:         originalTransport,
+	}
+	defer func() { http.DefaultTransport = originalTransport }()
+
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:     "rt-err",
+		SessionID:     "sess-err",
+		ServiceName:   "runtime-rt-err",
+		SessionAPIKey: "key-err",
+		Status:        types.StatusRunning,
+		PodName:       "pod-err",
+	})
+
+	reqBody := types.BatchConversationsRequest{
+		Sandboxes: map[string]types.BatchConversationSandbox{
+			"rt-err": {
+				SessionID:       "sess-err",
+				ConversationIDs: []string{"conv1"},
+			},
+		},
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.BatchGetConversations(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 (batch doesn't fail on individual errors), got %d", rr.Code)
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// Should return empty array for the failed sandbox
+	data, ok := resp["rt-err"]
+	if !ok {
+		t.Fatal("Expected key 'rt-err' in response")
+	}
+	if string(data) != "[]" {
+		t.Errorf("Expected empty array for failed sandbox, got %s", string(data))
+	}
+}
+
+func TestBatchGetConversations_MixedResults(t *testing.T) {
+	// One sandbox succeeds, one fails (not found), one has agent-server error
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids := r.URL.Query().Get("ids")
+		if strings.Contains(ids, "conv-fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"id":"conv-ok"}]`)
+	}))
+	defer mockServer.Close()
+
+	handler, stateMgr := setupTestHandler()
+
+	originalTransport := http.DefaultTransport
+	http.DefaultTransport = &mockTransport{
+		mockServerURL: mockServer.URL,
+		inner:         originalTransport,
+	}
+	defer func() { http.DefaultTransport = originalTransport }()
+
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:     "rt-ok",
+		SessionID:     "sess-ok",
+		ServiceName:   "runtime-rt-ok",
+		SessionAPIKey: "key-ok",
+		Status:        types.StatusRunning,
+		PodName:       "pod-ok",
+	})
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:     "rt-fail",
+		SessionID:     "sess-fail",
+		ServiceName:   "runtime-rt-fail",
+		SessionAPIKey: "key-fail",
+		Status:        types.StatusRunning,
+		PodName:       "pod-fail",
+	})
+
+	reqBody := types.BatchConversationsRequest{
+		Sandboxes: map[string]types.BatchConversationSandbox{
+			"rt-ok": {
+				SessionID:       "sess-ok",
+				ConversationIDs: []string{"conv-ok"},
+			},
+			"rt-fail": {
+				SessionID:       "sess-fail",
+				ConversationIDs: []string{"conv-fail"},
+			},
+			"rt-notfound": {
+				SessionID:       "sess-notfound",
+				ConversationIDs: []string{"conv-x"},
+			},
+		},
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/sessions/batch-conversations", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.BatchGetConversations(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(resp) != 3 {
+		t.Errorf("Expected 3 entries, got %d", len(resp))
+	}
+
+	// rt-ok should have conversation data
+	if string(resp["rt-ok"]) != `[{"id":"conv-ok"}]` {
+		t.Errorf("Expected conversation data for rt-ok, got %s", string(resp["rt-ok"]))
+	}
+
+	// rt-fail should have empty array (agent-server error)
 	if string(resp["rt-fail"]) != "[]" {
 		t.Errorf("Expected empty array for rt-fail, got %s", string(resp["rt-fail"]))
 	}
 
-	// rt-notfound should have empty array (runtime not found)
-	if string(resp["rt-notfound"]) != "[]" {
-		t.Errorf("Expected empty array for rt-notfound, got %s", string(resp["rt-notfound"]))
+	// rt-notfound should have empty array (runtime not found)
+	if string(resp["rt-notfound"]) != "[]" {
+		t.Errorf("Expected empty array for rt-notfound, got %s", string(resp["rt-notfound"]))
+	}
+}
+
+// mockTransport intercepts HTTP requests to in-cluster service URLs and redirects them
+// to a mock test server. This lets us test the full BatchGetConversations flow without
+// requiring actual Kubernetes DNS resolution.
+type mockTransport struct {
+	mockServerURL string
+	inner         http.RoundTripper
+}
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Intercept requests to *.svc.cluster.local and redirect to mock server
+	if strings.Contains(req.URL.Host, "svc.cluster.local") {
+		// Rewrite the URL to point to our mock server
+		mockURL := t.mockServerURL + req.URL.Path + "?" + req.URL.RawQuery
+		newReq, err := http.NewRequestWithContext(req.Context(), req.Method, mockURL, req.Body)
+		if err != nil {
+			return nil, err
+		}
+		newReq.Header = req.Header
+		return t.inner.RoundTrip(newReq)
+	}
+	return t.inner.RoundTrip(req)
+}
+
+func TestGetStats(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.CleanupEnabled = true
+
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "runtime-1", SessionID: "session-1"})
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "runtime-2", SessionID: "session-2"})
+
+	t.Run("Without stats sources", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/stats", nil)
+		rr := httptest.NewRecorder()
+		handler.GetStats(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rr.Code)
+		}
+		var resp types.StatsResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.RuntimeCount != 2 {
+			t.Errorf("Expected runtime_count 2, got %d", resp.RuntimeCount)
+		}
+		if resp.LastReconcileTime != nil {
+			t.Errorf("Expected no last_reconcile_time before reconcile, got %v", resp.LastReconcileTime)
+		}
+		if !resp.Cleanup.Enabled {
+			t.Error("Expected cleanup.enabled to be true")
+		}
+		if resp.Cleanup.LastRunTime != nil || resp.Reaper.LastRunTime != nil {
+			t.Error("Expected no last_run_time without stats sources")
+		}
+	})
+
+	t.Run("With reconcile and reaper", func(t *testing.T) {
+		stateMgr.MarkReconciled()
+		handler.SetStatsSources(nil, reaper.NewReaper(stateMgr, nil, handler.config))
+
+		req := httptest.NewRequest("GET", "/stats", nil)
+		rr := httptest.NewRecorder()
+		handler.GetStats(rr, req)
+
+		var raw map[string]interface{}
+		if err := json.NewDecoder(rr.Body).Decode(&raw); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if _, ok := raw["last_reconcile_time"]; !ok {
+			t.Error("Expected last_reconcile_time after reconcile")
+		}
+		reaperStats, ok := raw["reaper"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected reaper object, got %v", raw["reaper"])
+		}
+		if errs, ok := reaperStats["last_reap_errors"].([]interface{}); !ok || len(errs) != 0 {
+			t.Errorf("Expected empty last_reap_errors array, got %v", reaperStats["last_reap_errors"])
+		}
+	})
+
+	t.Run("With cleanup errors", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset()
+		clientset.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("pods is forbidden")
+		})
+		cfg := *handler.config
+		cfg.CleanupIntervalMinutes = 60
+		cleanupSvc := cleanup.NewService(k8s.NewClientWithClientset(clientset, &cfg), stateMgr, &cfg)
+		cleanupSvc.Start(context.Background())
+		defer cleanupSvc.Stop()
+		handler.SetStatsSources(cleanupSvc, nil)
+
+		// The first run starts immediately; its errors are recorded when it finishes
+		deadline := time.Now().Add(5 * time.Second)
+		for len(cleanupSvc.GetStats().LastCleanupErrors) == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.GetStats(rr, httptest.NewRequest("GET", "/stats", nil))
+		var resp types.StatsResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.Cleanup.TotalRunCount != 1 || resp.Cleanup.LastRunTime == nil {
+			t.Errorf("Expected one cleanup run with a last_run_time, got %+v", resp.Cleanup)
+		}
+		if len(resp.Cleanup.LastCleanupErrors) != 1 || !strings.Contains(resp.Cleanup.LastCleanupErrors[0], "pods is forbidden") {
+			t.Errorf("Expected the cleanup failure in last_cleanup_errors, got %v", resp.Cleanup.LastCleanupErrors)
+		}
+	})
+}
+
+func TestDeleteRuntime(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:   "runtime-123",
+		SessionID:   "session-456",
+		Status:      types.StatusRunning,
+		PodName:     "runtime-runtime-123",
+		ServiceName: "runtime-runtime-123",
+		IngressName: "runtime-runtime-123",
+	})
+
+	deleteRuntime := func(runtimeID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/runtime/"+runtimeID, nil)
+		req = mux.SetURLVars(req, map[string]string{"runtime_id": runtimeID})
+		rr := httptest.NewRecorder()
+		handler.DeleteRuntime(rr, req)
+		return rr
+	}
+
+	t.Run("Delete existing runtime", func(t *testing.T) {
+		rr := deleteRuntime("runtime-123")
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", rr.Code)
+		}
+		var resp types.RuntimeResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.RuntimeID != "runtime-123" || resp.Status != types.StatusStopped {
+			t.Errorf("Expected stopped runtime-123 in response, got %+v", resp)
+		}
+		if _, err := stateMgr.GetRuntimeByID("runtime-123"); err == nil {
+			t.Error("Expected runtime to be removed from state")
+		}
+	})
+
+	t.Run("Delete already-deleted runtime", func(t *testing.T) {
+		rr := deleteRuntime("runtime-123")
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Delete non-existent runtime", func(t *testing.T) {
+		rr := deleteRuntime("non-existent")
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", rr.Code)
+		}
+	})
+}
+
+func TestGetRuntimeLogs(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID: "runtime-123",
+		SessionID: "session-456",
+		Status:    types.StatusRunning,
+		PodName:   "runtime-runtime-123",
+	})
+
+	getLogs := func(runtimeID, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/runtime/"+runtimeID+"/logs"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"runtime_id": runtimeID})
+		rr := httptest.NewRecorder()
+		handler.GetRuntimeLogs(rr, req)
+		return rr
+	}
+
+	t.Run("Streams logs", func(t *testing.T) {
+		rr := getLogs("runtime-123", "?follow=true&tail=100&previous=true")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Errorf("Expected text/plain content type, got %q", ct)
+		}
+		// The fake clientset serves a fixed log body
+		if rr.Body.String() != "fake logs" {
+			t.Errorf("Expected streamed log body, got %q", rr.Body.String())
+		}
+		if !rr.Flushed {
+			t.Error("Expected log output to be flushed as it streams")
+		}
+	})
+
+	t.Run("Unknown runtime", func(t *testing.T) {
+		rr := getLogs("non-existent", "")
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Invalid tail", func(t *testing.T) {
+		rr := getLogs("runtime-123", "?tail=-5")
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rr.Code)
+		}
+	})
+}
+
+func TestGetRuntimeEvents(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	event := func(name, pod, reason string, last time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "test"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: pod, Namespace: "test"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         reason,
+			Message:        reason + " message",
+			Count:          2,
+			Source:         corev1.EventSource{Component: "default-scheduler"},
+			FirstTimestamp: metav1.NewTime(base),
+			LastTimestamp:  metav1.NewTime(last),
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		event("e-backoff", "runtime-runtime-123", "BackOff", base.Add(2*time.Minute)),
+		event("e-sched", "runtime-runtime-123", "FailedScheduling", base.Add(time.Minute)),
+		event("e-other", "runtime-other", "Pulled", base),
+	)
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID: "runtime-123",
+		SessionID: "session-456",
+		Status:    types.StatusPending,
+		PodName:   "runtime-runtime-123",
+	})
+
+	getEvents := func(runtimeID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/runtime/"+runtimeID+"/events", nil)
+		req = mux.SetURLVars(req, map[string]string{"runtime_id": runtimeID})
+		rr := httptest.NewRecorder()
+		handler.GetRuntimeEvents(rr, req)
+		return rr
+	}
+
+	t.Run("Lists pod events oldest first", func(t *testing.T) {
+		rr := getEvents("runtime-123")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp types.PodEventsResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.PodName != "runtime-runtime-123" || len(resp.Events) != 2 {
+			t.Fatalf("Expected 2 events for runtime-runtime-123, got %+v", resp)
+		}
+		first := resp.Events[0]
+		if first.Reason != "FailedScheduling" || first.Message != "FailedScheduling message" || first.Type != "Warning" {
+			t.Errorf("Unexpected first event %+v", first)
+		}
+		if first.Count != 2 || first.Source != "default-scheduler" || !first.FirstTimestamp.Equal(base) || !first.LastTimestamp.Equal(base.Add(time.Minute)) {
+			t.Errorf("Unexpected first event details %+v", first)
+		}
+		if resp.Events[1].Reason != "BackOff" {
+			t.Errorf("Expected BackOff second, got %q", resp.Events[1].Reason)
+		}
+	})
+
+	t.Run("Unknown runtime", func(t *testing.T) {
+		if rr := getEvents("non-existent"); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", rr.Code)
+		}
+	})
+}
+
+func TestStartRuntime_AnnotatesRequestSpan(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	handler, _ := setupTestHandler()
+	handler.config.K8sOperationTimeout = 10 * time.Second
+	handler.config.AnnotatePodTrace = true
+	clientset := fake.NewSimpleClientset()
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "http.request")
+	defer span.Finish()
+	body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: "session-traced"})
+	rr := httptest.NewRecorder()
+	handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)).WithContext(ctx))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected start to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var started types.RuntimeResponse
+	_ = json.NewDecoder(rr.Body).Decode(&started)
+
+	pod, err := clientset.CoreV1().Pods("test").Get(context.Background(), "runtime-"+started.RuntimeID, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected created pod, got %v", err)
+	}
+	if want := strconv.FormatUint(span.Context().TraceID(), 10); pod.Annotations["openhands.dev/trace-id"] != want {
+		t.Errorf("Expected trace ID annotation %q, got %q", want, pod.Annotations["openhands.dev/trace-id"])
+	}
+	if want := strconv.FormatUint(span.Context().SpanID(), 10); pod.Annotations["openhands.dev/span-id"] != want {
+		t.Errorf("Expected the /start request's span ID %q, got %q", want, pod.Annotations["openhands.dev/span-id"])
+	}
+}
+
+func TestResumeRuntime_RecreatesOriginalPod(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.K8sOperationTimeout = 10 * time.Second
+	clientset := fake.NewSimpleClientset()
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+	resume := func(t *testing.T, runtimeID string) *corev1.Pod {
+		body, _ := json.Marshal(types.ResumeRequest{RuntimeID: runtimeID})
+		rr := httptest.NewRecorder()
+		handler.ResumeRuntime(rr, httptest.NewRequest("POST", "/resume", bytes.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected resume to succeed, got %d: %s", rr.Code, rr.Body.String())
+		}
+		pod, err := clientset.CoreV1().Pods("test").Get(context.Background(), "runtime-"+runtimeID, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Expected recreated pod, got %v", err)
+		}
+		return pod
+	}
+
+	t.Run("Started runtime keeps its pod shape", func(t *testing.T) {
+		body, _ := json.Marshal(types.StartRequest{
+			Image:         "custom-image",
+			SessionID:     "session-resume",
+			CPURequest:    "250m",
+			MemoryRequest: "512Mi",
+			NodeSelector:  map[string]string{"pool": "sandboxes"},
+			PodLabels:     map[string]string{"team": "ml"},
+			Environment:   map[string]string{"FOO": "bar"},
+		})
+		rr := httptest.NewRecorder()
+		handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected start to succeed, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var started types.RuntimeResponse
+		_ = json.NewDecoder(rr.Body).Decode(&started)
+
+		body, _ = json.Marshal(types.PauseRequest{RuntimeID: started.RuntimeID})
+		rr = httptest.NewRecorder()
+		handler.PauseRuntime(rr, httptest.NewRequest("POST", "/pause", bytes.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected pause to succeed, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		pod := resume(t, started.RuntimeID)
+		container := pod.Spec.Containers[0]
+		if container.Image != "custom-image" {
+			t.Errorf("Expected image custom-image, got %s", container.Image)
+		}
+		if got := container.Resources.Requests.Cpu().MilliValue(); got != 250 {
+			t.Errorf("Expected 250m CPU request, got %dm", got)
+		}
+		if pod.Spec.NodeSelector["pool"] != "sandboxes" {
+			t.Errorf("Expected node selector to be kept, got %v", pod.Spec.NodeSelector)
+		}
+		if pod.Labels["team"] != "ml" {
+			t.Errorf("Expected pod label to be kept, got %v", pod.Labels)
+		}
+		foundEnv := false
+		for _, env := range container.Env {
+			foundEnv = foundEnv || (env.Name == "FOO" && env.Value == "bar")
+		}
+		if !foundEnv {
+			t.Errorf("Expected environment to be kept, got %v", container.Env)
+		}
+		if cpu, memory := stateMgr.CommittedResources(); cpu != 250 || memory != 512<<20 {
+			t.Errorf("Expected committed 250m / 512Mi after resume, got %dm / %d", cpu, memory)
+		}
+	})
+
+	t.Run("Discovered runtime totals follow the recreated pod", func(t *testing.T) {
+		stateMgr.AddRuntime(&state.RuntimeInfo{
+			RuntimeID:          "discovered",
+			SessionID:          "session-discovered",
+			Status:             types.StatusPaused,
+			PodName:            "runtime-discovered",
+			ServiceName:        "runtime-discovered",
+			IngressName:        "runtime-discovered",
+			CPURequestMillis:   4000,
+			MemoryRequestBytes: 8 << 30,
+		})
+		pod := resume(t, "discovered")
+		if got := pod.Spec.Containers[0].Resources.Requests.Cpu().MilliValue(); got != 1000 {
+			t.Errorf("Expected default 1000m CPU request, got %dm", got)
+		}
+		info, _ := stateMgr.GetRuntimeByID("discovered")
+		if info.CPURequestMillis != 1000 || info.MemoryRequestBytes != 2048<<20 {
+			t.Errorf("Expected committed resources of the recreated pod, got %dm / %d", info.CPURequestMillis, info.MemoryRequestBytes)
+		}
+	})
+}
+
+func TestGetRuntimeHistory(t *testing.T) {
+	handler, _ := setupTestHandler()
+	handler.config.K8sOperationTimeout = 10 * time.Second
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+
+	body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: "session-history"})
+	rr := httptest.NewRecorder()
+	handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected start to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var started types.RuntimeResponse
+	_ = json.NewDecoder(rr.Body).Decode(&started)
+
+	body, _ = json.Marshal(types.PauseRequest{RuntimeID: started.RuntimeID})
+	rr = httptest.NewRecorder()
+	handler.PauseRuntime(rr, httptest.NewRequest("POST", "/pause", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected pause to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	body, _ = json.Marshal(types.ResumeRequest{RuntimeID: started.RuntimeID})
+	rr = httptest.NewRecorder()
+	handler.ResumeRuntime(rr, httptest.NewRequest("POST", "/resume", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected resume to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	body, _ = json.Marshal(types.StopRequest{RuntimeID: started.RuntimeID})
+	rr = httptest.NewRecorder()
+	handler.StopRuntime(rr, httptest.NewRequest("POST", "/stop", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected stop to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	getHistory := func(query string) (int, types.HistoryResponse) {
+		req := httptest.NewRequest("GET", "/runtime/"+started.RuntimeID+"/history"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"runtime_id": started.RuntimeID})
+		rr := httptest.NewRecorder()
+		handler.GetRuntimeHistory(rr, req)
+		var resp types.HistoryResponse
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp
+	}
+
+	// The stopped runtime's history is still available, newest first
+	code, resp := getHistory("")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	want := []types.RuntimeEventType{types.RuntimeEventStopped, types.RuntimeEventResumed, types.RuntimeEventPaused, types.RuntimeEventCreated}
+	if len(resp.Events) != len(want) || resp.Total != len(want) {
+		t.Fatalf("Expected %d events, got %v (total %d)", len(want), resp.Events, resp.Total)
+	}
+	for i, eventType := range want {
+		if resp.Events[i].Type != eventType {
+			t.Errorf("Event %d: expected %s, got %s", i, eventType, resp.Events[i].Type)
+		}
+	}
+
+	code, resp = getHistory("?limit=2&offset=1")
+	if code != http.StatusOK || len(resp.Events) != 2 || resp.Events[0].Type != types.RuntimeEventResumed || resp.NextOffset != 3 {
+		t.Errorf("Unexpected page: status %d, events %v, next offset %d", code, resp.Events, resp.NextOffset)
+	}
+
+	if code, _ := getHistory("?limit=-1"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative limit, got %d", code)
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/runtime/unknown/history", nil), map[string]string{"runtime_id": "unknown"})
+	rr = httptest.NewRecorder()
+	handler.GetRuntimeHistory(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown runtime, got %d", rr.Code)
+	}
+}
+
+func TestRecordPodStatusEvents(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	info := &state.RuntimeInfo{RuntimeID: "rt-1", SessionID: "s1", PodStatus: types.PodStatusPending}
+	stateMgr.AddRuntime(info)
+
+	handler.recordPodStatusEvents(info, &k8s.PodStatusInfo{Status: types.PodStatusReady})
+	info.PodStatus = types.PodStatusReady
+	handler.recordPodStatusEvents(info, &k8s.PodStatusInfo{Status: types.PodStatusReady})
+	handler.recordPodStatusEvents(info, &k8s.PodStatusInfo{Status: types.PodStatusCrashLoopBackOff, RestartCount: 2, LastTerminationReason: "OOMKilled"})
+
+	history, _ := stateMgr.History("rt-1")
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 events, got %v", history)
+	}
+	if history[2].Type != types.RuntimeEventReady {
+		t.Errorf("Expected ready first, got %s", history[2].Type)
+	}
+	if history[1].Type != types.RuntimeEventError || history[1].Message != "pod status crashloopbackoff" {
+		t.Errorf("Expected crashloop error event, got %+v", history[1])
+	}
+	if history[0].Type != types.RuntimeEventRestarted || history[0].Message != "restart count 2, last termination OOMKilled" {
+		t.Errorf("Expected restarted event, got %+v", history[0])
+	}
+}
+
+func TestGetRuntime_GoneForStopped(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:   "runtime-123",
+		SessionID:   "session-456",
+		Status:      types.StatusRunning,
+		PodName:     "runtime-runtime-123",
+		ServiceName: "runtime-runtime-123",
+		IngressName: "runtime-runtime-123",
+	})
+	req := httptest.NewRequest("DELETE", "/runtime/runtime-123", nil)
+	req = mux.SetURLVars(req, map[string]string{"runtime_id": "runtime-123"})
+	handler.DeleteRuntime(httptest.NewRecorder(), req)
+
+	getRuntime := func(runtimeID string) int {
+		req := httptest.NewRequest("GET", "/runtime/"+runtimeID, nil)
+		req = mux.SetURLVars(req, map[string]string{"runtime_id": runtimeID})
+		rr := httptest.NewRecorder()
+		handler.GetRuntime(rr, req)
+		return rr.Code
+	}
+	getSession := func(sessionID string) int {
+		req := httptest.NewRequest("GET", "/sessions/"+sessionID, nil)
+		req = mux.SetURLVars(req, map[string]string{"session_id": sessionID})
+		rr := httptest.NewRecorder()
+		handler.GetSession(rr, req)
+		return rr.Code
+	}
+
+	t.Run("404 by default", func(t *testing.T) {
+		if code := getRuntime("runtime-123"); code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for stopped runtime by default, got %d", code)
+		}
+		if code := getSession("session-456"); code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for stopped session by default, got %d", code)
+		}
+	})
+
+	handler.config.UseGoneForStopped = true
+
+	t.Run("410 for stopped runtime", func(t *testing.T) {
+		if code := getRuntime("runtime-123"); code != http.StatusGone {
+			t.Errorf("Expected status 410 for stopped runtime, got %d", code)
+		}
+		if code := getSession("session-456"); code != http.StatusGone {
+			t.Errorf("Expected status 410 for stopped session, got %d", code)
+		}
+	})
+
+	t.Run("404 for unknown runtime", func(t *testing.T) {
+		if code := getRuntime("never-existed"); code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for unknown runtime, got %d", code)
+		}
+		if code := getSession("never-existed"); code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for unknown session, got %d", code)
+		}
+	})
+}
+
+func TestListRuntimes_Verbose(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runtime-pull", Namespace: "test", Labels: map[string]string{"app": "openhands-runtime"}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+				{Type: corev1.PodReady, Status: corev1.ConditionFalse, Reason: "ContainersNotReady"},
+			},
+			InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: "workspace-init", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "openhands-agent", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}},
+			},
+		},
+	}
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(pod), handler.config)
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "pull", SessionID: "s-pull", Status: types.StatusRunning, PodName: "runtime-pull"})
+
+	list := func(query string) types.RuntimeResponse {
+		rr := httptest.NewRecorder()
+		handler.ListRuntimes(rr, httptest.NewRequest("GET", "/list"+query, nil))
+		var resp types.ListResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil || len(resp.Runtimes) != 1 {
+			t.Fatalf("Expected one runtime, got %d (%v)", len(resp.Runtimes), err)
+		}
+		return resp.Runtimes[0]
+	}
+
+	if lean := list(""); lean.PodDetail != nil {
+		t.Errorf("Expected the default list to omit pod_detail, got %+v", lean.PodDetail)
+	}
+	rr := httptest.NewRecorder()
+	handler.ListRuntimes(rr, httptest.NewRequest("GET", "/list", nil))
+	if strings.Contains(rr.Body.String(), "pod_detail") {
+		t.Errorf("Expected no pod_detail key in the default response, got %s", rr.Body.String())
+	}
+
+	detail := list("?verbose=true").PodDetail
+	if detail == nil {
+		t.Fatal("Expected pod_detail with verbose=true")
+	}
+	if detail.Phase != "Pending" || len(detail.Conditions) != 2 || detail.Conditions[1].Reason != "ContainersNotReady" {
+		t.Errorf("Expected Pending phase with both conditions, got %+v", detail)
+	}
+	if len(detail.Containers) != 2 {
+		t.Fatalf("Expected init and agent container states, got %+v", detail.Containers)
+	}
+	if init := detail.Containers[0]; !init.Init || init.State != "terminated" || init.Reason != "Completed" {
+		t.Errorf("Expected completed init container first, got %+v", init)
+	}
+	if agent := detail.Containers[1]; agent.Init || agent.State != "waiting" || agent.Reason != "ImagePullBackOff" || agent.Message == "" {
+		t.Errorf("Expected agent waiting on ImagePullBackOff, got %+v", agent)
+	}
+}
+
+func TestListRuntimes_Pagination(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+
+	base := time.Now().Add(-time.Hour)
+	statuses := []types.RuntimeStatus{types.StatusRunning, types.StatusPaused, types.StatusRunning, types.StatusPending, types.StatusRunning}
+	for i, status := range statuses {
+		stateMgr.AddRuntime(&state.RuntimeInfo{
+			RuntimeID: fmt.Sprintf("runtime-%d", i),
+			SessionID: fmt.Sprintf("session-%d", i),
+			Status:    status,
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	list := func(query string) (*httptest.ResponseRecorder, types.ListResponse) {
+		req := httptest.NewRequest("GET", "/list"+query, nil)
+		rr := httptest.NewRecorder()
+		handler.ListRuntimes(rr, req)
+		var resp types.ListResponse
+		if rr.Code == http.StatusOK {
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return rr, resp
+	}
+	ids := func(resp types.ListResponse) []string {
+		out := make([]string, 0, len(resp.Runtimes))
+		for _, rt := range resp.Runtimes {
+			out = append(out, rt.RuntimeID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedIDs    []string
+		expectedTotal  int
+		expectedOffset int
+	}{
+		{"No parameters returns everything", "", []string{"runtime-0", "runtime-1", "runtime-2", "runtime-3", "runtime-4"}, 5, 0},
+		{"First page", "?limit=2", []string{"runtime-0", "runtime-1"}, 5, 2},
+		{"Middle page", "?limit=2&offset=2", []string{"runtime-2", "runtime-3"}, 5, 4},
+		{"Last partial page", "?limit=2&offset=4", []string{"runtime-4"}, 5, 0},
+		{"Page ending exactly at total", "?limit=5", []string{"runtime-0", "runtime-1", "runtime-2", "runtime-3", "runtime-4"}, 5, 0},
+		{"Offset past the end", "?limit=2&offset=10", []string{}, 5, 0},
+		{"Status filter", "?status=running", []string{"runtime-0", "runtime-2", "runtime-4"}, 3, 0},
+		{"Status filter with pagination", "?status=running&limit=1&offset=1", []string{"runtime-2"}, 3, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, resp := list(tt.query)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rr.Code)
+			}
+			got := ids(resp)
+			if strings.Join(got, ",") != strings.Join(tt.expectedIDs, ",") {
+				t.Errorf("Expected runtimes %v, got %v", tt.expectedIDs, got)
+			}
+			if resp.Total != tt.expectedTotal {
+				t.Errorf("Expected total %d, got %d", tt.expectedTotal, resp.Total)
+			}
+			if resp.NextOffset != tt.expectedOffset {
+				t.Errorf("Expected next_offset %d, got %d", tt.expectedOffset, resp.NextOffset)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name  string
+		query string
+	}{
+		{"Invalid status", "?status=bogus"},
+		{"Stopped status is not listable", "?status=stopped"},
+		{"Negative limit", "?limit=-1"},
+		{"Non-numeric offset", "?offset=abc"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, _ := list(tt.query)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestStartRuntime_WorkspacePVC(t *testing.T) {
+	startWithPVC := func(handler *Handler, pvcName string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: "session-1", WorkspacePVCName: pvcName})
+		req := httptest.NewRequest("POST", "/start", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.StartRuntime(rr, req)
+		return rr
+	}
+
+	t.Run("Missing PVC returns 400", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.K8sQueryTimeout = 5 * time.Second
+		handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+
+		rr := startWithPVC(handler, "missing-workspace")
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var errResp types.ErrorResponse
+		_ = json.NewDecoder(rr.Body).Decode(&errResp)
+		if errResp.Error != "workspace_pvc_not_found" || !strings.Contains(errResp.Message, "missing-workspace") {
+			t.Errorf("Expected workspace_pvc_not_found naming the PVC, got %+v", errResp)
+		}
+		if stateMgr.Count() != 0 {
+			t.Error("Expected no runtime to be added to state")
+		}
+	})
+
+	t.Run("Invalid PVC name returns 400", func(t *testing.T) {
+		handler, _ := setupTestHandler()
+		rr := startWithPVC(handler, "Not_A_Valid_Name")
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Existing PVC is mounted", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.K8sQueryTimeout = 5 * time.Second
+		handler.config.K8sOperationTimeout = 5 * time.Second
+		handler.config.WorkspaceMountPath = "/workspace"
+		clientset := fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "previous-workspace", Namespace: "test"},
+			Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}},
+		})
+		handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+		rr := startWithPVC(handler, "previous-workspace")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		info, err := stateMgr.GetRuntimeBySessionID("session-1")
+		if err != nil {
+			t.Fatalf("Expected runtime in state: %v", err)
+		}
+		if info.WorkspacePVCName != "previous-workspace" {
+			t.Errorf("Expected WorkspacePVCName to be recorded, got %q", info.WorkspacePVCName)
+		}
+		pod, err := clientset.CoreV1().Pods("test").Get(context.Background(), info.PodName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get pod: %v", err)
+		}
+		mounted := false
+		for _, vol := range pod.Spec.Volumes {
+			if vol.PersistentVolumeClaim != nil && vol.PersistentVolumeClaim.ClaimName == "previous-workspace" {
+				mounted = true
+			}
+		}
+		if !mounted {
+			t.Errorf("Expected previous-workspace PVC volume on the pod, got %+v", pod.Spec.Volumes)
+		}
+	})
+
+	t.Run("own_workspace_pvc without a PVC returns 400", func(t *testing.T) {
+		handler, _ := setupTestHandler()
+		body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: "session-1", OwnWorkspacePVC: true})
+		rr := httptest.NewRecorder()
+		handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Owned PVC is deleted on stop", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.K8sQueryTimeout = 5 * time.Second
+		handler.config.K8sOperationTimeout = 5 * time.Second
+		handler.config.DeletePVCOnStop = true
+		clientset := fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "scratch-workspace", Namespace: "test"},
+		})
+		handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+		body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: "session-1", WorkspacePVCName: "scratch-workspace", OwnWorkspacePVC: true})
+		rr := httptest.NewRecorder()
+		handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		info, err := stateMgr.GetRuntimeBySessionID("session-1")
+		if err != nil {
+			t.Fatalf("Expected runtime in state: %v", err)
+		}
+
+		stopBody, _ := json.Marshal(types.StopRequest{RuntimeID: info.RuntimeID})
+		stop := httptest.NewRecorder()
+		handler.StopRuntime(stop, httptest.NewRequest("POST", "/stop", bytes.NewReader(stopBody)))
+		if stop.Code != http.StatusOK {
+			t.Fatalf("Expected stop to succeed, got %d: %s", stop.Code, stop.Body.String())
+		}
+		if _, err := clientset.CoreV1().PersistentVolumeClaims("test").Get(context.Background(), "scratch-workspace", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+			t.Errorf("Expected the owned workspace PVC to be deleted on stop, got %v", err)
+		}
+	})
+}
+
+func TestStartRuntime_ConcurrentSameSession(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.ReuseExistingSandboxes = false
+	handler.config.K8sOperationTimeout = 10 * time.Second
+	clientset := fake.NewSimpleClientset()
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+	start := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		rr := httptest.NewRecorder()
+		handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+		return rr
+	}
+
+	// A failed creation must release the session lock for the next attempt
+	var failOnce atomic.Bool
+	failOnce.Store(true)
+	clientset.PrependReactor("create", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if failOnce.CompareAndSwap(true, false) {
+			return true, nil, fmt.Errorf("admission webhook denied the request")
+		}
+		return false, nil, nil
+	})
+	if rr := start(); rr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected the first start to fail with 500, got %d", rr.Code)
+	}
+
+	const n = 8
+	results := make(chan *httptest.ResponseRecorder, n)
+	for i := 0; i < n; i++ {
+		go func() { results <- start() }()
+	}
+
+	runtimeIDs := map[string]bool{}
+	for i := 0; i < n; i++ {
+		rr := <-results
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			continue
+		}
+		var resp types.RuntimeResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		runtimeIDs[resp.RuntimeID] = true
+	}
+	if len(runtimeIDs) != 1 {
+		t.Errorf("Expected every concurrent start to return the same runtime, got %v", runtimeIDs)
+	}
+
+	pods, err := clientset.CoreV1().Pods("test").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list pods: %v", err)
+	}
+	if len(pods.Items) != 1 {
+		t.Errorf("Expected exactly 1 pod, found %d", len(pods.Items))
+	}
+	if stateMgr.Count() != 1 {
+		t.Errorf("Expected exactly 1 runtime in state, got %d", stateMgr.Count())
 	}
 }
 
-// mockTransport intercepts HTTP requests to in-cluster service URLs and redirects them
-// to a mock test server. This lets us test the full BatchGetConversations flow without
-// requiring actual Kubernetes DNS resolution.
-type mockTransport struct {
-	mockServerURL string
-	inner         http.RoundTripper
+func TestStartRuntime_AdoptsExistingSandbox(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.ReuseExistingSandboxes = true
+	handler.config.K8sQueryTimeout = 5 * time.Second
+
+	// A live sandbox for the session exists in the cluster but not in state
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "runtime-existing",
+			Namespace: "test",
+			Labels: map[string]string{
+				"app":        "openhands-runtime",
+				"runtime-id": "existing",
+				"session-id": "session-1",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "openhands-agent",
+				Env:  []corev1.EnvVar{{Name: "OH_SESSION_API_KEYS_0", Value: "existing-key"}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	})
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+	body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: "session-1"})
+	req := httptest.NewRequest("POST", "/start", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.StartRuntime(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp types.RuntimeResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.RuntimeID != "existing" {
+		t.Errorf("Expected existing runtime to be adopted, got runtime %s", resp.RuntimeID)
+	}
+	if resp.SessionAPIKey != "existing-key" {
+		t.Errorf("Expected existing session API key, got %q", resp.SessionAPIKey)
+	}
+	if _, err := stateMgr.GetRuntimeBySessionID("session-1"); err != nil {
+		t.Error("Expected adopted runtime to be added to state")
+	}
+
+	pods, err := clientset.CoreV1().Pods("test").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list pods: %v", err)
+	}
+	if len(pods.Items) != 1 {
+		t.Errorf("Expected no new pod to be created, found %d pods", len(pods.Items))
+	}
 }
 
-func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	// Intercept requests to *.svc.cluster.local and redirect to mock server
-	if strings.Contains(req.URL.Host, "svc.cluster.local") {
-		// Rewrite the URL to point to our mock server
-		mockURL := t.mockServerURL + req.URL.Path + "?" + req.URL.RawQuery
-		newReq, err := http.NewRequestWithContext(req.Context(), req.Method, mockURL, req.Body)
+func TestStartRuntime_AdoptsLeftoverSandbox(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.ReuseExistingSandboxes = false
+	handler.config.K8sOperationTimeout = 5 * time.Second
+
+	// Leftovers from a start whose state update never happened
+	existing := metav1.ObjectMeta{
+		Name:      "runtime-existing",
+		Namespace: "test",
+		Labels: map[string]string{
+			"app":        "openhands-runtime",
+			"runtime-id": "existing",
+			"session-id": "session-1",
+		},
+	}
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: existing,
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name: "openhands-agent",
+					Env:  []corev1.EnvVar{{Name: "OH_SESSION_API_KEYS_0", Value: "existing-key"}},
+				}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		&corev1.Service{ObjectMeta: existing},
+		&networkingv1.Ingress{ObjectMeta: existing},
+	)
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+	body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: "session-1"})
+	req := httptest.NewRequest("POST", "/start", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.StartRuntime(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp types.RuntimeResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.RuntimeID != "existing" || resp.SessionAPIKey != "existing-key" {
+		t.Errorf("Expected existing runtime to be adopted, got runtime %s with key %q", resp.RuntimeID, resp.SessionAPIKey)
+	}
+	adopted, err := stateMgr.GetRuntimeBySessionID("session-1")
+	if err != nil {
+		t.Fatal("Expected adopted runtime to be in state")
+	}
+	if adopted.RuntimeID != "existing" || stateMgr.Count() != 1 {
+		t.Errorf("Expected only the adopted runtime in state, got %s of %d runtimes", adopted.RuntimeID, stateMgr.Count())
+	}
+
+	ctx := context.Background()
+	pods, _ := clientset.CoreV1().Pods("test").List(ctx, metav1.ListOptions{})
+	services, _ := clientset.CoreV1().Services("test").List(ctx, metav1.ListOptions{})
+	ingresses, _ := clientset.NetworkingV1().Ingresses("test").List(ctx, metav1.ListOptions{})
+	if len(pods.Items) != 1 || len(services.Items) != 1 || len(ingresses.Items) != 1 {
+		t.Errorf("Expected no duplicate resources, found %d pods, %d services, %d ingresses", len(pods.Items), len(services.Items), len(ingresses.Items))
+	}
+}
+
+func TestStartRuntime_ThrottlesCreatesPerImage(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.K8sOperationTimeout = 200 * time.Millisecond
+	handler.config.ImagePullSlotTimeout = time.Minute
+	handler.imageLimiter = newImageLimiter(1)
+	clientset := fake.NewSimpleClientset()
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+	start := func(sessionID, image string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.StartRequest{Image: image, SessionID: sessionID})
+		rr := httptest.NewRecorder()
+		handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+		return rr
+	}
+
+	first := start("s1", "cold-image")
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected first start to succeed, got %d: %s", first.Code, first.Body.String())
+	}
+	var firstResp types.RuntimeResponse
+	if err := json.NewDecoder(first.Body).Decode(&firstResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// The first pod is still pending (pulling the image), so its slot is still taken
+	rr := start("s2", "cold-image")
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 while the first pod pulls the image, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var errResp types.ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if errResp.Error != "image_create_throttled" {
+		t.Errorf("Expected error image_create_throttled, got %q", errResp.Error)
+	}
+	if _, err := stateMgr.GetRuntimeBySessionID("s2"); err == nil {
+		t.Error("Expected the throttled runtime to be removed from state")
+	}
+
+	// Other images have their own slots
+	if rr := start("s3", "warm-image"); rr.Code != http.StatusOK {
+		t.Errorf("Expected a different image not to be throttled, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	t.Run("Slot is released once the pod starts", func(t *testing.T) {
+		pod, err := clientset.CoreV1().Pods("test").Get(context.Background(), "runtime-"+firstResp.RuntimeID, metav1.GetOptions{})
 		if err != nil {
-			return nil, err
+			t.Fatalf("Expected first pod to exist: %v", err)
 		}
-		newReq.Header = req.Header
-		return t.inner.RoundTrip(newReq)
+		pod.Status.Phase = corev1.PodRunning
+		if _, err := clientset.CoreV1().Pods("test").UpdateStatus(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("Failed to update pod status: %v", err)
+		}
+
+		handler.config.K8sOperationTimeout = 5 * time.Second
+		if rr := start("s4", "cold-image"); rr.Code != http.StatusOK {
+			t.Fatalf("Expected start to proceed once the first pod started, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Slot is released on teardown", func(t *testing.T) {
+		handler.config.K8sOperationTimeout = 200 * time.Millisecond
+		if rr := start("s5", "cold-image"); rr.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected 503 while s4's pod is pending, got %d", rr.Code)
+		}
+		pending, err := stateMgr.GetRuntimeBySessionID("s4")
+		if err != nil {
+			t.Fatal("Expected s4's runtime in state")
+		}
+		body, _ := json.Marshal(types.StopRequest{RuntimeID: pending.RuntimeID})
+		stop := httptest.NewRecorder()
+		handler.StopRuntime(stop, httptest.NewRequest("POST", "/stop", bytes.NewReader(body)))
+		if stop.Code != http.StatusOK {
+			t.Fatalf("Expected stop to succeed, got %d: %s", stop.Code, stop.Body.String())
+		}
+		if rr := start("s5", "cold-image"); rr.Code != http.StatusOK {
+			t.Errorf("Expected start to proceed after the pending sandbox was stopped, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+}
+
+func TestStartRuntime_MaxSandboxes(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.MaxSandboxes = 2
+	handler.config.K8sOperationTimeout = 5 * time.Second
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "rt-1", SessionID: "s1", Status: types.StatusRunning})
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "rt-2", SessionID: "s2", Status: types.StatusPaused})
+
+	start := func(sessionID string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: sessionID})
+		rr := httptest.NewRecorder()
+		handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+		return rr
+	}
+
+	t.Run("New session at the cap is rejected", func(t *testing.T) {
+		rr := start("s3")
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected status 429, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp types.ErrorResponse
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.Error != "sandbox_limit_reached" {
+			t.Errorf("Expected sandbox_limit_reached error, got %q", resp.Error)
+		}
+		if _, err := stateMgr.GetRuntimeBySessionID("s3"); err == nil {
+			t.Error("Expected rejected runtime not to be added to state")
+		}
+	})
+
+	t.Run("Existing session bypasses the cap", func(t *testing.T) {
+		rr := start("s1")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for an existing session, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp types.RuntimeResponse
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.RuntimeID != "rt-1" {
+			t.Errorf("Expected the existing runtime rt-1, got %s", resp.RuntimeID)
+		}
+	})
+
+	t.Run("New session below the cap is created", func(t *testing.T) {
+		_ = stateMgr.DeleteRuntime("rt-2")
+		if rr := start("s3"); rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200 below the cap, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+}
+
+func TestStartRuntime_ResourceCeiling(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.MaxTotalCPU = "2"
+	handler.config.MaxTotalMemory = "8Gi"
+	handler.config.K8sOperationTimeout = 5 * time.Second
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+
+	start := func(sessionID, cpuRequest string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: sessionID, CPURequest: cpuRequest})
+		rr := httptest.NewRecorder()
+		handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+		return rr
+	}
+
+	var firstID string
+	t.Run("Starts are admitted up to the ceiling", func(t *testing.T) {
+		rr := start("s1", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp types.RuntimeResponse
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		firstID = resp.RuntimeID
+		if rr := start("s2", "1000m"); rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 at the ceiling, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if cpu, _ := stateMgr.CommittedResources(); cpu != 2000 {
+			t.Errorf("Expected 2000m CPU committed, got %dm", cpu)
+		}
+	})
+
+	t.Run("Start beyond the ceiling is rejected", func(t *testing.T) {
+		rr := start("s3", "100m")
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected status 429, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp types.ErrorResponse
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.Error != "resource_ceiling" {
+			t.Errorf("Expected resource_ceiling error, got %q", resp.Error)
+		}
+		if _, err := stateMgr.GetRuntimeBySessionID("s3"); err == nil {
+			t.Error("Expected rejected runtime not to be added to state")
+		}
+	})
+
+	t.Run("Stop frees the committed resources", func(t *testing.T) {
+		body, _ := json.Marshal(types.StopRequest{RuntimeID: firstID})
+		rr := httptest.NewRecorder()
+		handler.StopRuntime(rr, httptest.NewRequest("POST", "/stop", bytes.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected stop to succeed, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if cpu, _ := stateMgr.CommittedResources(); cpu != 1000 {
+			t.Errorf("Expected 1000m CPU committed after stop, got %dm", cpu)
+		}
+		if rr := start("s3", "100m"); rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200 after stop freed resources, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+}
+
+func TestStartRuntime_Async(t *testing.T) {
+	startAsync := func(handler *Handler, sessionID string) types.OperationResponse {
+		t.Helper()
+		body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: sessionID})
+		rr := httptest.NewRecorder()
+		handler.StartRuntime(rr, httptest.NewRequest("POST", "/start?async=true", bytes.NewReader(body)))
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("Expected status 202, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var op types.OperationResponse
+		if err := json.NewDecoder(rr.Body).Decode(&op); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if op.OperationID == "" || op.RuntimeID == "" || op.Status != types.OperationPending {
+			t.Errorf("Expected a pending operation with IDs, got %+v", op)
+		}
+		if loc := rr.Header().Get("Location"); loc != "/operations/"+op.OperationID {
+			t.Errorf("Expected Location /operations/%s, got %q", op.OperationID, loc)
+		}
+		return op
+	}
+	getOperation := func(handler *Handler, operationID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/operations/"+operationID, nil)
+		req = mux.SetURLVars(req, map[string]string{"op_id": operationID})
+		rr := httptest.NewRecorder()
+		handler.GetOperation(rr, req)
+		return rr
+	}
+	// pollOperation polls GET /operations/{op_id} until the operation completes
+	pollOperation := func(handler *Handler, operationID string) types.OperationResponse {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			rr := getOperation(handler, operationID)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200 polling the operation, got %d: %s", rr.Code, rr.Body.String())
+			}
+			var op types.OperationResponse
+			_ = json.NewDecoder(rr.Body).Decode(&op)
+			if op.Status != types.OperationPending {
+				return op
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Operation %s still pending", operationID)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	t.Run("Polls to completion", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.K8sOperationTimeout = 5 * time.Second
+		handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+
+		op := startAsync(handler, "async-session")
+		done := pollOperation(handler, op.OperationID)
+		if done.Status != types.OperationSucceeded || done.Runtime == nil || done.CompletedAt == nil {
+			t.Fatalf("Expected a succeeded operation with the runtime, got %+v", done)
+		}
+		if done.Runtime.RuntimeID != op.RuntimeID || done.Runtime.Status != types.StatusRunning {
+			t.Errorf("Expected running runtime %s, got %+v", op.RuntimeID, done.Runtime)
+		}
+		if runtimeInfo, err := stateMgr.GetRuntimeByID(op.RuntimeID); err != nil || runtimeInfo.Status != types.StatusRunning {
+			t.Errorf("Expected runtime to be running in state, got %v (err %v)", runtimeInfo, err)
+		}
+	})
+
+	t.Run("Polls to failure", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.K8sOperationTimeout = 5 * time.Second
+		clientset := fake.NewSimpleClientset()
+		clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("quota exceeded")
+		})
+		handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+		op := startAsync(handler, "failing-session")
+		done := pollOperation(handler, op.OperationID)
+		if done.Status != types.OperationFailed || done.Error != "sandbox_creation_failed" {
+			t.Fatalf("Expected a failed operation with sandbox_creation_failed, got %+v", done)
+		}
+		if !strings.Contains(done.Message, "quota exceeded") {
+			t.Errorf("Expected the creation error in the message, got %q", done.Message)
+		}
+		if _, err := stateMgr.GetRuntimeByID(op.RuntimeID); err == nil {
+			t.Error("Expected failed runtime to be removed from state")
+		}
+	})
+
+	t.Run("Unknown operation", func(t *testing.T) {
+		handler, _ := setupTestHandler()
+		if rr := getOperation(handler, "missing"); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", rr.Code)
+		}
+	})
+}
+
+func TestStartRuntime_WaitForReady(t *testing.T) {
+	start := func(handler *Handler, url string, req types.StartRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		rr := httptest.NewRecorder()
+		handler.StartRuntime(rr, httptest.NewRequest("POST", url, bytes.NewReader(body)))
+		return rr
+	}
+
+	t.Run("Responds once the pod becomes ready", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.K8sOperationTimeout = 10 * time.Second
+		clientset := fake.NewSimpleClientset()
+		handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+		// Mark the sandbox pod ready shortly after it is created
+		go func() {
+			for range 100 {
+				time.Sleep(50 * time.Millisecond)
+				pods, err := clientset.CoreV1().Pods("test").List(context.Background(), metav1.ListOptions{})
+				if err != nil || len(pods.Items) == 0 {
+					continue
+				}
+				pod := pods.Items[0]
+				pod.Status = corev1.PodStatus{
+					Phase:             corev1.PodRunning,
+					ContainerStatuses: []corev1.ContainerStatus{{Name: "runtime", Ready: true}},
+				}
+				_, _ = clientset.CoreV1().Pods("test").UpdateStatus(context.Background(), &pod, metav1.UpdateOptions{})
+				return
+			}
+		}()
+
+		rr := start(handler, "/start?wait=true", types.StartRequest{Image: "test-image", SessionID: "wait-session"})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp types.RuntimeResponse
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.PodStatus != types.PodStatusReady {
+			t.Errorf("Expected pod_status ready, got %s", resp.PodStatus)
+		}
+		if _, err := stateMgr.GetRuntimeBySessionID("wait-session"); err != nil {
+			t.Errorf("Expected runtime in state: %v", err)
+		}
+	})
+
+	t.Run("Returns 504 on timeout and keeps the sandbox", func(t *testing.T) {
+		handler, stateMgr := setupTestHandler()
+		handler.config.K8sOperationTimeout = 500 * time.Millisecond
+		handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+
+		rr := start(handler, "/start", types.StartRequest{Image: "test-image", SessionID: "slow-session", WaitForReady: true})
+		if rr.Code != http.StatusGatewayTimeout {
+			t.Fatalf("Expected status 504, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp types.ErrorResponse
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.Error != "runtime_not_ready" {
+			t.Errorf("Expected runtime_not_ready error, got %q", resp.Error)
+		}
+		runtimeInfo, err := stateMgr.GetRuntimeBySessionID("slow-session")
+		if err != nil {
+			t.Fatalf("Expected sandbox to be left in state: %v", err)
+		}
+		if runtimeInfo.Status != types.StatusRunning {
+			t.Errorf("Expected sandbox to stay running, got %s", runtimeInfo.Status)
+		}
+	})
+}
+
+func TestStartRuntime_LifecycleMetrics(t *testing.T) {
+	handler, _ := setupTestHandler()
+	handler.config.K8sOperationTimeout = 5 * time.Second
+	handler.k8sClient = k8s.NewClientWithClientset(fake.NewSimpleClientset(), handler.config)
+
+	// Counters are process-wide, so use images no other test starts
+	okImage := "registry.example.com/metrics-ok:v1"
+	startsBefore := metrics.SandboxStarts.Value("registry.example.com/metrics-ok")
+	body, _ := json.Marshal(types.StartRequest{Image: okImage, SessionID: "session-ok"})
+	rr := httptest.NewRecorder()
+	handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := metrics.SandboxStarts.Value("registry.example.com/metrics-ok"); got != startsBefore+1 {
+		t.Errorf("Expected starts counter to increment to %v, got %v", startsBefore+1, got)
+	}
+
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", errors.New("exceeded quota"))
+	})
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+	failImage := "registry.example.com/metrics-fail:v1"
+	failuresBefore := metrics.SandboxCreateFailures.Value("forbidden", "registry.example.com/metrics-fail")
+	body, _ = json.Marshal(types.StartRequest{Image: failImage, SessionID: "session-fail"})
+	rr = httptest.NewRecorder()
+	handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+	if rr.Code == http.StatusOK {
+		t.Fatal("Expected start to fail when pod creation is forbidden")
+	}
+	if got := metrics.SandboxCreateFailures.Value("forbidden", "registry.example.com/metrics-fail"); got != failuresBefore+1 {
+		t.Errorf("Expected create failures counter to increment to %v, got %v", failuresBefore+1, got)
+	}
+}
+
+func TestCreateFailureReason(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Deadline", fmt.Errorf("create pod: %w", context.DeadlineExceeded), "timeout"},
+		{"Server timeout", apierrors.NewServerTimeout(pods, "create", 1), "timeout"},
+		{"Throttled", apierrors.NewTooManyRequests("slow down", 1), "throttled"},
+		{"Forbidden", apierrors.NewForbidden(pods, "p", errors.New("quota")), "forbidden"},
+		{"Invalid", apierrors.NewInvalid(schema.GroupKind{Kind: "Pod"}, "p", nil), "invalid"},
+		{"Already exists", apierrors.NewAlreadyExists(pods, "p"), "already_exists"},
+		{"Other", errors.New("boom"), "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createFailureReason(tt.err); got != tt.want {
+				t.Errorf("createFailureReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSchedulingHint(t *testing.T) {
+	tests := []struct {
+		name      string
+		hint      *types.SchedulingHint
+		expectErr bool
+	}{
+		{"No hint", nil, false},
+		{"Zone only", &types.SchedulingHint{Zone: "us-east-1a"}, false},
+		{"Node label only", &types.SchedulingHint{NodeLabel: "example.com/dataset=imagenet"}, false},
+		{"Zone and node label", &types.SchedulingHint{Zone: "westeurope-1", NodeLabel: "pool=data"}, false},
+		{"Empty hint", &types.SchedulingHint{}, true},
+		{"Invalid zone", &types.SchedulingHint{Zone: "us east"}, true},
+		{"Node label without value separator", &types.SchedulingHint{NodeLabel: "pool"}, true},
+		{"Invalid node label key", &types.SchedulingHint{NodeLabel: "bad key=value"}, true},
+		{"Invalid node label value", &types.SchedulingHint{NodeLabel: "pool=bad value"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchedulingHint(tt.hint)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error=%v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestStartRuntime_InvalidSchedulingHint(t *testing.T) {
+	handler, _ := setupTestHandler()
+
+	body, _ := json.Marshal(types.StartRequest{
+		Image:          "test-image",
+		SessionID:      "session-1",
+		SchedulingHint: &types.SchedulingHint{NodeLabel: "no-value"},
+	})
+	req := httptest.NewRequest("POST", "/start", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.StartRuntime(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+}
+
+func TestValidateResourceQuantities(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       types.StartRequest
+		expectErr bool
+	}{
+		{"None set", types.StartRequest{}, false},
+		{"All set", types.StartRequest{CPURequest: "250m", CPULimit: "1", MemoryRequest: "8Gi", MemoryLimit: "16Gi"}, false},
+		{"Request only", types.StartRequest{MemoryRequest: "32Gi"}, false},
+		{"Malformed CPU", types.StartRequest{CPURequest: "lots"}, true},
+		{"Malformed memory limit", types.StartRequest{MemoryLimit: "16GB!"}, true},
+		{"Negative quantity", types.StartRequest{CPULimit: "-1"}, true},
+		{"Request exceeds limit", types.StartRequest{MemoryRequest: "8Gi", MemoryLimit: "4Gi"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateResourceQuantities(&tt.req)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error=%v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestStartRuntime_InvalidResourceQuantity(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+
+	body, _ := json.Marshal(types.StartRequest{
+		Image:         "test-image",
+		SessionID:     "session-1",
+		MemoryRequest: "a lot",
+	})
+	req := httptest.NewRequest("POST", "/start", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.StartRuntime(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+	if stateMgr.Count() != 0 {
+		t.Errorf("Expected no runtime in state, got %d", stateMgr.Count())
+	}
+}
+
+func TestValidateEnvironment(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		expectErr bool
+	}{
+		{"No environment", nil, false},
+		{"Valid names", map[string]string{"DEBUG": "1", "_private": "x", "my.setting-2": "y"}, false},
+		{"Space in name", map[string]string{"MY VAR": "1"}, true},
+		{"Leading digit", map[string]string{"1VAR": "1"}, true},
+		{"Special character", map[string]string{"VAR$": "1"}, true},
+		{"Empty name", map[string]string{"": "1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEnvironment(tt.env)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error=%v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidatePodMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expectErr   bool
+	}{
+		{"No metadata", nil, nil, false},
+		{"Valid labels and annotations", map[string]string{"team": "ml", "cost.example.com/center": "r-and-d"}, map[string]string{"sidecar.istio.io/inject": "true", "note": "any value, even with spaces"}, false},
+		{"Reserved session-id label", map[string]string{"session-id": "other"}, nil, true},
+		{"Reserved runtime-id label", map[string]string{"runtime-id": "other"}, nil, true},
+		{"Reserved openhands.dev label", map[string]string{"openhands.dev/protected": "true"}, nil, true},
+		{"Reserved openhands.dev annotation", nil, map[string]string{"openhands.dev/ttl-seconds": "1"}, true},
+		{"Invalid label key", map[string]string{"bad key": "x"}, nil, true},
+		{"Invalid label value", map[string]string{"team": "has spaces"}, nil, true},
+		{"Invalid annotation key", nil, map[string]string{"bad key": "x"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePodMetadata(tt.labels, tt.annotations)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error=%v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateVolumes(t *testing.T) {
+	handler, _ := setupTestHandler()
+	handler.config.WorkspaceMountPath = "/workspace"
+	handler.config.CACertSecretName = "corp-ca"
+
+	npmrc := types.VolumeRequest{Name: "npmrc", ConfigMap: "npmrc", MountPath: "/home/openhands/.npmrc", SubPath: ".npmrc", ReadOnly: true}
+	tests := []struct {
+		name      string
+		volumes   []types.VolumeRequest
+		expectErr bool
+	}{
+		{"No volumes", nil, false},
+		{"ConfigMap and Secret", []types.VolumeRequest{npmrc, {Name: "data", Secret: "dataset-creds", MountPath: "/data"}}, false},
+		{"Invalid name", []types.VolumeRequest{{Name: "Bad_Name", ConfigMap: "x", MountPath: "/x"}}, true},
+		{"Reserved name", []types.VolumeRequest{{Name: "workspace", ConfigMap: "x", MountPath: "/x"}}, true},
+		{"Duplicate name", []types.VolumeRequest{npmrc, {Name: "npmrc", ConfigMap: "x", MountPath: "/x"}}, true},
+		{"No source", []types.VolumeRequest{{Name: "x", MountPath: "/x"}}, true},
+		{"Both sources", []types.VolumeRequest{{Name: "x", ConfigMap: "x", Secret: "x", MountPath: "/x"}}, true},
+		{"Relative mount path", []types.VolumeRequest{{Name: "x", ConfigMap: "x", MountPath: "x"}}, true},
+		{"Workspace mount path", []types.VolumeRequest{{Name: "x", ConfigMap: "x", MountPath: "/workspace/"}}, true},
+		{"Inside workspace", []types.VolumeRequest{{Name: "x", ConfigMap: "x", MountPath: "/workspace/data"}}, true},
+		{"Parent of CA cert", []types.VolumeRequest{{Name: "x", ConfigMap: "x", MountPath: "/usr/local/share"}}, true},
+		{"Sibling of workspace", []types.VolumeRequest{{Name: "x", ConfigMap: "x", MountPath: "/workspace-data"}}, false},
+		{"Duplicate mount path", []types.VolumeRequest{npmrc, {Name: "other", ConfigMap: "x", MountPath: "/home/openhands/.npmrc"}}, true},
+		{"Escaping sub path", []types.VolumeRequest{{Name: "x", ConfigMap: "x", MountPath: "/x", SubPath: "../etc"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := handler.validateVolumes(tt.volumes)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error=%v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestStartRuntime_InitContainerValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		registries     []string
+		init           types.InitContainerRequest
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"No allowed registries", nil, types.InitContainerRequest{Image: "ghcr.io/my-org/git:2"}, http.StatusForbidden, "init_container_not_allowed"},
+		{"Registry not allowed", []string{"ghcr.io/my-org"}, types.InitContainerRequest{Image: "docker.io/evil/git:2"}, http.StatusForbidden, "init_container_not_allowed"},
+		{"Partial segment match", []string{"ghcr.io/my-org"}, types.InitContainerRequest{Image: "ghcr.io/my-org2/git:2"}, http.StatusForbidden, "init_container_not_allowed"},
+		{"Missing image", []string{"ghcr.io/my-org"}, types.InitContainerRequest{}, http.StatusBadRequest, "invalid_request"},
+		{"Invalid env key", []string{"ghcr.io/my-org/"}, types.InitContainerRequest{Image: "ghcr.io/my-org/git:2", Environment: map[string]string{"BAD KEY": "1"}}, http.StatusBadRequest, "invalid_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := setupTestHandler()
+			handler.config.InitContainerRegistries = tt.registries
+			init := tt.init
+			body, _ := json.Marshal(types.StartRequest{Image: "test-image", SessionID: "session-1", InitContainer: &init})
+			rr := httptest.NewRecorder()
+			handler.StartRuntime(rr, httptest.NewRequest("POST", "/start", bytes.NewReader(body)))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedCode) {
+				t.Errorf("Expected error code %s, got %s", tt.expectedCode, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("Allowed registry", func(t *testing.T) {
+		handler, _ := setupTestHandler()
+		handler.config.InitContainerRegistries = []string{"registry.internal", "ghcr.io/my-org"}
+		if err := handler.validateInitContainer(&types.InitContainerRequest{Image: "ghcr.io/my-org/git:2", Command: types.FlexibleCommand{"git", "clone"}}); err != nil {
+			t.Errorf("Expected image from an allowed registry to pass, got %v", err)
+		}
+	})
+}
+
+func TestStartRuntime_InvalidEnvironmentKey(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+
+	body, _ := json.Marshal(types.StartRequest{
+		Image:       "test-image",
+		SessionID:   "session-1",
+		Environment: map[string]string{"GOOD": "1", "BAD KEY": "2"},
+	})
+	req := httptest.NewRequest("POST", "/start", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.StartRuntime(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "BAD KEY") {
+		t.Errorf("Expected error to name the bad key, got: %s", rr.Body.String())
+	}
+	if stateMgr.Count() != 0 {
+		t.Errorf("Expected no runtime in state, got %d", stateMgr.Count())
 	}
-	return t.inner.RoundTrip(req)
 }
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// imageLimiter caps the number of concurrent sandbox creations per image so a burst of
+// starts for a cold image doesn't have every node pull it at once. Callers beyond the
+// limit queue until a slot frees up or their context is done.
+//
+// A creation keeps its slot until its pod has pulled the image, which outlives the
+// /start request, so slots taken for a runtime are parked with hold and released by
+// releaseHeld once the pod has started, failed or been torn down.
+type imageLimiter struct {
+	mu    sync.Mutex
+	slots map[string]*imageSlots
+	held  map[string]func()
+	limit int
+}
+
+// imageSlots is one image's semaphore. refs counts holders and queued callers; the entry
+// is dropped when it reaches zero so images that are no longer started don't leak.
+type imageSlots struct {
+	sem  chan struct{}
+	refs int
+}
+
+// newImageLimiter returns a limiter allowing limit concurrent creations per image.
+// A limit of 0 or less disables throttling.
+func newImageLimiter(limit int) *imageLimiter {
+	return &imageLimiter{
+		slots: make(map[string]*imageSlots),
+		held:  make(map[string]func()),
+		limit: limit,
+	}
+}
+
+// enabled reports whether creations are throttled at all
+func (l *imageLimiter) enabled() bool {
+	return l != nil && l.limit > 0
+}
+
+// acquire blocks until a creation slot for image is available and returns the release
+// func, which is safe to call more than once
+func (l *imageLimiter) acquire(ctx context.Context, image string) (func(), error) {
+	if !l.enabled() {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	entry, ok := l.slots[image]
+	if !ok {
+		entry = &imageSlots{sem: make(chan struct{}, l.limit)}
+		l.slots[image] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	select {
+	case entry.sem <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				<-entry.sem
+				l.unref(image, entry)
+			})
+		}, nil
+	case <-ctx.Done():
+		l.unref(image, entry)
+		return nil, ctx.Err()
+	}
+}
+
+// unref drops a holder or queued caller of image, removing the entry once it has none
+func (l *imageLimiter) unref(image string, entry *imageSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry.refs--
+	if entry.refs == 0 {
+		delete(l.slots, image)
+	}
+}
+
+// hold parks a runtime's creation slot until releaseHeld is called for it
+func (l *imageLimiter) hold(runtimeID string, release func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.held[runtimeID] = release
+}
+
+// releaseHeld releases the slot parked for runtimeID, if any
+func (l *imageLimiter) releaseHeld(runtimeID string) {
+	if !l.enabled() {
+		return
+	}
+	l.mu.Lock()
+	release, ok := l.held[runtimeID]
+	delete(l.held, runtimeID)
+	l.mu.Unlock()
+	if ok {
+		release()
+	}
+}
+
+// len returns the number of images with holders or queued callers
+func (l *imageLimiter) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.slots)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestImageLimiter_ThrottlesPerImage(t *testing.T) {
+	limiter := newImageLimiter(2)
+	ctx := context.Background()
+
+	release1, err := limiter.acquire(ctx, "image-a")
+	if err != nil {
+		t.Fatalf("Unexpected error acquiring first slot: %v", err)
+	}
+	release2, err := limiter.acquire(ctx, "image-a")
+	if err != nil {
+		t.Fatalf("Unexpected error acquiring second slot: %v", err)
+	}
+
+	// Other images are not affected by image-a's limit
+	releaseOther, err := limiter.acquire(ctx, "image-b")
+	if err != nil {
+		t.Fatalf("Expected a different image to get its own slots, got %v", err)
+	}
+	releaseOther()
+
+	// The third creation of image-a must queue
+	acquired := make(chan func(), 1)
+	go func() {
+		release, acquireErr := limiter.acquire(ctx, "image-a")
+		if acquireErr == nil {
+			acquired <- release
+		}
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected third creation of the same image to be throttled")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release1()
+	select {
+	case release3 := <-acquired:
+		release3()
+	case <-time.After(time.Second):
+		t.Fatal("Expected queued creation to proceed once a slot was released")
+	}
+	release2()
+}
+
+func TestImageLimiter_ContextTimeout(t *testing.T) {
+	limiter := newImageLimiter(1)
+
+	release, err := limiter.acquire(context.Background(), "image-a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx, "image-a"); err == nil {
+		t.Error("Expected an error when the context expires while queued")
+	}
+}
+
+func TestImageLimiter_Unlimited(t *testing.T) {
+	limiter := newImageLimiter(0)
+	for i := 0; i < 100; i++ {
+		if _, err := limiter.acquire(context.Background(), "image-a"); err != nil {
+			t.Fatalf("Expected no throttling with a zero limit, got %v", err)
+		}
+	}
+}
+
+func TestImageLimiter_DropsDrainedImages(t *testing.T) {
+	limiter := newImageLimiter(1)
+	for i := 0; i < 10; i++ {
+		release, err := limiter.acquire(context.Background(), fmt.Sprintf("image-%d", i))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		release()
+		release() // releasing twice must not free a second slot
+	}
+	if n := limiter.len(); n != 0 {
+		t.Errorf("Expected no tracked images once all slots were released, got %d", n)
+	}
+
+	// A caller that gives up while queued doesn't keep the entry alive either
+	release, err := limiter.acquire(context.Background(), "image-a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx, "image-a"); err == nil {
+		t.Fatal("Expected queued acquire to time out")
+	}
+	release()
+	if n := limiter.len(); n != 0 {
+		t.Errorf("Expected image-a to be dropped after its last holder released, got %d tracked", n)
+	}
+}
+
+func TestImageLimiter_HoldUntilReleased(t *testing.T) {
+	limiter := newImageLimiter(1)
+	release, err := limiter.acquire(context.Background(), "image-a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	limiter.hold("rt-1", release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx, "image-a"); err == nil {
+		t.Fatal("Expected a held slot to keep throttling the image")
+	}
+
+	limiter.releaseHeld("rt-1")
+	limiter.releaseHeld("rt-1")
+	next, err := limiter.acquire(context.Background(), "image-a")
+	if err != nil {
+		t.Fatalf("Expected the slot to be free after releaseHeld, got %v", err)
+	}
+	next()
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/types"
+)
+
+// operationStore tracks async /start operations in memory so GET /operations/{op_id} can
+// report their outcome. Operations older than ttl are dropped whenever a new one is
+// created, which bounds the store without a background sweep. Operations are lost on
+// restart; the runtime itself is rediscovered from Kubernetes.
+type operationStore struct {
+	mu         sync.Mutex
+	operations map[string]*types.OperationResponse
+	ttl        time.Duration
+}
+
+// defaultOperationTTL is used when no positive TTL is configured
+const defaultOperationTTL = time.Hour
+
+func newOperationStore(ttl time.Duration) *operationStore {
+	if ttl <= 0 {
+		ttl = defaultOperationTTL
+	}
+	return &operationStore{
+		operations: make(map[string]*types.OperationResponse),
+		ttl:        ttl,
+	}
+}
+
+// create records a pending operation for runtimeID and returns a copy of it
+func (s *operationStore) create(runtimeID string) types.OperationResponse {
+	now := time.Now()
+	op := &types.OperationResponse{
+		OperationID: generateID(),
+		Status:      types.OperationPending,
+		RuntimeID:   runtimeID,
+		CreatedAt:   now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, existing := range s.operations {
+		if now.Sub(existing.CreatedAt) > s.ttl {
+			delete(s.operations, id)
+		}
+	}
+	s.operations[op.OperationID] = op
+	return *op
+}
+
+// succeed marks an operation as succeeded with the created runtime
+func (s *operationStore) succeed(operationID string, runtime *types.RuntimeResponse) {
+	s.complete(operationID, func(op *types.OperationResponse) {
+		op.Status = types.OperationSucceeded
+		op.Runtime = runtime
+	})
+}
+
+// fail marks an operation as failed with an error code and message
+func (s *operationStore) fail(operationID, code, message string) {
+	s.complete(operationID, func(op *types.OperationResponse) {
+		op.Status = types.OperationFailed
+		op.Error = code
+		op.Message = message
+	})
+}
+
+func (s *operationStore) complete(operationID string, update func(op *types.OperationResponse)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op, ok := s.operations[operationID]
+	if !ok {
+		return
+	}
+	update(op)
+	now := time.Now()
+	op.CompletedAt = &now
+}
+
+// get returns a copy of an operation, or false if it is unknown or expired
+func (s *operationStore) get(operationID string) (types.OperationResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op, ok := s.operations[operationID]
+	if !ok || time.Since(op.CreatedAt) > s.ttl {
+		return types.OperationResponse{}, false
+	}
+	return *op, true
+}
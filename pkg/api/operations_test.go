@@ -0,0 +1,31 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/types"
+)
+
+func TestOperationStore_Expiry(t *testing.T) {
+	store := newOperationStore(time.Minute)
+
+	old := store.create("runtime-old")
+	store.operations[old.OperationID].CreatedAt = time.Now().Add(-2 * time.Minute)
+	if _, ok := store.get(old.OperationID); ok {
+		t.Error("Expected an expired operation not to be returned")
+	}
+
+	recent := store.create("runtime-recent")
+	if _, ok := store.operations[old.OperationID]; ok {
+		t.Error("Expected creating an operation to drop expired ones")
+	}
+	store.fail(recent.OperationID, "sandbox_creation_failed", "boom")
+	got, ok := store.get(recent.OperationID)
+	if !ok {
+		t.Fatal("Expected recent operation to be returned")
+	}
+	if got.Status != types.OperationFailed || got.Error != "sandbox_creation_failed" || got.CompletedAt == nil {
+		t.Errorf("Expected a completed failed operation, got %+v", got)
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// activityTouchInterval limits how often traffic on a long-lived proxied connection
+// updates the sandbox's LastActivityTime
+const activityTouchInterval = 30 * time.Second
+
+// activityToucher returns a func that marks runtimeID as active, at most once per
+// activityTouchInterval
+func (h *Handler) activityToucher(runtimeID string) func() {
+	var last atomic.Int64
+	return func() {
+		now := time.Now().UnixNano()
+		prev := last.Load()
+		if now-prev < int64(activityTouchInterval) || !last.CompareAndSwap(prev, now) {
+			return
+		}
+		_ = h.stateMgr.UpdateLastActivity(runtimeID)
+	}
+}
+
+// isUpgradeRequest reports whether r asks to switch protocols (e.g. to WebSocket)
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acquireWebSocket reserves a slot for an upgraded connection, returning false when
+// MaxProxyWebSockets are already open. Callers release the slot by decrementing
+// activeWebSockets once the proxied connection closes.
+func (h *Handler) acquireWebSocket() bool {
+	if n := h.activeWebSockets.Add(1); h.config.MaxProxyWebSockets > 0 && n > int64(h.config.MaxProxyWebSockets) {
+		h.activeWebSockets.Add(-1)
+		return false
+	}
+	return true
+}
+
+// activityResponseWriter wraps the proxy's ResponseWriter so that a hijacked (upgraded)
+// connection reports activity on every frame read or written, not just the initial request.
+type activityResponseWriter struct {
+	http.ResponseWriter
+	touch func()
+}
+
+// Hijack hijacks the underlying connection and wraps it to report activity
+func (w *activityResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &activityConn{Conn: conn, touch: w.touch}, brw, nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (e.g. for Flush)
+func (w *activityResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// activityConn calls touch whenever data is read from or written to the connection
+type activityConn struct {
+	net.Conn
+	touch func()
+}
+
+func (c *activityConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.touch()
+	}
+	return n, err
+}
+
+func (c *activityConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		c.touch()
+	}
+	return n, err
+}
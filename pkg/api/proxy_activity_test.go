@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/state"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/types"
+)
+
+// newEchoWebSocketBackend returns a server that completes a WebSocket upgrade handshake
+// and then echoes every byte it receives (frames are opaque to the proxy).
+func newEchoWebSocketBackend(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+			!strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") {
+			http.Error(w, "expected websocket upgrade", http.StatusBadRequest)
+			return
+		}
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Backend hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		_ = brw.Flush()
+		_, _ = io.Copy(conn, brw)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// routeSandboxesTo makes ProxySandbox dial backend for every sandbox service address
+func routeSandboxesTo(handler *Handler, backend *httptest.Server) {
+	backendAddr := backend.Listener.Addr().String()
+	handler.sandboxDialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, backendAddr)
+	}
+}
+
+// dialWebSocket sends a WebSocket upgrade request for runtimeID through front and returns
+// the raw connection, a reader positioned after the response headers, and the response.
+func dialWebSocket(t *testing.T, front *httptest.Server, runtimeID string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", front.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial proxy: %v", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	_, _ = io.WriteString(conn, "GET /sandbox/"+runtimeID+"/sockets/events HTTP/1.1\r\nHost: runtime-api\r\n"+
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		conn.Close()
+		t.Fatalf("Failed to read upgrade response: %v", err)
+	}
+	return conn, reader, resp
+}
+
+func TestProxySandbox_WebSocket(t *testing.T) {
+	backend := newEchoWebSocketBackend(t)
+	handler, stateMgr := setupTestHandler()
+	routeSandboxesTo(handler, backend)
+	info := &state.RuntimeInfo{
+		RuntimeID:   "rt-ws",
+		SessionID:   "s-ws",
+		Status:      types.StatusRunning,
+		ServiceName: "runtime-rt-ws",
+	}
+	stateMgr.AddRuntime(info)
+
+	front := httptest.NewServer(http.HandlerFunc(handler.ProxySandbox))
+	defer front.Close()
+
+	conn, reader, resp := dialWebSocket(t, front, "rt-ws")
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101 Switching Protocols, got %d", resp.StatusCode)
+	}
+
+	// Pretend the sandbox has been quiet for a while; frames on the open socket must count as activity
+	stale := time.Now().Add(-time.Hour)
+	info.LastActivityTime = stale
+
+	for _, msg := range []string{"hello", "world"} {
+		if _, err := io.WriteString(conn, msg); err != nil {
+			t.Fatalf("Failed to write frame: %v", err)
+		}
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			t.Fatalf("Failed to read echoed frame: %v", err)
+		}
+		if string(buf) != msg {
+			t.Errorf("Expected echo %q, got %q", msg, buf)
+		}
+	}
+
+	updated, _ := stateMgr.GetRuntimeByID("rt-ws")
+	if !updated.LastActivityTime.After(stale) {
+		t.Error("Expected WebSocket traffic to update LastActivityTime")
+	}
+}
+
+func TestProxySandbox_ReusesBackendConnections(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	handler, stateMgr := setupTestHandler()
+	handler.config.ProxyMaxIdleConnsPerHost = 4
+	backendAddr := backend.Listener.Addr().String()
+	var dials atomic.Int32
+	handler.sandboxDialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		dials.Add(1)
+		return (&net.Dialer{}).DialContext(ctx, network, backendAddr)
+	}
+	stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "rt-pool", SessionID: "s-pool", Status: types.StatusRunning, ServiceName: "runtime-rt-pool"})
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ProxySandbox(rr, httptest.NewRequest("GET", "/sandbox/rt-pool/api/health", nil))
+		if rr.Code != http.StatusOK || rr.Body.String() != "ok" {
+			t.Fatalf("Request %d: expected 200 ok, got %d %q", i+1, rr.Code, rr.Body.String())
+		}
+	}
+	// The second request reuses the kept-alive connection from the first
+	if n := dials.Load(); n != 1 {
+		t.Errorf("Expected 1 backend dial for 2 sequential requests, got %d", n)
+	}
+}
+
+func TestProxySandbox_WebSocketLimit(t *testing.T) {
+	backend := newEchoWebSocketBackend(t)
+	handler, stateMgr := setupTestHandler()
+	handler.config.MaxProxyWebSockets = 1
+	routeSandboxesTo(handler, backend)
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:   "rt-ws",
+		SessionID:   "s-ws",
+		Status:      types.StatusRunning,
+		ServiceName: "runtime-rt-ws",
+	})
+
+	front := httptest.NewServer(http.HandlerFunc(handler.ProxySandbox))
+	defer front.Close()
+
+	first, _, resp := dialWebSocket(t, front, "rt-ws")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected first WebSocket to be accepted, got %d", resp.StatusCode)
+	}
+	if got := handler.activeWebSockets.Load(); got != 1 {
+		t.Errorf("Expected 1 active WebSocket, got %d", got)
+	}
+
+	second, _, resp := dialWebSocket(t, front, "rt-ws")
+	second.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when the WebSocket limit is exceeded, got %d", resp.StatusCode)
+	}
+
+	// Plain requests are not counted against the limit
+	if isUpgradeRequest(httptest.NewRequest("GET", "/sandbox/rt-ws/alive", nil)) {
+		t.Error("Expected a plain GET not to be treated as an upgrade")
+	}
+
+	// Closing the first connection frees its slot
+	first.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for handler.activeWebSockets.Load() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := handler.activeWebSockets.Load(); got != 0 {
+		t.Fatalf("Expected active count to drop to 0 after close, got %d", got)
+	}
+
+	third, _, resp := dialWebSocket(t, front, "rt-ws")
+	defer third.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Expected a WebSocket to be accepted after a slot freed, got %d", resp.StatusCode)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.GetStats(rr, httptest.NewRequest("GET", "/stats", nil))
+	var stats types.StatsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if stats.ActiveWebSockets != 1 {
+		t.Errorf("Expected active_websockets 1 in stats, got %d", stats.ActiveWebSockets)
+	}
+}
+
+func TestActivityToucher_Throttles(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	info := &state.RuntimeInfo{RuntimeID: "rt-1", SessionID: "s1"}
+	stateMgr.AddRuntime(info)
+
+	touch := handler.activityToucher("rt-1")
+	touch()
+	first := info.LastActivityTime
+	if first.IsZero() {
+		t.Fatal("Expected first touch to record activity")
+	}
+	touch()
+	if !info.LastActivityTime.Equal(first) {
+		t.Error("Expected touches within the throttle interval to be skipped")
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/logger"
+)
+
+// readinessCache remembers the outcome of the last Kubernetes API check so frequent
+// readiness probes (possibly from several kubelets and load balancers) don't each
+// query the API server. Concurrent probes wait for one in-flight check.
+type readinessCache struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// check returns the cached result if it is younger than ttl, otherwise runs probe
+func (c *readinessCache) check(ttl time.Duration, probe func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < ttl {
+		return c.err
+	}
+	c.err = probe()
+	c.checkedAt = time.Now()
+	return c.err
+}
+
+// Readiness handles GET /readiness. Unlike /liveness it verifies the Kubernetes API is
+// reachable with our credentials, so a replica that can't manage sandboxes is taken
+// out of rotation instead of failing every request. With WAIT_FOR_DISCOVERY it also
+// waits for the first successful discovery of existing sandboxes.
+func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	if h.config.WaitForDiscovery && h.stateMgr.LastReconcileTime().IsZero() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("Sandbox discovery not complete"))
+		return
+	}
+	if h.k8sClient != nil {
+		err := h.readiness.check(h.config.ReadinessCacheTTL, func() error {
+			// The result is shared with other probers, so a caller hanging up must not
+			// cancel the check and have its error cached for everyone
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), h.config.ReadinessCheckTimeout)
+			defer cancel()
+			return h.k8sClient.CheckAPI(ctx)
+		})
+		if err != nil {
+			logger.Warn("Readiness: Kubernetes API check failed: %v", err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("Kubernetes API unavailable"))
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
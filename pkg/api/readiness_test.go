@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/k8s"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestReadiness(t *testing.T) {
+	handler, _ := setupTestHandler()
+	handler.config.ReadinessCheckTimeout = time.Second
+	handler.config.ReadinessCacheTTL = time.Hour
+
+	var lists atomic.Int32
+	var apiDown atomic.Bool
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		lists.Add(1)
+		if apiDown.Load() {
+			return true, nil, errors.New("connection refused")
+		}
+		return false, nil, nil
+	})
+	handler.k8sClient = k8s.NewClientWithClientset(clientset, handler.config)
+
+	probe := func() int {
+		rr := httptest.NewRecorder()
+		handler.Readiness(rr, httptest.NewRequest("GET", "/readiness", nil))
+		return rr.Code
+	}
+
+	if code := probe(); code != http.StatusOK {
+		t.Fatalf("Expected 200 when the Kubernetes API is reachable, got %d", code)
+	}
+
+	// Within the cache TTL the API server isn't queried again, even if it went down
+	apiDown.Store(true)
+	if code := probe(); code != http.StatusOK {
+		t.Errorf("Expected cached 200 within the TTL, got %d", code)
+	}
+	if got := lists.Load(); got != 1 {
+		t.Errorf("Expected 1 API check within the cache TTL, got %d", got)
+	}
+
+	// Once the cache expires the failure is seen
+	handler.config.ReadinessCacheTTL = 0
+	if code := probe(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when the Kubernetes API is unreachable, got %d", code)
+	}
+
+	apiDown.Store(false)
+	if code := probe(); code != http.StatusOK {
+		t.Errorf("Expected 200 after the Kubernetes API recovers, got %d", code)
+	}
+}
+
+func TestReadiness_WaitForDiscovery(t *testing.T) {
+	handler, stateMgr := setupTestHandler()
+	handler.config.WaitForDiscovery = true
+
+	probe := func() int {
+		rr := httptest.NewRecorder()
+		handler.Readiness(rr, httptest.NewRequest("GET", "/readiness", nil))
+		return rr.Code
+	}
+
+	if code := probe(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before discovery completes, got %d", code)
+	}
+	stateMgr.MarkReconciled()
+	if code := probe(); code != http.StatusOK {
+		t.Errorf("Expected 200 after discovery completes, got %d", code)
+	}
+
+	// Off by default: ready without waiting for discovery
+	handler, _ = setupTestHandler()
+	if code := probe(); code != http.StatusOK {
+		t.Errorf("Expected 200 without WAIT_FOR_DISCOVERY, got %d", code)
+	}
+}
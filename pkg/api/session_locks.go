@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/logger"
+)
+
+// sessionLocks serializes /start requests per session so concurrent starts for the
+// same session cannot create duplicate sandboxes. Entries are only needed while a
+// start is in flight; idle entries older than ttl are removed by a periodic sweep so
+// sessions that never start again don't leak bookkeeping.
+type sessionLocks struct {
+	mu      sync.Mutex
+	entries map[string]*sessionLockEntry
+	ttl     time.Duration
+}
+
+type sessionLockEntry struct {
+	mu       sync.Mutex
+	refs     int
+	lastUsed time.Time
+}
+
+// defaultSessionLockTTL is used when no positive TTL is configured
+const defaultSessionLockTTL = 10 * time.Minute
+
+func newSessionLocks(ttl time.Duration) *sessionLocks {
+	if ttl <= 0 {
+		ttl = defaultSessionLockTTL
+	}
+	return &sessionLocks{
+		entries: make(map[string]*sessionLockEntry),
+		ttl:     ttl,
+	}
+}
+
+// lock blocks until the caller holds the lock for sessionID and returns the unlock func
+func (l *sessionLocks) lock(sessionID string) func() {
+	l.mu.Lock()
+	entry, ok := l.entries[sessionID]
+	if !ok {
+		entry = &sessionLockEntry{}
+		l.entries[sessionID] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	entry.mu.Lock()
+	return func() {
+		entry.mu.Unlock()
+		l.mu.Lock()
+		entry.refs--
+		entry.lastUsed = time.Now()
+		l.mu.Unlock()
+	}
+}
+
+// sweep removes entries that are not held and were last used more than ttl before now.
+// It returns the number of entries removed.
+func (l *sessionLocks) sweep(now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	removed := 0
+	for sessionID, entry := range l.entries {
+		if entry.refs == 0 && now.Sub(entry.lastUsed) > l.ttl {
+			delete(l.entries, sessionID)
+			removed++
+		}
+	}
+	return removed
+}
+
+// len returns the number of tracked sessions
+func (l *sessionLocks) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+// run sweeps expired entries every ttl until ctx is done
+func (l *sessionLocks) run(ctx context.Context) {
+	ticker := time.NewTicker(l.ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if removed := l.sweep(now); removed > 0 {
+				logger.Debug("Session locks: swept %d expired entries", removed)
+			}
+		}
+	}
+}
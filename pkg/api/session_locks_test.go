@@ -0,0 +1,82 @@
+package api
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSessionLocks_SweepExpired(t *testing.T) {
+	locks := newSessionLocks(time.Minute)
+
+	locks.lock("session-old")()
+	locks.lock("session-recent")()
+	unlockHeld := locks.lock("session-held")
+
+	// Age one idle entry and the held entry past the TTL
+	locks.entries["session-old"].lastUsed = time.Now().Add(-2 * time.Minute)
+	locks.entries["session-held"].lastUsed = time.Now().Add(-2 * time.Minute)
+
+	removed := locks.sweep(time.Now())
+	if removed != 1 {
+		t.Errorf("Expected 1 entry to be swept, got %d", removed)
+	}
+	if _, ok := locks.entries["session-old"]; ok {
+		t.Error("Expected expired entry to be swept")
+	}
+	if _, ok := locks.entries["session-recent"]; !ok {
+		t.Error("Expected recently used entry to be kept")
+	}
+	if _, ok := locks.entries["session-held"]; !ok {
+		t.Error("Expected held entry to be kept even past the TTL")
+	}
+
+	unlockHeld()
+	removed = locks.sweep(time.Now().Add(2 * time.Minute))
+	if removed != 2 || locks.len() != 0 {
+		t.Errorf("Expected all entries to be swept once idle past the TTL, removed %d, remaining %d", removed, locks.len())
+	}
+}
+
+func TestSessionLocks_SerializesSameSession(t *testing.T) {
+	locks := newSessionLocks(time.Minute)
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.lock("session-1")
+			defer unlock()
+
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight != 1 {
+		t.Errorf("Expected at most 1 holder at a time, got %d", maxInFlight)
+	}
+	if locks.len() != 1 {
+		t.Errorf("Expected 1 tracked session, got %d", locks.len())
+	}
+}
+
+func TestNewSessionLocks_DefaultTTL(t *testing.T) {
+	if locks := newSessionLocks(0); locks.ttl != defaultSessionLockTTL {
+		t.Errorf("Expected default TTL %v, got %v", defaultSessionLockTTL, locks.ttl)
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/alert"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/config"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/k8s"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/logger"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/metrics"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/state"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/types"
 )
@@ -23,6 +25,14 @@ type Service struct {
 	mu        sync.RWMutex
 	lastRun   time.Time
 	stats     CleanupStats
+	alerts    *alert.Notifier
+	activity  ActivityChecker
+}
+
+// ActivityChecker confirms whether a runtime that looks idle from its LastActivityTime
+// is still in use, e.g. by asking its agent server (IDLE_SIGNAL). Implemented by the reaper.
+type ActivityChecker interface {
+	StillActive(runtime *state.RuntimeInfo, now time.Time, idleTimeout time.Duration) bool
 }
 
 // CleanupStats tracks cleanup metrics
@@ -32,6 +42,9 @@ type CleanupStats struct {
 	TotalCleaned      int
 	FailedCleaned     int
 	IdleCleaned       int
+	WouldClean        int // Runtimes the last run would have cleaned up (CLEANUP_DRY_RUN)
+	Repaired          int // Missing Services/Ingresses recreated (RECONCILE_RESOURCES)
+	OrphansDeleted    int // Runtimes whose leftover Services/Ingresses were deleted
 	LastCleanupErrors []string
 }
 
@@ -45,6 +58,17 @@ func NewService(k8sClient *k8s.Client, stateMgr *state.StateManager, cfg *config
 	}
 }
 
+// SetAlertNotifier sets the notifier told about failed and successful sandbox deletions
+func (s *Service) SetAlertNotifier(n *alert.Notifier) {
+	s.alerts = n
+}
+
+// SetActivityChecker sets the check consulted before a runtime is cleaned up as idle.
+// Without one, idleness is judged from LastActivityTime alone.
+func (s *Service) SetActivityChecker(c ActivityChecker) {
+	s.activity = c
+}
+
 // Start begins the cleanup service
 func (s *Service) Start(ctx context.Context) {
 	if !s.config.CleanupEnabled {
@@ -52,8 +76,8 @@ func (s *Service) Start(ctx context.Context) {
 		return
 	}
 
-	logger.Info("Starting cleanup service - Interval: %d minutes, Failed threshold: %d minutes, Idle threshold: %d minutes",
-		s.config.CleanupIntervalMinutes, s.config.CleanupFailedThresholdMin, s.config.CleanupIdleThresholdMin)
+	logger.Info("Starting cleanup service - Interval: %d minutes, Failed threshold: %d minutes, Idle threshold: %d minutes, Dry run: %v",
+		s.config.CleanupIntervalMinutes, s.config.CleanupFailedThresholdMin, s.config.CleanupIdleThresholdMin, s.config.CleanupDryRun)
 
 	s.wg.Add(1)
 	go s.run(ctx)
@@ -105,6 +129,7 @@ func (s *Service) runCleanup(ctx context.Context) {
 	logger.Debug("Cleanup: Starting cleanup run")
 	s.mu.Lock()
 	s.lastRun = time.Now()
+	s.stats.LastRunTime = s.lastRun
 	s.stats.TotalRunCount++
 	s.stats.LastCleanupErrors = []string{}
 	s.mu.Unlock()
@@ -112,13 +137,13 @@ func (s *Service) runCleanup(ctx context.Context) {
 	runtimes := s.stateMgr.ListRuntimes()
 	logger.Debug("Cleanup: Found %d runtimes to check", len(runtimes))
 
-	var cleanedCount, failedCount, idleCount int
+	var cleanedCount, failedCount, idleCount, wouldCleanCount, repairedCount int
 	var errors []string
 
 	// Batch-fetch all pod statuses in a single K8s API call.
 	podNames := make([]string, 0, len(runtimes))
 	for _, runtime := range runtimes {
-		if runtime.Status != types.StatusStopped {
+		if runtime.Status != types.StatusStopped && runtime.Status != types.StatusStopping && runtime.Status != types.StatusPaused {
 			podNames = append(podNames, runtime.PodName)
 		}
 	}
@@ -130,56 +155,93 @@ func (s *Service) runCleanup(ctx context.Context) {
 
 	for _, runtime := range runtimes {
 		// Skip if runtime is already stopped or being stopped
-		if runtime.Status == types.StatusStopped {
-			continue
-		}
-
-		// Skip if batch fetch failed or pod not found in results
-		if statusErr != nil {
+		if runtime.Status == types.StatusStopped || runtime.Status == types.StatusStopping {
 			continue
 		}
-		podStatus, ok := statuses[runtime.PodName]
-		if !ok {
-			continue
+		// Paused runtimes have no pod by design, so there is no status to fetch
+		podStatus := &k8s.PodStatusInfo{Status: types.PodStatusNotFound}
+		if runtime.Status != types.StatusPaused {
+			// Skip if batch fetch failed or pod not found in results
+			if statusErr != nil {
+				continue
+			}
+			var ok bool
+			if podStatus, ok = statuses[runtime.PodName]; !ok {
+				continue
+			}
 		}
 
 		shouldCleanup, reason := s.shouldCleanupRuntime(runtime, podStatus)
+		if shouldCleanup && s.config.CleanupDryRun {
+			logger.Info("Cleanup: [dry run] Would clean up runtime %s (session: %s) - Reason: %s, Restarts: %d",
+				runtime.RuntimeID, runtime.SessionID, reason, podStatus.RestartCount)
+			wouldCleanCount++
+			continue
+		}
 		if shouldCleanup {
 			logger.Info("Cleanup: Cleaning up runtime %s (session: %s) - Reason: %s, Restarts: %d, LastTermination: %s (exit %d) %s",
 				runtime.RuntimeID, runtime.SessionID, reason,
 				podStatus.RestartCount, podStatus.LastTerminationReason,
 				podStatus.LastTerminationExitCode, podStatus.LastTerminationMessage)
 
-			if err := s.k8sClient.DeleteSandbox(ctx, runtime); err != nil {
-				logger.Info("Cleanup: Error deleting sandbox for runtime %s: %v", runtime.RuntimeID, err)
+			cleaned, err := s.cleanupRuntime(ctx, runtime, reason)
+			if err != nil {
+				logger.Error("Cleanup: Error deleting sandbox for runtime %s: %v", runtime.RuntimeID, err)
 				errors = append(errors, fmt.Sprintf("error deleting sandbox for %s: %v", runtime.RuntimeID, err))
+				s.alerts.RecordFailure("cleanup", runtime.RuntimeID, err)
 				continue
 			}
-
-			// Remove from state
-			if err := s.stateMgr.DeleteRuntime(runtime.RuntimeID); err != nil {
-				logger.Debug("Cleanup: Error removing runtime from state %s: %v", runtime.RuntimeID, err)
+			if !cleaned {
+				logger.Debug("Cleanup: Runtime %s was already torn down, skipping", runtime.RuntimeID)
+				continue
 			}
+			s.alerts.RecordSuccess(runtime.RuntimeID)
+			metrics.SandboxReaps.Inc("cleanup", reason)
 
 			cleanedCount++
 			switch reason {
 			case "pod_failed", "excessive_restarts", "pod_not_found":
 				failedCount++
-			case "pod_idle":
+			case "pod_idle", "paused_idle":
 				idleCount++
 			}
 
 			logger.Debug("Cleanup: Successfully cleaned up runtime %s", runtime.RuntimeID)
+			continue
+		}
+
+		if s.config.ReconcileResources && !s.config.CleanupDryRun && runtime.Status == types.StatusRunning &&
+			podStatus.Status != types.PodStatusNotFound {
+			recreated, err := s.repairRuntime(ctx, runtime)
+			for _, name := range recreated {
+				logger.Info("Cleanup: Recreated missing %s for runtime %s (session: %s)", name, runtime.RuntimeID, runtime.SessionID)
+			}
+			repairedCount += len(recreated)
+			if err != nil {
+				logger.Error("Cleanup: Error repairing resources for runtime %s: %v", runtime.RuntimeID, err)
+				errors = append(errors, fmt.Sprintf("error repairing resources for %s: %v", runtime.RuntimeID, err))
+			}
 		}
 	}
 
+	orphansDeleted, orphanErrors := s.sweepOrphans(ctx)
+	errors = append(errors, orphanErrors...)
+
 	s.mu.Lock()
 	s.stats.TotalCleaned += cleanedCount
 	s.stats.FailedCleaned += failedCount
 	s.stats.IdleCleaned += idleCount
+	s.stats.WouldClean = wouldCleanCount
+	s.stats.Repaired += repairedCount
+	s.stats.OrphansDeleted += orphansDeleted
 	s.stats.LastCleanupErrors = errors
 	s.mu.Unlock()
 
+	if s.config.CleanupDryRun {
+		logger.Info("Cleanup: [dry run] Completed - Would have cleaned %d runtimes", wouldCleanCount)
+		return
+	}
+
 	if cleanedCount > 0 {
 		logger.Info("Cleanup: Completed - Cleaned %d runtimes (%d failed, %d idle)", cleanedCount, failedCount, idleCount)
 	} else {
@@ -187,8 +249,100 @@ func (s *Service) runCleanup(ctx context.Context) {
 	}
 }
 
-// shouldCleanupRuntime determines if a runtime should be cleaned up
+// sweepOrphans deletes Services and Ingresses (and their TLS secrets) left behind by
+// runtimes that have no pod and are not tracked in state, e.g. after state was lost or a
+// pod was deleted by hand. It returns the number of runtimes swept and any errors.
+func (s *Service) sweepOrphans(ctx context.Context) (int, []string) {
+	if s.config.CleanupOrphanGraceMin <= 0 {
+		return 0, nil
+	}
+	tracked := map[string]bool{}
+	for _, runtime := range s.stateMgr.ListRuntimes() {
+		tracked[runtime.RuntimeID] = true
+	}
+	grace := time.Duration(s.config.CleanupOrphanGraceMin) * time.Minute
+	orphans, err := s.k8sClient.ListOrphanedSandboxes(ctx, grace, tracked)
+	if err != nil {
+		logger.Debug("Cleanup: Failed to list orphaned resources: %v", err)
+		return 0, []string{fmt.Sprintf("orphan sweep failed: %v", err)}
+	}
+
+	deleted := 0
+	var errors []string
+	for _, orphan := range orphans {
+		if s.config.CleanupDryRun {
+			logger.Info("Cleanup: [dry run] Would delete orphaned resources of runtime %s - Services: %v, Ingresses: %v",
+				orphan.RuntimeID, orphan.Services, orphan.Ingresses)
+			continue
+		}
+		logger.Info("Cleanup: Deleting orphaned resources of runtime %s - Services: %v, Ingresses: %v, Secrets: %v",
+			orphan.RuntimeID, orphan.Services, orphan.Ingresses, orphan.Secrets)
+		if err := s.k8sClient.DeleteOrphanedSandbox(ctx, orphan); err != nil {
+			logger.Error("Cleanup: Error deleting orphaned resources of runtime %s: %v", orphan.RuntimeID, err)
+			errors = append(errors, err.Error())
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors
+}
+
+// cleanupRuntime deletes a runtime's sandbox, records the reason in its history and removes
+// it from state. It returns false without error if a concurrent stop or reap already
+// removed the runtime.
+func (s *Service) cleanupRuntime(ctx context.Context, runtime *state.RuntimeInfo, reason string) (bool, error) {
+	unlock := s.stateMgr.LockRuntime(runtime.RuntimeID)
+	defer unlock()
+	live, err := s.stateMgr.GetRuntimeByID(runtime.RuntimeID)
+	if err != nil {
+		return false, nil
+	}
+	previous := live.Status
+	if !s.stateMgr.TransitionStatus(runtime.RuntimeID, previous, types.StatusStopping) {
+		return false, nil
+	}
+
+	if err := s.k8sClient.DeleteSandbox(ctx, runtime); err != nil {
+		s.stateMgr.TransitionStatus(runtime.RuntimeID, types.StatusStopping, previous)
+		return false, err
+	}
+	_ = s.stateMgr.RecordEvent(runtime.RuntimeID, types.RuntimeEventReaped, "cleanup: "+reason)
+
+	// Remove from state
+	if err := s.stateMgr.DeleteRuntime(runtime.RuntimeID); err != nil {
+		logger.Debug("Cleanup: Error removing runtime from state %s: %v", runtime.RuntimeID, err)
+	}
+	s.stateMgr.Tombstone(runtime)
+	return true, nil
+}
+
+// repairRuntime recreates a running runtime's missing Service or Ingresses, returning the
+// objects it recreated. It holds the runtime's teardown lock so a concurrent stop can't
+// have its deletions undone.
+func (s *Service) repairRuntime(ctx context.Context, runtime *state.RuntimeInfo) ([]string, error) {
+	unlock := s.stateMgr.LockRuntime(runtime.RuntimeID)
+	defer unlock()
+	live, err := s.stateMgr.GetRuntimeByID(runtime.RuntimeID)
+	if err != nil || live.Status != types.StatusRunning {
+		return nil, nil
+	}
+	return s.k8sClient.EnsureSandboxResources(ctx, live)
+}
+
+// shouldCleanupRuntime determines if a runtime should be cleaned up. Protected runtimes
+// are never cleaned up.
 func (s *Service) shouldCleanupRuntime(runtime *state.RuntimeInfo, podStatus *k8s.PodStatusInfo) (bool, string) {
+	shouldCleanup, reason := s.cleanupReason(runtime, podStatus)
+	if shouldCleanup && runtime.Protected {
+		logger.Info("Cleanup: Skipping protected runtime %s (session: %s) that would be cleaned up (reason: %s)",
+			runtime.RuntimeID, runtime.SessionID, reason)
+		return false, ""
+	}
+	return shouldCleanup, reason
+}
+
+// cleanupReason reports whether a runtime is due for cleanup and why, ignoring protection
+func (s *Service) cleanupReason(runtime *state.RuntimeInfo, podStatus *k8s.PodStatusInfo) (bool, string) {
 	now := time.Now()
 
 	// Grace period: never clean up runtimes that are still pending or were
@@ -201,6 +355,21 @@ func (s *Service) shouldCleanupRuntime(runtime *state.RuntimeInfo, podStatus *k8
 		return false, ""
 	}
 
+	// Paused runtimes have no pod by design and keep their workspace PVC for resume, but
+	// not indefinitely: one left paused past the idle threshold is removed like an idle pod.
+	// Nothing reaches a paused sandbox without resuming it, so its last activity is final.
+	if runtime.Status == types.StatusPaused {
+		idleThreshold := time.Duration(s.config.CleanupIdleThresholdMin) * time.Minute
+		lastActive := runtime.LastActivityTime
+		if lastActive.IsZero() {
+			lastActive = runtime.CreatedAt
+		}
+		if now.Sub(lastActive) >= idleThreshold {
+			return true, "paused_idle"
+		}
+		return false, ""
+	}
+
 	// Pod no longer exists — clean up orphaned services/ingresses immediately.
 	if podStatus.Status == types.PodStatusNotFound {
 		return true, "pod_not_found"
@@ -222,7 +391,8 @@ func (s *Service) shouldCleanupRuntime(runtime *state.RuntimeInfo, podStatus *k8
 
 	// Check if pod has been idle for too long based on last activity time.
 	// LastActivityTime is updated on every proxied request (ProxySandbox handler)
-	// and on activity heartbeats from the app-server.
+	// and on activity heartbeats from the app-server. Traffic that bypasses the proxy
+	// (direct ingress) doesn't advance it, so the activity checker gets the final say.
 	if podStatus.Status != types.PodStatusFailed && podStatus.Status != types.PodStatusCrashLoopBackOff {
 		idleThreshold := time.Duration(s.config.CleanupIdleThresholdMin) * time.Minute
 		lastActive := runtime.LastActivityTime
@@ -230,6 +400,10 @@ func (s *Service) shouldCleanupRuntime(runtime *state.RuntimeInfo, podStatus *k8
 			lastActive = runtime.CreatedAt
 		}
 		if now.Sub(lastActive) >= idleThreshold {
+			if s.activity != nil && s.activity.StillActive(runtime, now, idleThreshold) {
+				logger.Debug("Cleanup: Runtime %s has no recent proxied activity but is still active, not cleaning up", runtime.RuntimeID)
+				return false, ""
+			}
 			return true, "pod_idle"
 		}
 	}
@@ -1,13 +1,25 @@
 package cleanup
 
 import (
+	"context"
+	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/config"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/k8s"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/reaper"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/state"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestShouldCleanupRuntime(t *testing.T) {
@@ -67,7 +79,7 @@ func TestShouldCleanupRuntime(t *testing.T) {
 			name: "Idle running pod past threshold",
 			runtime: &state.RuntimeInfo{
 				RuntimeID: "test4",
-				CreatedAt: time.Now().Add(-25 * time.Hour), // 25 hours ago
+				CreatedAt: time.Now().Add(-25 * time.Hour), // 25 hours ago, no activity recorded
 			},
 			podStatus: &k8s.PodStatusInfo{
 				Status: types.PodStatusReady,
@@ -138,6 +150,74 @@ func TestShouldCleanupRuntime(t *testing.T) {
 			expectedCleanup: false,
 			expectedReason:  "",
 		},
+		{
+			name: "Protected runtime past failed threshold",
+			runtime: &state.RuntimeInfo{
+				RuntimeID: "test10",
+				CreatedAt: time.Now().Add(-2 * time.Hour),
+				Protected: true,
+			},
+			podStatus: &k8s.PodStatusInfo{
+				Status: types.PodStatusFailed,
+			},
+			expectedCleanup: false,
+			expectedReason:  "",
+		},
+		{
+			name: "Protected runtime idle past threshold",
+			runtime: &state.RuntimeInfo{
+				RuntimeID:        "test11",
+				CreatedAt:        time.Now().Add(-48 * time.Hour),
+				LastActivityTime: time.Now().Add(-48 * time.Hour),
+				Protected:        true,
+			},
+			podStatus: &k8s.PodStatusInfo{
+				Status: types.PodStatusReady,
+			},
+			expectedCleanup: false,
+			expectedReason:  "",
+		},
+		{
+			// A long-running session is idle only once its activity stops, not when it gets old
+			name: "Old runtime with recent activity",
+			runtime: &state.RuntimeInfo{
+				RuntimeID:        "test12",
+				CreatedAt:        time.Now().Add(-48 * time.Hour),
+				LastActivityTime: time.Now().Add(-5 * time.Minute),
+			},
+			podStatus: &k8s.PodStatusInfo{
+				Status: types.PodStatusReady,
+			},
+			expectedCleanup: false,
+			expectedReason:  "",
+		},
+		{
+			name: "Old runtime whose activity stopped past threshold",
+			runtime: &state.RuntimeInfo{
+				RuntimeID:        "test13",
+				CreatedAt:        time.Now().Add(-48 * time.Hour),
+				LastActivityTime: time.Now().Add(-25 * time.Hour),
+			},
+			podStatus: &k8s.PodStatusInfo{
+				Status: types.PodStatusReady,
+			},
+			expectedCleanup: true,
+			expectedReason:  "pod_idle",
+		},
+		{
+			// The failed-pod threshold still counts from creation, whatever the activity
+			name: "Failed pod with recent activity past threshold",
+			runtime: &state.RuntimeInfo{
+				RuntimeID:        "test14",
+				CreatedAt:        time.Now().Add(-2 * time.Hour),
+				LastActivityTime: time.Now().Add(-time.Minute),
+			},
+			podStatus: &k8s.PodStatusInfo{
+				Status: types.PodStatusFailed,
+			},
+			expectedCleanup: true,
+			expectedReason:  "pod_failed",
+		},
 	}
 
 	for _, tt := range tests {
@@ -153,6 +233,283 @@ func TestShouldCleanupRuntime(t *testing.T) {
 	}
 }
 
+// stubActivityChecker reports the runtimes in active as still in use
+type stubActivityChecker struct {
+	active      map[string]bool
+	idleTimeout time.Duration
+}
+
+func (c *stubActivityChecker) StillActive(runtime *state.RuntimeInfo, now time.Time, idleTimeout time.Duration) bool {
+	c.idleTimeout = idleTimeout
+	return c.active[runtime.RuntimeID]
+}
+
+func TestShouldCleanupRuntime_ConsultsActivityChecker(t *testing.T) {
+	cfg := &config.Config{
+		CleanupFailedThresholdMin: 60,
+		CleanupIdleThresholdMin:   1440,
+	}
+	s := NewService(nil, nil, cfg)
+	checker := &stubActivityChecker{active: map[string]bool{"busy": true}}
+	s.SetActivityChecker(checker)
+
+	// Both look idle from proxied traffic, as with direct ingress routing
+	idle := func(id string) *state.RuntimeInfo {
+		return &state.RuntimeInfo{
+			RuntimeID:        id,
+			CreatedAt:        time.Now().Add(-48 * time.Hour),
+			LastActivityTime: time.Now().Add(-25 * time.Hour),
+		}
+	}
+	ready := &k8s.PodStatusInfo{Status: types.PodStatusReady}
+
+	if cleanup, _ := s.shouldCleanupRuntime(idle("busy"), ready); cleanup {
+		t.Error("Expected a runtime the activity checker reports active not to be cleaned up")
+	}
+	if checker.idleTimeout != 24*time.Hour {
+		t.Errorf("Expected the cleanup idle threshold to be passed to the checker, got %s", checker.idleTimeout)
+	}
+	if cleanup, reason := s.shouldCleanupRuntime(idle("quiet"), ready); !cleanup || reason != "pod_idle" {
+		t.Errorf("Expected an inactive runtime to be cleaned up as pod_idle, got %v %q", cleanup, reason)
+	}
+}
+
+func TestRunCleanup_PausedRuntimes(t *testing.T) {
+	cfg := &config.Config{
+		Namespace:                 "test",
+		CleanupFailedThresholdMin: 60,
+		CleanupIdleThresholdMin:   60,
+		DeletePVCOnStop:           true,
+	}
+	ownedPVC := func(runtimeID string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+			Name:      "workspace-" + runtimeID,
+			Namespace: "test",
+			Labels:    map[string]string{"app": "openhands-runtime", "runtime-id": runtimeID},
+		}}
+	}
+	clientset := fake.NewSimpleClientset(ownedPVC("recent"), ownedPVC("forgotten"))
+	stateMgr := state.NewStateManager()
+	s := NewService(k8s.NewClientWithClientset(clientset, cfg), stateMgr, cfg)
+
+	// Neither has a pod or a TTL; only the one paused past the idle threshold is removed
+	paused := func(runtimeID string, lastActive time.Time) *state.RuntimeInfo {
+		return &state.RuntimeInfo{
+			RuntimeID:        runtimeID,
+			SessionID:        "session-" + runtimeID,
+			Status:           types.StatusPaused,
+			PodName:          "runtime-" + runtimeID,
+			CreatedAt:        time.Now().Add(-48 * time.Hour),
+			LastActivityTime: lastActive,
+			WorkspacePVCName: "workspace-" + runtimeID,
+		}
+	}
+	stateMgr.AddRuntime(paused("recent", time.Now().Add(-30*time.Minute)))
+	stateMgr.AddRuntime(paused("forgotten", time.Now().Add(-2*time.Hour)))
+
+	s.runCleanup(context.Background())
+
+	t.Run("Recently paused runtime keeps its workspace", func(t *testing.T) {
+		if _, err := stateMgr.GetRuntimeByID("recent"); err != nil {
+			t.Error("Expected recently paused runtime to remain in state")
+		}
+		if _, err := clientset.CoreV1().PersistentVolumeClaims("test").Get(context.Background(), "workspace-recent", metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected paused runtime's workspace PVC to be kept, got %v", err)
+		}
+	})
+
+	t.Run("Runtime paused past the idle threshold is removed", func(t *testing.T) {
+		if _, err := stateMgr.GetRuntimeByID("forgotten"); err == nil {
+			t.Error("Expected long-paused runtime to be removed from state")
+		}
+		if _, err := clientset.CoreV1().PersistentVolumeClaims("test").Get(context.Background(), "workspace-forgotten", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+			t.Errorf("Expected long-paused runtime's owned PVC to be deleted, got %v", err)
+		}
+		if stats := s.GetStats(); stats.IdleCleaned != 1 {
+			t.Errorf("Expected 1 idle cleanup, got %d", stats.IdleCleaned)
+		}
+	})
+}
+
+func TestRunCleanup_DryRun(t *testing.T) {
+	cfg := &config.Config{
+		Namespace:                 "test",
+		CleanupFailedThresholdMin: 60,
+		CleanupIdleThresholdMin:   60,
+		CleanupDryRun:             true,
+	}
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runtime-failed", Namespace: "test", Labels: map[string]string{"app": "openhands-runtime"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodFailed},
+	}, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runtime-healthy", Namespace: "test", Labels: map[string]string{"app": "openhands-runtime"}},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "openhands-agent", Ready: true}},
+		},
+	})
+	stateMgr := state.NewStateManager()
+	s := NewService(k8s.NewClientWithClientset(clientset, cfg), stateMgr, cfg)
+
+	created := time.Now().Add(-2 * time.Hour)
+	for _, id := range []string{"failed", "healthy", "gone"} {
+		stateMgr.AddRuntime(&state.RuntimeInfo{
+			RuntimeID:        id,
+			SessionID:        "session-" + id,
+			Status:           types.StatusRunning,
+			PodName:          "runtime-" + id,
+			CreatedAt:        created,
+			LastActivityTime: time.Now(),
+		})
+	}
+
+	s.runCleanup(context.Background())
+
+	// The failed pod and the missing pod are candidates, but nothing is deleted
+	if stats := s.GetStats(); stats.WouldClean != 2 || stats.TotalCleaned != 0 {
+		t.Errorf("Expected 2 would-clean and 0 cleaned, got %d and %d", stats.WouldClean, stats.TotalCleaned)
+	}
+	if stateMgr.Count() != 3 {
+		t.Errorf("Expected all runtimes to remain in state, got %d", stateMgr.Count())
+	}
+	if _, err := clientset.CoreV1().Pods("test").Get(context.Background(), "runtime-failed", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected failed pod not to be deleted in dry run: %v", err)
+	}
+}
+
+func TestRunCleanup_ReconcileResources(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{
+		Namespace:                 "test",
+		BaseDomain:                "example.com",
+		AgentServerPort:           60000,
+		VSCodePort:                60001,
+		WorkerPorts:               []int{12000},
+		CleanupFailedThresholdMin: 60,
+		CleanupIdleThresholdMin:   60,
+		ReconcileResources:        true,
+	}
+	clientset := fake.NewSimpleClientset()
+	k8sClient := k8s.NewClientWithClientset(clientset, cfg)
+	stateMgr := state.NewStateManager()
+	s := NewService(k8sClient, stateMgr, cfg)
+
+	runtime := &state.RuntimeInfo{
+		RuntimeID:        "repair",
+		SessionID:        "session-repair",
+		SessionAPIKey:    "key",
+		Status:           types.StatusRunning,
+		PodName:          "runtime-repair",
+		ServiceName:      "runtime-repair",
+		IngressName:      "runtime-repair",
+		CreatedAt:        time.Now().Add(-2 * time.Hour),
+		LastActivityTime: time.Now(),
+	}
+	if err := k8sClient.CreateSandbox(ctx, &types.StartRequest{Image: "test-image", SessionID: runtime.SessionID}, runtime); err != nil {
+		t.Fatalf("CreateSandbox failed: %v", err)
+	}
+	stateMgr.AddRuntime(runtime)
+
+	// Mark the pod running so cleanup leaves it alone, then lose its service and ingress
+	pod, _ := clientset.CoreV1().Pods("test").Get(ctx, "runtime-repair", metav1.GetOptions{})
+	pod.Status = corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{{Name: "openhands-agent", Ready: true}}}
+	_, _ = clientset.CoreV1().Pods("test").UpdateStatus(ctx, pod, metav1.UpdateOptions{})
+	if err := clientset.CoreV1().Services("test").Delete(ctx, "runtime-repair", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete service: %v", err)
+	}
+	if err := clientset.NetworkingV1().Ingresses("test").Delete(ctx, "runtime-repair", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete ingress: %v", err)
+	}
+
+	s.runCleanup(ctx)
+
+	if _, err := clientset.CoreV1().Services("test").Get(ctx, "runtime-repair", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected missing service to be recreated, got %v", err)
+	}
+	if _, err := clientset.NetworkingV1().Ingresses("test").Get(ctx, "runtime-repair", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected missing ingress to be recreated, got %v", err)
+	}
+	if stats := s.GetStats(); stats.Repaired != 2 || stats.TotalCleaned != 0 || len(stats.LastCleanupErrors) != 0 {
+		t.Errorf("Expected 2 repaired objects and nothing cleaned, got %+v", stats)
+	}
+
+	// A second run finds nothing to repair
+	s.runCleanup(ctx)
+	if stats := s.GetStats(); stats.Repaired != 2 {
+		t.Errorf("Expected no further repairs, got %d total", stats.Repaired)
+	}
+}
+
+func TestRunCleanup_SweepsOrphans(t *testing.T) {
+	ctx := context.Background()
+	old := metav1.NewTime(time.Now().Add(-time.Hour))
+	labels := func(runtimeID string) map[string]string {
+		return map[string]string{"app": "openhands-runtime", "runtime-id": runtimeID}
+	}
+	service := func(runtimeID string, created metav1.Time) *corev1.Service {
+		return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "runtime-" + runtimeID, Namespace: "test", Labels: labels(runtimeID), CreationTimestamp: created}}
+	}
+	ingress := func(runtimeID string) *networkingv1.Ingress {
+		return &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "runtime-" + runtimeID, Namespace: "test", Labels: labels(runtimeID), CreationTimestamp: old},
+			Spec:       networkingv1.IngressSpec{TLS: []networkingv1.IngressTLS{{SecretName: "runtime-" + runtimeID + "-tls"}, {SecretName: "shared-wildcard-tls"}}},
+		}
+	}
+	secret := func(name string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"}}
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry run %v", dryRun), func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				// A valid sandbox with its pod
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "runtime-valid", Namespace: "test", Labels: labels("valid")}},
+				service("valid", old), ingress("valid"), secret("runtime-valid-tls"),
+				// An orphan whose pod is gone
+				service("orphan", old), ingress("orphan"), secret("runtime-orphan-tls"), secret("shared-wildcard-tls"),
+				// Too new to sweep: its pod may still be on the way
+				service("young", metav1.Now()),
+				// Paused: no pod by design, but still tracked
+				service("paused", old),
+			)
+			cfg := &config.Config{Namespace: "test", CleanupFailedThresholdMin: 60, CleanupIdleThresholdMin: 60, CleanupOrphanGraceMin: 10, CleanupDryRun: dryRun}
+			stateMgr := state.NewStateManager()
+			stateMgr.AddRuntime(&state.RuntimeInfo{RuntimeID: "paused", SessionID: "s-paused", Status: types.StatusPaused, PodName: "runtime-paused", LastActivityTime: time.Now()})
+			s := NewService(k8s.NewClientWithClientset(clientset, cfg), stateMgr, cfg)
+
+			s.runCleanup(ctx)
+
+			_, svcErr := clientset.CoreV1().Services("test").Get(ctx, "runtime-orphan", metav1.GetOptions{})
+			_, ingErr := clientset.NetworkingV1().Ingresses("test").Get(ctx, "runtime-orphan", metav1.GetOptions{})
+			_, secretErr := clientset.CoreV1().Secrets("test").Get(ctx, "runtime-orphan-tls", metav1.GetOptions{})
+			for name, err := range map[string]error{"service": svcErr, "ingress": ingErr, "secret": secretErr} {
+				if gone := apierrors.IsNotFound(err); gone == dryRun {
+					t.Errorf("Expected orphaned %s deleted=%v, got err %v", name, !dryRun, err)
+				}
+			}
+
+			for _, name := range []string{"runtime-valid", "runtime-young", "runtime-paused"} {
+				if _, err := clientset.CoreV1().Services("test").Get(ctx, name, metav1.GetOptions{}); err != nil {
+					t.Errorf("Expected service %s to be kept, got %v", name, err)
+				}
+			}
+			for _, name := range []string{"runtime-valid-tls", "shared-wildcard-tls"} {
+				if _, err := clientset.CoreV1().Secrets("test").Get(ctx, name, metav1.GetOptions{}); err != nil {
+					t.Errorf("Expected secret %s to be kept, got %v", name, err)
+				}
+			}
+
+			expected := 1
+			if dryRun {
+				expected = 0
+			}
+			if stats := s.GetStats(); stats.OrphansDeleted != expected || len(stats.LastCleanupErrors) != 0 {
+				t.Errorf("Expected %d orphans deleted and no errors, got %+v", expected, stats)
+			}
+		})
+	}
+}
+
 func TestGetStats(t *testing.T) {
 	cfg := &config.Config{
 		CleanupEnabled: true,
@@ -204,3 +561,82 @@ func TestNewService(t *testing.T) {
 		t.Error("NewService() stopChan not initialized")
 	}
 }
+
+func TestCleanupRuntime_ConcurrentWithReaper(t *testing.T) {
+	cfg := &config.Config{
+		Namespace:           "test",
+		K8sOperationTimeout: 10 * time.Second,
+		IdleTimeoutHours:    1,
+		ReaperCheckInterval: time.Millisecond,
+	}
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runtime-rt-1", Namespace: "test"},
+	})
+	var podDeletes atomic.Int32
+	reapStarted := make(chan struct{})
+	releaseReap := make(chan struct{})
+	// The reaper's teardown blocks mid-delete so cleanup targets the same runtime meanwhile
+	clientset.PrependReactor("delete", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if podDeletes.Add(1) == 1 {
+			close(reapStarted)
+			<-releaseReap
+		}
+		return false, nil, nil
+	})
+	k8sClient := k8s.NewClientWithClientset(clientset, cfg)
+	stateMgr := state.NewStateManager()
+	rt := &state.RuntimeInfo{
+		RuntimeID:        "rt-1",
+		SessionID:        "s1",
+		Status:           types.StatusRunning,
+		PodName:          "runtime-rt-1",
+		ServiceName:      "runtime-rt-1",
+		IngressName:      "runtime-rt-1",
+		CreatedAt:        time.Now().Add(-3 * time.Hour),
+		LastActivityTime: time.Now().Add(-2 * time.Hour),
+	}
+	stateMgr.AddRuntime(rt)
+
+	reaperInstance := reaper.NewReaper(stateMgr, k8sClient, cfg)
+	reaperInstance.Start()
+	defer reaperInstance.Stop()
+	<-reapStarted
+
+	s := NewService(k8sClient, stateMgr, cfg)
+	type result struct {
+		cleaned bool
+		err     error
+	}
+	done := make(chan result)
+	go func() {
+		cleaned, err := s.cleanupRuntime(context.Background(), rt, "pod_failed")
+		done <- result{cleaned, err}
+	}()
+	// Give cleanup time to queue behind the reaper's teardown
+	time.Sleep(50 * time.Millisecond)
+	close(releaseReap)
+	res := <-done
+
+	if res.err != nil {
+		t.Errorf("Expected cleanup to step aside without error, got %v", res.err)
+	}
+	if res.cleaned {
+		t.Error("Expected cleanup to skip a runtime the reaper already tore down")
+	}
+	if n := podDeletes.Load(); n != 1 {
+		t.Errorf("Expected a single teardown, got %d pod deletions", n)
+	}
+	history, err := stateMgr.History("rt-1")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	reaped := 0
+	for _, event := range history {
+		if event.Type == types.RuntimeEventReaped {
+			reaped++
+		}
+	}
+	if reaped != 1 {
+		t.Errorf("Expected one reaped event, got %d", reaped)
+	}
+}
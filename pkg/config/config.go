@@ -2,30 +2,50 @@ package config
 
 import (
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 type Config struct {
 	// Server configuration
 	ServerPort      string
-	APIKey          string //nolint:gosec // G117: not a hardcoded secret, loaded from env
+	APIKey          string   //nolint:gosec // G117: not a hardcoded secret, loaded from env
+	APIKeys         []string //nolint:gosec // G117: accepted keys from API_KEYS plus API_KEY, for rotation
+	ReadOnlyAPIKeys []string //nolint:gosec // G117: keys from READONLY_API_KEYS, accepted only for read endpoints
 	LogLevel        string
+	LogFormat       string // "text" (default) or "json"
 	ShutdownTimeout time.Duration
 
 	// Kubernetes operation timeouts
 	K8sOperationTimeout time.Duration // Timeout for create/delete operations (pods, services, ingresses)
 	K8sQueryTimeout     time.Duration // Timeout for get/list operations
 
+	// Retries for transient errors (server timeout, 429, 500) when creating sandbox
+	// resources: up to K8sCreateMaxRetries extra attempts with exponential backoff
+	// starting at K8sCreateRetryBaseDelay. 0 disables retries.
+	K8sCreateMaxRetries     int
+	K8sCreateRetryBaseDelay time.Duration
+
+	// How long a single pod's status is reused after it is fetched. Concurrent fetches
+	// of the same pod always share one API call; 0 disables reuse beyond that.
+	PodStatusCacheTTL time.Duration
+
 	// Kubernetes configuration
 	Namespace    string
 	IngressClass string
 	BaseDomain   string
 
 	// Sandbox ingress: optional annotations added to each sandbox Ingress (e.g. cert-manager, TLS)
-	// Set via SANDBOX_INGRESS_ANNOTATIONS as comma-separated key=value pairs.
+	// Set via SANDBOX_INGRESS_ANNOTATIONS as comma-separated key=value pairs. Keys not
+	// permitted by ALLOWED_INGRESS_ANNOTATION_KEYS are removed at load time and listed in
+	// DroppedIngressAnnotations so they can be logged once.
 	SandboxIngressAnnotations map[string]string
+	DroppedIngressAnnotations []string
 
 	// Container configuration
 	RegistryPrefix   string
@@ -35,8 +55,7 @@ type Config struct {
 	// Pod configuration
 	AgentServerPort int
 	VSCodePort      int
-	Worker1Port     int
-	Worker2Port     int
+	WorkerPorts     []int // Exposed as work-1, work-2, ... (WORKER_PORTS; default 12000,12001)
 
 	// App server configuration
 	AppServerURL       string
@@ -45,6 +64,12 @@ type Config struct {
 	// Proxy mode: when set, /start returns URLs under this base (e.g. https://runtime-api.example.com)
 	// so sandbox traffic goes through this API instead of per-sandbox DNS. Avoids DNS propagation delay.
 	ProxyBaseURL string
+	// Build proxy URLs on the host each request arrived on (X-Forwarded-Host or Host)
+	// instead of PROXY_BASE_URL's, for a runtime API served under several hostnames
+	ProxyBaseFromRequest bool
+	// Hosts PROXY_BASE_FROM_REQUEST may use, comma-separated; an entry starting with "*."
+	// matches any subdomain. Other hosts get PROXY_BASE_URL, as does every host when empty.
+	ProxyAllowedHosts []string
 
 	// Cleanup configuration
 	CleanupEnabled            bool // Enable automatic cleanup of orphaned resources
@@ -52,6 +77,9 @@ type Config struct {
 	CleanupFailedThresholdMin int  // Time before cleaning up failed pods (in minutes)
 	CleanupIdleThresholdMin   int  // Time before cleaning up idle pods (in minutes)
 	CleanupRestartThreshold   int  // Restart count above which a pod is cleaned up
+	CleanupDryRun             bool // Log what cleanup would delete without deleting anything
+	ReconcileResources        bool // Recreate missing Services/Ingresses of running sandboxes on each cleanup run
+	CleanupOrphanGraceMin     int  // Age before an untracked Service/Ingress with no pod is deleted (in minutes, 0 = never)
 
 	// Optional CA certificate for sandbox pods. When set, the secret is mounted into each sandbox
 	// at /usr/local/share/ca-certificates/additional-ca.crt. The runtime image runs update-ca-certificates
@@ -71,8 +99,9 @@ type Config struct {
 	DirectRoutingCORSAllowOrigin string
 
 	// Idle timeout reaper configuration
-	IdleTimeoutHours    int           // Idle timeout in hours before reaping sandboxes (default: 72)
-	ReaperCheckInterval time.Duration // How often to check for idle sandboxes (default: 15 minutes)
+	IdleTimeoutHours      int           // Idle timeout in hours before reaping sandboxes (default: 72)
+	ReaperCheckInterval   time.Duration // How often to check for idle sandboxes (default: 15 minutes)
+	MaxIdleTimeoutMinutes int           // Upper bound for a start request's idle_timeout_minutes (default: 10080 = 7 days)
 
 	// Node scoring: when enabled, the runtime API evaluates node load via the
 	// Kubernetes Metrics API before pod creation and sets a preferred scheduling
@@ -82,46 +111,386 @@ type Config struct {
 	NodeScoringCPUThreshold  int    // Max CPU utilization % before excluding a node (default: 80)
 	NodeScoringMemThreshold  int    // Max memory utilization % before excluding a node (default: 80)
 	NodeScoringLabelSelector string // Optional label selector to limit eligible nodes (e.g. "pool=sandbox")
+
+	// Sandbox scheduling defaults applied to every sandbox pod. Start requests may add to
+	// or override these per sandbox. SANDBOX_NODE_SELECTOR is comma-separated key=value
+	// pairs; SANDBOX_TOLERATIONS is comma-separated key[=value][:Effect] entries
+	// (e.g. "sandbox=true:NoSchedule,dedicated:NoExecute").
+	SandboxNodeSelector map[string]string
+	SandboxTolerations  []corev1.Toleration
+
+	// GPU scheduling: applied only to sandboxes whose start request asks for GPUs.
+	// GPUNodeSelector is set via GPU_NODE_SELECTOR as comma-separated key=value pairs
+	// (e.g. "accelerator=nvidia"). When GPUTolerationKey is set, GPU sandboxes tolerate
+	// NoSchedule taints with that key (e.g. "nvidia.com/gpu" on AKS/GKE GPU pools).
+	GPUNodeSelector  map[string]string
+	GPUTolerationKey string
+
+	// Pod Security Standards "restricted" profile for sandbox pods (opt-in because some
+	// runtime images, e.g. under sysbox-runc, expect to run as root). When enabled, pods
+	// run as non-root with RuntimeDefault seccomp, no privilege escalation and all
+	// capabilities dropped. PodRunAsUser, when non-zero, pins the UID.
+	PodSecurityRestricted bool
+	PodRunAsUser          int64
+
+	// Eviction protection: when enabled, sandbox pods are annotated so the descheduler
+	// and cluster autoscaler leave them alone. SandboxPriorityClassName optionally puts
+	// sandbox pods in a (typically high-priority) PriorityClass so they are not chosen
+	// for node-pressure eviction or preemption ahead of batch workloads. Applied whether
+	// or not eviction protection is enabled; SANDBOX_PRIORITY_CLASS is accepted as an alias.
+	EvictionProtection       bool
+	SandboxPriorityClassName string
+
+	// When true, /start for a session that is not in state first looks for a live sandbox
+	// pod labelled with that session and adopts it instead of creating a duplicate.
+	ReuseExistingSandboxes bool
+
+	// Maximum sandbox creations in flight per image (0 = unlimited); a creation is in
+	// flight until its pod has left pending. Further /start requests for the same image
+	// queue, up to K8S_OPERATION_TIMEOUT, so a cold image is pulled by a few nodes first
+	// instead of all of them at once.
+	MaxConcurrentCreatesPerImage int
+
+	// How long a creation keeps its MAX_CONCURRENT_CREATES_PER_IMAGE slot while waiting
+	// for the sandbox pod to pull its image and start (default: 10 minutes). The slot is
+	// released earlier if the pod starts, fails or is deleted.
+	ImagePullSlotTimeout time.Duration
+
+	// How long per-session /start bookkeeping is kept after its last use before the
+	// periodic sweep drops it (default: 10 minutes).
+	StartDedupTTL time.Duration
+
+	// How long the outcome of an async /start is kept for GET /operations/{op_id} after
+	// it was created (default: 1 hour).
+	OperationTTL time.Duration
+
+	// When true, sandbox containers receive OH_POD_NAME, OH_POD_NAMESPACE, OH_NODE_NAME
+	// and OH_POD_IP via the Kubernetes downward API.
+	InjectDownwardAPI bool
+
+	// When true, sandbox containers receive OH_TRACE_SESSION_ID and OH_TRACE_RUNTIME_ID, and
+	// the same IDs as OTEL_RESOURCE_ATTRIBUTES, so sandbox telemetry can be tied to its session.
+	InjectTraceCorrelation bool
+
+	// When true, pods created by a traced /start are annotated with its trace and span IDs
+	AnnotatePodTrace bool
+
+	// Teardown failure alerting: when cleanup or the reaper fails to delete the same
+	// runtime this many times in a row, one alert is POSTed to AlertWebhookURL. Further
+	// failures are suppressed until a teardown of that runtime succeeds.
+	// Disabled when the webhook URL is empty or the threshold is 0.
+	CleanupErrorAlertThreshold int
+	AlertWebhookURL            string
+
+	// Optional liveness probe on the agent server's /alive endpoint so Kubernetes restarts
+	// a hung agent that keeps its socket open. Off by default; the initial delay defaults
+	// to 5 minutes so slow-starting agents are not killed during startup.
+	LivenessProbeEnabled          bool
+	LivenessProbePeriod           time.Duration
+	LivenessProbeFailureThreshold int
+	LivenessProbeInitialDelay     time.Duration
+
+	// When true, GET /runtime/{id} and GET /sessions/{id} return 410 Gone instead of
+	// 404 for runtimes stopped within the last 24 hours.
+	UseGoneForStopped bool
+
+	// When true, the reaper checks an HTTP-idle sandbox's usage via metrics-server before
+	// reaping it and spares it while CPU (millicores) or memory (MiB) is above the threshold
+	// (a threshold of 0 ignores that resource). Falls back to activity-only reaping when
+	// metrics are unavailable. TTL expiry is not affected.
+	ReaperRequireLowUsage        bool
+	ReaperCPUThresholdMillicores int
+	ReaperMemoryThresholdMiB     int
+	ReaperDryRun                 bool // Log what the reaper would reap without deleting anything
+
+	// How long a request proxied to a paused sandbox waits for the resumed pod to become
+	// ready before getting 503 with Retry-After (default: 60 seconds).
+	ProxyResumeTimeout time.Duration
+
+	// Where a start request's workspace_pvc_name is mounted in the sandbox container
+	WorkspaceMountPath string
+
+	// When true (default), tearing down a sandbox (stop, reaper, cleanup) also deletes its
+	// workspace PVC if /start labelled it as owned by that runtime (own_workspace_pvc sets
+	// app=openhands-runtime, runtime-id=<id>). Other PVCs and paused sandboxes keep theirs.
+	DeletePVCOnStop bool
+
+	// Which signal the reaper trusts for idleness: "proxy" (default) uses traffic through
+	// /sandbox/{id} only; "metrics" also spares sandboxes with CPU/memory usage above the
+	// REAPER_*_THRESHOLD values; "agent" asks each agent server for its idle time.
+	IdleSignal string
+
+	// Scheme ("HTTP" or "HTTPS") used by the startup, readiness and liveness probes on
+	// the agent server's /alive endpoint; HTTPS for agents terminating TLS in-pod.
+	AgentProbeScheme string
+
+	// Maximum concurrent WebSocket (upgraded) connections proxied through /sandbox/{id};
+	// further upgrades get 503. 0 means unlimited.
+	MaxProxyWebSockets int
+
+	// When true, ProxySandbox rejects agent server requests whose session API key doesn't
+	// match the runtime's with 401 instead of leaving the check to the sandbox.
+	ProxyValidateSessionKey bool
+
+	// When true, a proxied request whose sandbox Service no longer resolves recreates the
+	// Service (if the pod is still running) and is retried once.
+	ProxySelfHealService bool
+
+	// GET /readiness lists one pod to confirm the Kubernetes API is reachable, giving up
+	// after ReadinessCheckTimeout; the result is reused for ReadinessCacheTTL.
+	ReadinessCheckTimeout time.Duration
+	ReadinessCacheTTL     time.Duration
+
+	// When true, GET /readiness reports 503 until existing sandboxes have been discovered
+	// from Kubernetes at least once, so a replica whose startup discovery failed isn't
+	// routed traffic with empty state.
+	WaitForDiscovery bool
+
+	// How long a proxied request keeps retrying while a pending or running sandbox's agent
+	// server refuses connections (it's still starting) before giving up with 503. Zero
+	// disables the retry.
+	ProxyStartupGrace time.Duration
+
+	// Typical time from /start until a sandbox's pod is running. While a runtime or its
+	// pod is pending the proxy answers 503 sandbox_starting with the remaining wait
+	// estimated from this. Zero proxies pending sandboxes as usual.
+	SandboxExpectedStartup time.Duration
+
+	// Trailing slashes on proxied agent paths: "preserve" (default) forwards them as
+	// received; "normalize" strips them (/sandbox/{id}/api/x/ -> /api/x) for backends
+	// that answer a trailing slash with an absolute redirect to their internal address.
+	ProxyTrailingSlash string
+
+	// Maximum number of sandboxes (running, paused or pending) in state; POST /start
+	// rejects new sandboxes with 429 at the cap. 0 means unlimited.
+	MaxSandboxes int
+
+	// Ceilings on the total CPU and memory requested by sandboxes in state (running, paused
+	// or pending), as Kubernetes quantities (e.g. "64", "256Gi"), for clusters without a
+	// ResourceQuota. POST /start rejects a sandbox that would exceed one with 429. "" means unlimited.
+	MaxTotalCPU    string
+	MaxTotalMemory string
+
+	// Which sandbox port the readiness probe checks, and so when a sandbox is reported
+	// ready: "agent" (default), "vscode" or "workerN" (Nth WORKER_PORTS entry).
+	SandboxReadinessPortRole string
+
+	// Transport timeouts for ProxySandbox: connecting to a sandbox, waiting for its
+	// response headers, and keeping idle backend connections for reuse
+	ProxyDialTimeout           time.Duration
+	ProxyResponseHeaderTimeout time.Duration
+	ProxyIdleConnTimeout       time.Duration
+
+	// Idle backend connections the shared proxy transport keeps, in total and per sandbox
+	ProxyMaxIdleConns        int
+	ProxyMaxIdleConnsPerHost int
+
+	// Upper bound for a start request's proxy_timeout_seconds; larger values are capped
+	MaxProxyTimeout time.Duration
+
+	// Base sandbox CPU and memory requests and limits, multiplied by a start request's
+	// resource_factor. Invalid quantities fall back to the defaults at load.
+	SandboxCPURequest    string
+	SandboxCPULimit      string
+	SandboxMemoryRequest string
+	SandboxMemoryLimit   string
+	// "burstable" (default) or "guaranteed", which sets defaulted limits equal to the
+	// requests so sandboxes get the Guaranteed QoS class
+	SandboxQoSMode string
+
+	// Log the (env-redacted) pod that Kubernetes rejected when creating a sandbox fails.
+	// A debugging aid, off by default.
+	DebugDumpPodSpec bool
+
+	// PEM certificate and key for serving the API over TLS. Plaintext when both are
+	// unset; setting only one is a startup error.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Labels and annotations added to every sandbox pod (e.g. for cost allocation or
+	// mesh injection), as comma-separated key=value pairs. A start request's pod_labels
+	// and pod_annotations are merged over them; reserved keys are never overridden.
+	SandboxPodLabels      map[string]string
+	SandboxPodAnnotations map[string]string
+
+	// Pull policy for the sandbox container ("Always", "IfNotPresent" or "Never").
+	// When unset, images pinned by digest use IfNotPresent and everything else Always.
+	ImagePullPolicy string
+
+	// Reserved env var names (OH_SESSION*, SESSION_API_KEY*, OH_RUNTIME_ID) a start request
+	// may still set, comma-separated. All other reserved names are dropped from requests.
+	AllowedReservedEnvVars []string
+
+	// SANDBOX_INGRESS_ANNOTATIONS keys that are applied, comma-separated; an entry ending
+	// in "*" matches a prefix. Empty allows every key. Otherwise snippet annotations (e.g.
+	// nginx server-snippet) are only applied when listed exactly.
+	AllowedIngressAnnotationKeys []string
+
+	// Registries a start request's init_container image may come from, comma-separated
+	// (e.g. "ghcr.io/my-org,registry.internal"). Empty rejects every init container.
+	InitContainerRegistries []string
+
+	// Create and delete a canary sandbox at startup to surface RBAC, quota or ingress
+	// misconfiguration: "true" exits on failure, "warn" only logs it. Anything else skips it.
+	StartupSelfTest string
 }
 
 func LoadConfig() *Config {
-	return &Config{
-		ServerPort:                getEnv("SERVER_PORT", "8080"),
-		APIKey:                    getEnv("API_KEY", ""),
-		LogLevel:                  getEnv("LOG_LEVEL", "info"),
-		ShutdownTimeout:           getEnvAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
-		K8sOperationTimeout:       getEnvAsDuration("K8S_OPERATION_TIMEOUT", 60*time.Second),
-		K8sQueryTimeout:           getEnvAsDuration("K8S_QUERY_TIMEOUT", 10*time.Second),
-		Namespace:                 getEnv("NAMESPACE", "openhands"),
-		IngressClass:              getEnv("INGRESS_CLASS", "nginx"),
-		BaseDomain:                getEnv("BASE_DOMAIN", "sandbox.example.com"),
-		SandboxIngressAnnotations: parseAnnotations(getEnv("SANDBOX_INGRESS_ANNOTATIONS", "")),
-		RegistryPrefix:            getEnv("REGISTRY_PREFIX", "ghcr.io/openhands"),
-		DefaultImage:              getEnv("DEFAULT_IMAGE", "ghcr.io/openhands/runtime:latest"),
-		ImagePullSecrets:          parseSecretNames(getEnv("IMAGE_PULL_SECRETS", "")),
-		AgentServerPort:           getEnvAsInt("AGENT_SERVER_PORT", 60000),
-		VSCodePort:                getEnvAsInt("VSCODE_PORT", 60001),
-		Worker1Port:               getEnvAsInt("WORKER_1_PORT", 12000),
-		Worker2Port:               getEnvAsInt("WORKER_2_PORT", 12001),
-		AppServerURL:              getEnv("APP_SERVER_URL", ""),
-		AppServerPublicURL:        getEnv("APP_SERVER_PUBLIC_URL", ""),
-		ProxyBaseURL:              strings.TrimSuffix(getEnv("PROXY_BASE_URL", ""), "/"),
-		CleanupEnabled:            getEnvAsBool("CLEANUP_ENABLED", true),
-		CleanupIntervalMinutes:    getEnvAsInt("CLEANUP_INTERVAL_MINUTES", 5),
-		CleanupFailedThresholdMin: getEnvAsInt("CLEANUP_FAILED_THRESHOLD_MINUTES", 60),
-		CleanupIdleThresholdMin:   getEnvAsInt("CLEANUP_IDLE_THRESHOLD_MINUTES", 1440), // 24 hours
-		CleanupRestartThreshold:   getEnvAsInt("CLEANUP_RESTART_THRESHOLD", 5),
-		CACertSecretName:          getEnv("CA_CERT_SECRET_NAME", ""),
-		CACertSecretKey:           getEnv("CA_CERT_SECRET_KEY", "ca-certificates.crt"),
-		DirectRouting:             getEnvAsBool("DIRECT_ROUTING", false),
-		DirectRoutingCORSAllowOrigin: getEnv("DIRECT_ROUTING_CORS_ALLOW_ORIGIN", ""),
-		IdleTimeoutHours:          getEnvAsInt("IDLE_TIMEOUT_HOURS", 72),
-		ReaperCheckInterval:       getEnvAsDuration("REAPER_CHECK_INTERVAL", 15*time.Minute),
-		NodeScoringEnabled:        getEnvAsBool("NODE_SCORING_ENABLED", false),
-		NodeScoringCPUThreshold:   getEnvAsInt("NODE_SCORING_CPU_THRESHOLD", 80),
-		NodeScoringMemThreshold:   getEnvAsInt("NODE_SCORING_MEM_THRESHOLD", 80),
-		NodeScoringLabelSelector:  getEnv("NODE_SCORING_LABEL_SELECTOR", ""),
+	cfg := &Config{
+		ServerPort:                    getEnv("SERVER_PORT", "8080"),
+		APIKey:                        getEnv("API_KEY", ""),
+		APIKeys:                       parseAPIKeys(getEnv("API_KEYS", ""), getEnv("API_KEY", "")),
+		ReadOnlyAPIKeys:               parseAPIKeys(getEnv("READONLY_API_KEYS", ""), ""),
+		LogLevel:                      getEnv("LOG_LEVEL", "info"),
+		LogFormat:                     getEnv("LOG_FORMAT", "text"),
+		ShutdownTimeout:               getEnvAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		K8sOperationTimeout:           getEnvAsDuration("K8S_OPERATION_TIMEOUT", 60*time.Second),
+		K8sQueryTimeout:               getEnvAsDuration("K8S_QUERY_TIMEOUT", 10*time.Second),
+		K8sCreateMaxRetries:           getEnvAsInt("K8S_CREATE_MAX_RETRIES", 3),
+		K8sCreateRetryBaseDelay:       getEnvAsDuration("K8S_CREATE_RETRY_BASE_DELAY", 500*time.Millisecond),
+		PodStatusCacheTTL:             getEnvAsDuration("POD_STATUS_CACHE_TTL", time.Second),
+		Namespace:                     getEnv("NAMESPACE", "openhands"),
+		IngressClass:                  getEnv("INGRESS_CLASS", "nginx"),
+		BaseDomain:                    getEnv("BASE_DOMAIN", "sandbox.example.com"),
+		SandboxIngressAnnotations:     parseAnnotations(getEnv("SANDBOX_INGRESS_ANNOTATIONS", "")),
+		RegistryPrefix:                getEnv("REGISTRY_PREFIX", "ghcr.io/openhands"),
+		DefaultImage:                  getEnv("DEFAULT_IMAGE", "ghcr.io/openhands/runtime:latest"),
+		ImagePullSecrets:              parseNameList(getEnv("IMAGE_PULL_SECRETS", "")),
+		AgentServerPort:               getEnvAsInt("AGENT_SERVER_PORT", 60000),
+		VSCodePort:                    getEnvAsInt("VSCODE_PORT", 60001),
+		WorkerPorts:                   parseWorkerPorts(getEnv("WORKER_PORTS", "")),
+		AppServerURL:                  getEnv("APP_SERVER_URL", ""),
+		AppServerPublicURL:            getEnv("APP_SERVER_PUBLIC_URL", ""),
+		ProxyBaseURL:                  strings.TrimSuffix(getEnv("PROXY_BASE_URL", ""), "/"),
+		ProxyBaseFromRequest:          getEnvAsBool("PROXY_BASE_FROM_REQUEST", false),
+		ProxyAllowedHosts:             parseNameList(getEnv("PROXY_ALLOWED_HOSTS", "")),
+		CleanupEnabled:                getEnvAsBool("CLEANUP_ENABLED", true),
+		CleanupIntervalMinutes:        getEnvAsInt("CLEANUP_INTERVAL_MINUTES", 5),
+		CleanupFailedThresholdMin:     getEnvAsInt("CLEANUP_FAILED_THRESHOLD_MINUTES", 60),
+		CleanupIdleThresholdMin:       getEnvAsInt("CLEANUP_IDLE_THRESHOLD_MINUTES", 1440), // 24 hours
+		CleanupRestartThreshold:       getEnvAsInt("CLEANUP_RESTART_THRESHOLD", 5),
+		CleanupDryRun:                 getEnvAsBool("CLEANUP_DRY_RUN", false),
+		ReconcileResources:            getEnvAsBool("RECONCILE_RESOURCES", false),
+		CleanupOrphanGraceMin:         getEnvAsInt("CLEANUP_ORPHAN_GRACE_MINUTES", 10),
+		CACertSecretName:              getEnv("CA_CERT_SECRET_NAME", ""),
+		CACertSecretKey:               getEnv("CA_CERT_SECRET_KEY", "ca-certificates.crt"),
+		DirectRouting:                 getEnvAsBool("DIRECT_ROUTING", false),
+		DirectRoutingCORSAllowOrigin:  getEnv("DIRECT_ROUTING_CORS_ALLOW_ORIGIN", ""),
+		IdleTimeoutHours:              getEnvAsInt("IDLE_TIMEOUT_HOURS", 72),
+		ReaperCheckInterval:           getEnvAsDuration("REAPER_CHECK_INTERVAL", 15*time.Minute),
+		MaxIdleTimeoutMinutes:         getEnvAsInt("MAX_IDLE_TIMEOUT_MINUTES", 7*24*60),
+		NodeScoringEnabled:            getEnvAsBool("NODE_SCORING_ENABLED", false),
+		NodeScoringCPUThreshold:       getEnvAsInt("NODE_SCORING_CPU_THRESHOLD", 80),
+		NodeScoringMemThreshold:       getEnvAsInt("NODE_SCORING_MEM_THRESHOLD", 80),
+		NodeScoringLabelSelector:      getEnv("NODE_SCORING_LABEL_SELECTOR", ""),
+		SandboxNodeSelector:           parseAnnotations(getEnv("SANDBOX_NODE_SELECTOR", "")),
+		SandboxTolerations:            parseTolerations(getEnv("SANDBOX_TOLERATIONS", "")),
+		GPUNodeSelector:               parseAnnotations(getEnv("GPU_NODE_SELECTOR", "")),
+		GPUTolerationKey:              getEnv("GPU_TOLERATION_KEY", ""),
+		InjectDownwardAPI:             getEnvAsBool("INJECT_DOWNWARD_API", false),
+		InjectTraceCorrelation:        getEnvAsBool("INJECT_TRACE_CORRELATION", true),
+		AnnotatePodTrace:              getEnvAsBool("ANNOTATE_POD_TRACE", false),
+		CleanupErrorAlertThreshold:    getEnvAsInt("CLEANUP_ERROR_ALERT_THRESHOLD", 3),
+		AlertWebhookURL:               getEnv("ALERT_WEBHOOK_URL", ""),
+		LivenessProbeEnabled:          getEnvAsBool("LIVENESS_PROBE_ENABLED", false),
+		LivenessProbePeriod:           getEnvAsDuration("LIVENESS_PROBE_PERIOD", 30*time.Second),
+		LivenessProbeFailureThreshold: getEnvAsInt("LIVENESS_PROBE_FAILURE_THRESHOLD", 3),
+		LivenessProbeInitialDelay:     getEnvAsDuration("LIVENESS_PROBE_INITIAL_DELAY", 5*time.Minute),
+		UseGoneForStopped:             getEnvAsBool("USE_GONE_FOR_STOPPED", false),
+		ReaperRequireLowUsage:         getEnvAsBool("REAPER_REQUIRE_LOW_USAGE", false),
+		ReaperCPUThresholdMillicores:  getEnvAsInt("REAPER_CPU_THRESHOLD_MILLICORES", 100),
+		ReaperMemoryThresholdMiB:      getEnvAsInt("REAPER_MEMORY_THRESHOLD_MIB", 0),
+		ReaperDryRun:                  getEnvAsBool("REAPER_DRY_RUN", false),
+		ProxyResumeTimeout:            getEnvAsDuration("PROXY_RESUME_TIMEOUT", 60*time.Second),
+		WorkspaceMountPath:            getEnv("WORKSPACE_MOUNT_PATH", "/workspace"),
+		DeletePVCOnStop:               getEnvAsBool("DELETE_PVC_ON_STOP", true),
+		IdleSignal:                    strings.ToLower(getEnv("IDLE_SIGNAL", "proxy")),
+		AgentProbeScheme:              strings.ToUpper(getEnv("AGENT_PROBE_SCHEME", "HTTP")),
+		MaxProxyWebSockets:            getEnvAsInt("MAX_PROXY_WEBSOCKETS", 0),
+		ProxyValidateSessionKey:       getEnvAsBool("PROXY_VALIDATE_SESSION_KEY", false),
+		ProxySelfHealService:          getEnvAsBool("PROXY_SELF_HEAL_SERVICE", false),
+		ReadinessCheckTimeout:         getEnvAsDuration("READINESS_CHECK_TIMEOUT", 2*time.Second),
+		ReadinessCacheTTL:             getEnvAsDuration("READINESS_CACHE_TTL", 5*time.Second),
+		WaitForDiscovery:              getEnvAsBool("WAIT_FOR_DISCOVERY", false),
+		ProxyStartupGrace:             getEnvAsDuration("PROXY_STARTUP_GRACE", 20*time.Second),
+		SandboxExpectedStartup:        getEnvAsDuration("SANDBOX_EXPECTED_STARTUP", 60*time.Second),
+		ProxyTrailingSlash:            strings.ToLower(getEnv("PROXY_TRAILING_SLASH", "preserve")),
+		MaxSandboxes:                  getEnvAsInt("MAX_SANDBOXES", 0),
+		MaxTotalCPU:                   getEnvAsQuantity("MAX_TOTAL_CPU", ""),
+		MaxTotalMemory:                getEnvAsQuantity("MAX_TOTAL_MEMORY", ""),
+		SandboxReadinessPortRole:      strings.ToLower(getEnv("SANDBOX_READINESS_PORT_ROLE", "agent")),
+		ProxyDialTimeout:              getEnvAsDuration("PROXY_DIAL_TIMEOUT", 30*time.Second),
+		ProxyResponseHeaderTimeout:    getEnvAsDuration("PROXY_RESPONSE_HEADER_TIMEOUT", 300*time.Second),
+		ProxyIdleConnTimeout:          getEnvAsDuration("PROXY_IDLE_CONN_TIMEOUT", 90*time.Second),
+		ProxyMaxIdleConns:             getEnvAsInt("PROXY_MAX_IDLE_CONNS", 100),
+		ProxyMaxIdleConnsPerHost:      getEnvAsInt("PROXY_MAX_IDLE_CONNS_PER_HOST", 10),
+		MaxProxyTimeout:               getEnvAsDuration("MAX_PROXY_TIMEOUT", time.Hour),
+		SandboxCPURequest:             getEnvAsQuantity("SANDBOX_CPU_REQUEST", "1000m"),
+		SandboxCPULimit:               getEnvAsQuantity("SANDBOX_CPU_LIMIT", "2000m"),
+		SandboxMemoryRequest:          getEnvAsQuantity("SANDBOX_MEM_REQUEST", "2048Mi"),
+		SandboxMemoryLimit:            getEnvAsQuantity("SANDBOX_MEM_LIMIT", "4096Mi"),
+		SandboxQoSMode:                strings.ToLower(getEnv("SANDBOX_QOS_MODE", "burstable")),
+		DebugDumpPodSpec:              getEnvAsBool("DEBUG_DUMP_POD_SPEC", false),
+		TLSCertFile:                   getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:                    getEnv("TLS_KEY_FILE", ""),
+		SandboxPodLabels:              parseAnnotations(getEnv("SANDBOX_POD_LABELS", "")),
+		SandboxPodAnnotations:         parseAnnotations(getEnv("SANDBOX_POD_ANNOTATIONS", "")),
+		ImagePullPolicy:               getEnv("IMAGE_PULL_POLICY", ""),
+		AllowedReservedEnvVars:        parseNameList(getEnv("ALLOWED_RESERVED_ENV_VARS", "")),
+		AllowedIngressAnnotationKeys:  parseNameList(getEnv("ALLOWED_INGRESS_ANNOTATION_KEYS", "")),
+		InitContainerRegistries:       parseNameList(getEnv("INIT_CONTAINER_ALLOWED_REGISTRIES", "")),
+		StartupSelfTest:               strings.ToLower(getEnv("STARTUP_SELFTEST", "false")),
+		PodSecurityRestricted:         getEnvAsBool("POD_SECURITY_RESTRICTED", false),
+		PodRunAsUser:                  int64(getEnvAsInt("POD_RUN_AS_USER", 0)),
+		EvictionProtection:            getEnvAsBool("SANDBOX_EVICTION_PROTECTION", false),
+		SandboxPriorityClassName:      getEnv("SANDBOX_PRIORITY_CLASS_NAME", getEnv("SANDBOX_PRIORITY_CLASS", "")),
+		ReuseExistingSandboxes:        getEnvAsBool("REUSE_EXISTING_SANDBOXES", true),
+		MaxConcurrentCreatesPerImage:  getEnvAsInt("MAX_CONCURRENT_CREATES_PER_IMAGE", 0),
+		ImagePullSlotTimeout:          getEnvAsDuration("IMAGE_PULL_SLOT_TIMEOUT", 10*time.Minute),
+		StartDedupTTL:                 getEnvAsDuration("START_DEDUP_TTL", 10*time.Minute),
+		OperationTTL:                  getEnvAsDuration("OPERATION_TTL", time.Hour),
+	}
+	cfg.SandboxIngressAnnotations, cfg.DroppedIngressAnnotations = filterIngressAnnotations(
+		cfg.SandboxIngressAnnotations, cfg.AllowedIngressAnnotationKeys)
+	return cfg
+}
+
+// filterIngressAnnotations splits annotations into those permitted by allowed and the
+// sorted keys of those that are not
+func filterIngressAnnotations(annotations map[string]string, allowed []string) (map[string]string, []string) {
+	var dropped []string
+	for key := range annotations {
+		if !ingressAnnotationAllowed(key, allowed) {
+			dropped = append(dropped, key)
+		}
+	}
+	slices.Sort(dropped)
+	for _, key := range dropped {
+		delete(annotations, key)
 	}
+	return annotations, dropped
+}
+
+// ingressAnnotationAllowed reports whether an ingress annotation key may be applied: any
+// key when the allowlist is empty, otherwise one matching an exact entry or a "prefix*"
+// entry. Snippet annotations inject raw controller config (e.g. nginx server-snippet), so
+// with an allowlist they must be listed exactly.
+func ingressAnnotationAllowed(key string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	if strings.HasSuffix(key, "snippet") {
+		return slices.Contains(allowed, key)
+	}
+	for _, entry := range allowed {
+		if entry == key {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok && strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // parseAnnotations parses "key1=value1,key2=value2" into a map. Values may contain "=".
@@ -144,8 +513,67 @@ func parseAnnotations(s string) map[string]string {
 	return out
 }
 
-// parseSecretNames parses a comma-separated list of Kubernetes secret names (e.g. for imagePullSecrets).
-func parseSecretNames(s string) []string {
+// parseTolerations parses "key1=value1:NoSchedule,key2:NoExecute" into tolerations.
+// An entry with a value uses operator Equal, otherwise Exists; an omitted effect
+// tolerates all effects.
+func parseTolerations(s string) []corev1.Toleration {
+	if s == "" {
+		return nil
+	}
+	var out []corev1.Toleration
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var effect string
+		if idx := strings.LastIndex(entry, ":"); idx >= 0 {
+			effect = strings.TrimSpace(entry[idx+1:])
+			entry = strings.TrimSpace(entry[:idx])
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if key == "" {
+			continue
+		}
+		t := corev1.Toleration{
+			Key:      key,
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffect(effect),
+		}
+		if len(parts) == 2 {
+			t.Operator = corev1.TolerationOpEqual
+			t.Value = strings.TrimSpace(parts[1])
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// parseWorkerPorts parses a comma-separated list of worker ports (e.g. "12000,12001,12002").
+// Entries that aren't valid ports are skipped. When the list is empty, the legacy
+// WORKER_1_PORT and WORKER_2_PORT settings (default 12000 and 12001) are used.
+func parseWorkerPorts(s string) []int {
+	// "none" exposes no worker ports, for images without forwarded app ports
+	if strings.EqualFold(strings.TrimSpace(s), "none") {
+		return []int{}
+	}
+	var out []int
+	for _, entry := range strings.Split(s, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(entry))
+		if err != nil || port < 1 || port > 65535 {
+			continue
+		}
+		out = append(out, port)
+	}
+	if len(out) == 0 {
+		return []int{getEnvAsInt("WORKER_1_PORT", 12000), getEnvAsInt("WORKER_2_PORT", 12001)}
+	}
+	return out
+}
+
+// parseNameList parses a comma-separated list of names (e.g. secret names for imagePullSecrets).
+func parseNameList(s string) []string {
 	if s == "" {
 		return nil
 	}
@@ -159,6 +587,21 @@ func parseSecretNames(s string) []string {
 	return out
 }
 
+// parseAPIKeys combines a comma-separated API_KEYS list with the single API_KEY so old and
+// new keys can both be accepted while a key is rotated. Duplicates are dropped.
+func parseAPIKeys(list, single string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, key := range append(strings.Split(list, ","), single) {
+		key = strings.TrimSpace(key)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
 func getEnv(key, defaultVal string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -184,6 +627,17 @@ func getEnvAsBool(key string, defaultVal bool) bool {
 	return defaultVal
 }
 
+// getEnvAsQuantity returns the variable if it parses as a Kubernetes resource quantity
+// (e.g. "500m", "4Gi"), otherwise defaultVal
+func getEnvAsQuantity(key, defaultVal string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		if _, err := resource.ParseQuantity(value); err == nil {
+			return value
+		}
+	}
+	return defaultVal
+}
+
 func getEnvAsDuration(key string, defaultVal time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
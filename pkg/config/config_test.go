@@ -2,8 +2,11 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -15,6 +18,9 @@ func TestLoadConfig(t *testing.T) {
 		"BASE_DOMAIN":       os.Getenv("BASE_DOMAIN"),
 		"REGISTRY_PREFIX":   os.Getenv("REGISTRY_PREFIX"),
 		"AGENT_SERVER_PORT": os.Getenv("AGENT_SERVER_PORT"),
+		"WORKER_PORTS":      os.Getenv("WORKER_PORTS"),
+		"WORKER_1_PORT":     os.Getenv("WORKER_1_PORT"),
+		"WORKER_2_PORT":     os.Getenv("WORKER_2_PORT"),
 	}
 
 	// Restore env vars after test
@@ -51,11 +57,8 @@ func TestLoadConfig(t *testing.T) {
 		if cfg.VSCodePort != 60001 {
 			t.Errorf("Expected default VSCodePort 60001, got %d", cfg.VSCodePort)
 		}
-		if cfg.Worker1Port != 12000 {
-			t.Errorf("Expected default Worker1Port 12000, got %d", cfg.Worker1Port)
-		}
-		if cfg.Worker2Port != 12001 {
-			t.Errorf("Expected default Worker2Port 12001, got %d", cfg.Worker2Port)
+		if len(cfg.WorkerPorts) != 2 || cfg.WorkerPorts[0] != 12000 || cfg.WorkerPorts[1] != 12001 {
+			t.Errorf("Expected default WorkerPorts [12000 12001], got %v", cfg.WorkerPorts)
 		}
 	})
 
@@ -149,6 +152,37 @@ func TestGetEnvAsInt(t *testing.T) {
 	}
 }
 
+func TestGetEnvAsQuantity(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		defaultVal string
+		envValue   string
+		expected   string
+	}{
+		{"Use default when env not set", "TEST_QUANTITY_1", "1000m", "", "1000m"},
+		{"Use env value when set", "TEST_QUANTITY_2", "1000m", "500m", "500m"},
+		{"Binary suffix", "TEST_QUANTITY_3", "2048Mi", "8Gi", "8Gi"},
+		{"Use default when env is invalid", "TEST_QUANTITY_4", "2048Mi", "lots", "2048Mi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue != "" {
+				os.Setenv(tt.key, tt.envValue)
+				defer os.Unsetenv(tt.key)
+			} else {
+				os.Unsetenv(tt.key)
+			}
+
+			result := getEnvAsQuantity(tt.key, tt.defaultVal)
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestParseAnnotations(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -178,6 +212,96 @@ func TestParseAnnotations(t *testing.T) {
 	}
 }
 
+func TestParseAPIKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		list     string
+		single   string
+		expected []string
+	}{
+		{"Nothing configured", "", "", nil},
+		{"Single key only", "", "old", []string{"old"}},
+		{"List only", "new, old", "", []string{"new", "old"}},
+		{"List and single", "new", "old", []string{"new", "old"}},
+		{"Duplicates and blanks dropped", "new,,old", "old", []string{"new", "old"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseAPIKeys(tt.list, tt.single)
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseTolerations(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []corev1.Toleration
+	}{
+		{"Empty string", "", nil},
+		{"Key value effect", "sandbox=true:NoSchedule", []corev1.Toleration{
+			{Key: "sandbox", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule},
+		}},
+		{"Key effect", "dedicated:NoExecute", []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		}},
+		{"Key only", "nvidia.com/gpu", []corev1.Toleration{
+			{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists},
+		}},
+		{"Multiple with spaces", " a=b:NoSchedule , c ", []corev1.Toleration{
+			{Key: "a", Operator: corev1.TolerationOpEqual, Value: "b", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "c", Operator: corev1.TolerationOpExists},
+		}},
+		{"Skip empty and keyless", "a:NoSchedule,,=b:NoSchedule", []corev1.Toleration{
+			{Key: "a", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTolerations(tt.input)
+			if len(got) != len(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+				return
+			}
+			for i := range tt.expected {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Index %d: expected %+v, got %+v", i, tt.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseWorkerPorts(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []int
+	}{
+		{"Unset uses the two defaults", "", []int{12000, 12001}},
+		{"None", "none", []int{}},
+		{"Two ports", "8000,8001", []int{8000, 8001}},
+		{"Four ports", "8000, 8001, 8002, 8003", []int{8000, 8001, 8002, 8003}},
+		{"Invalid entries skipped", "8000,abc,70000,8001", []int{8000, 8001}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseWorkerPorts(tt.input)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range tt.expected {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Index %d: expected %d, got %d", i, tt.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
 func TestParseSecretNames(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -192,7 +316,7 @@ func TestParseSecretNames(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parseSecretNames(tt.input)
+			got := parseNameList(tt.input)
 			if len(got) != len(tt.expected) {
 				t.Errorf("Expected %v, got %v", tt.expected, got)
 				return
@@ -267,6 +391,43 @@ func TestLoadConfig_ProxyBaseURL(t *testing.T) {
 	})
 }
 
+func TestFilterIngressAnnotations(t *testing.T) {
+	configured := func() map[string]string {
+		return map[string]string{
+			"cert-manager.io/issuer":                            "my-issuer",
+			"nginx.ingress.kubernetes.io/proxy-read-timeout":    "3600",
+			"nginx.ingress.kubernetes.io/configuration-snippet": "more_set_headers X-Debug: 1;",
+		}
+	}
+	tests := []struct {
+		name    string
+		allowed []string
+		dropped []string
+	}{
+		{"Empty allowlist keeps every key", nil, nil},
+		{"Exact and prefix entries", []string{"cert-manager.io/*"}, []string{"nginx.ingress.kubernetes.io/configuration-snippet", "nginx.ingress.kubernetes.io/proxy-read-timeout"}},
+		{"Prefix does not admit snippets", []string{"cert-manager.io/issuer", "nginx.ingress.kubernetes.io/*"}, []string{"nginx.ingress.kubernetes.io/configuration-snippet"}},
+		{"Snippet listed exactly", []string{"nginx.ingress.kubernetes.io/configuration-snippet"}, []string{"cert-manager.io/issuer", "nginx.ingress.kubernetes.io/proxy-read-timeout"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, dropped := filterIngressAnnotations(configured(), tt.allowed)
+			if strings.Join(dropped, ",") != strings.Join(tt.dropped, ",") {
+				t.Errorf("Expected dropped %v, got %v", tt.dropped, dropped)
+			}
+			if len(kept)+len(dropped) != len(configured()) {
+				t.Errorf("Expected %d kept annotations, got %v", len(configured())-len(dropped), kept)
+			}
+			for _, key := range dropped {
+				if _, ok := kept[key]; ok {
+					t.Errorf("Expected %s to be removed", key)
+				}
+			}
+		})
+	}
+}
+
 func TestLoadConfig_CACert(t *testing.T) {
 	origName := os.Getenv("CA_CERT_SECRET_NAME")
 	origKey := os.Getenv("CA_CERT_SECRET_KEY")
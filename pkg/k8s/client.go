@@ -2,8 +2,15 @@ package k8s
 
 import (
 	"context"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -21,22 +28,180 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	metricsClientset "k8s.io/metrics/pkg/client/clientset/versioned"
+	metricsv1beta1 "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
 )
 
+// workspaceVolumeName is the pod volume backed by a request's workspace_pvc_name
+const workspaceVolumeName = "workspace"
+
+// reservedEnvVarPrefixes are env var names set by the runtime API that carry the session
+// key or identify the runtime; a start request may not override them
+var reservedEnvVarPrefixes = []string{"OH_SESSION", "SESSION_API_KEY", "OH_RUNTIME_ID"}
+
+// IsReservedEnvVar reports whether a start request may only set the env var name when
+// ALLOWED_RESERVED_ENV_VARS permits it
+func IsReservedEnvVar(name string) bool {
+	for _, prefix := range reservedEnvVarPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// caCertVolumeName is the pod volume backed by CA_CERT_SECRET_NAME
+const caCertVolumeName = "ca-certificates"
+
+// CACertMountPath is where the CA_CERT_SECRET_NAME certificate is mounted
+const CACertMountPath = "/usr/local/share/ca-certificates/additional-ca.crt"
+
+// IsReservedVolumeName reports whether a pod volume name is used by the runtime API's
+// own mounts, so requested volumes may not take it
+func IsReservedVolumeName(name string) bool {
+	return name == workspaceVolumeName || name == caCertVolumeName
+}
+
+// Errors returned by ValidateWorkspacePVC
+var (
+	ErrWorkspacePVCNotFound = stderrors.New("workspace PVC not found")
+	ErrWorkspacePVCInUse    = stderrors.New("workspace PVC is already mounted by another sandbox")
+)
+
+// ErrPriorityClassNotFound is returned by ValidatePriorityClass when SANDBOX_PRIORITY_CLASS_NAME
+// names a PriorityClass that doesn't exist (sandbox pods would be rejected at admission)
+var ErrPriorityClassNotFound = stderrors.New("priority class not found")
+
+// ErrPodReadyTimeout is returned by WaitForPodReady when the pod isn't ready in time
+var ErrPodReadyTimeout = stderrors.New("timeout waiting for pod to be ready")
+
+// ErrPodStartTimeout is returned by WaitForPodStarted when the pod is still pending in time
+var ErrPodStartTimeout = stderrors.New("timeout waiting for pod to start")
+
+// SandboxExistsError is returned by CreateSandbox when a live sandbox is already left
+// over for the session, e.g. because the runtime API crashed after an earlier start
+// created it but before that start was recorded. Runtime is the existing sandbox,
+// reconstructed from the cluster for the caller to adopt instead of creating a duplicate.
+type SandboxExistsError struct {
+	Runtime *state.RuntimeInfo
+}
+
+func (e *SandboxExistsError) Error() string {
+	return fmt.Sprintf("sandbox already exists for session %s as runtime %s", e.Runtime.SessionID, e.Runtime.RuntimeID)
+}
+
+// ttlAnnotation records the sandbox's maximum lifetime on the pod so it survives a
+// runtime API restart (read back by buildRuntimeInfoFromPod during discovery).
+const ttlAnnotation = "openhands.dev/ttl-seconds"
+
+// idleTimeoutAnnotation records a per-sandbox idle timeout override the same way
+const idleTimeoutAnnotation = "openhands.dev/idle-timeout-seconds"
+
+// traceIDAnnotation and spanIDAnnotation record the (decimal Datadog) trace and span of
+// the request that created the sandbox, when that request was traced
+const (
+	traceIDAnnotation = "openhands.dev/trace-id"
+	spanIDAnnotation  = "openhands.dev/span-id"
+)
+
+// proxyTimeoutAnnotation records a per-sandbox proxy timeout override the same way
+const proxyTimeoutAnnotation = "openhands.dev/proxy-timeout-seconds"
+
+// protectedLabel marks a sandbox that the idle reaper and cleanup service must never
+// remove. Set from StartRequest.Protected and read back during discovery.
+const protectedLabel = "openhands.dev/protected"
+
+// reservedPodMetadataPrefix marks label and annotation keys owned by the runtime API
+const reservedPodMetadataPrefix = "openhands.dev/"
+
+// IsReservedPodMetadataKey reports whether a pod label or annotation key is set by the
+// runtime API itself (discovery and cleanup depend on them), so callers may not set it
+func IsReservedPodMetadataKey(key string) bool {
+	switch key {
+	case "app", "runtime-id", "session-id", resourceFactorLabel:
+		return true
+	}
+	return strings.HasPrefix(key, reservedPodMetadataPrefix)
+}
+
+// customPodMetadata merges operator defaults and per-request pod labels or annotations
+// into a new map, dropping reserved keys
+func customPodMetadata(defaults, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaults)+len(overrides))
+	for _, m := range []map[string]string{defaults, overrides} {
+		for k, v := range m {
+			if IsReservedPodMetadataKey(k) {
+				logger.Debug("createPod: Ignoring reserved pod metadata key %q", k)
+				continue
+			}
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// Sizing metadata recorded on sandbox pods so cluster tooling can select and account
+// by size: the effective resource_factor as a label, and the factor with the computed
+// CPU/memory requests and limits as annotations.
+const (
+	resourceFactorLabel      = "resource-factor"
+	resourceFactorAnnotation = "openhands.dev/resource-factor"
+	cpuRequestAnnotation     = "openhands.dev/cpu-request"
+	memoryRequestAnnotation  = "openhands.dev/memory-request"
+	cpuLimitAnnotation       = "openhands.dev/cpu-limit"
+	memoryLimitAnnotation    = "openhands.dev/memory-limit"
+)
+
+// evictionProtectionAnnotations exempt interactive sandboxes from voluntary evictions
+// by the descheduler and cluster autoscaler scale-down.
+var evictionProtectionAnnotations = map[string]string{
+	"descheduler.alpha.kubernetes.io/prevent-eviction": "true",
+	"cluster-autoscaler.kubernetes.io/safe-to-evict":   "false",
+}
+
+// sanitizeLabelValue coerces s into valid label value syntax: at most 63 characters of
+// alphanumerics, '-', '_' and '.', beginning and ending with an alphanumeric.
+// Invalid characters become '-'; an empty result means no usable value.
+func sanitizeLabelValue(s string) string {
+	b := []byte(s)
+	for i, ch := range b {
+		if !isAlphanumeric(ch) && ch != '-' && ch != '_' && ch != '.' {
+			b[i] = '-'
+		}
+	}
+	if len(b) > 63 {
+		b = b[:63]
+	}
+	start, end := 0, len(b)
+	for start < end && !isAlphanumeric(b[start]) {
+		start++
+	}
+	for end > start && !isAlphanumeric(b[end-1]) {
+		end--
+	}
+	return string(b[start:end])
+}
+
+func isAlphanumeric(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
+
 // ddTracingEnabled caches whether Datadog tracing is active (DD_AGENT_HOST is set).
 var ddTracingEnabled = os.Getenv("DD_AGENT_HOST") != ""
 
 // Client wraps Kubernetes client operations
 type Client struct {
-	clientset  *kubernetes.Clientset
+	clientset  kubernetes.Interface
 	config     *config.Config
 	namespace  string
-	nodeScorer *nodescore.Scorer // nil when scoring is disabled or metrics unavailable
+	nodeScorer *nodescore.Scorer                  // nil when scoring is disabled or metrics unavailable
+	podMetrics metricsv1beta1.PodMetricsInterface // nil unless reaper usage checks are enabled
 
 	// Pod status cache: deduplicates concurrent K8s List calls and caches results briefly.
 	podCacheMu   sync.RWMutex
@@ -44,6 +209,17 @@ type Client struct {
 	podCacheTime time.Time
 	podCacheTTL  time.Duration
 	podCacheSF   singleflight.Group
+
+	// Single pod status cache for GetPodStatus, keyed by pod name
+	podStatusMu    sync.Mutex
+	podStatusCache map[string]cachedPodStatus
+	podStatusSF    singleflight.Group
+}
+
+// cachedPodStatus is a GetPodStatus result and when it was fetched
+type cachedPodStatus struct {
+	info      *PodStatusInfo
+	fetchedAt time.Time
 }
 
 // NewClient creates a new Kubernetes client
@@ -74,30 +250,97 @@ func NewClient(cfg *config.Config) (*Client, error) {
 	logger.Debug("NewClient: Kubernetes client created successfully for namespace %s", cfg.Namespace)
 
 	var scorer *nodescore.Scorer
-	if cfg.NodeScoringEnabled {
+	var podMetrics metricsv1beta1.PodMetricsInterface
+	usageChecks := cfg.ReaperRequireLowUsage || cfg.IdleSignal == "metrics"
+	if cfg.NodeScoringEnabled || usageChecks {
 		metricsCS, metricsErr := metricsClientset.NewForConfig(k8sConfig)
 		if metricsErr != nil {
-			logger.Info("Node scoring: failed to create metrics client, scoring disabled: %v", metricsErr)
+			logger.Warn("Failed to create metrics client, node scoring and reaper usage checks disabled: %v", metricsErr)
 		} else {
-			scorer = nodescore.NewScorer(
-				metricsCS.MetricsV1beta1().NodeMetricses(),
-				clientset.CoreV1().Nodes(),
-				cfg.NodeScoringCPUThreshold,
-				cfg.NodeScoringMemThreshold,
-				cfg.NodeScoringLabelSelector,
-			)
-			logger.Info("Node scoring enabled (CPU threshold: %d%%, memory threshold: %d%%)",
-				cfg.NodeScoringCPUThreshold, cfg.NodeScoringMemThreshold)
+			if cfg.NodeScoringEnabled {
+				scorer = nodescore.NewScorer(
+					metricsCS.MetricsV1beta1().NodeMetricses(),
+					clientset.CoreV1().Nodes(),
+					cfg.NodeScoringCPUThreshold,
+					cfg.NodeScoringMemThreshold,
+					cfg.NodeScoringLabelSelector,
+				)
+				logger.Info("Node scoring enabled (CPU threshold: %d%%, memory threshold: %d%%)",
+					cfg.NodeScoringCPUThreshold, cfg.NodeScoringMemThreshold)
+			}
+			if usageChecks {
+				podMetrics = metricsCS.MetricsV1beta1().PodMetricses(cfg.Namespace)
+			}
 		}
 	}
 
+	c := NewClientWithClientset(clientset, cfg)
+	c.nodeScorer = scorer
+	c.podMetrics = podMetrics
+	return c, nil
+}
+
+// NewClientWithClientset creates a client backed by an existing clientset (e.g. a fake
+// clientset in tests). Node scoring is not configured.
+func NewClientWithClientset(clientset kubernetes.Interface, cfg *config.Config) *Client {
 	return &Client{
 		clientset:   clientset,
 		config:      cfg,
 		namespace:   cfg.Namespace,
-		nodeScorer:  scorer,
 		podCacheTTL: 3 * time.Second,
-	}, nil
+	}
+}
+
+// PodUsage returns the sandbox pod's current CPU (millicores) and memory (bytes) usage,
+// summed over its containers, as reported by metrics-server.
+func (c *Client) PodUsage(ctx context.Context, runtimeInfo *state.RuntimeInfo) (int64, int64, error) {
+	if c.podMetrics == nil {
+		return 0, 0, fmt.Errorf("pod metrics unavailable")
+	}
+	metrics, err := c.podMetrics.Get(ctx, runtimeInfo.PodName, metav1.GetOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get pod metrics: %w", err)
+	}
+	var cpuMillis, memBytes int64
+	for _, container := range metrics.Containers {
+		cpuMillis += container.Usage.Cpu().MilliValue()
+		memBytes += container.Usage.Memory().Value()
+	}
+	return cpuMillis, memBytes, nil
+}
+
+// PodExists reports whether the sandbox's pod still exists, bypassing the pod status cache
+func (c *Client) PodExists(ctx context.Context, runtimeInfo *state.RuntimeInfo) (bool, error) {
+	_, err := c.clientset.CoreV1().Pods(c.namespace).Get(ctx, runtimeInfo.PodName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// WorkHosts returns the public URL of each configured worker port, keyed URL → port
+// (https://work-1-{session}.{domain}, https://work-2-{session}.{domain}, ...).
+func WorkHosts(cfg *config.Config, sessionID string) map[string]int {
+	sessionIDForHost := strings.ToLower(sessionID)
+	workHosts := make(map[string]int, len(cfg.WorkerPorts))
+	for i, port := range cfg.WorkerPorts {
+		workHosts[fmt.Sprintf("https://%s.%s", workerHostPrefix(i, sessionIDForHost), cfg.BaseDomain)] = port
+	}
+	return workHosts
+}
+
+// workerHostPrefix returns the subdomain label for the i-th (0-based) worker, e.g. work-1-{session}
+func workerHostPrefix(i int, sessionIDForHost string) string {
+	return fmt.Sprintf("work-%d-%s", i+1, sessionIDForHost)
+}
+
+// workerPortName returns the container/service port name for the i-th (0-based) worker
+// (worker1, worker2, ...); also used as the direct-routing path segment.
+func workerPortName(i int) string {
+	return fmt.Sprintf("worker%d", i+1)
 }
 
 // portToInt32 converts a port number to int32 for Kubernetes APIs.
@@ -125,6 +368,16 @@ func (c *Client) CreateSandbox(ctx context.Context, req *types.StartRequest, run
 	}
 	logger.Debug("CreateSandbox: Creating sandbox for runtime %s", runtimeInfo.RuntimeID)
 
+	// Pod names are random per start, so leftovers of an interrupted start never conflict
+	// by name; look them up by session instead. With REUSE_EXISTING_SANDBOXES on,
+	// StartRuntime has already done this before admission.
+	if !c.config.ReuseExistingSandboxes {
+		if existing := c.findExistingSandbox(ctx, runtimeInfo.SessionID); existing != nil {
+			logger.Info("CreateSandbox: Session %s already has sandbox %s, adopting it", runtimeInfo.SessionID, existing.RuntimeID)
+			return &SandboxExistsError{Runtime: existing}
+		}
+	}
+
 	// Create Pod
 	logger.Debug("CreateSandbox: Creating pod %s", runtimeInfo.PodName)
 	if err := c.createPod(ctx, req, runtimeInfo); err != nil {
@@ -151,16 +404,74 @@ func (c *Client) CreateSandbox(ctx context.Context, req *types.StartRequest, run
 	}
 	logger.Debug("CreateSandbox: Ingress created successfully")
 
+	// Record ownership of a handed-over workspace PVC only once the sandbox exists, so a
+	// failed start never leaves the caller's PVC labelled as deletable with the runtime
+	if req.OwnWorkspacePVC && req.WorkspacePVCName != "" {
+		logger.Debug("CreateSandbox: Labelling workspace PVC %s as owned by runtime %s", req.WorkspacePVCName, runtimeInfo.RuntimeID)
+		if err := c.claimPVC(ctx, req.WorkspacePVCName, runtimeInfo.RuntimeID); err != nil {
+			// Clean up pod, service and ingress on failure
+			_ = c.DeletePod(ctx, runtimeInfo.PodName)
+			_ = c.DeleteService(ctx, runtimeInfo.ServiceName)
+			_ = c.DeleteIngress(ctx, runtimeInfo.IngressName)
+			return fmt.Errorf("failed to label workspace pvc: %w", err)
+		}
+	}
+
 	logger.Debug("CreateSandbox: Sandbox created successfully for runtime %s", runtimeInfo.RuntimeID)
 	return nil
 }
 
-func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtimeInfo *state.RuntimeInfo) error {
-	labels := map[string]string{
-		"app":        "openhands-runtime",
-		"runtime-id": runtimeInfo.RuntimeID,
-		"session-id": runtimeInfo.SessionID,
+// findExistingSandbox looks up a live sandbox left over for the session, returning nil if
+// there is none or the lookup fails (creation then goes ahead as usual).
+func (c *Client) findExistingSandbox(ctx context.Context, sessionID string) *state.RuntimeInfo {
+	existing, err := c.DiscoverRuntimeBySessionID(ctx, sessionID)
+	if err != nil {
+		logger.Warn("CreateSandbox: Failed to look up existing sandbox for session %s: %v", sessionID, err)
+		return nil
 	}
+	return existing
+}
+
+// isRetryableCreateError reports whether a create failed for a transient reason worth
+// retrying. AlreadyExists, Invalid and other client errors are permanent.
+func isRetryableCreateError(err error) bool {
+	return errors.IsServerTimeout(err) || errors.IsTooManyRequests(err) || errors.IsInternalError(err)
+}
+
+// createWithRetry runs create, retrying transient API errors up to K8sCreateMaxRetries
+// times with exponential backoff from K8sCreateRetryBaseDelay. A timed-out attempt may
+// still have been persisted, so AlreadyExists on a retry counts as success.
+func (c *Client) createWithRetry(ctx context.Context, kind, name string, create func() error) error {
+	delay := c.config.K8sCreateRetryBaseDelay
+	for attempt := 0; ; attempt++ {
+		err := create()
+		if err == nil {
+			return nil
+		}
+		if attempt > 0 && errors.IsAlreadyExists(err) {
+			logger.Debug("createWithRetry: %s %s already exists after retry, treating as created", kind, name)
+			return nil
+		}
+		if attempt >= c.config.K8sCreateMaxRetries || !isRetryableCreateError(err) {
+			return err
+		}
+
+		logger.Warn("createWithRetry: Transient error creating %s %s (attempt %d/%d), retrying in %s: %v",
+			kind, name, attempt+1, c.config.K8sCreateMaxRetries+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtimeInfo *state.RuntimeInfo) error {
+	labels := customPodMetadata(c.config.SandboxPodLabels, req.PodLabels)
+	labels["app"] = "openhands-runtime"
+	labels["runtime-id"] = runtimeInfo.RuntimeID
+	labels["session-id"] = runtimeInfo.SessionID
 
 	// Build environment variables.
 	// Set both OH_SESSION_API_KEYS_0 (app_server convention) and SESSION_API_KEY
@@ -171,8 +482,9 @@ func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtime
 		{Name: "OH_RUNTIME_ID", Value: runtimeInfo.RuntimeID},
 		{Name: "OH_VSCODE_BASE_PATH", Value: fmt.Sprintf("/sandbox/%s/vscode", runtimeInfo.RuntimeID)},
 		{Name: "OH_VSCODE_PORT", Value: fmt.Sprintf("%d", c.config.VSCodePort)},
-		{Name: "WORKER_1", Value: fmt.Sprintf("%d", c.config.Worker1Port)},
-		{Name: "WORKER_2", Value: fmt.Sprintf("%d", c.config.Worker2Port)},
+	}
+	for i, port := range c.config.WorkerPorts {
+		envVars = append(envVars, corev1.EnvVar{Name: fmt.Sprintf("WORKER_%d", i+1), Value: fmt.Sprintf("%d", port)})
 	}
 	// If custom CA certificate is mounted, point Python/httpx at the system bundle.
 	// The entrypoint runs update-ca-certificates, which merges the mounted cert
@@ -193,14 +505,30 @@ func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtime
 		})
 	}
 
-	// Add custom environment variables from request
+	// Expose the pod's own identity to agent features that need it
+	if c.config.InjectDownwardAPI {
+		envVars = append(envVars, downwardAPIEnvVars()...)
+	}
+
+	// Add custom environment variables from request. Reserved names would override the
+	// session key or runtime ID above (the last duplicate wins), so they are dropped.
 	for key, value := range req.Environment {
+		if IsReservedEnvVar(key) && !slices.Contains(c.config.AllowedReservedEnvVars, key) {
+			logger.Warn("createPod: Dropping reserved env var %s requested for runtime %s", key, runtimeInfo.RuntimeID)
+			continue
+		}
 		envVars = append(envVars, corev1.EnvVar{
 			Name:  key,
 			Value: value,
 		})
 	}
 
+	// Correlation IDs for sandbox-side logs and traces. Set after custom env vars so an
+	// OTEL_RESOURCE_ATTRIBUTES from the request is extended rather than replaced.
+	if c.config.InjectTraceCorrelation {
+		envVars = append(envVars, traceCorrelationEnvVars(runtimeInfo, req.Environment["OTEL_RESOURCE_ATTRIBUTES"])...)
+	}
+
 	// Add webhook URL if app server URL is configured.
 	// This is set AFTER custom env vars so the runtime API's internal
 	// cluster URL overrides the app-server's external URL. In Kubernetes,
@@ -227,43 +555,98 @@ func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtime
 		args = []string{req.Command[0]}
 	}
 
-	// Set resource requests/limits based on resource_factor
-	resourceFactor := req.ResourceFactor
-	if resourceFactor == 0 {
-		resourceFactor = 1.0
+	// Set resource requests/limits: the configured base sizes multiplied by resource_factor
+	resourceFactor := resourceFactorFor(req)
+	cpuRequest, memoryRequest := requestQuantities(c.config, req)
+	cpuLimit := scaledCPU(orDefault(c.config.SandboxCPULimit, "2000m"), resourceFactor)
+	memoryLimit := scaledMemory(orDefault(c.config.SandboxMemoryLimit, "4096Mi"), resourceFactor)
+
+	// Explicit limits override the factor-based defaults. Validated by the API handler;
+	// a defaulted limit below an explicit request is raised to match it, and in guaranteed
+	// QoS mode a defaulted limit always equals the request.
+	guaranteed := c.config.SandboxQoSMode == "guaranteed"
+	switch {
+	case req.CPULimit != "":
+		cpuLimit = req.CPULimit
+	case guaranteed:
+		cpuLimit = cpuRequest
+	default:
+		cpuLimit = atLeast(cpuLimit, cpuRequest)
+	}
+	switch {
+	case req.MemoryLimit != "":
+		memoryLimit = req.MemoryLimit
+	case guaranteed:
+		memoryLimit = memoryRequest
+	default:
+		memoryLimit = atLeast(memoryLimit, memoryRequest)
+	}
+
+	if runtimeInfo.Protected {
+		labels[protectedLabel] = "true"
+	}
+
+	factor := strconv.FormatFloat(resourceFactor, 'f', -1, 64)
+	if value := sanitizeLabelValue(factor); value != "" {
+		labels[resourceFactorLabel] = value
+	}
+
+	containerPorts := []corev1.ContainerPort{
+		//nolint:gosec // Port values are validated to be in valid range (1-65535)
+		{ContainerPort: portToInt32(c.config.AgentServerPort), Name: "agent", Protocol: corev1.ProtocolTCP},
+		//nolint:gosec // Port values are validated to be in valid range (1-65535)
+		{ContainerPort: portToInt32(c.config.VSCodePort), Name: "vscode", Protocol: corev1.ProtocolTCP},
+	}
+	for i, port := range c.config.WorkerPorts {
+		containerPorts = append(containerPorts, corev1.ContainerPort{
+			ContainerPort: portToInt32(port), Name: workerPortName(i), Protocol: corev1.ProtocolTCP,
+		})
 	}
 
-	cpuRequest := fmt.Sprintf("%.0fm", 1000*resourceFactor)
-	memoryRequest := fmt.Sprintf("%.0fMi", 2048*resourceFactor)
-	cpuLimit := fmt.Sprintf("%.0fm", 2000*resourceFactor)
-	memoryLimit := fmt.Sprintf("%.0fMi", 4096*resourceFactor)
+	annotations := customPodMetadata(c.config.SandboxPodAnnotations, req.PodAnnotations)
+	annotations[resourceFactorAnnotation] = factor
+	annotations[cpuRequestAnnotation] = cpuRequest
+	annotations[memoryRequestAnnotation] = memoryRequest
+	annotations[cpuLimitAnnotation] = cpuLimit
+	annotations[memoryLimitAnnotation] = memoryLimit
+	if runtimeInfo.TTL > 0 {
+		annotations[ttlAnnotation] = strconv.Itoa(int(runtimeInfo.TTL.Seconds()))
+	}
+	if runtimeInfo.IdleTimeout > 0 {
+		annotations[idleTimeoutAnnotation] = strconv.Itoa(int(runtimeInfo.IdleTimeout.Seconds()))
+	}
+	if runtimeInfo.ProxyTimeout > 0 {
+		annotations[proxyTimeoutAnnotation] = strconv.Itoa(int(runtimeInfo.ProxyTimeout.Seconds()))
+	}
+	if c.config.AnnotatePodTrace && runtimeInfo.StartTraceID != 0 {
+		// Stamp the trace of the /start request so a described pod leads back to it
+		annotations[traceIDAnnotation] = strconv.FormatUint(runtimeInfo.StartTraceID, 10)
+		annotations[spanIDAnnotation] = strconv.FormatUint(runtimeInfo.StartSpanID, 10)
+	}
+	if c.config.EvictionProtection {
+		for k, v := range evictionProtectionAnnotations {
+			annotations[k] = v
+		}
+	}
 
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      runtimeInfo.PodName,
-			Namespace: c.namespace,
-			Labels:    labels,
+			Name:        runtimeInfo.PodName,
+			Namespace:   c.namespace,
+			Labels:      labels,
+			Annotations: annotations,
 		},
 		Spec: corev1.PodSpec{
 			Containers: []corev1.Container{
 				{
-					Name:            "openhands-agent",
+					Name:            agentContainerName,
 					Image:           req.Image,
 					Command:         command,
 					Args:            args,
 					WorkingDir:      req.WorkingDir,
 					Env:             envVars,
-					ImagePullPolicy: corev1.PullAlways,
-					Ports: []corev1.ContainerPort{
-						//nolint:gosec // Port values are validated to be in valid range (1-65535)
-						{ContainerPort: portToInt32(c.config.AgentServerPort), Name: "agent", Protocol: corev1.ProtocolTCP},
-						//nolint:gosec // Port values are validated to be in valid range (1-65535)
-						{ContainerPort: portToInt32(c.config.VSCodePort), Name: "vscode", Protocol: corev1.ProtocolTCP},
-						//nolint:gosec // Port values are validated to be in valid range (1-65535)
-						{ContainerPort: portToInt32(c.config.Worker1Port), Name: "worker1", Protocol: corev1.ProtocolTCP},
-						//nolint:gosec // Port values are validated to be in valid range (1-65535)
-						{ContainerPort: portToInt32(c.config.Worker2Port), Name: "worker2", Protocol: corev1.ProtocolTCP},
-					},
+					ImagePullPolicy: c.imagePullPolicy(req.Image),
+					Ports:           containerPorts,
 					Resources: corev1.ResourceRequirements{
 						Requests: corev1.ResourceList{
 							corev1.ResourceCPU:    resource.MustParse(cpuRequest),
@@ -279,8 +662,9 @@ func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtime
 					StartupProbe: &corev1.Probe{
 						ProbeHandler: corev1.ProbeHandler{
 							HTTPGet: &corev1.HTTPGetAction{
-								Path: "/alive",
-								Port: intstr.FromInt(c.config.AgentServerPort),
+								Path:   "/alive",
+								Port:   intstr.FromInt(c.config.AgentServerPort),
+								Scheme: c.agentProbeScheme(),
 							},
 						},
 						PeriodSeconds:    5,
@@ -288,12 +672,7 @@ func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtime
 						FailureThreshold: 60, // 60 * 5s = 300s max startup time
 					},
 					ReadinessProbe: &corev1.Probe{
-						ProbeHandler: corev1.ProbeHandler{
-							HTTPGet: &corev1.HTTPGetAction{
-								Path: "/alive",
-								Port: intstr.FromInt(c.config.AgentServerPort),
-							},
-						},
+						ProbeHandler:     c.readinessProbeHandler(),
 						PeriodSeconds:    5,
 						TimeoutSeconds:   5,
 						SuccessThreshold: 1,
@@ -310,6 +689,28 @@ func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtime
 		pod.Spec.RuntimeClassName = &req.RuntimeClass
 	}
 
+	if c.config.SandboxPriorityClassName != "" {
+		pod.Spec.PriorityClassName = c.config.SandboxPriorityClassName
+	}
+
+	// Restart the agent container when /alive stops answering
+	if c.config.LivenessProbeEnabled {
+		pod.Spec.Containers[0].LivenessProbe = c.livenessProbe()
+	}
+
+	// Restricted security context for clusters enforcing Pod Security Standards
+	if c.config.PodSecurityRestricted {
+		c.applySecurityContext(pod)
+	}
+
+	// Node selector, tolerations and affinity (config defaults + request overrides)
+	c.applyScheduling(pod, req)
+
+	// Attach GPUs and steer the pod onto GPU nodes when requested
+	if req.GPU != nil && req.GPU.Count > 0 {
+		c.applyGPU(pod, req.GPU)
+	}
+
 	// Set image pull secrets when using a private registry
 	if len(c.config.ImagePullSecrets) > 0 {
 		pod.Spec.ImagePullSecrets = make([]corev1.LocalObjectReference, 0, len(c.config.ImagePullSecrets))
@@ -326,9 +727,8 @@ func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtime
 		if secretKey == "" {
 			secretKey = "ca-certificates.crt"
 		}
-		const caCertMountPath = "/usr/local/share/ca-certificates/additional-ca.crt"
 		vol := corev1.Volume{
-			Name: "ca-certificates",
+			Name: caCertVolumeName,
 			VolumeSource: corev1.VolumeSource{
 				Secret: &corev1.SecretVolumeSource{
 					SecretName: c.config.CACertSecretName,
@@ -337,13 +737,53 @@ func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtime
 		}
 		pod.Spec.Volumes = append(pod.Spec.Volumes, vol)
 		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
-			Name:      c.config.CACertSecretName,
-			MountPath: caCertMountPath,
+			Name:      caCertVolumeName,
+			MountPath: CACertMountPath,
 			SubPath:   secretKey,
 			ReadOnly:  true,
 		})
 	}
 
+	// Mount an existing PVC as the workspace (validated by the caller via ValidateWorkspacePVC)
+	if req.WorkspacePVCName != "" {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: workspaceVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: req.WorkspacePVCName,
+				},
+			},
+		})
+		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      workspaceVolumeName,
+			MountPath: c.config.WorkspaceMountPath,
+		})
+	}
+
+	// Pre-populate the workspace before the agent starts (image validated by the API handler)
+	if req.InitContainer != nil {
+		c.applyInitContainer(pod, req.InitContainer)
+	}
+
+	// Mount requested ConfigMaps and Secrets (validated by the API handler)
+	for _, v := range req.Volumes {
+		vol := corev1.Volume{Name: v.Name}
+		if v.ConfigMap != "" {
+			vol.ConfigMap = &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: v.ConfigMap},
+			}
+		} else {
+			vol.Secret = &corev1.SecretVolumeSource{SecretName: v.Secret}
+		}
+		pod.Spec.Volumes = append(pod.Spec.Volumes, vol)
+		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      v.Name,
+			MountPath: v.MountPath,
+			SubPath:   v.SubPath,
+			ReadOnly:  v.ReadOnly,
+		})
+	}
+
 	// Apply node scoring preference if scorer is available.
 	if c.nodeScorer != nil {
 		if selectedNode := c.nodeScorer.SelectNode(ctx); selectedNode != "" {
@@ -352,10 +792,469 @@ func (c *Client) createPod(ctx context.Context, req *types.StartRequest, runtime
 		}
 	}
 
-	_, err := c.clientset.CoreV1().Pods(c.namespace).Create(ctx, pod, metav1.CreateOptions{})
+	err := c.createWithRetry(ctx, "pod", pod.Name, func() error {
+		_, err := c.clientset.CoreV1().Pods(c.namespace).Create(ctx, pod, metav1.CreateOptions{})
+		return err
+	})
+	c.forgetPodStatus(pod.Name)
+	if err != nil && c.config.DebugDumpPodSpec && !errors.IsAlreadyExists(err) {
+		logger.Warn("createPod: Pod %s was rejected: %v; pod: %s", pod.Name, err, redactedPodJSON(pod))
+	}
 	return err
 }
 
+// redactedPodValue replaces literal env values when a pod is logged for debugging
+const redactedPodValue = "[REDACTED]"
+
+// redactedPodJSON marshals a pod for logging with every literal env value replaced, since
+// they include the session API key and operator-supplied secrets. References to secrets
+// and config maps (valueFrom) are kept.
+func redactedPodJSON(pod *corev1.Pod) string {
+	redacted := pod.DeepCopy()
+	redactEnv := func(containers []corev1.Container) {
+		for i := range containers {
+			for j := range containers[i].Env {
+				if containers[i].Env[j].Value != "" {
+					containers[i].Env[j].Value = redactedPodValue
+				}
+			}
+		}
+	}
+	redactEnv(redacted.Spec.InitContainers)
+	redactEnv(redacted.Spec.Containers)
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		return fmt.Sprintf("<failed to marshal pod: %v>", err)
+	}
+	return string(data)
+}
+
+// resourceFactorFor returns the request's resource_factor, defaulting to 1
+func resourceFactorFor(req *types.StartRequest) float64 {
+	if req.ResourceFactor == 0 {
+		return 1.0
+	}
+	return req.ResourceFactor
+}
+
+// requestQuantities returns a sandbox's CPU and memory requests: the configured base sizes
+// multiplied by resource_factor, unless the request sets cpu_request / memory_request
+func requestQuantities(cfg *config.Config, req *types.StartRequest) (cpu, memory string) {
+	factor := resourceFactorFor(req)
+	cpu = scaledCPU(orDefault(cfg.SandboxCPURequest, "1000m"), factor)
+	memory = scaledMemory(orDefault(cfg.SandboxMemoryRequest, "2048Mi"), factor)
+	if req.CPURequest != "" {
+		cpu = req.CPURequest
+	}
+	if req.MemoryRequest != "" {
+		memory = req.MemoryRequest
+	}
+	return cpu, memory
+}
+
+// RequestedResources returns the CPU (millicores) and memory (bytes) the pod of a sandbox
+// started with req will request, for MAX_TOTAL_CPU / MAX_TOTAL_MEMORY accounting.
+// Quantities in req must already be validated.
+func RequestedResources(cfg *config.Config, req *types.StartRequest) (cpuMillis, memoryBytes int64) {
+	cpu, memory := requestQuantities(cfg, req)
+	cpuQuantity := resource.MustParse(cpu)
+	memoryQuantity := resource.MustParse(memory)
+	return cpuQuantity.MilliValue(), memoryQuantity.Value()
+}
+
+// podRequests sums the CPU (millicores) and memory (bytes) requested by a pod's containers
+func podRequests(pod *corev1.Pod) (cpuMillis, memoryBytes int64) {
+	for _, container := range pod.Spec.Containers {
+		cpuMillis += container.Resources.Requests.Cpu().MilliValue()
+		memoryBytes += container.Resources.Requests.Memory().Value()
+	}
+	return cpuMillis, memoryBytes
+}
+
+// scaledCPU returns the CPU quantity base multiplied by factor, in millicores
+func scaledCPU(base string, factor float64) string {
+	q := resource.MustParse(base)
+	return fmt.Sprintf("%.0fm", float64(q.MilliValue())*factor)
+}
+
+// scaledMemory returns the memory quantity base multiplied by factor, in MiB
+func scaledMemory(base string, factor float64) string {
+	q := resource.MustParse(base)
+	return fmt.Sprintf("%.0fMi", float64(q.Value())/(1<<20)*factor)
+}
+
+// orDefault returns value, or fallback if value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// atLeast returns limit, or request if it is the larger quantity
+func atLeast(limit, request string) string {
+	requestQuantity := resource.MustParse(request)
+	if requestQuantity.Cmp(resource.MustParse(limit)) > 0 {
+		return request
+	}
+	return limit
+}
+
+// applyScheduling sets the pod's node selector, tolerations and affinity from the
+// SANDBOX_NODE_SELECTOR / SANDBOX_TOLERATIONS defaults, with request values taking precedence.
+func (c *Client) applyScheduling(pod *corev1.Pod, req *types.StartRequest) {
+	nodeSelector := make(map[string]string, len(c.config.SandboxNodeSelector)+len(req.NodeSelector))
+	for k, v := range c.config.SandboxNodeSelector {
+		nodeSelector[k] = v
+	}
+	for k, v := range req.NodeSelector {
+		nodeSelector[k] = v
+	}
+	if len(nodeSelector) > 0 {
+		pod.Spec.NodeSelector = nodeSelector
+	}
+
+	pod.Spec.Tolerations = mergeTolerations(c.config.SandboxTolerations, req.Tolerations)
+
+	if req.Affinity != nil {
+		pod.Spec.Affinity = req.Affinity.DeepCopy()
+	}
+
+	if req.SchedulingHint != nil {
+		applySchedulingHint(pod, req.SchedulingHint)
+	}
+}
+
+// applySchedulingHint turns a scheduling hint into required node affinity. The hint's
+// requirements are ANDed into every existing required term (terms are ORed by the
+// scheduler), so a request affinity can't be used to escape the hint.
+func applySchedulingHint(pod *corev1.Pod, hint *types.SchedulingHint) {
+	var reqs []corev1.NodeSelectorRequirement
+	if hint.Zone != "" {
+		reqs = append(reqs, corev1.NodeSelectorRequirement{
+			Key:      corev1.LabelTopologyZone,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{hint.Zone},
+		})
+	}
+	if key, value, ok := strings.Cut(hint.NodeLabel, "="); ok {
+		reqs = append(reqs, corev1.NodeSelectorRequirement{
+			Key:      key,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{value},
+		})
+	}
+	if len(reqs) == 0 {
+		return
+	}
+
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := pod.Spec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(required.NodeSelectorTerms) == 0 {
+		required.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	for i := range required.NodeSelectorTerms {
+		required.NodeSelectorTerms[i].MatchExpressions = append(required.NodeSelectorTerms[i].MatchExpressions, reqs...)
+	}
+}
+
+// mergeTolerations returns the default tolerations overlaid with the overrides.
+// An override replaces any default with the same key and effect.
+func mergeTolerations(defaults, overrides []corev1.Toleration) []corev1.Toleration {
+	if len(defaults) == 0 && len(overrides) == 0 {
+		return nil
+	}
+	merged := make([]corev1.Toleration, 0, len(defaults)+len(overrides))
+	for _, d := range defaults {
+		overridden := false
+		for _, o := range overrides {
+			if o.Key == d.Key && o.Effect == d.Effect {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, d)
+		}
+	}
+	return append(merged, overrides...)
+}
+
+// defaultGPUResourceName is the extended resource advertised by the NVIDIA device plugin.
+const defaultGPUResourceName = "nvidia.com/gpu"
+
+// IsExtendedResourceName reports whether name is a domain-qualified extended resource
+// (e.g. nvidia.com/gpu) outside the kubernetes.io namespace, and so safe to use as a GPU
+// resource name: native resources like cpu or memory would overwrite the sandbox's own
+// requests. Mirrors IsExtendedResourceName in Kubernetes' core v1 helper.
+func IsExtendedResourceName(name string) bool {
+	if !strings.Contains(name, "/") || strings.Contains(name, "kubernetes.io/") || strings.HasPrefix(name, corev1.DefaultResourceRequestsPrefix) {
+		return false
+	}
+	// The quota system tracks it as requests.<name>, which must also be a valid name
+	return len(validation.IsQualifiedName(corev1.DefaultResourceRequestsPrefix+name)) == 0
+}
+
+// applySecurityContext sets pod and container security contexts that satisfy the
+// Pod Security Standards "restricted" profile.
+func (c *Client) applySecurityContext(pod *corev1.Pod) {
+	runAsNonRoot := true
+	podSC := &corev1.PodSecurityContext{
+		RunAsNonRoot: &runAsNonRoot,
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+	if c.config.PodRunAsUser > 0 {
+		runAsUser := c.config.PodRunAsUser
+		podSC.RunAsUser = &runAsUser
+	}
+	pod.Spec.SecurityContext = podSC
+
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].SecurityContext = restrictedContainerSecurityContext()
+	}
+}
+
+// restrictedContainerSecurityContext drops all capabilities and privilege escalation, as
+// the restricted Pod Security Standard requires of every container
+func restrictedContainerSecurityContext() *corev1.SecurityContext {
+	allowPrivilegeEscalation := false
+	return &corev1.SecurityContext{
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+	}
+}
+
+// initContainerName is the init container that pre-populates the workspace
+const initContainerName = "workspace-init"
+
+// applyInitContainer adds the requested init container with the workspace volume mounted
+// at WORKSPACE_MOUNT_PATH. Without a workspace PVC, an emptyDir is mounted in both
+// containers so the agent sees what the init container wrote.
+func (c *Client) applyInitContainer(pod *corev1.Pod, init *types.InitContainerRequest) {
+	hasWorkspace := false
+	for _, vol := range pod.Spec.Volumes {
+		if vol.Name == workspaceVolumeName {
+			hasWorkspace = true
+			break
+		}
+	}
+	workspaceMount := corev1.VolumeMount{
+		Name:      workspaceVolumeName,
+		MountPath: c.config.WorkspaceMountPath,
+	}
+	if !hasWorkspace {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: workspaceVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, workspaceMount)
+	}
+
+	var command, args []string
+	if len(init.Command) == 1 {
+		command = []string{"/bin/sh", "-c"}
+		args = []string{init.Command[0]}
+	} else if len(init.Command) > 1 {
+		command = []string(init.Command)
+	}
+
+	keys := make([]string, 0, len(init.Environment))
+	for key := range init.Environment {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	env := make([]corev1.EnvVar, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, corev1.EnvVar{Name: key, Value: init.Environment[key]})
+	}
+
+	container := corev1.Container{
+		Name:            initContainerName,
+		Image:           init.Image,
+		ImagePullPolicy: c.imagePullPolicy(init.Image),
+		Command:         command,
+		Args:            args,
+		Env:             env,
+		WorkingDir:      c.config.WorkspaceMountPath,
+		VolumeMounts:    []corev1.VolumeMount{workspaceMount},
+	}
+	if c.config.PodSecurityRestricted {
+		container.SecurityContext = restrictedContainerSecurityContext()
+	}
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, container)
+}
+
+// agentProbeScheme returns the scheme for probes on the agent server; anything other
+// than HTTPS falls back to HTTP.
+func (c *Client) agentProbeScheme() corev1.URIScheme {
+	if strings.EqualFold(c.config.AgentProbeScheme, string(corev1.URISchemeHTTPS)) {
+		return corev1.URISchemeHTTPS
+	}
+	return corev1.URISchemeHTTP
+}
+
+// imagePullPolicy returns the pull policy for image: IMAGE_PULL_POLICY when it names a
+// valid policy, otherwise IfNotPresent for digest references (which cannot change) and
+// Always for tags.
+func (c *Client) imagePullPolicy(image string) corev1.PullPolicy {
+	for _, policy := range []corev1.PullPolicy{corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever} {
+		if strings.EqualFold(strings.TrimSpace(c.config.ImagePullPolicy), string(policy)) {
+			return policy
+		}
+	}
+	if strings.Contains(image, "@") {
+		return corev1.PullIfNotPresent
+	}
+	return corev1.PullAlways
+}
+
+// ReadinessProbePort resolves SANDBOX_READINESS_PORT_ROLE to a container port: "agent"
+// (the default), "vscode", or "workerN" for the Nth entry of WORKER_PORTS.
+func ReadinessProbePort(cfg *config.Config) (int, error) {
+	role := strings.ToLower(strings.TrimSpace(cfg.SandboxReadinessPortRole))
+	switch role {
+	case "", "agent":
+		return cfg.AgentServerPort, nil
+	case "vscode":
+		return cfg.VSCodePort, nil
+	}
+	if rest, ok := strings.CutPrefix(role, "worker"); ok {
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 1 || n > len(cfg.WorkerPorts) {
+			return 0, fmt.Errorf("readiness port role %q: want worker1 to worker%d", role, len(cfg.WorkerPorts))
+		}
+		return cfg.WorkerPorts[n-1], nil
+	}
+	return 0, fmt.Errorf("unknown readiness port role %q (want agent, vscode or workerN)", role)
+}
+
+// readinessProbeHandler checks the agent server's /alive endpoint, or for other port
+// roles (whose servers have no health endpoint) that the port accepts connections.
+// Invalid roles are rejected at startup; if one slips through, the agent port is used.
+func (c *Client) readinessProbeHandler() corev1.ProbeHandler {
+	port, err := ReadinessProbePort(c.config)
+	if err != nil || port == c.config.AgentServerPort {
+		return corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   "/alive",
+				Port:   intstr.FromInt(c.config.AgentServerPort),
+				Scheme: c.agentProbeScheme(),
+			},
+		}
+	}
+	return corev1.ProbeHandler{
+		TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(port)},
+	}
+}
+
+// livenessProbe builds the agent server liveness probe from config. Periods and delays
+// are rounded up to whole seconds, with a minimum period of 1s and failure threshold of 1.
+func (c *Client) livenessProbe() *corev1.Probe {
+	period := int32(math.Ceil(c.config.LivenessProbePeriod.Seconds()))
+	if period < 1 {
+		period = 1
+	}
+	failureThreshold := int32(c.config.LivenessProbeFailureThreshold)
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	initialDelay := int32(math.Ceil(c.config.LivenessProbeInitialDelay.Seconds()))
+	if initialDelay < 0 {
+		initialDelay = 0
+	}
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   "/alive",
+				Port:   intstr.FromInt(c.config.AgentServerPort),
+				Scheme: c.agentProbeScheme(),
+			},
+		},
+		InitialDelaySeconds: initialDelay,
+		PeriodSeconds:       period,
+		TimeoutSeconds:      5,
+		SuccessThreshold:    1,
+		FailureThreshold:    failureThreshold,
+	}
+}
+
+// downwardAPIEnvVars returns env vars populated from the pod's own metadata and status
+func downwardAPIEnvVars() []corev1.EnvVar {
+	fieldEnv := func(name, fieldPath string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: fieldPath},
+			},
+		}
+	}
+	return []corev1.EnvVar{
+		fieldEnv("OH_POD_NAME", "metadata.name"),
+		fieldEnv("OH_POD_NAMESPACE", "metadata.namespace"),
+		fieldEnv("OH_NODE_NAME", "spec.nodeName"),
+		fieldEnv("OH_POD_IP", "status.podIP"),
+	}
+}
+
+// traceCorrelationEnvVars returns env vars carrying the session and runtime IDs, both as
+// plain variables and as OpenTelemetry resource attributes appended to existingAttrs.
+func traceCorrelationEnvVars(runtimeInfo *state.RuntimeInfo, existingAttrs string) []corev1.EnvVar {
+	attrs := fmt.Sprintf("openhands.session_id=%s,openhands.runtime_id=%s", runtimeInfo.SessionID, runtimeInfo.RuntimeID)
+	if existingAttrs != "" {
+		attrs = existingAttrs + "," + attrs
+	}
+	return []corev1.EnvVar{
+		{Name: "OH_TRACE_SESSION_ID", Value: runtimeInfo.SessionID},
+		{Name: "OH_TRACE_RUNTIME_ID", Value: runtimeInfo.RuntimeID},
+		{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: attrs},
+	}
+}
+
+// applyGPU adds the requested GPU count to the agent container's requests and limits
+// (extended resources can't be overcommitted, so the two must be equal) and applies the
+// configured GPU node selector and toleration.
+func (c *Client) applyGPU(pod *corev1.Pod, gpu *types.GPURequest) {
+	resourceName := corev1.ResourceName(gpu.ResourceName)
+	if resourceName == "" {
+		resourceName = defaultGPUResourceName
+	}
+	quantity := *resource.NewQuantity(int64(gpu.Count), resource.DecimalSI)
+	container := &pod.Spec.Containers[0]
+	container.Resources.Requests[resourceName] = quantity
+	container.Resources.Limits[resourceName] = quantity
+
+	if len(c.config.GPUNodeSelector) > 0 {
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = make(map[string]string, len(c.config.GPUNodeSelector))
+		}
+		for k, v := range c.config.GPUNodeSelector {
+			pod.Spec.NodeSelector[k] = v
+		}
+	}
+	if c.config.GPUTolerationKey != "" {
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, corev1.Toleration{
+			Key:      c.config.GPUTolerationKey,
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		})
+	}
+}
+
 func (c *Client) createService(ctx context.Context, runtimeInfo *state.RuntimeInfo) error {
 	labels := map[string]string{
 		"app":        "openhands-runtime",
@@ -387,37 +1286,82 @@ func (c *Client) createService(ctx context.Context, runtimeInfo *state.RuntimeIn
 					TargetPort: intstr.FromInt(c.config.VSCodePort),
 					Protocol:   corev1.ProtocolTCP,
 				},
-				{
-					Name: "worker1",
-					//nolint:gosec // Port values are validated to be in valid range (1-65535)
-					Port:       portToInt32(c.config.Worker1Port),
-					TargetPort: intstr.FromInt(c.config.Worker1Port),
-					Protocol:   corev1.ProtocolTCP,
-				},
-				{
-					Name:       "worker2",
-					Port:       portToInt32(c.config.Worker2Port),
-					TargetPort: intstr.FromInt(c.config.Worker2Port),
-					Protocol:   corev1.ProtocolTCP,
-				},
 			},
 			Type: corev1.ServiceTypeClusterIP,
 		},
 	}
+	for i, port := range c.config.WorkerPorts {
+		service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{
+			Name:       workerPortName(i),
+			Port:       portToInt32(port),
+			TargetPort: intstr.FromInt(port),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
 
-	_, err := c.clientset.CoreV1().Services(c.namespace).Create(ctx, service, metav1.CreateOptions{})
-	return err
+	return c.createWithRetry(ctx, "service", service.Name, func() error {
+		_, err := c.clientset.CoreV1().Services(c.namespace).Create(ctx, service, metav1.CreateOptions{})
+		return err
+	})
 }
 
 func (c *Client) createIngress(ctx context.Context, runtimeInfo *state.RuntimeInfo) error {
+	ingresses := c.sandboxIngresses(runtimeInfo)
+	for i, ingress := range ingresses {
+		if err := c.createWithRetry(ctx, "ingress", ingress.Name, func() error {
+			_, err := c.clientset.NetworkingV1().Ingresses(c.namespace).Create(ctx, ingress, metav1.CreateOptions{})
+			return err
+		}); err != nil {
+			// Roll back the ingresses we already created
+			for _, created := range ingresses[:i] {
+				_ = c.DeleteIngress(ctx, created.Name)
+			}
+			return fmt.Errorf("create ingress %s: %w", ingress.Name, err)
+		}
+	}
+	return nil
+}
+
+// sandboxIngresses returns the ingresses routing to a sandbox in the configured mode
+func (c *Client) sandboxIngresses(runtimeInfo *state.RuntimeInfo) []*networkingv1.Ingress {
 	if c.config.DirectRouting {
-		return c.createDirectRoutingIngresses(ctx, runtimeInfo)
+		return c.directRoutingIngresses(runtimeInfo)
+	}
+	return c.subdomainIngresses(runtimeInfo)
+}
+
+// subdomainIngresses builds the legacy subdomain-based ingresses: one per host for the
+// agent, VSCode and each work-N host. Every ingress carries its own TLS secret, so a
+// certificate that cert-manager fails to issue for one host leaves the others serving.
+func (c *Client) subdomainIngresses(runtimeInfo *state.RuntimeInfo) []*networkingv1.Ingress {
+	// Ingress hostnames must be RFC 1123 subdomains (lowercase alphanumeric, '-' or '.')
+	sessionIDForHost := strings.ToLower(runtimeInfo.SessionID)
+	tlsPrefix := fmt.Sprintf("runtime-%s", runtimeInfo.RuntimeID)
+
+	// The agent ingress keeps the runtime's IngressName and original TLS secret
+	ingresses := []*networkingv1.Ingress{
+		c.hostIngress(runtimeInfo, runtimeInfo.IngressName,
+			fmt.Sprintf("%s.%s", sessionIDForHost, c.config.BaseDomain),
+			c.config.AgentServerPort, tlsPrefix+"-tls"),
+		c.hostIngress(runtimeInfo, runtimeInfo.IngressName+"-vscode",
+			fmt.Sprintf("vscode-%s.%s", sessionIDForHost, c.config.BaseDomain),
+			c.config.VSCodePort, tlsPrefix+"-vscode-tls"),
+	}
+
+	// One work-N-{session} host per configured worker port
+	for i, port := range c.config.WorkerPorts {
+		suffix := fmt.Sprintf("work-%d", i+1)
+		ingresses = append(ingresses, c.hostIngress(runtimeInfo, runtimeInfo.IngressName+"-"+suffix,
+			fmt.Sprintf("%s.%s", workerHostPrefix(i, sessionIDForHost), c.config.BaseDomain),
+			port, tlsPrefix+"-"+suffix+"-tls"))
 	}
-	return c.createSubdomainIngress(ctx, runtimeInfo)
+
+	return ingresses
 }
 
-// createSubdomainIngress creates the legacy 4-rule subdomain-based ingress.
-func (c *Client) createSubdomainIngress(ctx context.Context, runtimeInfo *state.RuntimeInfo) error {
+// hostIngress builds a single-host ingress routing every path to port on the sandbox
+// service, terminating TLS with its own secret.
+func (c *Client) hostIngress(runtimeInfo *state.RuntimeInfo, name, host string, port int, tlsSecret string) *networkingv1.Ingress {
 	labels := map[string]string{
 		"app":        "openhands-runtime",
 		"runtime-id": runtimeInfo.RuntimeID,
@@ -426,13 +1370,6 @@ func (c *Client) createSubdomainIngress(ctx context.Context, runtimeInfo *state.
 	pathTypePrefix := networkingv1.PathTypePrefix
 	ingressClassName := c.config.IngressClass
 
-	// Ingress hostnames must be RFC 1123 subdomains (lowercase alphanumeric, '-' or '.')
-	sessionIDForHost := strings.ToLower(runtimeInfo.SessionID)
-	agentHost := fmt.Sprintf("%s.%s", sessionIDForHost, c.config.BaseDomain)
-	vscodeHost := fmt.Sprintf("vscode-%s.%s", sessionIDForHost, c.config.BaseDomain)
-	worker1Host := fmt.Sprintf("work-1-%s.%s", sessionIDForHost, c.config.BaseDomain)
-	worker2Host := fmt.Sprintf("work-2-%s.%s", sessionIDForHost, c.config.BaseDomain)
-
 	annotations := map[string]string{
 		"nginx.ingress.kubernetes.io/ssl-redirect":       "true",
 		"nginx.ingress.kubernetes.io/websocket-services": runtimeInfo.ServiceName,
@@ -440,9 +1377,10 @@ func (c *Client) createSubdomainIngress(ctx context.Context, runtimeInfo *state.
 	for k, v := range c.config.SandboxIngressAnnotations {
 		annotations[k] = v
 	}
-	ingress := &networkingv1.Ingress{
+
+	return &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        runtimeInfo.IngressName,
+			Name:        name,
 			Namespace:   c.namespace,
 			Labels:      labels,
 			Annotations: annotations,
@@ -451,70 +1389,7 @@ func (c *Client) createSubdomainIngress(ctx context.Context, runtimeInfo *state.
 			IngressClassName: &ingressClassName,
 			Rules: []networkingv1.IngressRule{
 				{
-					Host: agentHost,
-					IngressRuleValue: networkingv1.IngressRuleValue{
-						HTTP: &networkingv1.HTTPIngressRuleValue{
-							Paths: []networkingv1.HTTPIngressPath{
-								{
-									Path:     "/",
-									PathType: &pathTypePrefix,
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: runtimeInfo.ServiceName,
-											Port: networkingv1.ServiceBackendPort{
-												Number: portToInt32(c.config.AgentServerPort),
-											},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-				{
-					Host: vscodeHost,
-					IngressRuleValue: networkingv1.IngressRuleValue{
-						HTTP: &networkingv1.HTTPIngressRuleValue{
-							Paths: []networkingv1.HTTPIngressPath{
-								{
-									Path:     "/",
-									PathType: &pathTypePrefix,
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: runtimeInfo.ServiceName,
-											Port: networkingv1.ServiceBackendPort{
-												Number: portToInt32(c.config.VSCodePort),
-											},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-				{
-					Host: worker1Host,
-					IngressRuleValue: networkingv1.IngressRuleValue{
-						HTTP: &networkingv1.HTTPIngressRuleValue{
-							Paths: []networkingv1.HTTPIngressPath{
-								{
-									Path:     "/",
-									PathType: &pathTypePrefix,
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: runtimeInfo.ServiceName,
-											Port: networkingv1.ServiceBackendPort{
-												Number: portToInt32(c.config.Worker1Port),
-											},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-				{
-					Host: worker2Host,
+					Host: host,
 					IngressRuleValue: networkingv1.IngressRuleValue{
 						HTTP: &networkingv1.HTTPIngressRuleValue{
 							Paths: []networkingv1.HTTPIngressPath{
@@ -525,7 +1400,7 @@ func (c *Client) createSubdomainIngress(ctx context.Context, runtimeInfo *state.
 										Service: &networkingv1.IngressServiceBackend{
 											Name: runtimeInfo.ServiceName,
 											Port: networkingv1.ServiceBackendPort{
-												Number: portToInt32(c.config.Worker2Port),
+												Number: portToInt32(port),
 											},
 										},
 									},
@@ -537,18 +1412,15 @@ func (c *Client) createSubdomainIngress(ctx context.Context, runtimeInfo *state.
 			},
 			TLS: []networkingv1.IngressTLS{
 				{
-					Hosts:      []string{agentHost, vscodeHost, worker1Host, worker2Host},
-					SecretName: fmt.Sprintf("runtime-%s-tls", runtimeInfo.RuntimeID),
+					Hosts:      []string{host},
+					SecretName: tlsSecret,
 				},
 			},
 		},
 	}
-
-	_, err := c.clientset.NetworkingV1().Ingresses(c.namespace).Create(ctx, ingress, metav1.CreateOptions{})
-	return err
 }
 
-// createDirectRoutingIngresses creates two path-based ingresses on the shared BaseDomain host.
+// directRoutingIngresses builds two path-based ingresses on the shared BaseDomain host.
 // Ingress 1 (agent + workers): regex paths with rewrite-target to strip the /sandbox/{id} prefix.
 // Ingress 2 (vscode): regex path with rewrite-target that preserves the full path for VSCode's
 // --server-base-path setting.
@@ -558,7 +1430,11 @@ func (c *Client) createSubdomainIngress(ctx context.Context, runtimeInfo *state.
 // Both ingresses use regex paths. The NGINX ingress controller sorts regex locations by path length
 // (longest first), so the VSCode path /sandbox/{id}/vscode(/|$)(.*) is always tried before the
 // shorter agent catch-all /sandbox/{id}(/|$)(.*), ensuring VSCode requests reach the VSCode port.
-func (c *Client) createDirectRoutingIngresses(ctx context.Context, runtimeInfo *state.RuntimeInfo) error {
+//
+// The host is shared but the Ingress objects are not: each sandbox owns its pair, named after
+// the runtime, so object size stays constant as sandboxes are added and DeleteSandbox finds
+// them by name. There is no shared many-path Ingress that would need splitting.
+func (c *Client) directRoutingIngresses(runtimeInfo *state.RuntimeInfo) []*networkingv1.Ingress {
 	labels := map[string]string{
 		"app":        "openhands-runtime",
 		"runtime-id": runtimeInfo.RuntimeID,
@@ -596,6 +1472,24 @@ func (c *Client) createDirectRoutingIngresses(ctx context.Context, runtimeInfo *
 	agentAnnotations["nginx.ingress.kubernetes.io/rewrite-target"] = "/$2"
 
 	pathTypeImplementationSpecific := networkingv1.PathTypeImplementationSpecific
+
+	// Worker paths are more specific, so they are listed before the agent catch-all
+	workerPaths := make([]networkingv1.HTTPIngressPath, 0, len(c.config.WorkerPorts)+1)
+	for i, port := range c.config.WorkerPorts {
+		workerPaths = append(workerPaths, networkingv1.HTTPIngressPath{
+			Path:     fmt.Sprintf("/sandbox/%s/%s(/|$)(.*)", runtimeID, workerPortName(i)),
+			PathType: &pathTypeImplementationSpecific,
+			Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: runtimeInfo.ServiceName,
+					Port: networkingv1.ServiceBackendPort{
+						Number: portToInt32(port),
+					},
+				},
+			},
+		})
+	}
+
 	agentIngress := &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        runtimeInfo.IngressName,
@@ -610,37 +1504,11 @@ func (c *Client) createDirectRoutingIngresses(ctx context.Context, runtimeInfo *
 					Host: host,
 					IngressRuleValue: networkingv1.IngressRuleValue{
 						HTTP: &networkingv1.HTTPIngressRuleValue{
-							Paths: []networkingv1.HTTPIngressPath{
-								// Worker 1 (more specific, matched before agent catch-all)
-								{
-									Path:     fmt.Sprintf("/sandbox/%s/worker1(/|$)(.*)", runtimeID),
-									PathType: &pathTypeImplementationSpecific,
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: runtimeInfo.ServiceName,
-											Port: networkingv1.ServiceBackendPort{
-												Number: portToInt32(c.config.Worker1Port),
-											},
-										},
-									},
-								},
-								// Worker 2
-								{
-									Path:     fmt.Sprintf("/sandbox/%s/worker2(/|$)(.*)", runtimeID),
-									PathType: &pathTypeImplementationSpecific,
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: runtimeInfo.ServiceName,
-											Port: networkingv1.ServiceBackendPort{
-												Number: portToInt32(c.config.Worker2Port),
-											},
-										},
-									},
-								},
+							Paths: append(workerPaths,
 								// Agent server catch-all (must be last — least specific).
 								// VSCode paths are handled by the separate VSCode ingress which
 								// has a longer regex path, so NGINX tries it first (longest match).
-								{
+								networkingv1.HTTPIngressPath{
 									Path:     fmt.Sprintf("/sandbox/%s(/|$)(.*)", runtimeID),
 									PathType: &pathTypeImplementationSpecific,
 									Backend: networkingv1.IngressBackend{
@@ -652,7 +1520,7 @@ func (c *Client) createDirectRoutingIngresses(ctx context.Context, runtimeInfo *
 										},
 									},
 								},
-							},
+							),
 						},
 					},
 				},
@@ -669,10 +1537,6 @@ func (c *Client) createDirectRoutingIngresses(ctx context.Context, runtimeInfo *
 		},
 	}
 
-	if _, err := c.clientset.NetworkingV1().Ingresses(c.namespace).Create(ctx, agentIngress, metav1.CreateOptions{}); err != nil {
-		return fmt.Errorf("create agent ingress: %w", err)
-	}
-
 	// --- Ingress 2: VSCode (regex path, rewrite preserves full path) ---
 	// Uses regex so NGINX ingress controller sorts by path length (longest first).
 	// The VSCode path /sandbox/{id}/vscode(/|$)(.*) is always longer than the agent
@@ -726,13 +1590,7 @@ func (c *Client) createDirectRoutingIngresses(ctx context.Context, runtimeInfo *
 		},
 	}
 
-	if _, err := c.clientset.NetworkingV1().Ingresses(c.namespace).Create(ctx, vscodeIngress, metav1.CreateOptions{}); err != nil {
-		// Roll back the agent ingress we already created
-		_ = c.DeleteIngress(ctx, runtimeInfo.IngressName)
-		return fmt.Errorf("create vscode ingress: %w", err)
-	}
-
-	return nil
+	return []*networkingv1.Ingress{agentIngress, vscodeIngress}
 }
 
 // parsePodStatus extracts PodStatusInfo from a Kubernetes pod object.
@@ -740,12 +1598,16 @@ func parsePodStatus(pod *corev1.Pod) *PodStatusInfo {
 	status := types.PodStatusPending
 	restartCount := 0
 	restartReasons := []string{}
+	var restarts []types.RestartRecord
 	var lastTermReason, lastTermMessage string
 	var lastTermExitCode int
 
 	// Check container statuses
 	for _, containerStatus := range pod.Status.ContainerStatuses {
 		restartCount += int(containerStatus.RestartCount)
+		if record, ok := restartRecord(containerStatus); ok {
+			restarts = append(restarts, record)
+		}
 
 		if containerStatus.State.Waiting != nil {
 			if containerStatus.State.Waiting.Reason == "CrashLoopBackOff" {
@@ -800,14 +1662,234 @@ func parsePodStatus(pod *corev1.Pod) *PodStatusInfo {
 		Status:                  status,
 		RestartCount:            restartCount,
 		RestartReasons:          restartReasons,
+		Restarts:                restarts,
 		LastTerminationReason:   lastTermReason,
 		LastTerminationExitCode: lastTermExitCode,
 		LastTerminationMessage:  lastTermMessage,
+		Detail:                  podDetail(pod),
+	}
+}
+
+// podDetail copies a pod's phase, conditions and init/regular container states
+func podDetail(pod *corev1.Pod) *types.PodDetail {
+	detail := &types.PodDetail{Phase: string(pod.Status.Phase)}
+	for _, cond := range pod.Status.Conditions {
+		detail.Conditions = append(detail.Conditions, types.PodCondition{
+			Type:    string(cond.Type),
+			Status:  string(cond.Status),
+			Reason:  cond.Reason,
+			Message: cond.Message,
+		})
+	}
+	for _, cs := range pod.Status.InitContainerStatuses {
+		state := containerState(cs)
+		state.Init = true
+		detail.Containers = append(detail.Containers, state)
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		detail.Containers = append(detail.Containers, containerState(cs))
+	}
+	return detail
+}
+
+// containerState summarizes a container status as waiting, running or terminated
+func containerState(cs corev1.ContainerStatus) types.ContainerState {
+	state := types.ContainerState{
+		Name:         cs.Name,
+		Ready:        cs.Ready,
+		RestartCount: int(cs.RestartCount),
+	}
+	switch {
+	case cs.State.Waiting != nil:
+		state.State = "waiting"
+		state.Reason = cs.State.Waiting.Reason
+		state.Message = cs.State.Waiting.Message
+	case cs.State.Terminated != nil:
+		state.State = "terminated"
+		state.Reason = cs.State.Terminated.Reason
+		state.Message = cs.State.Terminated.Message
+		state.ExitCode = int(cs.State.Terminated.ExitCode)
+	case cs.State.Running != nil:
+		state.State = "running"
+	}
+	return state
+}
+
+// restartRecord builds a structured restart record from a container status.
+// Returns false for containers that have never restarted.
+func restartRecord(cs corev1.ContainerStatus) (types.RestartRecord, bool) {
+	lt := cs.LastTerminationState.Terminated
+	if cs.RestartCount == 0 && lt == nil {
+		return types.RestartRecord{}, false
+	}
+	record := types.RestartRecord{
+		Container: cs.Name,
+		Count:     int(cs.RestartCount),
+	}
+	if lt != nil {
+		record.Reason = lt.Reason
+		record.ExitCode = int(lt.ExitCode)
+		if !lt.FinishedAt.IsZero() {
+			finishedAt := lt.FinishedAt.Time
+			record.LastTimestamp = &finishedAt
+		}
+	}
+	return record, true
+}
+
+// agentContainerName is the name of the agent server container in sandbox pods
+const agentContainerName = "openhands-agent"
+
+// LogOptions selects which agent container logs StreamPodLogs returns
+type LogOptions struct {
+	Follow    bool  // keep the stream open and send new lines as they are written
+	TailLines int64 // only the last N lines; 0 returns the whole log
+	Previous  bool  // logs of the previous (crashed) container instance
+}
+
+// StreamPodLogs opens a stream of the agent container's logs. The stream ends when ctx
+// is cancelled, so callers should pass the client request context when following.
+func (c *Client) StreamPodLogs(ctx context.Context, podName string, opts LogOptions) (io.ReadCloser, error) {
+	logOpts := &corev1.PodLogOptions{
+		Container: agentContainerName,
+		Follow:    opts.Follow,
+		Previous:  opts.Previous,
+	}
+	if opts.TailLines > 0 {
+		tail := opts.TailLines
+		logOpts.TailLines = &tail
+	}
+	return c.clientset.CoreV1().Pods(c.namespace).GetLogs(podName, logOpts).Stream(ctx)
+}
+
+// ListPodEvents returns the Kubernetes Events recorded for a sandbox pod, oldest first.
+// Events expire after the cluster's event TTL (one hour by default).
+func (c *Client) ListPodEvents(ctx context.Context, podName string) ([]types.PodEvent, error) {
+	list, err := c.clientset.CoreV1().Events(c.namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.AndSelectors(
+			fields.OneTermEqualSelector("involvedObject.kind", "Pod"),
+			fields.OneTermEqualSelector("involvedObject.name", podName),
+		).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]types.PodEvent, 0, len(list.Items))
+	for _, e := range list.Items {
+		if e.InvolvedObject.Name != podName {
+			continue
+		}
+		first, last := e.FirstTimestamp.Time, e.LastTimestamp.Time
+		if first.IsZero() {
+			first = e.EventTime.Time
+		}
+		if last.IsZero() {
+			last = first
+		}
+		source := e.Source.Component
+		if source == "" {
+			source = e.ReportingController
+		}
+		events = append(events, types.PodEvent{
+			Type:           e.Type,
+			Reason:         e.Reason,
+			Message:        e.Message,
+			Count:          e.Count,
+			Source:         source,
+			FirstTimestamp: first,
+			LastTimestamp:  last,
+		})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastTimestamp.Before(events[j].LastTimestamp)
+	})
+	return events, nil
+}
+
+// selfTestSessionID is the session of the canary sandbox created by SelfTest
+const selfTestSessionID = "runtime-api-selftest"
+
+// SelfTest creates a canary sandbox (pod, service and ingress) from DEFAULT_IMAGE and
+// deletes it again, so missing RBAC permissions, quota or ingress problems show up at
+// startup instead of on the first /start. The pod is deleted before it needs to run.
+func (c *Client) SelfTest(ctx context.Context) error {
+	runtimeID := fmt.Sprintf("selftest-%d", time.Now().UnixNano())
+	info := &state.RuntimeInfo{
+		RuntimeID:     runtimeID,
+		SessionID:     selfTestSessionID,
+		SessionAPIKey: runtimeID,
+		Status:        types.StatusPending,
+		PodStatus:     types.PodStatusPending,
+		PodName:       fmt.Sprintf("runtime-%s", runtimeID),
+		ServiceName:   fmt.Sprintf("runtime-%s", runtimeID),
+		IngressName:   fmt.Sprintf("runtime-%s", runtimeID),
+	}
+	req := &types.StartRequest{Image: c.config.DefaultImage, SessionID: selfTestSessionID}
+
+	logger.Info("SelfTest: Creating canary sandbox %s", runtimeID)
+	createErr := c.CreateSandbox(ctx, req, info)
+	// Delete whatever was created, even after a partial failure
+	deleteErr := c.DeleteSandbox(context.WithoutCancel(ctx), info)
+	if createErr != nil {
+		return fmt.Errorf("create canary sandbox: %w", createErr)
+	}
+	if deleteErr != nil {
+		return fmt.Errorf("delete canary sandbox: %w", deleteErr)
+	}
+	logger.Info("SelfTest: Canary sandbox %s created and deleted", runtimeID)
+	return nil
+}
+
+// ValidatePriorityClass checks that the configured SANDBOX_PRIORITY_CLASS_NAME exists. It
+// returns nil when none is configured, and ErrPriorityClassNotFound when it is missing;
+// other errors (e.g. no RBAC permission to read PriorityClasses) mean it couldn't be checked.
+func (c *Client) ValidatePriorityClass(ctx context.Context) error {
+	name := c.config.SandboxPriorityClassName
+	if name == "" {
+		return nil
+	}
+	_, err := c.clientset.SchedulingV1().PriorityClasses().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("%w: %s", ErrPriorityClassNotFound, name)
 	}
+	return err
+}
+
+// CheckAPI verifies the Kubernetes API server is reachable and that we may list pods in
+// the sandbox namespace, with the cheapest possible request (a single-item list)
+func (c *Client) CheckAPI(ctx context.Context) error {
+	_, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	return err
 }
 
-// GetPodStatus retrieves the current status of a pod
+// GetPodStatus retrieves the current status of a pod. Concurrent callers for the same pod
+// share one K8s API call via singleflight, and the result is reused for POD_STATUS_CACHE_TTL.
 func (c *Client) GetPodStatus(ctx context.Context, podName string) (*PodStatusInfo, error) {
+	ttl := c.config.PodStatusCacheTTL
+	if ttl > 0 {
+		c.podStatusMu.Lock()
+		cached, ok := c.podStatusCache[podName]
+		c.podStatusMu.Unlock()
+		if ok && time.Since(cached.fetchedAt) < ttl {
+			return cached.info, nil
+		}
+	}
+
+	v, err, _ := c.podStatusSF.Do(podName, func() (interface{}, error) {
+		info, err := c.fetchPodStatus(ctx, podName)
+		if err == nil && ttl > 0 {
+			c.storePodStatus(podName, info, ttl)
+		}
+		return info, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*PodStatusInfo), nil
+}
+
+// fetchPodStatus gets a pod's status from the K8s API
+func (c *Client) fetchPodStatus(ctx context.Context, podName string) (*PodStatusInfo, error) {
 	pod, err := c.clientset.CoreV1().Pods(c.namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -821,6 +1903,28 @@ func (c *Client) GetPodStatus(ctx context.Context, podName string) (*PodStatusIn
 	return parsePodStatus(pod), nil
 }
 
+// storePodStatus caches a pod's status, dropping entries that have expired
+func (c *Client) storePodStatus(podName string, info *PodStatusInfo, ttl time.Duration) {
+	c.podStatusMu.Lock()
+	defer c.podStatusMu.Unlock()
+	if c.podStatusCache == nil {
+		c.podStatusCache = make(map[string]cachedPodStatus)
+	}
+	for name, cached := range c.podStatusCache {
+		if time.Since(cached.fetchedAt) >= ttl {
+			delete(c.podStatusCache, name)
+		}
+	}
+	c.podStatusCache[podName] = cachedPodStatus{info: info, fetchedAt: time.Now()}
+}
+
+// forgetPodStatus drops a pod's cached status after we create or delete it
+func (c *Client) forgetPodStatus(podName string) {
+	c.podStatusMu.Lock()
+	delete(c.podStatusCache, podName)
+	c.podStatusMu.Unlock()
+}
+
 // GetPodStatuses retrieves the status of multiple pods in a single Kubernetes API call.
 // It uses a label selector (app=openhands-runtime) to list all runtime pods, then filters
 // the results to only the requested pod names. Pods not found in the list result are
@@ -909,12 +2013,16 @@ type PodStatusInfo struct {
 	Status         types.PodStatus
 	RestartCount   int
 	RestartReasons []string
+	Restarts       []types.RestartRecord // Per-container restart details (reason, count, time, exit code)
 
 	// LastTermination captures why the container last exited (from lastState.terminated).
 	// Populated when RestartCount > 0 (i.e. the container has been restarted at least once).
 	LastTerminationReason   string // e.g. "OOMKilled", "Error", "Completed"
 	LastTerminationExitCode int    // e.g. 137 (SIGKILL/OOM), 1 (general error), 0 (clean exit)
 	LastTerminationMessage  string // optional message from the container
+
+	// Detail is the uncollapsed phase, conditions and container states
+	Detail *types.PodDetail
 }
 
 // DeletePod deletes a pod
@@ -923,6 +2031,7 @@ func (c *Client) DeletePod(ctx context.Context, podName string) error {
 	deleteOptions := metav1.DeleteOptions{
 		GracePeriodSeconds: &gracePeriodSeconds,
 	}
+	defer c.forgetPodStatus(podName)
 	return c.clientset.CoreV1().Pods(c.namespace).Delete(ctx, podName, deleteOptions)
 }
 
@@ -936,6 +2045,140 @@ func (c *Client) DeleteIngress(ctx context.Context, ingressName string) error {
 	return c.clientset.NetworkingV1().Ingresses(c.namespace).Delete(ctx, ingressName, metav1.DeleteOptions{})
 }
 
+// EnsureService recreates a sandbox's Service from runtimeInfo if it is missing, e.g.
+// after it was deleted by mistake while the pod kept running. An existing Service is
+// left as is.
+func (c *Client) EnsureService(ctx context.Context, runtimeInfo *state.RuntimeInfo) error {
+	err := c.createService(ctx, runtimeInfo)
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// EnsureSandboxResources recreates a sandbox's Service and Ingresses from runtimeInfo if
+// any are missing (deleted by mistake, or never created after a partial failure) and
+// returns the "kind/name" of each object it recreated. Existing objects are left as is.
+func (c *Client) EnsureSandboxResources(ctx context.Context, runtimeInfo *state.RuntimeInfo) ([]string, error) {
+	var recreated []string
+	_, err := c.clientset.CoreV1().Services(c.namespace).Get(ctx, runtimeInfo.ServiceName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		if err = c.EnsureService(ctx, runtimeInfo); err == nil {
+			recreated = append(recreated, "service/"+runtimeInfo.ServiceName)
+		}
+	}
+	if err != nil {
+		return recreated, fmt.Errorf("ensure service %s: %w", runtimeInfo.ServiceName, err)
+	}
+
+	for _, ingress := range c.sandboxIngresses(runtimeInfo) {
+		_, err := c.clientset.NetworkingV1().Ingresses(c.namespace).Get(ctx, ingress.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err = c.clientset.NetworkingV1().Ingresses(c.namespace).Create(ctx, ingress, metav1.CreateOptions{})
+			if err == nil {
+				recreated = append(recreated, "ingress/"+ingress.Name)
+			} else if errors.IsAlreadyExists(err) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return recreated, fmt.Errorf("ensure ingress %s: %w", ingress.Name, err)
+		}
+	}
+	return recreated, nil
+}
+
+// DeletePVC deletes a persistent volume claim
+func (c *Client) DeletePVC(ctx context.Context, pvcName string) error {
+	return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Delete(ctx, pvcName, metav1.DeleteOptions{})
+}
+
+// ownsPVC reports whether a PVC carries the labels the runtime API puts on objects it
+// creates for a sandbox (app=openhands-runtime, runtime-id=<id>).
+func (c *Client) ownsPVC(ctx context.Context, pvcName, runtimeID string) (bool, error) {
+	pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return pvc.Labels["app"] == "openhands-runtime" && pvc.Labels["runtime-id"] == runtimeID, nil
+}
+
+// claimPVC labels a PVC as owned by a runtime (app=openhands-runtime, runtime-id=<id>)
+// so ownsPVC lets teardown delete it
+func (c *Client) claimPVC(ctx context.Context, pvcName, runtimeID string) error {
+	pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if pvc.Labels == nil {
+		pvc.Labels = map[string]string{}
+	}
+	pvc.Labels["app"] = "openhands-runtime"
+	pvc.Labels["runtime-id"] = runtimeID
+	_, err = c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Update(ctx, pvc, metav1.UpdateOptions{})
+	return err
+}
+
+// sandboxIngressNames returns every ingress that may route to a sandbox: those the
+// current configuration would create plus any labelled with the runtime ID, so
+// ingresses from a different routing mode or worker-port count are not left behind.
+// It also returns the TLS secrets those ingresses name that belong to the runtime.
+func (c *Client) sandboxIngressNames(ctx context.Context, runtimeInfo *state.RuntimeInfo) (names, secrets []string) {
+	seen := map[string]bool{}
+	add := func(ingress *networkingv1.Ingress) {
+		if !seen[ingress.Name] {
+			seen[ingress.Name] = true
+			names = append(names, ingress.Name)
+		}
+		secrets = appendRuntimeTLSSecrets(secrets, runtimeInfo.RuntimeID, ingress)
+	}
+	for _, ingress := range c.sandboxIngresses(runtimeInfo) {
+		add(ingress)
+	}
+	// Before ingresses were split per host, subdomain mode created only IngressName
+	// and direct routing added IngressName-vscode; both are covered above.
+	list, err := c.clientset.NetworkingV1().Ingresses(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app=openhands-runtime,runtime-id=%s", runtimeInfo.RuntimeID),
+	})
+	if err != nil {
+		logger.Debug("DeleteSandbox: Could not list ingresses for runtime %s: %v", runtimeInfo.RuntimeID, err)
+		return names, secrets
+	}
+	for i := range list.Items {
+		add(&list.Items[i])
+	}
+	return names, secrets
+}
+
+// appendRuntimeTLSSecrets appends the TLS secrets an ingress names that belong to a
+// runtime. Only secrets named after the runtime (runtime-<id>-...) are ours to delete;
+// others, e.g. a shared wildcard certificate, are left alone.
+func appendRuntimeTLSSecrets(secrets []string, runtimeID string, ingress *networkingv1.Ingress) []string {
+	for _, tls := range ingress.Spec.TLS {
+		if strings.HasPrefix(tls.SecretName, "runtime-"+runtimeID+"-") && !slices.Contains(secrets, tls.SecretName) {
+			secrets = append(secrets, tls.SecretName)
+		}
+	}
+	return secrets
+}
+
+// deleteTLSSecrets deletes a runtime's TLS secrets. Secrets that are already gone are
+// ignored, as is a secret the service account may not delete.
+func (c *Client) deleteTLSSecrets(ctx context.Context, caller string, names []string) []error {
+	var deleteErrors []error
+	for _, name := range names {
+		err := c.clientset.CoreV1().Secrets(c.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		switch {
+		case err == nil, errors.IsNotFound(err):
+		case errors.IsForbidden(err):
+			logger.Debug("%s: Not permitted to delete secret %s: %v", caller, name, err)
+		default:
+			deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete secret %s: %w", name, err))
+		}
+	}
+	return deleteErrors
+}
+
 // DeleteSandbox deletes all resources for a sandbox
 func (c *Client) DeleteSandbox(ctx context.Context, runtimeInfo *state.RuntimeInfo) error {
 	if ddTracingEnabled {
@@ -949,31 +2192,50 @@ func (c *Client) DeleteSandbox(ctx context.Context, runtimeInfo *state.RuntimeIn
 	logger.Debug("DeleteSandbox: Deleting sandbox for runtime %s", runtimeInfo.RuntimeID)
 	var deleteErrors []error
 
-	// Delete in reverse order: ingress, service, pod
-	logger.Debug("DeleteSandbox: Deleting ingress %s", runtimeInfo.IngressName)
-	if err := c.DeleteIngress(ctx, runtimeInfo.IngressName); err != nil && !errors.IsNotFound(err) {
-		deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete ingress: %w", err))
-		logger.Info("DeleteSandbox: Error deleting ingress: %v", err)
+	// Delete in reverse order: ingresses (and their per-host TLS secrets), service, pod
+	ingressNames, tlsSecrets := c.sandboxIngressNames(ctx, runtimeInfo)
+	for _, name := range ingressNames {
+		logger.Debug("DeleteSandbox: Deleting ingress %s", name)
+		if err := c.DeleteIngress(ctx, name); err != nil && !errors.IsNotFound(err) {
+			deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete ingress %s: %w", name, err))
+			logger.Error("DeleteSandbox: Error deleting ingress %s: %v", name, err)
+		}
 	}
-	// In direct routing mode a second VSCode ingress is created. Always attempt to
-	// delete it; NotFound is silently ignored so this is safe in subdomain mode too.
-	vsCodeIngressName := runtimeInfo.IngressName + "-vscode"
-	logger.Debug("DeleteSandbox: Deleting vscode ingress %s", vsCodeIngressName)
-	if err := c.DeleteIngress(ctx, vsCodeIngressName); err != nil && !errors.IsNotFound(err) {
-		deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete vscode ingress: %w", err))
-		logger.Info("DeleteSandbox: Error deleting vscode ingress: %v", err)
+	for _, err := range c.deleteTLSSecrets(ctx, "DeleteSandbox", tlsSecrets) {
+		deleteErrors = append(deleteErrors, err)
+		logger.Error("DeleteSandbox: Error deleting TLS secret: %v", err)
 	}
 
 	logger.Debug("DeleteSandbox: Deleting service %s", runtimeInfo.ServiceName)
 	if err := c.DeleteService(ctx, runtimeInfo.ServiceName); err != nil && !errors.IsNotFound(err) {
 		deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete service: %w", err))
-		logger.Info("DeleteSandbox: Error deleting service: %v", err)
+		logger.Error("DeleteSandbox: Error deleting service: %v", err)
 	}
 
 	logger.Debug("DeleteSandbox: Deleting pod %s", runtimeInfo.PodName)
 	if err := c.DeletePod(ctx, runtimeInfo.PodName); err != nil && !errors.IsNotFound(err) {
 		deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete pod: %w", err))
-		logger.Info("DeleteSandbox: Error deleting pod: %v", err)
+		logger.Error("DeleteSandbox: Error deleting pod: %v", err)
+	}
+
+	// Teardown reclaims workspace storage unless DELETE_PVC_ON_STOP=false; pause
+	// (ScalePodToZero) never reaches here, so paused sandboxes keep their PVC. A
+	// workspace_pvc_name PVC belongs to the caller and is only deleted when /start
+	// labelled it as owned by this runtime (own_workspace_pvc).
+	if c.config.DeletePVCOnStop && runtimeInfo.WorkspacePVCName != "" {
+		owned, err := c.ownsPVC(ctx, runtimeInfo.WorkspacePVCName, runtimeInfo.RuntimeID)
+		if err != nil && !errors.IsNotFound(err) {
+			deleteErrors = append(deleteErrors, fmt.Errorf("failed to get workspace pvc: %w", err))
+			logger.Error("DeleteSandbox: Error getting workspace PVC: %v", err)
+		} else if owned {
+			logger.Debug("DeleteSandbox: Deleting workspace PVC %s", runtimeInfo.WorkspacePVCName)
+			if err := c.DeletePVC(ctx, runtimeInfo.WorkspacePVCName); err != nil && !errors.IsNotFound(err) {
+				deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete workspace pvc: %w", err))
+				logger.Error("DeleteSandbox: Error deleting workspace PVC: %v", err)
+			}
+		} else if err == nil {
+			logger.Debug("DeleteSandbox: Keeping caller-owned workspace PVC %s", runtimeInfo.WorkspacePVCName)
+		}
 	}
 
 	if len(deleteErrors) > 0 {
@@ -984,6 +2246,100 @@ func (c *Client) DeleteSandbox(ctx context.Context, runtimeInfo *state.RuntimeIn
 	return nil
 }
 
+// OrphanedSandbox groups the Services and Ingresses left behind by a runtime that has no
+// pod, with the TLS secrets issued for its ingresses
+type OrphanedSandbox struct {
+	RuntimeID string
+	Services  []string
+	Ingresses []string
+	Secrets   []string
+}
+
+// ListOrphanedSandboxes finds sandbox Services and Ingresses (labelled app=openhands-runtime)
+// whose runtime has no pod. Runtimes in tracked are skipped, since a paused runtime has no
+// pod by design, as are objects younger than minAge, which may belong to a sandbox that
+// is still being created.
+func (c *Client) ListOrphanedSandboxes(ctx context.Context, minAge time.Duration, tracked map[string]bool) ([]OrphanedSandbox, error) {
+	selector := metav1.ListOptions{LabelSelector: "app=openhands-runtime"}
+	pods, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, selector)
+	if err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+	services, err := c.clientset.CoreV1().Services(c.namespace).List(ctx, selector)
+	if err != nil {
+		return nil, fmt.Errorf("list services: %w", err)
+	}
+	ingresses, err := c.clientset.NetworkingV1().Ingresses(c.namespace).List(ctx, selector)
+	if err != nil {
+		return nil, fmt.Errorf("list ingresses: %w", err)
+	}
+
+	hasPod := make(map[string]bool, len(pods.Items))
+	for _, pod := range pods.Items {
+		hasPod[pod.Labels["runtime-id"]] = true
+	}
+	cutoff := time.Now().Add(-minAge)
+	byRuntime := map[string]*OrphanedSandbox{}
+	var order []string
+	orphanFor := func(meta metav1.ObjectMeta) *OrphanedSandbox {
+		runtimeID := meta.Labels["runtime-id"]
+		if runtimeID == "" || hasPod[runtimeID] || tracked[runtimeID] || meta.CreationTimestamp.Time.After(cutoff) {
+			return nil
+		}
+		orphan, ok := byRuntime[runtimeID]
+		if !ok {
+			orphan = &OrphanedSandbox{RuntimeID: runtimeID}
+			byRuntime[runtimeID] = orphan
+			order = append(order, runtimeID)
+		}
+		return orphan
+	}
+	for _, svc := range services.Items {
+		if orphan := orphanFor(svc.ObjectMeta); orphan != nil {
+			orphan.Services = append(orphan.Services, svc.Name)
+		}
+	}
+	for _, ingress := range ingresses.Items {
+		orphan := orphanFor(ingress.ObjectMeta)
+		if orphan == nil {
+			continue
+		}
+		orphan.Ingresses = append(orphan.Ingresses, ingress.Name)
+		orphan.Secrets = appendRuntimeTLSSecrets(orphan.Secrets, orphan.RuntimeID, &ingress)
+	}
+
+	orphans := make([]OrphanedSandbox, 0, len(order))
+	for _, runtimeID := range order {
+		orphan := byRuntime[runtimeID]
+		if primary := fmt.Sprintf("runtime-%s-tls", runtimeID); !slices.Contains(orphan.Secrets, primary) {
+			orphan.Secrets = append(orphan.Secrets, primary)
+		}
+		orphans = append(orphans, *orphan)
+	}
+	return orphans, nil
+}
+
+// DeleteOrphanedSandbox deletes an orphan's Ingresses, Services and TLS secrets. Objects
+// that are already gone are ignored, as is a secret the service account may not delete.
+func (c *Client) DeleteOrphanedSandbox(ctx context.Context, orphan OrphanedSandbox) error {
+	var deleteErrors []error
+	for _, name := range orphan.Ingresses {
+		if err := c.DeleteIngress(ctx, name); err != nil && !errors.IsNotFound(err) {
+			deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete ingress %s: %w", name, err))
+		}
+	}
+	for _, name := range orphan.Services {
+		if err := c.DeleteService(ctx, name); err != nil && !errors.IsNotFound(err) {
+			deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete service %s: %w", name, err))
+		}
+	}
+	deleteErrors = append(deleteErrors, c.deleteTLSSecrets(ctx, "DeleteOrphanedSandbox", orphan.Secrets)...)
+	if len(deleteErrors) > 0 {
+		return fmt.Errorf("errors deleting orphaned resources of runtime %s: %v", orphan.RuntimeID, deleteErrors)
+	}
+	return nil
+}
+
 // ScalePodToZero scales the pod to zero replicas (pause simulation)
 func (c *Client) ScalePodToZero(ctx context.Context, podName string) error {
 	if ddTracingEnabled {
@@ -997,6 +2353,7 @@ func (c *Client) ScalePodToZero(ctx context.Context, podName string) error {
 	logger.Debug("ScalePodToZero: Scaling pod %s to zero", podName)
 	// For now, we'll just delete the pod for pause
 	// A more sophisticated approach would use deployments/statefulsets
+	// The workspace PVC, if any, is left in place for resume
 	return c.DeletePod(ctx, podName)
 }
 
@@ -1026,18 +2383,17 @@ func (c *Client) buildRuntimeInfoFromPod(ctx context.Context, pod *corev1.Pod, r
 	}
 	sessionIDForHost := strings.ToLower(sessionID)
 	baseURL := fmt.Sprintf("https://%s.%s", sessionIDForHost, c.config.BaseDomain)
-	workHosts := map[string]int{
-		fmt.Sprintf("https://work-1-%s.%s", sessionIDForHost, c.config.BaseDomain): c.config.Worker1Port,
-		fmt.Sprintf("https://work-2-%s.%s", sessionIDForHost, c.config.BaseDomain): c.config.Worker2Port,
-	}
+	workHosts := WorkHosts(c.config, sessionID)
 	statusInfo, err := c.GetPodStatus(ctx, pod.Name)
 	podStatus := types.PodStatusUnknown
 	restartCount := 0
 	restartReasons := []string{}
+	var restarts []types.RestartRecord
 	if err == nil {
 		podStatus = statusInfo.Status
 		restartCount = statusInfo.RestartCount
 		restartReasons = statusInfo.RestartReasons
+		restarts = statusInfo.Restarts
 	}
 	// Use the pod's actual creation time so that cleanup thresholds are measured
 	// from when the pod was originally created, not from when it was discovered.
@@ -1047,6 +2403,15 @@ func (c *Client) buildRuntimeInfoFromPod(ctx context.Context, pod *corev1.Pod, r
 	if createdAt.IsZero() {
 		createdAt = time.Now()
 	}
+	ttl := durationAnnotation(pod, ttlAnnotation)
+	idleTimeout := durationAnnotation(pod, idleTimeoutAnnotation)
+	var workspacePVCName string
+	for _, vol := range pod.Spec.Volumes {
+		if vol.Name == workspaceVolumeName && vol.PersistentVolumeClaim != nil {
+			workspacePVCName = vol.PersistentVolumeClaim.ClaimName
+		}
+	}
+	cpuMillis, memoryBytes := podRequests(pod)
 	return &state.RuntimeInfo{
 		RuntimeID:        runtimeID,
 		SessionID:        sessionID,
@@ -1060,9 +2425,26 @@ func (c *Client) buildRuntimeInfoFromPod(ctx context.Context, pod *corev1.Pod, r
 		IngressName:      pod.Name,
 		RestartCount:     restartCount,
 		RestartReasons:   restartReasons,
+		Restarts:         restarts,
 		CreatedAt:        createdAt,
 		LastActivityTime: time.Now(),
+		TTL:              ttl,
+		IdleTimeout:      idleTimeout,
+		ProxyTimeout:     durationAnnotation(pod, proxyTimeoutAnnotation),
+		WorkspacePVCName: workspacePVCName,
+		Protected:        pod.Labels[protectedLabel] == "true",
+
+		CPURequestMillis:   cpuMillis,
+		MemoryRequestBytes: memoryBytes,
+	}
+}
+
+// durationAnnotation reads a positive whole-seconds annotation, returning 0 if absent or invalid
+func durationAnnotation(pod *corev1.Pod, key string) time.Duration {
+	if seconds, err := strconv.Atoi(pod.Annotations[key]); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
 	}
+	return 0
 }
 
 // DiscoverAllRuntimes scans all sandbox pods in the namespace and returns
@@ -1087,7 +2469,7 @@ func (c *Client) DiscoverAllRuntimes(ctx context.Context) ([]*state.RuntimeInfo,
 			continue
 		}
 		// Skip pods that are terminating or completed
-		if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		if !isLivePod(pod) {
 			continue
 		}
 		runtimes = append(runtimes, c.buildRuntimeInfoFromPod(ctx, pod, runtimeID, sessionID))
@@ -1095,11 +2477,14 @@ func (c *Client) DiscoverAllRuntimes(ctx context.Context) ([]*state.RuntimeInfo,
 	return runtimes, nil
 }
 
+// isLivePod reports whether a sandbox pod is neither terminating nor completed
+func isLivePod(pod *corev1.Pod) bool {
+	return pod.DeletionTimestamp == nil && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
+}
+
 // DiscoverRuntimeBySessionID finds a running sandbox pod by session-id label and
 // reconstructs RuntimeInfo. Used when in-memory state was lost (e.g. runtime API restart).
-// Returns nil if no matching pod exists.
-//
-//nolint:dupl // Mirrors DiscoverRuntimeByRuntimeID; differs only in selector and label extraction
+// Terminating or completed pods are ignored. Returns nil if no live matching pod exists.
 func (c *Client) DiscoverRuntimeBySessionID(ctx context.Context, sessionID string) (*state.RuntimeInfo, error) {
 	selector := fmt.Sprintf("app=openhands-runtime,session-id=%s", sessionID)
 	list, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
@@ -1108,25 +2493,20 @@ func (c *Client) DiscoverRuntimeBySessionID(ctx context.Context, sessionID strin
 	if err != nil {
 		return nil, fmt.Errorf("list pods: %w", err)
 	}
-	if len(list.Items) == 0 {
-		return nil, nil
-	}
-	pod := &list.Items[0]
-	runtimeID, ok := pod.Labels["runtime-id"]
-	if !ok || runtimeID == "" {
-		return nil, nil
-	}
-	if len(pod.Spec.Containers) == 0 {
-		return nil, nil
+	for i := range list.Items {
+		pod := &list.Items[i]
+		runtimeID, ok := pod.Labels["runtime-id"]
+		if !ok || runtimeID == "" || len(pod.Spec.Containers) == 0 || !isLivePod(pod) {
+			continue
+		}
+		return c.buildRuntimeInfoFromPod(ctx, pod, runtimeID, sessionID), nil
 	}
-	return c.buildRuntimeInfoFromPod(ctx, pod, runtimeID, sessionID), nil
+	return nil, nil
 }
 
 // DiscoverRuntimeByRuntimeID finds a sandbox pod by runtime-id label and
 // reconstructs RuntimeInfo. Used when in-memory state was lost (e.g. runtime API restart).
 // Returns nil if no matching pod exists.
-//
-//nolint:dupl // Mirrors DiscoverRuntimeBySessionID; differs only in selector and label extraction
 func (c *Client) DiscoverRuntimeByRuntimeID(ctx context.Context, runtimeID string) (*state.RuntimeInfo, error) {
 	selector := fmt.Sprintf("app=openhands-runtime,runtime-id=%s", runtimeID)
 	list, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
@@ -1149,6 +2529,48 @@ func (c *Client) DiscoverRuntimeByRuntimeID(ctx context.Context, runtimeID strin
 	return c.buildRuntimeInfoFromPod(ctx, pod, runtimeID, sessionID), nil
 }
 
+// ValidateWorkspacePVC checks that a PVC requested as a sandbox workspace exists and,
+// if it is ReadWriteOnce(Pod), isn't already mounted by another live sandbox pod.
+// Returns an error wrapping ErrWorkspacePVCNotFound or ErrWorkspacePVCInUse for those cases.
+func (c *Client) ValidateWorkspacePVC(ctx context.Context, pvcName string) error {
+	pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrWorkspacePVCNotFound, pvcName)
+		}
+		return fmt.Errorf("get pvc: %w", err)
+	}
+
+	singleWriter := false
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == corev1.ReadWriteOnce || mode == corev1.ReadWriteOncePod {
+			singleWriter = true
+		}
+	}
+	if !singleWriter {
+		return nil
+	}
+
+	list, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=openhands-runtime",
+	})
+	if err != nil {
+		return fmt.Errorf("list pods: %w", err)
+	}
+	for i := range list.Items {
+		pod := &list.Items[i]
+		if !isLivePod(pod) {
+			continue
+		}
+		for _, vol := range pod.Spec.Volumes {
+			if vol.PersistentVolumeClaim != nil && vol.PersistentVolumeClaim.ClaimName == pvcName {
+				return fmt.Errorf("%w: %s is mounted by %s", ErrWorkspacePVCInUse, pvcName, pod.Name)
+			}
+		}
+	}
+	return nil
+}
+
 // WaitForPodReady waits for a pod to become ready
 func (c *Client) WaitForPodReady(ctx context.Context, podName string, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
@@ -1160,7 +2582,7 @@ func (c *Client) WaitForPodReady(ctx context.Context, podName string, timeout ti
 	for {
 		select {
 		case <-ctx.Done():
-			return fmt.Errorf("timeout waiting for pod to be ready")
+			return ErrPodReadyTimeout
 		case <-ticker.C:
 			statusInfo, err := c.GetPodStatus(ctx, podName)
 			if err != nil {
@@ -1177,3 +2599,29 @@ func (c *Client) WaitForPodReady(ctx context.Context, podName string, timeout ti
 		}
 	}
 }
+
+// WaitForPodStarted waits until a pod is no longer pending: its images have been pulled
+// and its containers started, or it failed or was deleted. It returns ErrPodStartTimeout
+// if the pod is still pending after timeout.
+func (c *Client) WaitForPodStarted(ctx context.Context, podName string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ErrPodStartTimeout
+		case <-ticker.C:
+			statusInfo, err := c.GetPodStatus(ctx, podName)
+			if err != nil {
+				return err
+			}
+			if statusInfo.Status != types.PodStatusPending {
+				return nil
+			}
+		}
+	}
+}
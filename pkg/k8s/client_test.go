@@ -0,0 +1,2034 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/config"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/logger"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/state"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestClient(cfg *config.Config) *Client {
+	if cfg.Namespace == "" {
+		cfg.Namespace = "test"
+	}
+	return &Client{
+		clientset:   fake.NewSimpleClientset(),
+		config:      cfg,
+		namespace:   cfg.Namespace,
+		podCacheTTL: 3 * time.Second,
+	}
+}
+
+func testRuntimeInfo() *state.RuntimeInfo {
+	return &state.RuntimeInfo{
+		RuntimeID:     "abc123",
+		SessionID:     "session-1",
+		SessionAPIKey: "key",
+		PodName:       "runtime-abc123",
+		ServiceName:   "runtime-abc123",
+		IngressName:   "runtime-abc123",
+	}
+}
+
+// createTestPod runs createPod against the fake clientset and returns the stored pod.
+func createTestPod(t *testing.T, c *Client, req *types.StartRequest) *corev1.Pod {
+	t.Helper()
+	info := testRuntimeInfo()
+	if err := c.createPod(context.Background(), req, info); err != nil {
+		t.Fatalf("createPod failed: %v", err)
+	}
+	pod, err := c.clientset.CoreV1().Pods(c.namespace).Get(context.Background(), info.PodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get created pod: %v", err)
+	}
+	return pod
+}
+
+func TestIsExtendedResourceName(t *testing.T) {
+	cases := map[string]bool{
+		"nvidia.com/gpu":          true,
+		"amd.com/gpu":             true,
+		"cpu":                     false,
+		"memory":                  false,
+		"ephemeral-storage":       false,
+		"kubernetes.io/gpu":       false,
+		"requests.nvidia.com/gpu": false,
+		"nvidia.com/":             false,
+	}
+	for name, want := range cases {
+		if got := IsExtendedResourceName(name); got != want {
+			t.Errorf("IsExtendedResourceName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestCreatePod_GPU(t *testing.T) {
+	t.Run("No GPU requested", func(t *testing.T) {
+		c := newTestClient(&config.Config{
+			GPUNodeSelector:  map[string]string{"accelerator": "nvidia"},
+			GPUTolerationKey: "nvidia.com/gpu",
+		})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+
+		limits := pod.Spec.Containers[0].Resources.Limits
+		if _, ok := limits[corev1.ResourceName("nvidia.com/gpu")]; ok {
+			t.Error("Expected no GPU limit when GPUs are not requested")
+		}
+		if len(limits) != 2 {
+			t.Errorf("Expected only cpu and memory limits, got %v", limits)
+		}
+		if len(pod.Spec.NodeSelector) != 0 {
+			t.Errorf("Expected no node selector, got %v", pod.Spec.NodeSelector)
+		}
+		if len(pod.Spec.Tolerations) != 0 {
+			t.Errorf("Expected no tolerations, got %v", pod.Spec.Tolerations)
+		}
+	})
+
+	t.Run("Zero GPUs requested", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{
+			Image:     "test-image",
+			SessionID: "session-1",
+			GPU:       &types.GPURequest{Count: 0},
+		})
+
+		if _, ok := pod.Spec.Containers[0].Resources.Limits[corev1.ResourceName("nvidia.com/gpu")]; ok {
+			t.Error("Expected no GPU limit for a zero GPU count")
+		}
+	})
+
+	t.Run("GPU requested with default resource name", func(t *testing.T) {
+		c := newTestClient(&config.Config{
+			GPUNodeSelector:  map[string]string{"accelerator": "nvidia"},
+			GPUTolerationKey: "nvidia.com/gpu",
+		})
+		pod := createTestPod(t, c, &types.StartRequest{
+			Image:     "test-image",
+			SessionID: "session-1",
+			GPU:       &types.GPURequest{Count: 2},
+		})
+
+		qty, ok := pod.Spec.Containers[0].Resources.Limits[corev1.ResourceName("nvidia.com/gpu")]
+		if !ok {
+			t.Fatal("Expected nvidia.com/gpu limit to be set")
+		}
+		if qty.Value() != 2 {
+			t.Errorf("Expected 2 GPUs, got %d", qty.Value())
+		}
+		if req := pod.Spec.Containers[0].Resources.Requests[corev1.ResourceName("nvidia.com/gpu")]; req.Value() != 2 {
+			t.Errorf("Expected GPU request to match the limit of 2, got %d", req.Value())
+		}
+		if pod.Spec.NodeSelector["accelerator"] != "nvidia" {
+			t.Errorf("Expected GPU node selector to be applied, got %v", pod.Spec.NodeSelector)
+		}
+		if len(pod.Spec.Tolerations) != 1 || pod.Spec.Tolerations[0].Key != "nvidia.com/gpu" {
+			t.Errorf("Expected GPU toleration, got %v", pod.Spec.Tolerations)
+		}
+	})
+
+	t.Run("GPU requested with custom resource name", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{
+			Image:     "test-image",
+			SessionID: "session-1",
+			GPU:       &types.GPURequest{Count: 1, ResourceName: "amd.com/gpu"},
+		})
+
+		limits := pod.Spec.Containers[0].Resources.Limits
+		if qty, ok := limits[corev1.ResourceName("amd.com/gpu")]; !ok || qty.Value() != 1 {
+			t.Errorf("Expected 1 amd.com/gpu limit, got %v", limits)
+		}
+		if _, ok := limits[corev1.ResourceName("nvidia.com/gpu")]; ok {
+			t.Error("Expected no nvidia.com/gpu limit when a custom resource name is given")
+		}
+		if len(pod.Spec.NodeSelector) != 0 || len(pod.Spec.Tolerations) != 0 {
+			t.Error("Expected no GPU scheduling constraints when none are configured")
+		}
+	})
+}
+
+func TestCreatePod_TTLAnnotationRoundTrip(t *testing.T) {
+	c := newTestClient(&config.Config{})
+	info := testRuntimeInfo()
+	info.TTL = 30 * time.Minute
+	info.IdleTimeout = 45 * time.Minute
+	info.ProxyTimeout = 20 * time.Minute
+	req := &types.StartRequest{Image: "test-image", SessionID: info.SessionID}
+	if err := c.createPod(context.Background(), req, info); err != nil {
+		t.Fatalf("createPod failed: %v", err)
+	}
+
+	pod, err := c.clientset.CoreV1().Pods(c.namespace).Get(context.Background(), info.PodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get created pod: %v", err)
+	}
+	if pod.Annotations[ttlAnnotation] != "1800" {
+		t.Errorf("Expected TTL annotation '1800', got %q", pod.Annotations[ttlAnnotation])
+	}
+
+	discovered, err := c.DiscoverRuntimeByRuntimeID(context.Background(), info.RuntimeID)
+	if err != nil || discovered == nil {
+		t.Fatalf("Expected runtime to be discovered, got %v (err %v)", discovered, err)
+	}
+	if discovered.TTL != 30*time.Minute {
+		t.Errorf("Expected discovered TTL of 30m, got %v", discovered.TTL)
+	}
+	if pod.Annotations[idleTimeoutAnnotation] != "2700" {
+		t.Errorf("Expected idle timeout annotation '2700', got %q", pod.Annotations[idleTimeoutAnnotation])
+	}
+	if discovered.IdleTimeout != 45*time.Minute {
+		t.Errorf("Expected discovered idle timeout of 45m, got %v", discovered.IdleTimeout)
+	}
+	if pod.Annotations[proxyTimeoutAnnotation] != "1200" {
+		t.Errorf("Expected proxy timeout annotation '1200', got %q", pod.Annotations[proxyTimeoutAnnotation])
+	}
+	if discovered.ProxyTimeout != 20*time.Minute {
+		t.Errorf("Expected discovered proxy timeout of 20m, got %v", discovered.ProxyTimeout)
+	}
+}
+
+func TestRequestedResources(t *testing.T) {
+	cfg := &config.Config{SandboxCPURequest: "500m", SandboxMemoryRequest: "1Gi"}
+	tests := []struct {
+		name       string
+		req        *types.StartRequest
+		wantCPU    int64
+		wantMemory int64
+	}{
+		{"Configured base", &types.StartRequest{}, 500, 1 << 30},
+		{"Scaled by resource_factor", &types.StartRequest{ResourceFactor: 2}, 1000, 2 << 30},
+		{"Explicit requests", &types.StartRequest{ResourceFactor: 2, CPURequest: "250m", MemoryRequest: "512Mi"}, 250, 512 << 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, memory := RequestedResources(cfg, tt.req)
+			if cpu != tt.wantCPU || memory != tt.wantMemory {
+				t.Errorf("Expected %dm / %d bytes, got %dm / %d bytes", tt.wantCPU, tt.wantMemory, cpu, memory)
+			}
+		})
+	}
+
+	// Discovered sandboxes carry their pod's requests so the totals survive a restart
+	c := newTestClient(cfg)
+	info := testRuntimeInfo()
+	req := &types.StartRequest{Image: "test-image", SessionID: info.SessionID, ResourceFactor: 2}
+	if err := c.createPod(context.Background(), req, info); err != nil {
+		t.Fatalf("createPod failed: %v", err)
+	}
+	discovered, err := c.DiscoverRuntimeByRuntimeID(context.Background(), info.RuntimeID)
+	if err != nil || discovered == nil {
+		t.Fatalf("Expected runtime to be discovered, got %v (err %v)", discovered, err)
+	}
+	if discovered.CPURequestMillis != 1000 || discovered.MemoryRequestBytes != 2<<30 {
+		t.Errorf("Expected discovered requests of 1000m / 2Gi, got %dm / %d bytes", discovered.CPURequestMillis, discovered.MemoryRequestBytes)
+	}
+}
+
+func TestCreatePod_TraceAnnotations(t *testing.T) {
+	traced := func() *state.RuntimeInfo {
+		info := testRuntimeInfo()
+		info.StartTraceID, info.StartSpanID = 1234, 5678
+		return info
+	}
+	tests := []struct {
+		name     string
+		annotate bool
+		info     *state.RuntimeInfo
+		want     bool
+	}{
+		{"Traced request", true, traced(), true},
+		{"Untraced request", true, testRuntimeInfo(), false},
+		{"Disabled", false, traced(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&config.Config{AnnotatePodTrace: tt.annotate})
+			req := &types.StartRequest{Image: "test-image", SessionID: tt.info.SessionID}
+			if err := c.createPod(context.Background(), req, tt.info); err != nil {
+				t.Fatalf("createPod failed: %v", err)
+			}
+			pod, err := c.clientset.CoreV1().Pods(c.namespace).Get(context.Background(), tt.info.PodName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Failed to get created pod: %v", err)
+			}
+			if !tt.want {
+				if _, ok := pod.Annotations[traceIDAnnotation]; ok {
+					t.Error("Expected no trace ID annotation")
+				}
+				return
+			}
+			if pod.Annotations[traceIDAnnotation] != "1234" || pod.Annotations[spanIDAnnotation] != "5678" {
+				t.Errorf("Expected trace 1234 / span 5678 annotations, got %q / %q", pod.Annotations[traceIDAnnotation], pod.Annotations[spanIDAnnotation])
+			}
+		})
+	}
+}
+
+func TestCreatePod_ProtectedLabelRoundTrip(t *testing.T) {
+	c := newTestClient(&config.Config{})
+	info := testRuntimeInfo()
+	info.Protected = true
+	req := &types.StartRequest{Image: "test-image", SessionID: info.SessionID}
+	if err := c.createPod(context.Background(), req, info); err != nil {
+		t.Fatalf("createPod failed: %v", err)
+	}
+
+	pod, err := c.clientset.CoreV1().Pods(c.namespace).Get(context.Background(), info.PodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get created pod: %v", err)
+	}
+	if pod.Labels[protectedLabel] != "true" {
+		t.Errorf("Expected %s label 'true', got %q", protectedLabel, pod.Labels[protectedLabel])
+	}
+
+	discovered, err := c.DiscoverRuntimeByRuntimeID(context.Background(), info.RuntimeID)
+	if err != nil || discovered == nil {
+		t.Fatalf("Expected runtime to be discovered, got %v (err %v)", discovered, err)
+	}
+	if !discovered.Protected {
+		t.Error("Expected discovered runtime to be protected")
+	}
+
+	unprotected := createTestPod(t, newTestClient(&config.Config{}), req)
+	if _, ok := unprotected.Labels[protectedLabel]; ok {
+		t.Error("Expected no protected label by default")
+	}
+}
+
+func TestCreatePod_NoTTLAnnotation(t *testing.T) {
+	c := newTestClient(&config.Config{})
+	pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+	if _, ok := pod.Annotations[ttlAnnotation]; ok {
+		t.Error("Expected no TTL annotation when no TTL is set")
+	}
+}
+
+func TestCreatePod_Scheduling(t *testing.T) {
+	t.Run("No scheduling constraints by default", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		if pod.Spec.NodeSelector != nil || pod.Spec.Tolerations != nil || pod.Spec.Affinity != nil {
+			t.Errorf("Expected no scheduling constraints, got selector=%v tolerations=%v affinity=%v",
+				pod.Spec.NodeSelector, pod.Spec.Tolerations, pod.Spec.Affinity)
+		}
+	})
+
+	t.Run("Config defaults", func(t *testing.T) {
+		c := newTestClient(&config.Config{
+			SandboxNodeSelector: map[string]string{"pool": "sandbox"},
+			SandboxTolerations: []corev1.Toleration{
+				{Key: "sandbox", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule},
+			},
+		})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		if pod.Spec.NodeSelector["pool"] != "sandbox" {
+			t.Errorf("Expected node selector pool=sandbox, got %v", pod.Spec.NodeSelector)
+		}
+		if len(pod.Spec.Tolerations) != 1 || pod.Spec.Tolerations[0].Key != "sandbox" {
+			t.Errorf("Expected sandbox toleration, got %v", pod.Spec.Tolerations)
+		}
+	})
+
+	t.Run("Request values merged over config defaults", func(t *testing.T) {
+		c := newTestClient(&config.Config{
+			SandboxNodeSelector: map[string]string{"pool": "sandbox", "zone": "a"},
+			SandboxTolerations: []corev1.Toleration{
+				{Key: "sandbox", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+			},
+		})
+		affinity := &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "disk", Operator: corev1.NodeSelectorOpIn, Values: []string{"ssd"}},
+						},
+					}},
+				},
+			},
+		}
+		pod := createTestPod(t, c, &types.StartRequest{
+			Image:        "test-image",
+			SessionID:    "session-1",
+			NodeSelector: map[string]string{"pool": "untrusted"},
+			Tolerations: []corev1.Toleration{
+				{Key: "sandbox", Operator: corev1.TolerationOpEqual, Value: "untrusted", Effect: corev1.TaintEffectNoSchedule},
+			},
+			Affinity: affinity,
+		})
+
+		if pod.Spec.NodeSelector["pool"] != "untrusted" || pod.Spec.NodeSelector["zone"] != "a" {
+			t.Errorf("Expected request selector to override config default, got %v", pod.Spec.NodeSelector)
+		}
+		if len(pod.Spec.Tolerations) != 2 {
+			t.Fatalf("Expected 2 tolerations, got %v", pod.Spec.Tolerations)
+		}
+		for _, tol := range pod.Spec.Tolerations {
+			if tol.Key == "sandbox" && tol.Value != "untrusted" {
+				t.Errorf("Expected request toleration to replace config default, got %+v", tol)
+			}
+		}
+		if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+			t.Errorf("Expected request affinity to be applied, got %v", pod.Spec.Affinity)
+		}
+	})
+
+	t.Run("Config defaults kept alongside GPU toleration", func(t *testing.T) {
+		c := newTestClient(&config.Config{
+			SandboxTolerations: []corev1.Toleration{
+				{Key: "sandbox", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule},
+			},
+			GPUTolerationKey: "nvidia.com/gpu",
+		})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1", GPU: &types.GPURequest{Count: 1}})
+
+		keys := make(map[string]bool)
+		for _, tol := range pod.Spec.Tolerations {
+			keys[tol.Key] = true
+		}
+		if len(pod.Spec.Tolerations) != 2 || !keys["sandbox"] || !keys["nvidia.com/gpu"] {
+			t.Errorf("Expected sandbox and GPU tolerations, got %v", pod.Spec.Tolerations)
+		}
+	})
+}
+
+func TestCreatePod_DownwardAPI(t *testing.T) {
+	expected := map[string]string{
+		"OH_POD_NAME":      "metadata.name",
+		"OH_POD_NAMESPACE": "metadata.namespace",
+		"OH_NODE_NAME":     "spec.nodeName",
+		"OH_POD_IP":        "status.podIP",
+	}
+
+	t.Run("Enabled", func(t *testing.T) {
+		c := newTestClient(&config.Config{InjectDownwardAPI: true})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+
+		found := map[string]string{}
+		for _, env := range pod.Spec.Containers[0].Env {
+			if env.ValueFrom != nil && env.ValueFrom.FieldRef != nil {
+				found[env.Name] = env.ValueFrom.FieldRef.FieldPath
+			}
+		}
+		for name, fieldPath := range expected {
+			if found[name] != fieldPath {
+				t.Errorf("Expected %s to reference %s, got %q", name, fieldPath, found[name])
+			}
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+
+		for _, env := range pod.Spec.Containers[0].Env {
+			if _, ok := expected[env.Name]; ok {
+				t.Errorf("Expected no %s env var when downward API injection is disabled", env.Name)
+			}
+		}
+	})
+}
+
+func TestCreatePod_TraceCorrelation(t *testing.T) {
+	// Kubernetes keeps the last of duplicate env var names, so read them in order
+	lastEnv := func(pod *corev1.Pod) map[string]string {
+		env := map[string]string{}
+		for _, e := range pod.Spec.Containers[0].Env {
+			env[e.Name] = e.Value
+		}
+		return env
+	}
+
+	t.Run("Enabled", func(t *testing.T) {
+		c := newTestClient(&config.Config{InjectTraceCorrelation: true})
+		info := testRuntimeInfo()
+		pod := createTestPod(t, c, &types.StartRequest{
+			Image:       "test-image",
+			SessionID:   info.SessionID,
+			Environment: map[string]string{"OTEL_RESOURCE_ATTRIBUTES": "team=agents"},
+		})
+
+		env := lastEnv(pod)
+		if env["OH_TRACE_SESSION_ID"] != info.SessionID {
+			t.Errorf("Expected OH_TRACE_SESSION_ID %q, got %q", info.SessionID, env["OH_TRACE_SESSION_ID"])
+		}
+		if env["OH_TRACE_RUNTIME_ID"] != info.RuntimeID {
+			t.Errorf("Expected OH_TRACE_RUNTIME_ID %q, got %q", info.RuntimeID, env["OH_TRACE_RUNTIME_ID"])
+		}
+		want := "team=agents,openhands.session_id=" + info.SessionID + ",openhands.runtime_id=" + info.RuntimeID
+		if env["OTEL_RESOURCE_ATTRIBUTES"] != want {
+			t.Errorf("Expected OTEL_RESOURCE_ATTRIBUTES %q, got %q", want, env["OTEL_RESOURCE_ATTRIBUTES"])
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		env := lastEnv(pod)
+		if _, ok := env["OH_TRACE_SESSION_ID"]; ok {
+			t.Error("Expected no OH_TRACE_SESSION_ID when trace correlation is disabled")
+		}
+		if _, ok := env["OTEL_RESOURCE_ATTRIBUTES"]; ok {
+			t.Error("Expected no OTEL_RESOURCE_ATTRIBUTES when trace correlation is disabled")
+		}
+	})
+}
+
+func TestCreatePod_ReservedEnvVars(t *testing.T) {
+	lastEnv := func(pod *corev1.Pod) map[string]string {
+		env := map[string]string{}
+		for _, e := range pod.Spec.Containers[0].Env {
+			env[e.Name] = e.Value
+		}
+		return env
+	}
+	requested := map[string]string{
+		"SESSION_API_KEY":       "attacker-key",
+		"OH_SESSION_API_KEYS_0": "attacker-key",
+		"OH_RUNTIME_ID":         "other-runtime",
+		"OH_SESSION_EXTRA":      "x",
+		"MY_VAR":                "kept",
+	}
+
+	t.Run("Dropped by default", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1", Environment: requested})
+		env := lastEnv(pod)
+		if env["SESSION_API_KEY"] != "key" || env["OH_SESSION_API_KEYS_0"] != "key" {
+			t.Errorf("Expected generated session key to win, got SESSION_API_KEY=%q OH_SESSION_API_KEYS_0=%q", env["SESSION_API_KEY"], env["OH_SESSION_API_KEYS_0"])
+		}
+		if env["OH_RUNTIME_ID"] != "abc123" {
+			t.Errorf("Expected OH_RUNTIME_ID abc123, got %q", env["OH_RUNTIME_ID"])
+		}
+		if _, ok := env["OH_SESSION_EXTRA"]; ok {
+			t.Error("Expected OH_SESSION_EXTRA to be dropped")
+		}
+		if env["MY_VAR"] != "kept" {
+			t.Errorf("Expected unreserved MY_VAR to be kept, got %q", env["MY_VAR"])
+		}
+	})
+
+	t.Run("Explicitly allowed", func(t *testing.T) {
+		c := newTestClient(&config.Config{AllowedReservedEnvVars: []string{"OH_SESSION_EXTRA"}})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1", Environment: requested})
+		env := lastEnv(pod)
+		if env["OH_SESSION_EXTRA"] != "x" {
+			t.Errorf("Expected allowed OH_SESSION_EXTRA to be kept, got %q", env["OH_SESSION_EXTRA"])
+		}
+		if env["SESSION_API_KEY"] != "key" {
+			t.Errorf("Expected SESSION_API_KEY to stay generated, got %q", env["SESSION_API_KEY"])
+		}
+	})
+}
+
+func TestCreatePod_SecurityContext(t *testing.T) {
+	t.Run("Disabled by default", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		if pod.Spec.SecurityContext != nil || pod.Spec.Containers[0].SecurityContext != nil {
+			t.Error("Expected no security context when POD_SECURITY_RESTRICTED is disabled")
+		}
+	})
+
+	t.Run("Restricted", func(t *testing.T) {
+		c := newTestClient(&config.Config{PodSecurityRestricted: true, PodRunAsUser: 1000})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+
+		podSC := pod.Spec.SecurityContext
+		if podSC == nil {
+			t.Fatal("Expected pod security context to be set")
+		}
+		if podSC.RunAsNonRoot == nil || !*podSC.RunAsNonRoot {
+			t.Error("Expected runAsNonRoot to be true")
+		}
+		if podSC.RunAsUser == nil || *podSC.RunAsUser != 1000 {
+			t.Errorf("Expected runAsUser 1000, got %v", podSC.RunAsUser)
+		}
+		if podSC.SeccompProfile == nil || podSC.SeccompProfile.Type != corev1.SeccompProfileTypeRuntimeDefault {
+			t.Errorf("Expected RuntimeDefault seccomp profile, got %v", podSC.SeccompProfile)
+		}
+
+		sc := pod.Spec.Containers[0].SecurityContext
+		if sc == nil {
+			t.Fatal("Expected container security context to be set")
+		}
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			t.Error("Expected allowPrivilegeEscalation to be false")
+		}
+		if sc.Capabilities == nil || len(sc.Capabilities.Drop) != 1 || sc.Capabilities.Drop[0] != "ALL" {
+			t.Errorf("Expected all capabilities dropped, got %v", sc.Capabilities)
+		}
+	})
+
+	t.Run("Restricted without run-as user", func(t *testing.T) {
+		c := newTestClient(&config.Config{PodSecurityRestricted: true})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		if pod.Spec.SecurityContext.RunAsUser != nil {
+			t.Errorf("Expected runAsUser to be unset, got %v", *pod.Spec.SecurityContext.RunAsUser)
+		}
+	})
+}
+
+func TestParsePodStatus_RestartRecords(t *testing.T) {
+	finishedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	pod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:         "openhands-agent",
+					Ready:        true,
+					RestartCount: 3,
+					State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							Reason:     "OOMKilled",
+							ExitCode:   137,
+							FinishedAt: metav1.NewTime(finishedAt),
+						},
+					},
+				},
+				{
+					Name:  "sidecar",
+					Ready: true,
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				},
+			},
+		},
+	}
+
+	info := parsePodStatus(pod)
+
+	if info.RestartCount != 3 {
+		t.Errorf("Expected restart count 3, got %d", info.RestartCount)
+	}
+	if len(info.RestartReasons) != 1 || info.RestartReasons[0] != "last:OOMKilled" {
+		t.Errorf("Expected flat restart reasons to be kept, got %v", info.RestartReasons)
+	}
+	if len(info.Restarts) != 1 {
+		t.Fatalf("Expected 1 restart record (containers without restarts are skipped), got %v", info.Restarts)
+	}
+	record := info.Restarts[0]
+	if record.Container != "openhands-agent" || record.Reason != "OOMKilled" || record.Count != 3 || record.ExitCode != 137 {
+		t.Errorf("Unexpected restart record: %+v", record)
+	}
+	if record.LastTimestamp == nil || !record.LastTimestamp.Equal(finishedAt) {
+		t.Errorf("Expected last timestamp %v, got %v", finishedAt, record.LastTimestamp)
+	}
+}
+
+func TestParsePodStatus_NoRestarts(t *testing.T) {
+	pod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "openhands-agent", Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			},
+		},
+	}
+
+	if info := parsePodStatus(pod); info.Restarts != nil {
+		t.Errorf("Expected no restart records, got %v", info.Restarts)
+	}
+}
+
+func TestCreatePod_EvictionProtection(t *testing.T) {
+	t.Run("Disabled by default", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		for key := range evictionProtectionAnnotations {
+			if _, ok := pod.Annotations[key]; ok {
+				t.Errorf("Expected no %s annotation when eviction protection is disabled", key)
+			}
+		}
+		if pod.Spec.PriorityClassName != "" {
+			t.Errorf("Expected no priority class, got %q", pod.Spec.PriorityClassName)
+		}
+	})
+
+	t.Run("Enabled with priority class", func(t *testing.T) {
+		c := newTestClient(&config.Config{
+			EvictionProtection:       true,
+			SandboxPriorityClassName: "sandbox-critical",
+		})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		if pod.Annotations["descheduler.alpha.kubernetes.io/prevent-eviction"] != "true" {
+			t.Errorf("Expected descheduler prevent-eviction annotation, got %v", pod.Annotations)
+		}
+		if pod.Annotations["cluster-autoscaler.kubernetes.io/safe-to-evict"] != "false" {
+			t.Errorf("Expected cluster autoscaler safe-to-evict=false annotation, got %v", pod.Annotations)
+		}
+		if pod.Spec.PriorityClassName != "sandbox-critical" {
+			t.Errorf("Expected priority class 'sandbox-critical', got %q", pod.Spec.PriorityClassName)
+		}
+	})
+}
+
+func TestCreatePod_PriorityClass(t *testing.T) {
+	c := newTestClient(&config.Config{SandboxPriorityClassName: "sandbox-preemptible"})
+	pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+	if pod.Spec.PriorityClassName != "sandbox-preemptible" {
+		t.Errorf("Expected priority class 'sandbox-preemptible' without eviction protection, got %q", pod.Spec.PriorityClassName)
+	}
+}
+
+func TestValidatePriorityClass(t *testing.T) {
+	ctx := context.Background()
+	existing := &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "sandbox-preemptible"}, Value: 1000}
+
+	tests := []struct {
+		name      string
+		className string
+		wantErr   error
+	}{
+		{name: "Unset", className: ""},
+		{name: "Existing class", className: "sandbox-preemptible"},
+		{name: "Missing class", className: "does-not-exist", wantErr: ErrPriorityClassNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClientWithClientset(fake.NewSimpleClientset(existing), &config.Config{SandboxPriorityClassName: tt.className})
+			err := c.ValidatePriorityClass(ctx)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("Forbidden is not reported as missing", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset()
+		clientset.PrependReactor("get", "priorityclasses", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "scheduling.k8s.io", Resource: "priorityclasses"}, "sandbox-preemptible", errors.New("no RBAC"))
+		})
+		c := NewClientWithClientset(clientset, &config.Config{SandboxPriorityClassName: "sandbox-preemptible"})
+		err := c.ValidatePriorityClass(ctx)
+		if err == nil || errors.Is(err, ErrPriorityClassNotFound) {
+			t.Errorf("Expected a non-not-found error, got %v", err)
+		}
+	})
+}
+
+func TestCreatePod_ResourceFactorMetadata(t *testing.T) {
+	tests := []struct {
+		name           string
+		resourceFactor float64
+		wantFactor     string
+		wantCPURequest string
+		wantMemLimit   string
+	}{
+		{name: "Default factor", resourceFactor: 0, wantFactor: "1", wantCPURequest: "1000m", wantMemLimit: "4096Mi"},
+		{name: "Half size", resourceFactor: 0.5, wantFactor: "0.5", wantCPURequest: "500m", wantMemLimit: "2048Mi"},
+		{name: "Double size", resourceFactor: 2, wantFactor: "2", wantCPURequest: "2000m", wantMemLimit: "8192Mi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&config.Config{})
+			pod := createTestPod(t, c, &types.StartRequest{
+				Image:          "test-image",
+				SessionID:      "session-1",
+				ResourceFactor: tt.resourceFactor,
+			})
+			if got := pod.Annotations[resourceFactorAnnotation]; got != tt.wantFactor {
+				t.Errorf("Expected %s annotation %q, got %q", resourceFactorAnnotation, tt.wantFactor, got)
+			}
+			if got := pod.Labels[resourceFactorLabel]; got != tt.wantFactor {
+				t.Errorf("Expected %s label %q, got %q", resourceFactorLabel, tt.wantFactor, got)
+			}
+			if got := pod.Annotations[cpuRequestAnnotation]; got != tt.wantCPURequest {
+				t.Errorf("Expected %s annotation %q, got %q", cpuRequestAnnotation, tt.wantCPURequest, got)
+			}
+			if got := pod.Annotations[memoryLimitAnnotation]; got != tt.wantMemLimit {
+				t.Errorf("Expected %s annotation %q, got %q", memoryLimitAnnotation, tt.wantMemLimit, got)
+			}
+		})
+	}
+}
+
+func TestCreatePod_CustomMetadata(t *testing.T) {
+	c := newTestClient(&config.Config{
+		SandboxPodLabels:      map[string]string{"team": "platform", "cost-center": "default", "session-id": "clobbered"},
+		SandboxPodAnnotations: map[string]string{"sidecar.istio.io/inject": "false", "openhands.dev/cpu-request": "1m"},
+	})
+	pod := createTestPod(t, c, &types.StartRequest{
+		Image:          "test-image",
+		SessionID:      "session-1",
+		PodLabels:      map[string]string{"cost-center": "ml", "runtime-id": "clobbered"},
+		PodAnnotations: map[string]string{"sidecar.istio.io/inject": "true"},
+	})
+
+	wantLabels := map[string]string{
+		"team":        "platform",
+		"cost-center": "ml",
+		"app":         "openhands-runtime",
+		"runtime-id":  "abc123",
+		"session-id":  "session-1",
+	}
+	for k, want := range wantLabels {
+		if got := pod.Labels[k]; got != want {
+			t.Errorf("Expected label %s=%q, got %q", k, want, got)
+		}
+	}
+	if got := pod.Annotations["sidecar.istio.io/inject"]; got != "true" {
+		t.Errorf("Expected the request annotation to override the default, got %q", got)
+	}
+	if got := pod.Annotations[cpuRequestAnnotation]; got == "1m" {
+		t.Error("Expected the reserved cpu-request annotation not to be overridden")
+	}
+}
+
+func TestCreatePod_ConfiguredResources(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.Config
+		req        types.StartRequest
+		wantCPUReq string
+		wantCPULim string
+		wantMemReq string
+		wantMemLim string
+	}{
+		{
+			name:       "Configured base sizes",
+			cfg:        config.Config{SandboxCPURequest: "250m", SandboxCPULimit: "1", SandboxMemoryRequest: "512Mi", SandboxMemoryLimit: "1Gi"},
+			wantCPUReq: "250m", wantCPULim: "1", wantMemReq: "512Mi", wantMemLim: "1Gi",
+		},
+		{
+			name:       "Factor multiplies configured sizes",
+			cfg:        config.Config{SandboxCPURequest: "250m", SandboxCPULimit: "1", SandboxMemoryRequest: "512Mi", SandboxMemoryLimit: "1Gi"},
+			req:        types.StartRequest{ResourceFactor: 2},
+			wantCPUReq: "500m", wantCPULim: "2", wantMemReq: "1Gi", wantMemLim: "2Gi",
+		},
+		{
+			name:       "Guaranteed QoS",
+			cfg:        config.Config{SandboxQoSMode: "guaranteed"},
+			req:        types.StartRequest{ResourceFactor: 0.5},
+			wantCPUReq: "500m", wantCPULim: "500m", wantMemReq: "1Gi", wantMemLim: "1Gi",
+		},
+		{
+			name:       "Guaranteed QoS follows explicit requests",
+			cfg:        config.Config{SandboxQoSMode: "guaranteed"},
+			req:        types.StartRequest{CPURequest: "250m", MemoryRequest: "16Gi", CPULimit: "4"},
+			wantCPUReq: "250m", wantCPULim: "4", wantMemReq: "16Gi", wantMemLim: "16Gi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&tt.cfg)
+			tt.req.Image = "test-image"
+			tt.req.SessionID = "session-1"
+			pod := createTestPod(t, c, &tt.req)
+
+			resources := pod.Spec.Containers[0].Resources
+			checks := []struct {
+				what string
+				got  resource.Quantity
+				want string
+			}{
+				{"cpu request", resources.Requests[corev1.ResourceCPU], tt.wantCPUReq},
+				{"cpu limit", resources.Limits[corev1.ResourceCPU], tt.wantCPULim},
+				{"memory request", resources.Requests[corev1.ResourceMemory], tt.wantMemReq},
+				{"memory limit", resources.Limits[corev1.ResourceMemory], tt.wantMemLim},
+			}
+			for _, check := range checks {
+				if check.got.Cmp(resource.MustParse(check.want)) != 0 {
+					t.Errorf("Expected %s %s, got %s", check.what, check.want, check.got.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCreatePod_ExplicitResources(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        types.StartRequest
+		wantCPUReq string
+		wantCPULim string
+		wantMemReq string
+		wantMemLim string
+	}{
+		{
+			name:       "Factor fallback",
+			req:        types.StartRequest{ResourceFactor: 2},
+			wantCPUReq: "2", wantCPULim: "4", wantMemReq: "4Gi", wantMemLim: "8Gi",
+		},
+		{
+			name:       "All explicit",
+			req:        types.StartRequest{CPURequest: "250m", CPULimit: "500m", MemoryRequest: "16Gi", MemoryLimit: "32Gi"},
+			wantCPUReq: "250m", wantCPULim: "500m", wantMemReq: "16Gi", wantMemLim: "32Gi",
+		},
+		{
+			name:       "Explicit override mixed with factor",
+			req:        types.StartRequest{ResourceFactor: 0.5, MemoryLimit: "12Gi"},
+			wantCPUReq: "500m", wantCPULim: "1", wantMemReq: "1Gi", wantMemLim: "12Gi",
+		},
+		{
+			name:       "Request above defaulted limit raises it",
+			req:        types.StartRequest{MemoryRequest: "16Gi"},
+			wantCPUReq: "1", wantCPULim: "2", wantMemReq: "16Gi", wantMemLim: "16Gi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&config.Config{})
+			tt.req.Image = "test-image"
+			tt.req.SessionID = "session-1"
+			pod := createTestPod(t, c, &tt.req)
+
+			resources := pod.Spec.Containers[0].Resources
+			checks := []struct {
+				what string
+				got  resource.Quantity
+				want string
+			}{
+				{"cpu request", resources.Requests[corev1.ResourceCPU], tt.wantCPUReq},
+				{"cpu limit", resources.Limits[corev1.ResourceCPU], tt.wantCPULim},
+				{"memory request", resources.Requests[corev1.ResourceMemory], tt.wantMemReq},
+				{"memory limit", resources.Limits[corev1.ResourceMemory], tt.wantMemLim},
+			}
+			for _, check := range checks {
+				if check.got.Cmp(resource.MustParse(check.want)) != 0 {
+					t.Errorf("Expected %s %s, got %s", check.what, check.want, check.got.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCreatePod_LivenessProbe(t *testing.T) {
+	t.Run("Disabled by default", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		if pod.Spec.Containers[0].LivenessProbe != nil {
+			t.Errorf("Expected no liveness probe by default, got %+v", pod.Spec.Containers[0].LivenessProbe)
+		}
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		c := newTestClient(&config.Config{
+			AgentServerPort:               60000,
+			LivenessProbeEnabled:          true,
+			LivenessProbePeriod:           20 * time.Second,
+			LivenessProbeFailureThreshold: 4,
+			LivenessProbeInitialDelay:     3 * time.Minute,
+		})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		probe := pod.Spec.Containers[0].LivenessProbe
+		if probe == nil {
+			t.Fatal("Expected a liveness probe when enabled")
+		}
+		if probe.HTTPGet == nil || probe.HTTPGet.Path != "/alive" || probe.HTTPGet.Port.IntValue() != 60000 {
+			t.Errorf("Expected HTTP GET /alive on port 60000, got %+v", probe.HTTPGet)
+		}
+		if probe.PeriodSeconds != 20 {
+			t.Errorf("Expected period 20s, got %d", probe.PeriodSeconds)
+		}
+		if probe.FailureThreshold != 4 {
+			t.Errorf("Expected failure threshold 4, got %d", probe.FailureThreshold)
+		}
+		if probe.InitialDelaySeconds != 180 {
+			t.Errorf("Expected initial delay 180s, got %d", probe.InitialDelaySeconds)
+		}
+	})
+}
+
+func TestCreatePod_AgentProbeScheme(t *testing.T) {
+	tests := []struct {
+		name     string
+		scheme   string
+		expected corev1.URIScheme
+	}{
+		{"Default", "", corev1.URISchemeHTTP},
+		{"HTTP", "HTTP", corev1.URISchemeHTTP},
+		{"HTTPS", "HTTPS", corev1.URISchemeHTTPS},
+		{"Lowercase https", "https", corev1.URISchemeHTTPS},
+		{"Unknown falls back to HTTP", "TCP", corev1.URISchemeHTTP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&config.Config{AgentProbeScheme: tt.scheme, LivenessProbeEnabled: true})
+			pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+			container := pod.Spec.Containers[0]
+			probes := map[string]*corev1.Probe{
+				"startup":   container.StartupProbe,
+				"readiness": container.ReadinessProbe,
+				"liveness":  container.LivenessProbe,
+			}
+			for kind, probe := range probes {
+				if probe == nil || probe.HTTPGet == nil {
+					t.Fatalf("Expected an HTTP %s probe", kind)
+				}
+				if probe.HTTPGet.Scheme != tt.expected {
+					t.Errorf("Expected %s probe scheme %s, got %s", kind, tt.expected, probe.HTTPGet.Scheme)
+				}
+			}
+		})
+	}
+}
+
+func TestCreatePod_ImagePullPolicy(t *testing.T) {
+	const digest = "ghcr.io/openhands/runtime@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name     string
+		policy   string
+		image    string
+		expected corev1.PullPolicy
+	}{
+		{"Default tag", "", "ghcr.io/openhands/runtime:latest", corev1.PullAlways},
+		{"Default digest", "", digest, corev1.PullIfNotPresent},
+		{"Always", "Always", "ghcr.io/openhands/runtime:1.0", corev1.PullAlways},
+		{"Always overrides digest", "Always", digest, corev1.PullAlways},
+		{"IfNotPresent", "IfNotPresent", "ghcr.io/openhands/runtime:1.0", corev1.PullIfNotPresent},
+		{"Never", "Never", "ghcr.io/openhands/runtime:1.0", corev1.PullNever},
+		{"Case insensitive", "ifnotpresent", "ghcr.io/openhands/runtime:1.0", corev1.PullIfNotPresent},
+		{"Unknown falls back", "Sometimes", "ghcr.io/openhands/runtime:1.0", corev1.PullAlways},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&config.Config{ImagePullPolicy: tt.policy})
+			pod := createTestPod(t, c, &types.StartRequest{Image: tt.image, SessionID: "session-1"})
+			if got := pod.Spec.Containers[0].ImagePullPolicy; got != tt.expected {
+				t.Errorf("Expected pull policy %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCreatePod_ReadinessPortRole(t *testing.T) {
+	base := config.Config{AgentServerPort: 60000, VSCodePort: 60001, WorkerPorts: []int{12000, 12001}}
+	tests := []struct {
+		role     string
+		httpPort int // agent: HTTP GET /alive
+		tcpPort  int // other roles: TCP connect
+	}{
+		{"", 60000, 0},
+		{"agent", 60000, 0},
+		{"vscode", 0, 60001},
+		{"worker1", 0, 12000},
+		{"worker2", 0, 12001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			cfg := base
+			cfg.SandboxReadinessPortRole = tt.role
+			c := newTestClient(&cfg)
+			pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+			probe := pod.Spec.Containers[0].ReadinessProbe
+			if tt.httpPort != 0 {
+				if probe.HTTPGet == nil || probe.HTTPGet.Path != "/alive" || probe.HTTPGet.Port.IntValue() != tt.httpPort {
+					t.Errorf("Expected HTTP /alive readiness probe on %d, got %+v", tt.httpPort, probe.ProbeHandler)
+				}
+				return
+			}
+			if probe.TCPSocket == nil || probe.TCPSocket.Port.IntValue() != tt.tcpPort || probe.HTTPGet != nil {
+				t.Errorf("Expected TCP readiness probe on %d, got %+v", tt.tcpPort, probe.ProbeHandler)
+			}
+			// Startup still waits for the agent server
+			if startup := pod.Spec.Containers[0].StartupProbe; startup.HTTPGet == nil || startup.HTTPGet.Port.IntValue() != 60000 {
+				t.Errorf("Expected startup probe to stay on the agent port, got %+v", startup.ProbeHandler)
+			}
+		})
+	}
+}
+
+func TestReadinessProbePort_Invalid(t *testing.T) {
+	for _, role := range []string{"worker0", "worker3", "workerx", "database"} {
+		cfg := &config.Config{WorkerPorts: []int{12000, 12001}, SandboxReadinessPortRole: role}
+		if _, err := ReadinessProbePort(cfg); err == nil {
+			t.Errorf("Expected error for readiness port role %q", role)
+		}
+	}
+}
+
+func TestCreateIngress_CustomAnnotations(t *testing.T) {
+	// ALLOWED_INGRESS_ANNOTATION_KEYS is applied by config.LoadConfig; whatever remains
+	// in SandboxIngressAnnotations is put on every ingress as-is
+	configured := map[string]string{
+		"cert-manager.io/issuer":                            "my-issuer",
+		"nginx.ingress.kubernetes.io/configuration-snippet": "more_set_headers X-Debug: 1;",
+	}
+	c := newTestClient(&config.Config{
+		BaseDomain:                "sandbox.example.com",
+		SandboxIngressAnnotations: configured,
+	})
+	info := testRuntimeInfo()
+	if err := c.createIngress(context.Background(), info); err != nil {
+		t.Fatalf("createIngress failed: %v", err)
+	}
+	ingress, err := c.clientset.NetworkingV1().Ingresses(c.namespace).Get(context.Background(), info.IngressName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get ingress: %v", err)
+	}
+	for key, value := range configured {
+		if got := ingress.Annotations[key]; got != value {
+			t.Errorf("Expected annotation %s=%q, got %q", key, value, got)
+		}
+	}
+	if ingress.Annotations["nginx.ingress.kubernetes.io/ssl-redirect"] != "true" {
+		t.Error("Expected built-in ssl-redirect annotation to be kept")
+	}
+}
+
+func TestCreateSandbox_WorkerPorts(t *testing.T) {
+	ctx := context.Background()
+	ports := []int{12000, 12001, 12002}
+
+	t.Run("Subdomain routing", func(t *testing.T) {
+		c := newTestClient(&config.Config{BaseDomain: "sandbox.example.com", WorkerPorts: ports})
+		info := testRuntimeInfo()
+		req := &types.StartRequest{Image: "test-image", SessionID: info.SessionID}
+		if err := c.createPod(ctx, req, info); err != nil {
+			t.Fatalf("createPod failed: %v", err)
+		}
+		if err := c.createService(ctx, info); err != nil {
+			t.Fatalf("createService failed: %v", err)
+		}
+		if err := c.createIngress(ctx, info); err != nil {
+			t.Fatalf("createIngress failed: %v", err)
+		}
+
+		pod, _ := c.clientset.CoreV1().Pods(c.namespace).Get(ctx, info.PodName, metav1.GetOptions{})
+		containerPorts := map[string]int32{}
+		for _, p := range pod.Spec.Containers[0].Ports {
+			containerPorts[p.Name] = p.ContainerPort
+		}
+		env := map[string]string{}
+		for _, e := range pod.Spec.Containers[0].Env {
+			env[e.Name] = e.Value
+		}
+		svc, _ := c.clientset.CoreV1().Services(c.namespace).Get(ctx, info.ServiceName, metav1.GetOptions{})
+		servicePorts := map[string]int32{}
+		for _, p := range svc.Spec.Ports {
+			servicePorts[p.Name] = p.Port
+		}
+		ingresses, _ := c.clientset.NetworkingV1().Ingresses(c.namespace).List(ctx, metav1.ListOptions{})
+		ruleHosts := map[string]int32{}
+		for _, ingress := range ingresses.Items {
+			for _, rule := range ingress.Spec.Rules {
+				ruleHosts[rule.Host] = rule.HTTP.Paths[0].Backend.Service.Port.Number
+			}
+		}
+
+		for i, port := range ports {
+			name := fmt.Sprintf("worker%d", i+1)
+			if containerPorts[name] != int32(port) {
+				t.Errorf("Expected container port %s=%d, got %v", name, port, containerPorts)
+			}
+			if servicePorts[name] != int32(port) {
+				t.Errorf("Expected service port %s=%d, got %v", name, port, servicePorts)
+			}
+			if envName := fmt.Sprintf("WORKER_%d", i+1); env[envName] != strconv.Itoa(port) {
+				t.Errorf("Expected env %s=%d, got %q", envName, port, env[envName])
+			}
+			host := fmt.Sprintf("work-%d-session-1.sandbox.example.com", i+1)
+			if ruleHosts[host] != int32(port) {
+				t.Errorf("Expected ingress rule %s -> %d, got %v", host, port, ruleHosts)
+			}
+		}
+		if len(ingresses.Items) != 5 || len(ruleHosts) != 5 {
+			t.Errorf("Expected 5 single-host ingresses, got %d ingresses for %d hosts", len(ingresses.Items), len(ruleHosts))
+		}
+	})
+
+	t.Run("Direct routing", func(t *testing.T) {
+		c := newTestClient(&config.Config{BaseDomain: "sandbox.example.com", WorkerPorts: ports, DirectRouting: true})
+		info := testRuntimeInfo()
+		if err := c.createIngress(ctx, info); err != nil {
+			t.Fatalf("createIngress failed: %v", err)
+		}
+		ingress, _ := c.clientset.NetworkingV1().Ingresses(c.namespace).Get(ctx, info.IngressName, metav1.GetOptions{})
+		paths := ingress.Spec.Rules[0].HTTP.Paths
+		if len(paths) != len(ports)+1 {
+			t.Fatalf("Expected %d paths, got %d", len(ports)+1, len(paths))
+		}
+		if paths[2].Path != "/sandbox/abc123/worker3(/|$)(.*)" || paths[2].Backend.Service.Port.Number != 12002 {
+			t.Errorf("Expected worker3 path to port 12002, got %s -> %d", paths[2].Path, paths[2].Backend.Service.Port.Number)
+		}
+		if paths[3].Path != "/sandbox/abc123(/|$)(.*)" {
+			t.Errorf("Expected agent catch-all last, got %s", paths[3].Path)
+		}
+		// The agent and worker paths strip the /sandbox/{id}[/workerN] prefix, keeping the subpath
+		if ingress.Annotations["nginx.ingress.kubernetes.io/use-regex"] != "true" ||
+			ingress.Annotations["nginx.ingress.kubernetes.io/rewrite-target"] != "/$2" {
+			t.Errorf("Expected regex paths rewritten to /$2, got %v", ingress.Annotations)
+		}
+
+		vscode, err := c.clientset.NetworkingV1().Ingresses(c.namespace).Get(ctx, info.IngressName+"-vscode", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Expected a VSCode ingress: %v", err)
+		}
+		vscodePaths := vscode.Spec.Rules[0].HTTP.Paths
+		if len(vscodePaths) != 1 || vscodePaths[0].Path != "/sandbox/abc123/vscode(/|$)(.*)" || vscodePaths[0].Backend.Service.Port.Number != portToInt32(c.config.VSCodePort) {
+			t.Errorf("Expected a single VSCode path to the VSCode port, got %+v", vscodePaths)
+		}
+		// VSCode is served under its base path, so the full path is kept
+		if vscode.Annotations["nginx.ingress.kubernetes.io/use-regex"] != "true" ||
+			vscode.Annotations["nginx.ingress.kubernetes.io/rewrite-target"] != "/sandbox/abc123/vscode/$2" {
+			t.Errorf("Expected VSCode rewrite to keep its base path, got %v", vscode.Annotations)
+		}
+	})
+
+	t.Run("Work hosts", func(t *testing.T) {
+		hosts := WorkHosts(&config.Config{BaseDomain: "sandbox.example.com", WorkerPorts: ports}, "Session-1")
+		if len(hosts) != 3 || hosts["https://work-3-session-1.sandbox.example.com"] != 12002 {
+			t.Errorf("Expected 3 work hosts including work-3, got %v", hosts)
+		}
+	})
+}
+
+func TestCreateSandbox_WorkerPortCounts(t *testing.T) {
+	ctx := context.Background()
+	for _, ports := range [][]int{{}, {12000, 12001}, {12000, 12001, 12002, 12003}} {
+		t.Run(fmt.Sprintf("%d worker ports", len(ports)), func(t *testing.T) {
+			cfg := &config.Config{BaseDomain: "sandbox.example.com", AgentServerPort: 60000, VSCodePort: 60001, WorkerPorts: ports}
+			c := newTestClient(cfg)
+			info := testRuntimeInfo()
+			if err := c.CreateSandbox(ctx, &types.StartRequest{Image: "test-image", SessionID: info.SessionID}, info); err != nil {
+				t.Fatalf("CreateSandbox failed: %v", err)
+			}
+
+			pod, _ := c.clientset.CoreV1().Pods(c.namespace).Get(ctx, info.PodName, metav1.GetOptions{})
+			svc, _ := c.clientset.CoreV1().Services(c.namespace).Get(ctx, info.ServiceName, metav1.GetOptions{})
+			ingresses, _ := c.clientset.NetworkingV1().Ingresses(c.namespace).List(ctx, metav1.ListOptions{})
+			// Agent and VSCode plus one per worker
+			if n := len(pod.Spec.Containers[0].Ports); n != 2+len(ports) {
+				t.Errorf("Expected %d container ports, got %d", 2+len(ports), n)
+			}
+			if n := len(svc.Spec.Ports); n != 2+len(ports) {
+				t.Errorf("Expected %d service ports, got %d", 2+len(ports), n)
+			}
+			if n := len(ingresses.Items); n != 2+len(ports) {
+				t.Errorf("Expected %d ingresses, got %d", 2+len(ports), n)
+			}
+			workerEnv := 0
+			for _, e := range pod.Spec.Containers[0].Env {
+				if strings.HasPrefix(e.Name, "WORKER_") {
+					workerEnv++
+				}
+			}
+			if workerEnv != len(ports) {
+				t.Errorf("Expected %d WORKER_N env vars, got %d", len(ports), workerEnv)
+			}
+			hosts := WorkHosts(cfg, info.SessionID)
+			if len(hosts) != len(ports) {
+				t.Errorf("Expected %d work hosts, got %v", len(ports), hosts)
+			}
+			for i, port := range ports {
+				host := fmt.Sprintf("https://work-%d-session-1.sandbox.example.com", i+1)
+				if hosts[host] != port {
+					t.Errorf("Expected %s -> %d, got %d", host, port, hosts[host])
+				}
+			}
+		})
+	}
+}
+
+func TestCreatePod_WorkspacePVC(t *testing.T) {
+	c := newTestClient(&config.Config{WorkspaceMountPath: "/workspace"})
+	pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1", WorkspacePVCName: "ws-1"})
+
+	var claim string
+	for _, vol := range pod.Spec.Volumes {
+		if vol.Name == workspaceVolumeName && vol.PersistentVolumeClaim != nil {
+			claim = vol.PersistentVolumeClaim.ClaimName
+		}
+	}
+	if claim != "ws-1" {
+		t.Errorf("Expected workspace volume for PVC ws-1, got %q", claim)
+	}
+	mountPath := ""
+	for _, m := range pod.Spec.Containers[0].VolumeMounts {
+		if m.Name == workspaceVolumeName {
+			mountPath = m.MountPath
+		}
+	}
+	if mountPath != "/workspace" {
+		t.Errorf("Expected workspace mounted at /workspace, got %q", mountPath)
+	}
+
+	discovered := c.buildRuntimeInfoFromPod(context.Background(), pod, "abc123", "session-1")
+	if discovered.WorkspacePVCName != "ws-1" {
+		t.Errorf("Expected discovered WorkspacePVCName ws-1, got %q", discovered.WorkspacePVCName)
+	}
+}
+
+func TestCreatePod_InitContainer(t *testing.T) {
+	init := &types.InitContainerRequest{
+		Image:       "ghcr.io/my-org/git:2",
+		Command:     types.FlexibleCommand{"git clone https://example.com/repo.git ."},
+		Environment: map[string]string{"B": "2", "A": "1"},
+	}
+	// workspaceMount returns the container's workspace mount path, or "" when absent
+	workspaceMount := func(container corev1.Container) string {
+		for _, m := range container.VolumeMounts {
+			if m.Name == workspaceVolumeName {
+				return m.MountPath
+			}
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name      string
+		pvc       string
+		expectPVC bool
+	}{
+		{"EmptyDir workspace", "", false},
+		{"PVC workspace", "ws-1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&config.Config{WorkspaceMountPath: "/workspace", PodSecurityRestricted: true})
+			pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1", WorkspacePVCName: tt.pvc, InitContainer: init})
+
+			if len(pod.Spec.InitContainers) != 1 {
+				t.Fatalf("Expected 1 init container, got %d", len(pod.Spec.InitContainers))
+			}
+			ic := pod.Spec.InitContainers[0]
+			if ic.Name != initContainerName || ic.Image != init.Image {
+				t.Errorf("Expected %s running %s, got %s running %s", initContainerName, init.Image, ic.Name, ic.Image)
+			}
+			if strings.Join(ic.Command, " ") != "/bin/sh -c" || len(ic.Args) != 1 || ic.Args[0] != init.Command[0] {
+				t.Errorf("Expected single-string command via /bin/sh -c, got %v %v", ic.Command, ic.Args)
+			}
+			if len(ic.Env) != 2 || ic.Env[0].Name != "A" || ic.Env[1].Name != "B" {
+				t.Errorf("Expected sorted env A, B, got %v", ic.Env)
+			}
+			if ic.SecurityContext == nil || ic.SecurityContext.AllowPrivilegeEscalation == nil || *ic.SecurityContext.AllowPrivilegeEscalation {
+				t.Errorf("Expected restricted security context on the init container, got %+v", ic.SecurityContext)
+			}
+
+			// Both containers mount the same workspace volume at the same path
+			if got := workspaceMount(ic); got != "/workspace" {
+				t.Errorf("Expected init container workspace at /workspace, got %q", got)
+			}
+			if got := workspaceMount(pod.Spec.Containers[0]); got != "/workspace" {
+				t.Errorf("Expected sandbox container workspace at /workspace, got %q", got)
+			}
+			var workspaceVolumes []corev1.Volume
+			for _, vol := range pod.Spec.Volumes {
+				if vol.Name == workspaceVolumeName {
+					workspaceVolumes = append(workspaceVolumes, vol)
+				}
+			}
+			if len(workspaceVolumes) != 1 {
+				t.Fatalf("Expected exactly one workspace volume, got %d", len(workspaceVolumes))
+			}
+			if gotPVC := workspaceVolumes[0].PersistentVolumeClaim != nil; gotPVC != tt.expectPVC {
+				t.Errorf("Expected PVC-backed workspace=%v, got %+v", tt.expectPVC, workspaceVolumes[0].VolumeSource)
+			}
+			if !tt.expectPVC && workspaceVolumes[0].EmptyDir == nil {
+				t.Errorf("Expected an emptyDir workspace, got %+v", workspaceVolumes[0].VolumeSource)
+			}
+		})
+	}
+
+	t.Run("No init container", func(t *testing.T) {
+		c := newTestClient(&config.Config{WorkspaceMountPath: "/workspace"})
+		pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1"})
+		if len(pod.Spec.InitContainers) != 0 || workspaceMount(pod.Spec.Containers[0]) != "" {
+			t.Errorf("Expected no init container or workspace volume, got %v", pod.Spec.InitContainers)
+		}
+	})
+}
+
+func TestCreatePod_RequestedVolumes(t *testing.T) {
+	c := newTestClient(&config.Config{CACertSecretName: "corp-ca"})
+	pod := createTestPod(t, c, &types.StartRequest{Image: "test-image", SessionID: "session-1", Volumes: []types.VolumeRequest{
+		{Name: "npmrc", ConfigMap: "team-npmrc", MountPath: "/home/openhands/.npmrc", SubPath: ".npmrc", ReadOnly: true},
+		{Name: "creds", Secret: "dataset-creds", MountPath: "/data/creds"},
+	}})
+
+	volumes := make(map[string]corev1.Volume)
+	for _, vol := range pod.Spec.Volumes {
+		volumes[vol.Name] = vol
+	}
+	mounts := make(map[string]corev1.VolumeMount)
+	for _, m := range pod.Spec.Containers[0].VolumeMounts {
+		if _, ok := volumes[m.Name]; !ok {
+			t.Errorf("Volume mount %q has no matching pod volume", m.Name)
+		}
+		mounts[m.Name] = m
+	}
+
+	if vol := volumes["npmrc"]; vol.ConfigMap == nil || vol.ConfigMap.Name != "team-npmrc" {
+		t.Errorf("Expected npmrc volume from ConfigMap team-npmrc, got %+v", vol.VolumeSource)
+	}
+	if m := mounts["npmrc"]; m.MountPath != "/home/openhands/.npmrc" || m.SubPath != ".npmrc" || !m.ReadOnly {
+		t.Errorf("Unexpected npmrc mount %+v", m)
+	}
+	if vol := volumes["creds"]; vol.Secret == nil || vol.Secret.SecretName != "dataset-creds" {
+		t.Errorf("Expected creds volume from Secret dataset-creds, got %+v", vol.VolumeSource)
+	}
+	if m := mounts["creds"]; m.MountPath != "/data/creds" || m.ReadOnly {
+		t.Errorf("Unexpected creds mount %+v", m)
+	}
+	if m := mounts[caCertVolumeName]; m.MountPath != CACertMountPath {
+		t.Errorf("Expected CA cert mounted at %s, got %+v", CACertMountPath, m)
+	}
+}
+
+func TestValidateWorkspacePVC(t *testing.T) {
+	pvc := func(name string, mode corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{mode}},
+		}
+	}
+	mountingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runtime-other", Namespace: "test", Labels: map[string]string{"app": "openhands-runtime"}},
+		Spec: corev1.PodSpec{Volumes: []corev1.Volume{
+			{Name: "workspace", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "rwo-used"}}},
+			{Name: "workspace", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "rwx-used"}}},
+		}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	c := &Client{
+		clientset: fake.NewSimpleClientset(
+			pvc("rwo-free", corev1.ReadWriteOnce),
+			pvc("rwo-used", corev1.ReadWriteOnce),
+			pvc("rwx-used", corev1.ReadWriteMany),
+			mountingPod,
+		),
+		config:    &config.Config{},
+		namespace: "test",
+	}
+
+	tests := []struct {
+		name    string
+		pvcName string
+		wantErr error
+	}{
+		{name: "Free RWO PVC", pvcName: "rwo-free"},
+		{name: "Missing PVC", pvcName: "missing", wantErr: ErrWorkspacePVCNotFound},
+		{name: "RWO PVC mounted by another sandbox", pvcName: "rwo-used", wantErr: ErrWorkspacePVCInUse},
+		{name: "RWX PVC may be shared", pvcName: "rwx-used"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.ValidateWorkspacePVC(context.Background(), tt.pvcName)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestStreamPodLogs(t *testing.T) {
+	c := newTestClient(&config.Config{})
+	stream, err := c.StreamPodLogs(context.Background(), "runtime-abc123", LogOptions{Follow: true, TailLines: 10})
+	if err != nil {
+		t.Fatalf("StreamPodLogs failed: %v", err)
+	}
+	defer stream.Close()
+	body, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("Failed to read log stream: %v", err)
+	}
+	if string(body) != "fake logs" {
+		t.Errorf("Expected fake log body, got %q", body)
+	}
+}
+
+func TestSubdomainIngresses_PerHost(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(&config.Config{BaseDomain: "sandbox.example.com", AgentServerPort: 60000, VSCodePort: 60001, WorkerPorts: []int{12000, 12001}})
+	info := testRuntimeInfo()
+	if err := c.CreateSandbox(ctx, &types.StartRequest{Image: "test-image", SessionID: info.SessionID}, info); err != nil {
+		t.Fatalf("CreateSandbox failed: %v", err)
+	}
+
+	expected := map[string]struct {
+		host   string
+		port   int32
+		secret string
+	}{
+		"runtime-abc123":        {"session-1.sandbox.example.com", 60000, "runtime-abc123-tls"},
+		"runtime-abc123-vscode": {"vscode-session-1.sandbox.example.com", 60001, "runtime-abc123-vscode-tls"},
+		"runtime-abc123-work-1": {"work-1-session-1.sandbox.example.com", 12000, "runtime-abc123-work-1-tls"},
+		"runtime-abc123-work-2": {"work-2-session-1.sandbox.example.com", 12001, "runtime-abc123-work-2-tls"},
+	}
+	ingresses, _ := c.clientset.NetworkingV1().Ingresses(c.namespace).List(ctx, metav1.ListOptions{})
+	if len(ingresses.Items) != len(expected) {
+		t.Fatalf("Expected %d ingresses, got %d", len(expected), len(ingresses.Items))
+	}
+	secrets := map[string]bool{}
+	for _, ingress := range ingresses.Items {
+		want, ok := expected[ingress.Name]
+		if !ok {
+			t.Errorf("Unexpected ingress %s", ingress.Name)
+			continue
+		}
+		if len(ingress.Spec.Rules) != 1 || ingress.Spec.Rules[0].Host != want.host ||
+			ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Port.Number != want.port {
+			t.Errorf("Expected %s to route only %s -> %d, got %+v", ingress.Name, want.host, want.port, ingress.Spec.Rules)
+		}
+		// Each host has its own certificate, so one failed issuance cannot break another host
+		if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].SecretName != want.secret ||
+			len(ingress.Spec.TLS[0].Hosts) != 1 || ingress.Spec.TLS[0].Hosts[0] != want.host {
+			t.Errorf("Expected %s to terminate TLS for %s with %s, got %+v", ingress.Name, want.host, want.secret, ingress.Spec.TLS)
+		}
+		secrets[ingress.Spec.TLS[0].SecretName] = true
+	}
+	if len(secrets) != len(expected) {
+		t.Errorf("Expected a distinct TLS secret per host, got %v", secrets)
+	}
+
+	// An ingress left over from another configuration is removed as well
+	stale := c.hostIngress(info, info.IngressName+"-work-3", "work-3-session-1.sandbox.example.com", 12002, "runtime-abc123-work-3-tls")
+	if _, err := c.clientset.NetworkingV1().Ingresses(c.namespace).Create(ctx, stale, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create stale ingress: %v", err)
+	}
+	if err := c.DeleteSandbox(ctx, info); err != nil {
+		t.Fatalf("DeleteSandbox failed: %v", err)
+	}
+	ingresses, _ = c.clientset.NetworkingV1().Ingresses(c.namespace).List(ctx, metav1.ListOptions{})
+	if len(ingresses.Items) != 0 {
+		t.Errorf("Expected DeleteSandbox to remove every ingress, %d remain", len(ingresses.Items))
+	}
+}
+
+func TestDeleteSandbox_DeletesTLSSecrets(t *testing.T) {
+	ctx := context.Background()
+	newSandbox := func(t *testing.T) (*Client, *state.RuntimeInfo) {
+		c := newTestClient(&config.Config{BaseDomain: "sandbox.example.com", AgentServerPort: 60000, VSCodePort: 60001, WorkerPorts: []int{12000}})
+		info := testRuntimeInfo()
+		if err := c.CreateSandbox(ctx, &types.StartRequest{Image: "test-image", SessionID: info.SessionID}, info); err != nil {
+			t.Fatalf("CreateSandbox failed: %v", err)
+		}
+		// Secrets as cert-manager would issue them, plus ones that aren't the sandbox's
+		for _, name := range []string{"runtime-abc123-tls", "runtime-abc123-vscode-tls", "runtime-abc123-work-1-tls", "wildcard-tls", "runtime-other-tls"} {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"}}
+			if _, err := c.clientset.CoreV1().Secrets("test").Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("Failed to create secret %s: %v", name, err)
+			}
+		}
+		return c, info
+	}
+	remainingSecrets := func(c *Client) []string {
+		list, _ := c.clientset.CoreV1().Secrets("test").List(ctx, metav1.ListOptions{})
+		var names []string
+		for _, secret := range list.Items {
+			names = append(names, secret.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	t.Run("Per-host secrets are deleted", func(t *testing.T) {
+		c, info := newSandbox(t)
+		if err := c.DeleteSandbox(ctx, info); err != nil {
+			t.Fatalf("DeleteSandbox failed: %v", err)
+		}
+		if got := remainingSecrets(c); !slices.Equal(got, []string{"runtime-other-tls", "wildcard-tls"}) {
+			t.Errorf("Expected only other secrets to remain, got %v", got)
+		}
+	})
+
+	t.Run("Forbidden secret deletes are tolerated", func(t *testing.T) {
+		c, info := newSandbox(t)
+		c.clientset.(*fake.Clientset).PrependReactor("delete", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, action.(k8stesting.DeleteAction).GetName(), errors.New("no RBAC"))
+		})
+		if err := c.DeleteSandbox(ctx, info); err != nil {
+			t.Errorf("Expected DeleteSandbox to succeed without secret permissions, got %v", err)
+		}
+	})
+}
+
+// failCreates makes the first n creates of resource fail with err, counting every attempt
+func failCreates(c *Client, resource string, n int, err error) *int {
+	attempts := 0
+	c.clientset.(*fake.Clientset).PrependReactor("create", resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+		attempts++
+		if attempts <= n {
+			return true, nil, err
+		}
+		return false, nil, nil
+	})
+	return &attempts
+}
+
+func TestSelfTest(t *testing.T) {
+	ctx := context.Background()
+	// expectNoCanary checks that the self-test left no sandbox resources behind
+	expectNoCanary := func(t *testing.T, c *Client) {
+		t.Helper()
+		pods, _ := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{})
+		services, _ := c.clientset.CoreV1().Services(c.namespace).List(ctx, metav1.ListOptions{})
+		ingresses, _ := c.clientset.NetworkingV1().Ingresses(c.namespace).List(ctx, metav1.ListOptions{})
+		if len(pods.Items)+len(services.Items)+len(ingresses.Items) != 0 {
+			t.Errorf("Expected canary resources to be deleted, found %d pods, %d services, %d ingresses",
+				len(pods.Items), len(services.Items), len(ingresses.Items))
+		}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		c := newTestClient(&config.Config{DefaultImage: "test-image", BaseDomain: "sandbox.example.com"})
+		var created []string
+		c.clientset.(*fake.Clientset).PrependReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			created = append(created, action.GetResource().Resource)
+			return false, nil, nil
+		})
+		if err := c.SelfTest(ctx); err != nil {
+			t.Fatalf("Expected self-test to pass, got %v", err)
+		}
+		// One ingress each for the agent and VSCode hosts
+		if strings.Join(created, ",") != "pods,services,ingresses,ingresses" {
+			t.Errorf("Expected pod, service and ingresses to be created, got %v", created)
+		}
+		expectNoCanary(t, c)
+	})
+
+	t.Run("Permission denied", func(t *testing.T) {
+		c := newTestClient(&config.Config{DefaultImage: "test-image", BaseDomain: "sandbox.example.com"})
+		forbidden := apierrors.NewForbidden(schema.GroupResource{Group: "networking.k8s.io", Resource: "ingresses"}, "", errors.New("RBAC: access denied"))
+		failCreates(c, "ingresses", 1, forbidden)
+		err := c.SelfTest(ctx)
+		if err == nil || !apierrors.IsForbidden(err) {
+			t.Fatalf("Expected a forbidden self-test error, got %v", err)
+		}
+		expectNoCanary(t, c)
+	})
+}
+
+func TestCreateSandbox_RetriesTransientErrors(t *testing.T) {
+	podsResource := schema.GroupResource{Resource: "pods"}
+	retryConfig := func() *config.Config {
+		return &config.Config{K8sCreateMaxRetries: 3, K8sCreateRetryBaseDelay: time.Millisecond}
+	}
+
+	transient := []struct {
+		name string
+		err  error
+	}{
+		{"Server timeout", apierrors.NewServerTimeout(podsResource, "create", 1)},
+		{"Too many requests", apierrors.NewTooManyRequests("slow down", 1)},
+		{"Internal error", apierrors.NewInternalError(errors.New("webhook timed out"))},
+	}
+	for _, tt := range transient {
+		t.Run(tt.name+" fails twice then succeeds", func(t *testing.T) {
+			c := newTestClient(retryConfig())
+			attempts := failCreates(c, "pods", 2, tt.err)
+			info := testRuntimeInfo()
+			if err := c.CreateSandbox(context.Background(), &types.StartRequest{Image: "test-image", SessionID: info.SessionID}, info); err != nil {
+				t.Fatalf("Expected CreateSandbox to succeed after retries, got %v", err)
+			}
+			if *attempts != 3 {
+				t.Errorf("Expected 3 pod create attempts, got %d", *attempts)
+			}
+		})
+	}
+
+	t.Run("Permanent errors are not retried", func(t *testing.T) {
+		for _, err := range []error{
+			apierrors.NewAlreadyExists(podsResource, "runtime-abc123"),
+			apierrors.NewInvalid(schema.GroupKind{Kind: "Pod"}, "runtime-abc123", nil),
+		} {
+			c := newTestClient(retryConfig())
+			attempts := failCreates(c, "pods", 1, err)
+			info := testRuntimeInfo()
+			if createErr := c.CreateSandbox(context.Background(), &types.StartRequest{Image: "test-image", SessionID: info.SessionID}, info); createErr == nil {
+				t.Errorf("Expected %v to fail CreateSandbox", err)
+			}
+			if *attempts != 1 {
+				t.Errorf("Expected a single attempt for %v, got %d", err, *attempts)
+			}
+		}
+	})
+
+	t.Run("Exhausted retries clean up created resources", func(t *testing.T) {
+		c := newTestClient(retryConfig())
+		attempts := failCreates(c, "services", 10, apierrors.NewInternalError(errors.New("etcd unavailable")))
+		info := testRuntimeInfo()
+		if err := c.CreateSandbox(context.Background(), &types.StartRequest{Image: "test-image", SessionID: info.SessionID}, info); err == nil {
+			t.Fatal("Expected CreateSandbox to fail once retries are exhausted")
+		}
+		if *attempts != 4 {
+			t.Errorf("Expected 1 attempt plus 3 retries, got %d", *attempts)
+		}
+		if _, err := c.clientset.CoreV1().Pods(c.namespace).Get(context.Background(), info.PodName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+			t.Errorf("Expected pod to be cleaned up after service creation failed, got %v", err)
+		}
+	})
+}
+
+func TestCreateSandbox_DumpPodSpec(t *testing.T) {
+	var buf bytes.Buffer
+	logger.Init("info")
+	logger.SetOutput(&buf)
+	defer logger.Reset()
+
+	invalid := apierrors.NewInvalid(schema.GroupKind{Kind: "Pod"}, "runtime-abc123", nil)
+	create := func(dump bool) {
+		buf.Reset()
+		c := newTestClient(&config.Config{DebugDumpPodSpec: dump})
+		failCreates(c, "pods", 1, invalid)
+		info := testRuntimeInfo()
+		info.SessionAPIKey = "secret-session-key"
+		req := &types.StartRequest{
+			Image:       "test-image",
+			SessionID:   info.SessionID,
+			Environment: map[string]string{"LLM_API_KEY": "secret-llm-key"},
+		}
+		if err := c.CreateSandbox(context.Background(), req, info); err == nil {
+			t.Fatal("Expected CreateSandbox to fail")
+		}
+	}
+
+	t.Run("Enabled logs the redacted pod", func(t *testing.T) {
+		create(true)
+		out := buf.String()
+		if !strings.Contains(out, `"name":"runtime-abc123"`) || !strings.Contains(out, `"image":"test-image"`) {
+			t.Errorf("Expected the rejected pod to be logged, got %q", out)
+		}
+		if !strings.Contains(out, redactedPodValue) {
+			t.Errorf("Expected env values to be redacted, got %q", out)
+		}
+		for _, secret := range []string{"secret-session-key", "secret-llm-key"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("Expected %q to be redacted from the log", secret)
+			}
+		}
+	})
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		create(false)
+		if strings.Contains(buf.String(), "was rejected") {
+			t.Errorf("Expected no pod dump when disabled, got %q", buf.String())
+		}
+	})
+}
+
+func TestGetPodStatus_Coalesces(t *testing.T) {
+	c := newTestClient(&config.Config{PodStatusCacheTTL: time.Hour})
+	_, _ = c.clientset.CoreV1().Pods("test").Create(context.Background(), &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runtime-abc123", Namespace: "test"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}, metav1.CreateOptions{})
+
+	var gets atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c.clientset.(*fake.Clientset).PrependReactor("get", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if gets.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		return false, nil, nil
+	})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := c.GetPodStatus(context.Background(), "runtime-abc123")
+			if err == nil && info.Status != types.PodStatusRunning {
+				err = fmt.Errorf("unexpected status %s", info.Status)
+			}
+			errs <- err
+		}()
+	}
+	<-started
+	// Let the other callers queue behind the in-flight fetch
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("GetPodStatus failed: %v", err)
+		}
+	}
+	if n := gets.Load(); n != 1 {
+		t.Errorf("Expected %d simultaneous fetches to share one API call, got %d", callers, n)
+	}
+
+	// Within the TTL the cached status is reused
+	if _, err := c.GetPodStatus(context.Background(), "runtime-abc123"); err != nil {
+		t.Fatalf("GetPodStatus failed: %v", err)
+	}
+	if n := gets.Load(); n != 1 {
+		t.Errorf("Expected the cached status to be reused, got %d API calls", n)
+	}
+
+	// Deleting the pod drops its cached status
+	if err := c.DeletePod(context.Background(), "runtime-abc123"); err != nil {
+		t.Fatalf("DeletePod failed: %v", err)
+	}
+	info, err := c.GetPodStatus(context.Background(), "runtime-abc123")
+	if err != nil {
+		t.Fatalf("GetPodStatus failed: %v", err)
+	}
+	if info.Status != types.PodStatusNotFound {
+		t.Errorf("Expected a fresh not found status after delete, got %s", info.Status)
+	}
+}
+
+func TestWorkspacePVCRetention(t *testing.T) {
+	newClient := func(deleteOnStop, own bool, labels map[string]string) (*Client, *state.RuntimeInfo) {
+		c := newTestClient(&config.Config{DeletePVCOnStop: deleteOnStop})
+		c.clientset = fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "workspace-pvc", Namespace: "test", Labels: labels},
+		})
+		info := testRuntimeInfo()
+		info.WorkspacePVCName = "workspace-pvc"
+		req := &types.StartRequest{Image: "test-image", SessionID: info.SessionID, WorkspacePVCName: "workspace-pvc", OwnWorkspacePVC: own}
+		if err := c.CreateSandbox(context.Background(), req, info); err != nil {
+			t.Fatalf("CreateSandbox failed: %v", err)
+		}
+		return c, info
+	}
+	pvcExists := func(c *Client) bool {
+		_, err := c.clientset.CoreV1().PersistentVolumeClaims("test").Get(context.Background(), "workspace-pvc", metav1.GetOptions{})
+		return err == nil
+	}
+
+	t.Run("Start labels a handed-over PVC", func(t *testing.T) {
+		c, info := newClient(true, true, map[string]string{"team": "a"})
+		pvc, err := c.clientset.CoreV1().PersistentVolumeClaims("test").Get(context.Background(), "workspace-pvc", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get PVC: %v", err)
+		}
+		if pvc.Labels["app"] != "openhands-runtime" || pvc.Labels["runtime-id"] != info.RuntimeID || pvc.Labels["team"] != "a" {
+			t.Errorf("Expected PVC to be labelled as owned by %s and keep its labels, got %v", info.RuntimeID, pvc.Labels)
+		}
+	})
+
+	t.Run("Stop deletes owned PVC", func(t *testing.T) {
+		c, info := newClient(true, true, nil)
+		if err := c.DeleteSandbox(context.Background(), info); err != nil {
+			t.Fatalf("DeleteSandbox failed: %v", err)
+		}
+		if pvcExists(c) {
+			t.Error("Expected workspace PVC to be deleted on stop")
+		}
+		// A second teardown (e.g. cleanup after stop) tolerates the PVC being gone
+		if err := c.DeleteSandbox(context.Background(), info); err != nil {
+			t.Errorf("Expected repeated DeleteSandbox to succeed, got %v", err)
+		}
+	})
+
+	t.Run("Stop keeps caller-provided PVC", func(t *testing.T) {
+		c, info := newClient(true, false, nil)
+		if err := c.DeleteSandbox(context.Background(), info); err != nil {
+			t.Fatalf("DeleteSandbox failed: %v", err)
+		}
+		if !pvcExists(c) {
+			t.Error("Expected unlabelled workspace PVC to be kept on stop")
+		}
+	})
+
+	t.Run("Stop keeps PVC owned by another runtime", func(t *testing.T) {
+		c, info := newClient(true, false, map[string]string{"app": "openhands-runtime", "runtime-id": "other"})
+		if err := c.DeleteSandbox(context.Background(), info); err != nil {
+			t.Fatalf("DeleteSandbox failed: %v", err)
+		}
+		if !pvcExists(c) {
+			t.Error("Expected another runtime's workspace PVC to be kept on stop")
+		}
+	})
+
+	t.Run("Stop keeps PVC when disabled", func(t *testing.T) {
+		c, info := newClient(false, true, nil)
+		if err := c.DeleteSandbox(context.Background(), info); err != nil {
+			t.Fatalf("DeleteSandbox failed: %v", err)
+		}
+		if !pvcExists(c) {
+			t.Error("Expected workspace PVC to be kept with DELETE_PVC_ON_STOP=false")
+		}
+	})
+
+	t.Run("Pause keeps PVC", func(t *testing.T) {
+		c, info := newClient(true, true, nil)
+		if err := c.ScalePodToZero(context.Background(), info.PodName); err != nil {
+			t.Fatalf("ScalePodToZero failed: %v", err)
+		}
+		if !pvcExists(c) {
+			t.Error("Expected workspace PVC to be kept on pause")
+		}
+	})
+
+	for _, resource := range []string{"pods", "services"} {
+		t.Run("Failed start leaves a handed-over PVC unlabelled when creating "+resource+" fails", func(t *testing.T) {
+			c := newTestClient(&config.Config{DeletePVCOnStop: true})
+			clientset := fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "workspace-pvc", Namespace: "test", Labels: map[string]string{"team": "a"}},
+			})
+			clientset.PrependReactor("create", resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, fmt.Errorf("admission webhook denied the request")
+			})
+			c.clientset = clientset
+			info := testRuntimeInfo()
+			info.WorkspacePVCName = "workspace-pvc"
+			req := &types.StartRequest{Image: "test-image", SessionID: info.SessionID, WorkspacePVCName: "workspace-pvc", OwnWorkspacePVC: true}
+			if err := c.CreateSandbox(context.Background(), req, info); err == nil {
+				t.Fatal("Expected CreateSandbox to fail")
+			}
+
+			pvc, err := clientset.CoreV1().PersistentVolumeClaims("test").Get(context.Background(), "workspace-pvc", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get PVC: %v", err)
+			}
+			if _, ok := pvc.Labels["runtime-id"]; ok || pvc.Labels["app"] != "" {
+				t.Errorf("Expected the PVC not to be labelled as owned after a failed start, got %v", pvc.Labels)
+			}
+		})
+	}
+}
+
+func TestSanitizeLabelValue(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1", "1"},
+		{"0.25", "0.25"},
+		{"-1.5", "1.5"},
+		{"a/b c", "a-b-c"},
+		{"...", ""},
+		{strings.Repeat("9", 70), strings.Repeat("9", 63)},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeLabelValue(tt.input); got != tt.want {
+			t.Errorf("sanitizeLabelValue(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverRuntimeBySessionID_SkipsTerminatingPods(t *testing.T) {
+	now := metav1.Now()
+	sandboxPod := func(name, runtimeID string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test",
+				Labels:    map[string]string{"app": "openhands-runtime", "runtime-id": runtimeID, "session-id": "session-1"},
+			},
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "openhands-agent"}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	terminating := sandboxPod("runtime-old", "old")
+	terminating.DeletionTimestamp = &now
+	terminating.Finalizers = []string{"test/finalizer"}
+
+	c := newTestClient(&config.Config{})
+	c.clientset = fake.NewSimpleClientset(terminating)
+	if discovered, err := c.DiscoverRuntimeBySessionID(context.Background(), "session-1"); err != nil || discovered != nil {
+		t.Errorf("Expected terminating pod to be ignored, got %v (err %v)", discovered, err)
+	}
+
+	c.clientset = fake.NewSimpleClientset(terminating, sandboxPod("runtime-new", "new"))
+	discovered, err := c.DiscoverRuntimeBySessionID(context.Background(), "session-1")
+	if err != nil || discovered == nil {
+		t.Fatalf("Expected live pod to be discovered, got %v (err %v)", discovered, err)
+	}
+	if discovered.RuntimeID != "new" {
+		t.Errorf("Expected live runtime 'new', got %s", discovered.RuntimeID)
+	}
+}
+
+func TestCreatePod_SchedulingHint(t *testing.T) {
+	t.Run("Zone and node label", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{
+			Image:          "test-image",
+			SessionID:      "session-1",
+			SchedulingHint: &types.SchedulingHint{Zone: "us-east-1a", NodeLabel: "dataset=imagenet"},
+		})
+
+		if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil ||
+			pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+			t.Fatalf("Expected required node affinity, got %v", pod.Spec.Affinity)
+		}
+		terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		if len(terms) != 1 {
+			t.Fatalf("Expected 1 node selector term, got %d", len(terms))
+		}
+		exprs := terms[0].MatchExpressions
+		if len(exprs) != 2 {
+			t.Fatalf("Expected 2 match expressions, got %v", exprs)
+		}
+		if exprs[0].Key != corev1.LabelTopologyZone || exprs[0].Operator != corev1.NodeSelectorOpIn || exprs[0].Values[0] != "us-east-1a" {
+			t.Errorf("Unexpected zone requirement: %+v", exprs[0])
+		}
+		if exprs[1].Key != "dataset" || exprs[1].Values[0] != "imagenet" {
+			t.Errorf("Unexpected node label requirement: %+v", exprs[1])
+		}
+	})
+
+	t.Run("Composed with request affinity", func(t *testing.T) {
+		c := newTestClient(&config.Config{})
+		pod := createTestPod(t, c, &types.StartRequest{
+			Image:     "test-image",
+			SessionID: "session-1",
+			Affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "disk", Operator: corev1.NodeSelectorOpIn, Values: []string{"ssd"}}}},
+							{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "disk", Operator: corev1.NodeSelectorOpIn, Values: []string{"nvme"}}}},
+						},
+					},
+				},
+				PodAntiAffinity: &corev1.PodAntiAffinity{},
+			},
+			SchedulingHint: &types.SchedulingHint{Zone: "us-east-1a"},
+		})
+
+		terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		if len(terms) != 2 {
+			t.Fatalf("Expected request terms to be kept, got %v", terms)
+		}
+		for i, term := range terms {
+			if len(term.MatchExpressions) != 2 || term.MatchExpressions[1].Key != corev1.LabelTopologyZone {
+				t.Errorf("Expected zone requirement ANDed into term %d, got %v", i, term.MatchExpressions)
+			}
+		}
+		if pod.Spec.Affinity.PodAntiAffinity == nil {
+			t.Error("Expected request pod anti-affinity to be preserved")
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID that the *Ctx log functions attach
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored by WithRequestID, or "" if there is none
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// InfoCtx logs like Info, tagging the line with the context's request ID
+func InfoCtx(ctx context.Context, format string, v ...interface{}) {
+	logCtx(ctx, InfoLevel, format, v)
+}
+
+// DebugCtx logs like Debug, tagging the line with the context's request ID
+func DebugCtx(ctx context.Context, format string, v ...interface{}) {
+	logCtx(ctx, DebugLevel, format, v)
+}
+
+// WarnCtx logs like Warn, tagging the line with the context's request ID
+func WarnCtx(ctx context.Context, format string, v ...interface{}) {
+	logCtx(ctx, WarnLevel, format, v)
+}
+
+// ErrorCtx logs like Error, tagging the line with the context's request ID
+func ErrorCtx(ctx context.Context, format string, v ...interface{}) {
+	logCtx(ctx, ErrorLevel, format, v)
+}
+
+// logCtx writes a printf-style message with a request_id field when the context has one
+func logCtx(ctx context.Context, level Level, format string, v []interface{}) {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		logf(level, format, v...)
+		return
+	}
+	logKV(level, fmt.Sprintf(format, v...), []interface{}{"request_id", requestID})
+}
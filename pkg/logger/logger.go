@@ -1,10 +1,14 @@
 package logger
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Level represents the logging level
@@ -15,13 +19,34 @@ const (
 	DebugLevel Level = iota
 	// InfoLevel enables info, warning, and error logging (default)
 	InfoLevel
+	// WarnLevel enables warning and error logging
+	WarnLevel
+	// ErrorLevel enables error logging only
+	ErrorLevel
+)
+
+// Format represents the log output format
+type Format int
+
+const (
+	// TextFormat emits standard library log lines (default)
+	TextFormat Format = iota
+	// JSONFormat emits one JSON object per line with level, ts, msg and any key/value fields
+	JSONFormat
 )
 
 // Logger wraps the standard logger with level-based logging
 type Logger struct {
 	level       Level
+	format      Format
 	infoLogger  *log.Logger
 	debugLogger *log.Logger
+	warnLogger  *log.Logger
+	errorLogger *log.Logger
+
+	// JSON output bypasses the standard loggers and writes here directly
+	mu  sync.Mutex
+	out io.Writer
 }
 
 var defaultLogger *Logger
@@ -34,38 +59,107 @@ func Init(levelStr string) {
 		level = DebugLevel
 	case "info":
 		level = InfoLevel
+	case "warn", "warning":
+		level = WarnLevel
+	case "error":
+		level = ErrorLevel
 	}
 
 	defaultLogger = &Logger{
 		level:       level,
 		infoLogger:  log.New(os.Stdout, "", log.LstdFlags),
 		debugLogger: log.New(os.Stdout, "[DEBUG] ", log.LstdFlags),
+		warnLogger:  log.New(os.Stdout, "[WARN] ", log.LstdFlags),
+		errorLogger: log.New(os.Stdout, "[ERROR] ", log.LstdFlags),
+		out:         os.Stdout,
 	}
 }
 
+// SetFormat sets the output format ("json" or "text"; anything else means text)
+func SetFormat(formatStr string) {
+	if defaultLogger == nil {
+		Init("info")
+	}
+	format := TextFormat
+	if strings.ToLower(formatStr) == "json" {
+		format = JSONFormat
+	}
+	defaultLogger.format = format
+}
+
 // SetOutput sets the output destination for the logger
 func SetOutput(w io.Writer) {
 	if defaultLogger != nil {
 		defaultLogger.infoLogger.SetOutput(w)
 		defaultLogger.debugLogger.SetOutput(w)
+		defaultLogger.warnLogger.SetOutput(w)
+		defaultLogger.errorLogger.SetOutput(w)
+		defaultLogger.mu.Lock()
+		defaultLogger.out = w
+		defaultLogger.mu.Unlock()
 	}
 }
 
-// Info logs an informational message
+// Info logs an informational message (suppressed at warn and error levels)
 func Info(format string, v ...interface{}) {
-	if defaultLogger == nil {
-		Init("info")
-	}
-	defaultLogger.infoLogger.Printf(format, v...)
+	logf(InfoLevel, format, v...)
 }
 
 // Debug logs a debug message (only if debug level is enabled)
 func Debug(format string, v ...interface{}) {
+	logf(DebugLevel, format, v...)
+}
+
+// Warn logs a warning (suppressed at error level)
+func Warn(format string, v ...interface{}) {
+	logf(WarnLevel, format, v...)
+}
+
+// Error logs an error; it is emitted at every level
+func Error(format string, v ...interface{}) {
+	logf(ErrorLevel, format, v...)
+}
+
+// logf writes a printf-style message if level is enabled
+func logf(level Level, format string, v ...interface{}) {
 	if defaultLogger == nil {
 		Init("info")
 	}
-	if defaultLogger.level == DebugLevel {
-		defaultLogger.debugLogger.Printf(format, v...)
+	if level < defaultLogger.level {
+		return
+	}
+	if defaultLogger.format == JSONFormat {
+		defaultLogger.writeJSON(level.String(), fmt.Sprintf(format, v...), nil)
+		return
+	}
+	defaultLogger.textLogger(level).Printf(format, v...)
+}
+
+// String returns the lowercase level name used in JSON output
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// textLogger returns the standard logger (with its prefix) for a level
+func (l *Logger) textLogger(level Level) *log.Logger {
+	switch level {
+	case DebugLevel:
+		return l.debugLogger
+	case WarnLevel:
+		return l.warnLogger
+	case ErrorLevel:
+		return l.errorLogger
+	default:
+		return l.infoLogger
 	}
 }
 
@@ -74,9 +168,87 @@ func Fatal(format string, v ...interface{}) {
 	if defaultLogger == nil {
 		Init("info")
 	}
+	if defaultLogger.format == JSONFormat {
+		defaultLogger.writeJSON("fatal", fmt.Sprintf(format, v...), nil)
+		os.Exit(1)
+	}
 	defaultLogger.infoLogger.Fatalf(format, v...)
 }
 
+// InfoKV logs an informational message with structured key/value pairs,
+// e.g. InfoKV("sandbox created", "runtime_id", id, "image", image).
+func InfoKV(msg string, keysAndValues ...interface{}) {
+	logKV(InfoLevel, msg, keysAndValues)
+}
+
+// DebugKV logs a debug message with structured key/value pairs (only if debug level is enabled)
+func DebugKV(msg string, keysAndValues ...interface{}) {
+	logKV(DebugLevel, msg, keysAndValues)
+}
+
+// logKV writes a key/value message as JSON, or as "msg key=value ..." in text format
+func logKV(level Level, msg string, keysAndValues []interface{}) {
+	if defaultLogger == nil {
+		Init("info")
+	}
+	if level < defaultLogger.level {
+		return
+	}
+	if defaultLogger.format == JSONFormat {
+		defaultLogger.writeJSON(level.String(), msg, keysAndValues)
+		return
+	}
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, value := kvPair(keysAndValues, i)
+		fmt.Fprintf(&b, " %s=%v", key, value)
+	}
+	defaultLogger.textLogger(level).Print(b.String())
+}
+
+// writeJSON emits a single JSON log line
+func (l *Logger) writeJSON(level, msg string, keysAndValues []interface{}) {
+	entry := map[string]interface{}{
+		"level": level,
+		"ts":    time.Now().UTC().Format(time.RFC3339Nano),
+		"msg":   msg,
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, value := kvPair(keysAndValues, i)
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		entry[key] = value
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		// Fall back to stringified values if a field can't be marshalled
+		for k, v := range entry {
+			entry[k] = fmt.Sprint(v)
+		}
+		line, _ = json.Marshal(entry)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.out.Write(append(line, '\n'))
+}
+
+// kvPair returns the key and value at position i of a key/value list.
+// A trailing key without a value gets a nil value.
+func kvPair(keysAndValues []interface{}, i int) (string, interface{}) {
+	key, ok := keysAndValues[i].(string)
+	if !ok {
+		key = fmt.Sprint(keysAndValues[i])
+	}
+	if i+1 >= len(keysAndValues) {
+		return key, nil
+	}
+	return key, keysAndValues[i+1]
+}
+
 // IsDebugEnabled returns true if debug logging is enabled
 func IsDebugEnabled() bool {
 	if defaultLogger == nil {
@@ -2,8 +2,13 @@ package logger
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestInit(t *testing.T) {
@@ -16,6 +21,9 @@ func TestInit(t *testing.T) {
 		{"Debug level uppercase", "DEBUG", DebugLevel},
 		{"Info level", "info", InfoLevel},
 		{"Info level uppercase", "INFO", InfoLevel},
+		{"Warn level", "warn", WarnLevel},
+		{"Warning alias", "warning", WarnLevel},
+		{"Error level", "ERROR", ErrorLevel},
 		{"Unknown level defaults to info", "unknown", InfoLevel},
 		{"Empty level defaults to info", "", InfoLevel},
 	}
@@ -133,3 +141,165 @@ func TestDebugWithoutInit(t *testing.T) {
 		t.Errorf("Expected debug to be suppressed with auto-init, got: %s", output)
 	}
 }
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	Init("debug")
+	SetFormat("json")
+	SetOutput(&buf)
+	defer Reset()
+
+	Info("started %d sandboxes", 3)
+	InfoKV("sandbox created", "runtime_id", "abc123", "port", 60000, "err", errors.New("boom"))
+	Debug("debug %s", "detail")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 JSON lines, got %d: %s", len(lines), buf.String())
+	}
+
+	entries := make([]map[string]interface{}, 0, len(lines))
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Expected valid JSON, got %q: %v", line, err)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, fmt.Sprint(entry["ts"])); err != nil {
+			t.Errorf("Expected RFC3339 ts field, got %v", entry["ts"])
+		}
+		entries = append(entries, entry)
+	}
+
+	if entries[0]["level"] != "info" || entries[0]["msg"] != "started 3 sandboxes" {
+		t.Errorf("Unexpected printf-style entry: %v", entries[0])
+	}
+	if entries[1]["msg"] != "sandbox created" || entries[1]["runtime_id"] != "abc123" ||
+		entries[1]["port"] != float64(60000) || entries[1]["err"] != "boom" {
+		t.Errorf("Unexpected key/value entry: %v", entries[1])
+	}
+	if entries[2]["level"] != "debug" || entries[2]["msg"] != "debug detail" {
+		t.Errorf("Unexpected debug entry: %v", entries[2])
+	}
+}
+
+func TestKVTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	Init("info")
+	SetOutput(&buf)
+
+	InfoKV("sandbox created", "runtime_id", "abc123", "dangling")
+	DebugKV("suppressed at info level", "key", "value")
+
+	output := buf.String()
+	if !strings.Contains(output, "sandbox created runtime_id=abc123 dangling=<nil>") {
+		t.Errorf("Expected key/value text output, got: %s", output)
+	}
+	if strings.Contains(output, "suppressed") {
+		t.Errorf("Expected DebugKV to be suppressed at info level, got: %s", output)
+	}
+}
+
+func TestLevelSuppression(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected map[string]bool
+	}{
+		{"debug", map[string]bool{"debug": true, "info": true, "warn": true, "error": true}},
+		{"info", map[string]bool{"debug": false, "info": true, "warn": true, "error": true}},
+		{"warn", map[string]bool{"debug": false, "info": false, "warn": true, "error": true}},
+		{"error", map[string]bool{"debug": false, "info": false, "warn": false, "error": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			var buf bytes.Buffer
+			Init(tt.level)
+			SetOutput(&buf)
+
+			Debug("debug-msg")
+			Info("info-msg")
+			Warn("warn-msg")
+			Error("error-msg")
+
+			output := buf.String()
+			for name, visible := range tt.expected {
+				if strings.Contains(output, name+"-msg") != visible {
+					t.Errorf("At level %s expected %s visible=%v, got output: %s", tt.level, name, visible, output)
+				}
+			}
+		})
+	}
+}
+
+func TestWarnAndErrorPrefixes(t *testing.T) {
+	var buf bytes.Buffer
+	Init("info")
+	SetOutput(&buf)
+
+	Warn("careful")
+	Error("broken: %v", errors.New("boom"))
+
+	output := buf.String()
+	if !strings.Contains(output, "[WARN] ") || !strings.Contains(output, "careful") {
+		t.Errorf("Expected [WARN] prefixed line, got: %s", output)
+	}
+	if !strings.Contains(output, "[ERROR] ") || !strings.Contains(output, "broken: boom") {
+		t.Errorf("Expected [ERROR] prefixed line, got: %s", output)
+	}
+}
+
+func TestErrorJSONLevel(t *testing.T) {
+	var buf bytes.Buffer
+	Init("error")
+	SetFormat("json")
+	SetOutput(&buf)
+	defer Reset()
+
+	Warn("suppressed")
+	Error("failed to delete sandbox")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &entry); err != nil {
+		t.Fatalf("Expected a single valid JSON line, got %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "error" || entry["msg"] != "failed to delete sandbox" {
+		t.Errorf("Unexpected entry: %v", entry)
+	}
+}
+
+func TestCtxLoggingRequestID(t *testing.T) {
+	defer Reset()
+	ctx := WithRequestID(context.Background(), "req-123")
+	if got := RequestIDFromContext(ctx); got != "req-123" {
+		t.Errorf("Expected request ID req-123, got %q", got)
+	}
+
+	var buf bytes.Buffer
+	Init("info")
+	SetOutput(&buf)
+	InfoCtx(ctx, "proxying %s", "/alive")
+	InfoCtx(context.Background(), "no request")
+	DebugCtx(ctx, "suppressed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	if !strings.HasSuffix(lines[0], "proxying /alive request_id=req-123") {
+		t.Errorf("Expected text line tagged with request ID, got: %s", lines[0])
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("Expected no request ID without one in context, got: %s", lines[1])
+	}
+
+	buf.Reset()
+	SetFormat("json")
+	ErrorCtx(ctx, "failed: %v", errors.New("boom"))
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected valid JSON, got %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "error" || entry["msg"] != "failed: boom" || entry["request_id"] != "req-123" {
+		t.Errorf("Unexpected JSON entry: %v", entry)
+	}
+}
@@ -0,0 +1,154 @@
+// Package metrics keeps counters for sandbox lifecycle events and serves them in the
+// Prometheus text exposition format on GET /metrics, so churn and failure rates can be
+// alerted on with rate(). Label values must come from small, fixed sets (or be bucketed
+// with ImageBucket) to keep cardinality bounded.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Counter is a monotonically increasing count partitioned by a fixed set of labels
+type Counter struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64 // keyed by label values joined with labelSep
+}
+
+// labelSep can't appear in label values we generate, so joined keys are unambiguous
+const labelSep = "\xff"
+
+func newCounter(name, help string, labels ...string) *Counter {
+	return &Counter{name: name, help: help, labels: labels, values: make(map[string]float64)}
+}
+
+// Inc adds one to the series identified by labelValues, given in label order
+func (c *Counter) Inc(labelValues ...string) {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	c.values[key]++
+	c.mu.Unlock()
+}
+
+// Value returns the current count of a series
+func (c *Counter) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *Counter) key(labelValues []string) string {
+	if len(labelValues) != len(c.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", c.name, len(c.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, labelSep)
+}
+
+// write emits the counter's HELP/TYPE header and series, sorted for stable output
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]float64, len(keys))
+	for i, key := range keys {
+		values[i] = c.values[key]
+	}
+	c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for i, key := range keys {
+		fmt.Fprintf(w, "%s%s %g\n", c.name, c.formatLabels(key), values[i])
+	}
+}
+
+func (c *Counter) formatLabels(key string) string {
+	if len(c.labels) == 0 {
+		return ""
+	}
+	values := strings.Split(key, labelSep)
+	pairs := make([]string, len(c.labels))
+	for i, label := range c.labels {
+		pairs[i] = fmt.Sprintf("%s=%q", label, escapeLabelValue(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escapeLabelValue drops characters %q would escape differently from the exposition format
+func escapeLabelValue(v string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '_'
+		}
+		return r
+	}, v)
+}
+
+// Sandbox lifecycle counters
+var (
+	SandboxStarts = newCounter("openhands_runtime_sandbox_starts_total",
+		"Sandboxes created by POST /start.", "image")
+	SandboxCreateFailures = newCounter("openhands_runtime_sandbox_create_failures_total",
+		"POST /start requests that failed to create a sandbox.", "reason", "image")
+	SandboxStops = newCounter("openhands_runtime_sandbox_stops_total",
+		"Sandboxes stopped through POST /stop or DELETE /runtime/{runtime_id}.")
+	SandboxReaps = newCounter("openhands_runtime_sandbox_reaps_total",
+		"Sandboxes removed by the idle reaper or cleanup service.", "source", "reason")
+)
+
+var all = []*Counter{SandboxStarts, SandboxCreateFailures, SandboxStops, SandboxReaps}
+
+// Handler serves all counters in the Prometheus text exposition format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, c := range all {
+			c.write(w)
+		}
+	})
+}
+
+// maxImageBuckets bounds the number of distinct image label values
+const maxImageBuckets = 50
+
+var (
+	imageBucketsMu sync.Mutex
+	imageBuckets   = make(map[string]bool)
+)
+
+// ImageBucket maps an image reference to a bounded label value: its repository without
+// tag or digest (so every tag of an image shares a series), with repositories beyond
+// the first maxImageBuckets seen reported as "other".
+func ImageBucket(image string) string {
+	repo := image
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo = repo[:i]
+	}
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	if repo == "" {
+		return "other"
+	}
+
+	imageBucketsMu.Lock()
+	defer imageBucketsMu.Unlock()
+	if !imageBuckets[repo] {
+		if len(imageBuckets) >= maxImageBuckets {
+			return "other"
+		}
+		imageBuckets[repo] = true
+	}
+	return repo
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterIncAndWrite(t *testing.T) {
+	c := newCounter("test_events_total", "Events.", "reason", "image")
+	c.Inc("idle", "repo/a")
+	c.Inc("idle", "repo/a")
+	c.Inc("failed", "repo/\"b\"")
+
+	if got := c.Value("idle", "repo/a"); got != 2 {
+		t.Errorf("Expected idle count 2, got %v", got)
+	}
+	if got := c.Value("unknown", "repo/a"); got != 0 {
+		t.Errorf("Expected unseen series to be 0, got %v", got)
+	}
+
+	var b strings.Builder
+	c.write(&b)
+	want := "# HELP test_events_total Events.\n" +
+		"# TYPE test_events_total counter\n" +
+		"test_events_total{reason=\"failed\",image=\"repo/\\\"b\\\"\"} 1\n" +
+		"test_events_total{reason=\"idle\",image=\"repo/a\"} 2\n"
+	if b.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestCounterWithoutLabels(t *testing.T) {
+	c := newCounter("test_plain_total", "Plain.")
+	c.Inc()
+
+	var b strings.Builder
+	c.write(&b)
+	if !strings.Contains(b.String(), "\ntest_plain_total 1\n") {
+		t.Errorf("Expected unlabelled series, got %q", b.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	SandboxStops.Inc()
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected Prometheus text content type, got %q", ct)
+	}
+	for _, c := range all {
+		if !strings.Contains(rr.Body.String(), "# TYPE "+c.name+" counter") {
+			t.Errorf("Expected %s in output", c.name)
+		}
+	}
+}
+
+func TestImageBucket(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"ghcr.io/openhands/runtime:0.9", "ghcr.io/openhands/runtime"},
+		{"ghcr.io/openhands/runtime@sha256:abc", "ghcr.io/openhands/runtime"},
+		{"ghcr.io/openhands/runtime:0.9@sha256:abc", "ghcr.io/openhands/runtime"},
+		{"localhost:5000/runtime", "localhost:5000/runtime"},
+		{"localhost:5000/runtime:latest", "localhost:5000/runtime"},
+		{"busybox", "busybox"},
+		{"", "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := ImageBucket(tt.image); got != tt.want {
+				t.Errorf("ImageBucket(%q) = %q, want %q", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageBucketBounded(t *testing.T) {
+	for i := 0; i < maxImageBuckets+10; i++ {
+		ImageBucket(fmt.Sprintf("bounded/image-%d:v1", i))
+	}
+	if got := ImageBucket("bounded/one-too-many:v1"); got != "other" {
+		t.Errorf("Expected images beyond the limit to bucket as other, got %q", got)
+	}
+
+	imageBucketsMu.Lock()
+	n := len(imageBuckets)
+	imageBucketsMu.Unlock()
+	if n > maxImageBuckets {
+		t.Errorf("Expected at most %d image buckets, got %d", maxImageBuckets, n)
+	}
+}
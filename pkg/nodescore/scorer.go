@@ -136,31 +136,36 @@ func (s *Scorer) SelectNode(ctx context.Context) string {
 	return selected.Name
 }
 
-// ApplyNodePreference sets a preferred node affinity on the pod spec.
+// ApplyNodePreference adds a preferred node affinity to the pod spec.
 // Uses preferredDuringSchedulingIgnoredDuringExecution so the scheduler
 // can fall back to other nodes if the preferred one becomes unavailable.
+// Any affinity already on the pod (e.g. from the start request) is preserved.
 func ApplyNodePreference(pod *corev1.Pod, nodeName string) {
 	if nodeName == "" {
 		return
 	}
-	pod.Spec.Affinity = &corev1.Affinity{
-		NodeAffinity: &corev1.NodeAffinity{
-			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
-				{
-					Weight: 100,
-					Preference: corev1.NodeSelectorTerm{
-						MatchExpressions: []corev1.NodeSelectorRequirement{
-							{
-								Key:      "kubernetes.io/hostname",
-								Operator: corev1.NodeSelectorOpIn,
-								Values:   []string{nodeName},
-							},
-						},
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := pod.Spec.Affinity.NodeAffinity
+	nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+		nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		corev1.PreferredSchedulingTerm{
+			Weight: 100,
+			Preference: corev1.NodeSelectorTerm{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					{
+						Key:      "kubernetes.io/hostname",
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{nodeName},
 					},
 				},
 			},
 		},
-	}
+	)
 }
 
 func hasNoScheduleTaint(node *corev1.Node) bool {
@@ -0,0 +1,74 @@
+package reaper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/config"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/state"
+)
+
+// Idle signals selectable via IDLE_SIGNAL
+const (
+	// IdleSignalProxy uses LastActivityTime, which only advances for traffic through ProxySandbox
+	IdleSignalProxy = "proxy"
+	// IdleSignalMetrics spares HTTP-idle sandboxes whose CPU/memory usage is above threshold
+	IdleSignalMetrics = "metrics"
+	// IdleSignalAgent asks the agent server how long it has been idle
+	IdleSignalAgent = "agent"
+)
+
+// ActivitySource reports when a sandbox was last active, independent of proxied traffic
+type ActivitySource interface {
+	LastActivity(ctx context.Context, runtimeInfo *state.RuntimeInfo) (time.Time, error)
+}
+
+// agentActivitySource reads idle_time from the agent server's /server_info endpoint
+type agentActivitySource struct {
+	client  *http.Client
+	baseURL func(runtimeInfo *state.RuntimeInfo) string
+}
+
+// newAgentActivitySource queries each sandbox's agent server through its in-cluster service
+func newAgentActivitySource(cfg *config.Config) *agentActivitySource {
+	return &agentActivitySource{
+		client: &http.Client{Timeout: 10 * time.Second},
+		baseURL: func(runtimeInfo *state.RuntimeInfo) string {
+			return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", runtimeInfo.ServiceName, cfg.Namespace, cfg.AgentServerPort)
+		},
+	}
+}
+
+// serverInfo is the subset of the agent server's /server_info response used here
+type serverInfo struct {
+	IdleTime *float64 `json:"idle_time"` // seconds since the agent last handled a request
+}
+
+func (a *agentActivitySource) LastActivity(ctx context.Context, runtimeInfo *state.RuntimeInfo) (time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL(runtimeInfo)+"/server_info", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	req.Header.Set("X-Session-API-Key", runtimeInfo.SessionAPIKey)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("server_info returned status %d", resp.StatusCode)
+	}
+
+	var info serverInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return time.Time{}, fmt.Errorf("decode server_info: %w", err)
+	}
+	if info.IdleTime == nil {
+		return time.Time{}, fmt.Errorf("server_info has no idle_time")
+	}
+	return time.Now().Add(-time.Duration(*info.IdleTime * float64(time.Second))), nil
+}
@@ -3,10 +3,13 @@ package reaper
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/alert"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/config"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/logger"
+	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/metrics"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/state"
 	"github.com/zparnold/openhands-kubernetes-remote-runtime/pkg/types"
 )
@@ -16,6 +19,19 @@ type K8sClient interface {
 	DeleteSandbox(ctx context.Context, runtimeInfo *state.RuntimeInfo) error
 }
 
+// UsageSource reports a sandbox's current resource usage (e.g. from metrics-server).
+// K8sClient implementations that also implement UsageSource enable REAPER_REQUIRE_LOW_USAGE.
+type UsageSource interface {
+	PodUsage(ctx context.Context, runtimeInfo *state.RuntimeInfo) (cpuMillis, memBytes int64, err error)
+}
+
+// PodSource reports whether a sandbox's pod still exists. K8sClient implementations that
+// also implement PodSource let the reaper prune idle runtimes whose pod is already gone
+// instead of counting them as reaps.
+type PodSource interface {
+	PodExists(ctx context.Context, runtimeInfo *state.RuntimeInfo) (bool, error)
+}
+
 // Reaper handles automatic cleanup of idle sandboxes
 type Reaper struct {
 	stateMgr      *state.StateManager
@@ -24,12 +40,28 @@ type Reaper struct {
 	stopChan      chan struct{}
 	idleTimeout   time.Duration
 	checkInterval time.Duration
+	mu            sync.RWMutex
+	stats         ReaperStats
+	alerts        *alert.Notifier
+	activity      ActivitySource // nil unless IDLE_SIGNAL=agent
+}
+
+// ReaperStats tracks reaper metrics
+type ReaperStats struct {
+	LastRunTime    time.Time
+	TotalRunCount  int
+	TotalReaped    int
+	IdleReaped     int
+	TTLReaped      int
+	WouldReap      int // Sandboxes the last run would have reaped (REAPER_DRY_RUN)
+	Pruned         int // Idle runtimes removed from state because their pod was already gone
+	LastReapErrors []string
 }
 
 // NewReaper creates a new idle sandbox reaper
 func NewReaper(stateMgr *state.StateManager, k8sClient K8sClient, cfg *config.Config) *Reaper {
 	idleTimeout := time.Duration(cfg.IdleTimeoutHours) * time.Hour
-	return &Reaper{
+	r := &Reaper{
 		stateMgr:      stateMgr,
 		k8sClient:     k8sClient,
 		config:        cfg,
@@ -37,12 +69,25 @@ func NewReaper(stateMgr *state.StateManager, k8sClient K8sClient, cfg *config.Co
 		idleTimeout:   idleTimeout,
 		checkInterval: cfg.ReaperCheckInterval,
 	}
+	switch cfg.IdleSignal {
+	case IdleSignalProxy, IdleSignalMetrics, "":
+	case IdleSignalAgent:
+		r.activity = newAgentActivitySource(cfg)
+	default:
+		logger.Warn("Reaper: Unknown IDLE_SIGNAL %q, using proxy activity only", cfg.IdleSignal)
+	}
+	return r
+}
+
+// SetAlertNotifier sets the notifier told about failed and successful reaps
+func (r *Reaper) SetAlertNotifier(n *alert.Notifier) {
+	r.alerts = n
 }
 
 // Start begins the reaper background goroutine
 func (r *Reaper) Start() {
-	logger.Info("Starting idle sandbox reaper (idle timeout: %s, check interval: %s)",
-		r.idleTimeout, r.checkInterval)
+	logger.Info("Starting idle sandbox reaper (idle timeout: %s, check interval: %s, dry run: %v)",
+		r.idleTimeout, r.checkInterval, r.config.ReaperDryRun)
 
 	go r.run()
 }
@@ -53,6 +98,13 @@ func (r *Reaper) Stop() {
 	close(r.stopChan)
 }
 
+// GetStats returns current reaper statistics
+func (r *Reaper) GetStats() ReaperStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.stats
+}
+
 // run is the main reaper loop
 func (r *Reaper) run() {
 	ticker := time.NewTicker(r.checkInterval)
@@ -69,51 +121,256 @@ func (r *Reaper) run() {
 	}
 }
 
-// checkAndReapIdleSandboxes checks all runtimes and reaps idle ones
+// checkAndReapIdleSandboxes checks all runtimes and reaps idle or TTL-expired ones
 func (r *Reaper) checkAndReapIdleSandboxes() {
 	logger.Debug("Reaper: Checking for idle sandboxes...")
 
 	runtimes := r.stateMgr.ListRuntimes()
 	now := time.Now()
-	reapedCount := 0
+	var reapedCount, idleCount, ttlCount, wouldReapCount, prunedCount int
+	errors := []string{}
 
 	for _, runtime := range runtimes {
-		// Only check running sandboxes
-		if runtime.Status != types.StatusRunning {
+		reason, ok := r.reapReason(runtime, now)
+		if !ok {
 			continue
 		}
-
-		// Check if idle
-		idleDuration := now.Sub(runtime.LastActivityTime)
-		if idleDuration > r.idleTimeout {
-			logger.Info("Reaper: Sandbox %s (session: %s) idle for %s, reaping...",
-				runtime.RuntimeID, runtime.SessionID, idleDuration.Round(time.Second))
-
-			if err := r.reapSandbox(runtime); err != nil {
-				logger.Info("Reaper: Failed to reap sandbox %s: %v", runtime.RuntimeID, err)
-			} else {
-				reapedCount++
-				logger.Info("Reaper: Successfully reaped idle sandbox %s", runtime.RuntimeID)
+		if runtime.Protected {
+			logger.Info("Reaper: Skipping protected sandbox %s (session: %s) that would be reaped (reason: %s)",
+				runtime.RuntimeID, runtime.SessionID, reason)
+			continue
+		}
+		if reason == "idle" && r.StillActive(runtime, now, r.idleTimeoutFor(runtime)) {
+			continue
+		}
+		// A running runtime whose pod was deleted externally (node drain, kubectl delete)
+		// only needs to leave state; deleting it again would just produce errors
+		if runtime.Status == types.StatusRunning && r.podGone(runtime) {
+			if r.config.ReaperDryRun {
+				logger.Info("Reaper: [dry run] Would prune runtime %s (session: %s) from state, pod %s no longer exists",
+					runtime.RuntimeID, runtime.SessionID, runtime.PodName)
+				continue
+			}
+			if r.pruneRuntime(runtime, reason) {
+				logger.Info("Reaper: Pruned runtime %s (session: %s) from state only, pod %s no longer exists (reason: %s)",
+					runtime.RuntimeID, runtime.SessionID, runtime.PodName, reason)
+				prunedCount++
 			}
+			continue
+		}
+		if r.config.ReaperDryRun {
+			logger.Info("Reaper: [dry run] Would reap sandbox %s (session: %s) age %s, idle %s (reason: %s)",
+				runtime.RuntimeID, runtime.SessionID, now.Sub(runtime.CreatedAt).Round(time.Second),
+				now.Sub(runtime.LastActivityTime).Round(time.Second), reason)
+			wouldReapCount++
+			continue
+		}
+
+		logger.Info("Reaper: Sandbox %s (session: %s) age %s, idle %s, reaping (reason: %s)...",
+			runtime.RuntimeID, runtime.SessionID, now.Sub(runtime.CreatedAt).Round(time.Second),
+			now.Sub(runtime.LastActivityTime).Round(time.Second), reason)
+
+		reaped, err := r.reapSandbox(runtime, reason)
+		if err != nil {
+			logger.Error("Reaper: Failed to reap sandbox %s: %v", runtime.RuntimeID, err)
+			errors = append(errors, fmt.Sprintf("error reaping sandbox %s: %v", runtime.RuntimeID, err))
+			r.alerts.RecordFailure("reaper", runtime.RuntimeID, err)
+			continue
 		}
+		if !reaped {
+			logger.Debug("Reaper: Sandbox %s was already torn down, skipping", runtime.RuntimeID)
+			continue
+		}
+		r.alerts.RecordSuccess(runtime.RuntimeID)
+		metrics.SandboxReaps.Inc("reaper", reason)
+		reapedCount++
+		switch reason {
+		case "idle":
+			idleCount++
+		case "ttl_expired":
+			ttlCount++
+		}
+		logger.Info("Reaper: Successfully reaped sandbox %s (reason: %s)", runtime.RuntimeID, reason)
+	}
+
+	r.mu.Lock()
+	r.stats.LastRunTime = now
+	r.stats.TotalRunCount++
+	r.stats.TotalReaped += reapedCount
+	r.stats.IdleReaped += idleCount
+	r.stats.TTLReaped += ttlCount
+	r.stats.WouldReap = wouldReapCount
+	r.stats.Pruned += prunedCount
+	r.stats.LastReapErrors = errors
+	r.mu.Unlock()
+
+	if r.config.ReaperDryRun {
+		logger.Info("Reaper: [dry run] Would have reaped %d sandbox(es)", wouldReapCount)
+		return
 	}
 
 	if reapedCount > 0 {
-		logger.Info("Reaper: Reaped %d idle sandbox(es)", reapedCount)
+		logger.Info("Reaper: Reaped %d sandbox(es)", reapedCount)
 	} else {
 		logger.Debug("Reaper: No idle sandboxes to reap")
 	}
 }
 
-// reapSandbox tears down a sandbox (pod, service, ingress)
-func (r *Reaper) reapSandbox(runtime *state.RuntimeInfo) error {
+// reapReason reports whether a runtime should be reaped and why.
+// A per-request TTL applies regardless of activity (running or paused);
+// the idle timeout only applies to running sandboxes.
+func (r *Reaper) reapReason(runtime *state.RuntimeInfo, now time.Time) (string, bool) {
+	if runtime.TTL > 0 && (runtime.Status == types.StatusRunning || runtime.Status == types.StatusPaused) &&
+		now.Sub(runtime.CreatedAt) > runtime.TTL {
+		return "ttl_expired", true
+	}
+
+	// Only check running sandboxes for idleness
+	if runtime.Status != types.StatusRunning {
+		return "", false
+	}
+	if now.Sub(runtime.LastActivityTime) > r.idleTimeoutFor(runtime) {
+		return "idle", true
+	}
+	return "", false
+}
+
+// idleTimeoutFor returns the sandbox's own idle timeout if it was started with one,
+// otherwise the global IDLE_TIMEOUT_HOURS
+func (r *Reaper) idleTimeoutFor(runtime *state.RuntimeInfo) time.Duration {
+	if runtime.IdleTimeout > 0 {
+		return runtime.IdleTimeout
+	}
+	return r.idleTimeout
+}
+
+// StillActive reports whether a sandbox that looks idle from its LastActivityTime should
+// be spared under IDLE_SIGNAL: its agent server was active within idleTimeout, or it is
+// still using CPU or memory above the thresholds. The cleanup service uses it too, so both
+// treat a sandbox as idle by the same signal.
+func (r *Reaper) StillActive(runtime *state.RuntimeInfo, now time.Time, idleTimeout time.Duration) bool {
+	return r.recentlyActive(runtime, now, idleTimeout) || r.busy(runtime)
+}
+
+// recentlyActive asks the activity source (IDLE_SIGNAL=agent) when a sandbox that looks
+// idle from proxied traffic was really last active. If that is within idleTimeout,
+// LastActivityTime is advanced and the sandbox is spared. Errors fall back to LastActivityTime.
+func (r *Reaper) recentlyActive(runtime *state.RuntimeInfo, now time.Time, idleTimeout time.Duration) bool {
+	if r.activity == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.K8sQueryTimeout)
+	defer cancel()
+	lastActivity, err := r.activity.LastActivity(ctx, runtime)
+	if err != nil {
+		logger.Debug("Reaper: Activity unavailable for sandbox %s, using last proxied activity: %v", runtime.RuntimeID, err)
+		return false
+	}
+	if now.Sub(lastActivity) > idleTimeout {
+		return false
+	}
+
+	logger.Debug("Reaper: Sandbox %s was active at %s, not reaping", runtime.RuntimeID, lastActivity.Format(time.RFC3339))
+	_ = r.stateMgr.TouchActivity(runtime.RuntimeID, lastActivity)
+	return true
+}
+
+// busy reports whether an HTTP-idle sandbox should be spared because it is still using
+// CPU or memory above the configured thresholds. Without REAPER_REQUIRE_LOW_USAGE or
+// IDLE_SIGNAL=metrics, or when usage can't be determined, it returns false so reaping
+// falls back to activity only.
+func (r *Reaper) busy(runtime *state.RuntimeInfo) bool {
+	if !r.config.ReaperRequireLowUsage && r.config.IdleSignal != IdleSignalMetrics {
+		return false
+	}
+	usage, ok := r.k8sClient.(UsageSource)
+	if !ok {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.K8sQueryTimeout)
+	defer cancel()
+	cpuMillis, memBytes, err := usage.PodUsage(ctx, runtime)
+	if err != nil {
+		logger.Debug("Reaper: Usage unavailable for sandbox %s, using activity only: %v", runtime.RuntimeID, err)
+		return false
+	}
+
+	cpuBusy := r.config.ReaperCPUThresholdMillicores > 0 && cpuMillis > int64(r.config.ReaperCPUThresholdMillicores)
+	memBusy := r.config.ReaperMemoryThresholdMiB > 0 && memBytes > int64(r.config.ReaperMemoryThresholdMiB)*1024*1024
+	if cpuBusy || memBusy {
+		logger.Info("Reaper: Sparing idle sandbox %s, usage above threshold (CPU: %dm, memory: %dMi)",
+			runtime.RuntimeID, cpuMillis, memBytes/(1024*1024))
+		return true
+	}
+	return false
+}
+
+// podGone reports whether a running sandbox's pod is confirmed to no longer exist (e.g.
+// it crashed and was removed while the runtime stayed "running" in state). It returns
+// false when the K8s client can't tell or the check fails, so the sandbox is reaped as usual.
+func (r *Reaper) podGone(runtime *state.RuntimeInfo) bool {
+	pods, ok := r.k8sClient.(PodSource)
+	if !ok || runtime.PodName == "" {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.K8sQueryTimeout)
+	defer cancel()
+	exists, err := pods.PodExists(ctx, runtime)
+	if err != nil {
+		logger.Debug("Reaper: Could not check pod for sandbox %s, reaping as usual: %v", runtime.RuntimeID, err)
+		return false
+	}
+	return !exists
+}
+
+// pruneRuntime removes a runtime whose pod no longer exists from state without deleting
+// any Kubernetes resources; once untracked, its remaining Service, Ingresses and TLS
+// secrets are removed by the cleanup service's orphan sweep. It returns false if a
+// concurrent stop or cleanup already removed the runtime.
+func (r *Reaper) pruneRuntime(runtime *state.RuntimeInfo, reason string) bool {
+	unlock := r.stateMgr.LockRuntime(runtime.RuntimeID)
+	defer unlock()
+	live, err := r.stateMgr.GetRuntimeByID(runtime.RuntimeID)
+	if err != nil || !r.stateMgr.TransitionStatus(runtime.RuntimeID, live.Status, types.StatusStopping) {
+		return false
+	}
+
+	runtime.Status = types.StatusStopped
+	_ = r.stateMgr.UpdateRuntime(runtime)
+	_ = r.stateMgr.RecordEvent(runtime.RuntimeID, types.RuntimeEventReaped, "reaper: "+reason+", pod already gone (state only)")
+	if err := r.stateMgr.DeleteRuntime(runtime.RuntimeID); err != nil {
+		logger.Debug("Reaper: Failed to delete runtime from state: %v", err)
+	}
+	r.stateMgr.Tombstone(runtime)
+	return true
+}
+
+// reapSandbox tears down a sandbox (pod, service, ingress), recording the reason in its
+// history. It returns false without error if a concurrent stop or cleanup already
+// removed the runtime.
+func (r *Reaper) reapSandbox(runtime *state.RuntimeInfo, reason string) (bool, error) {
+	unlock := r.stateMgr.LockRuntime(runtime.RuntimeID)
+	defer unlock()
+	live, err := r.stateMgr.GetRuntimeByID(runtime.RuntimeID)
+	if err != nil {
+		return false, nil
+	}
+	previous := live.Status
+	if !r.stateMgr.TransitionStatus(runtime.RuntimeID, previous, types.StatusStopping) {
+		return false, nil
+	}
+
 	// Create context with timeout for cleanup operations
 	ctx, cancel := context.WithTimeout(context.Background(), r.config.K8sOperationTimeout)
 	defer cancel()
 
 	// Delete the sandbox resources
 	if err := r.k8sClient.DeleteSandbox(ctx, runtime); err != nil {
-		return fmt.Errorf("failed to delete sandbox resources: %w", err)
+		r.stateMgr.TransitionStatus(runtime.RuntimeID, types.StatusStopping, previous)
+		return false, fmt.Errorf("failed to delete sandbox resources: %w", err)
 	}
 
 	// Update status and remove from state
@@ -121,10 +378,12 @@ func (r *Reaper) reapSandbox(runtime *state.RuntimeInfo) error {
 	if err := r.stateMgr.UpdateRuntime(runtime); err != nil {
 		logger.Debug("Reaper: Failed to update runtime status: %v", err)
 	}
+	_ = r.stateMgr.RecordEvent(runtime.RuntimeID, types.RuntimeEventReaped, "reaper: "+reason)
 
 	if err := r.stateMgr.DeleteRuntime(runtime.RuntimeID); err != nil {
 		logger.Debug("Reaper: Failed to delete runtime from state: %v", err)
 	}
+	r.stateMgr.Tombstone(runtime)
 
-	return nil
+	return true, nil
 }
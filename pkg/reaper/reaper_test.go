@@ -2,6 +2,11 @@ package reaper
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,6 +25,31 @@ func (m *mockK8sClient) DeleteSandbox(ctx context.Context, runtime *state.Runtim
 	return nil
 }
 
+// mockUsageClient is a mock Kubernetes client that also reports per-sandbox usage
+type mockUsageClient struct {
+	mockK8sClient
+	cpuMillis map[string]int64
+	memBytes  map[string]int64
+	err       error
+}
+
+func (m *mockUsageClient) PodUsage(ctx context.Context, runtime *state.RuntimeInfo) (int64, int64, error) {
+	if m.err != nil {
+		return 0, 0, m.err
+	}
+	return m.cpuMillis[runtime.RuntimeID], m.memBytes[runtime.RuntimeID], nil
+}
+
+// mockPodClient is a mock Kubernetes client that also reports which pods exist
+type mockPodClient struct {
+	mockK8sClient
+	existing map[string]bool
+}
+
+func (m *mockPodClient) PodExists(ctx context.Context, runtime *state.RuntimeInfo) (bool, error) {
+	return m.existing[runtime.PodName], nil
+}
+
 func TestNewReaper(t *testing.T) {
 	cfg := &config.Config{
 		IdleTimeoutHours:    12,
@@ -117,6 +147,12 @@ func TestReaper_ReapIdleSandbox(t *testing.T) {
 		t.Error("Expected idle runtime to be removed from state")
 	}
 
+	// The reap is recorded in the tombstoned runtime's history
+	history, err := stateMgr.History("runtime-idle-1")
+	if err != nil || len(history) == 0 || history[0].Type != types.RuntimeEventReaped || history[0].Message != "reaper: idle" {
+		t.Errorf("Expected a reaped history event, got %v (%v)", history, err)
+	}
+
 	// Verify active runtime still exists
 	_, err = stateMgr.GetRuntimeByID("runtime-active-1")
 	if err != nil {
@@ -229,3 +265,587 @@ func TestReaper_StartStop(t *testing.T) {
 
 	// Test passes if no panic occurs
 }
+
+func TestReaper_TTLExpired(t *testing.T) {
+	cfg := &config.Config{
+		IdleTimeoutHours:    1,
+		ReaperCheckInterval: 1 * time.Minute,
+		K8sOperationTimeout: 60 * time.Second,
+	}
+	stateMgr := state.NewStateManager()
+	mockClient := &mockK8sClient{
+		deletedRuntimes: make([]*state.RuntimeInfo, 0),
+	}
+
+	reaper := &Reaper{
+		stateMgr:      stateMgr,
+		k8sClient:     mockClient,
+		config:        cfg,
+		stopChan:      make(chan struct{}),
+		idleTimeout:   1 * time.Hour,
+		checkInterval: 1 * time.Minute,
+	}
+
+	// Active runtime whose 30-minute TTL has passed
+	expiredRuntime := &state.RuntimeInfo{
+		RuntimeID:        "runtime-ttl-expired",
+		SessionID:        "session-ttl-expired",
+		Status:           types.StatusRunning,
+		PodStatus:        types.PodStatusReady,
+		CreatedAt:        time.Now().Add(-45 * time.Minute),
+		LastActivityTime: time.Now(),
+		TTL:              30 * time.Minute,
+	}
+	stateMgr.AddRuntime(expiredRuntime)
+
+	// Active runtime still within its TTL
+	withinTTLRuntime := &state.RuntimeInfo{
+		RuntimeID:        "runtime-ttl-ok",
+		SessionID:        "session-ttl-ok",
+		Status:           types.StatusRunning,
+		PodStatus:        types.PodStatusReady,
+		CreatedAt:        time.Now().Add(-10 * time.Minute),
+		LastActivityTime: time.Now(),
+		TTL:              30 * time.Minute,
+	}
+	stateMgr.AddRuntime(withinTTLRuntime)
+
+	// Old runtime without a TTL (should only be subject to the idle timeout)
+	noTTLRuntime := &state.RuntimeInfo{
+		RuntimeID:        "runtime-no-ttl",
+		SessionID:        "session-no-ttl",
+		Status:           types.StatusRunning,
+		PodStatus:        types.PodStatusReady,
+		CreatedAt:        time.Now().Add(-48 * time.Hour),
+		LastActivityTime: time.Now(),
+	}
+	stateMgr.AddRuntime(noTTLRuntime)
+
+	reaper.checkAndReapIdleSandboxes()
+
+	if len(mockClient.deletedRuntimes) != 1 {
+		t.Fatalf("Expected 1 runtime to be deleted, got %d", len(mockClient.deletedRuntimes))
+	}
+	if mockClient.deletedRuntimes[0].RuntimeID != "runtime-ttl-expired" {
+		t.Errorf("Expected TTL-expired runtime to be deleted, got %s", mockClient.deletedRuntimes[0].RuntimeID)
+	}
+	if _, err := stateMgr.GetRuntimeByID("runtime-ttl-expired"); err == nil {
+		t.Error("Expected TTL-expired runtime to be removed from state")
+	}
+	if _, err := stateMgr.GetRuntimeByID("runtime-ttl-ok"); err != nil {
+		t.Error("Runtime within its TTL should still exist in state")
+	}
+	if _, err := stateMgr.GetRuntimeByID("runtime-no-ttl"); err != nil {
+		t.Error("Runtime without a TTL should still exist in state")
+	}
+}
+
+func TestReaper_SkipsProtected(t *testing.T) {
+	cfg := &config.Config{
+		IdleTimeoutHours:    1,
+		ReaperCheckInterval: 1 * time.Minute,
+		K8sOperationTimeout: 60 * time.Second,
+	}
+	stateMgr := state.NewStateManager()
+	mockClient := &mockK8sClient{}
+
+	reaper := &Reaper{
+		stateMgr:      stateMgr,
+		k8sClient:     mockClient,
+		config:        cfg,
+		stopChan:      make(chan struct{}),
+		idleTimeout:   1 * time.Hour,
+		checkInterval: 1 * time.Minute,
+	}
+
+	// Idle for well past the timeout and past its TTL, but protected
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:        "runtime-protected",
+		SessionID:        "session-protected",
+		Status:           types.StatusRunning,
+		PodStatus:        types.PodStatusReady,
+		CreatedAt:        time.Now().Add(-24 * time.Hour),
+		LastActivityTime: time.Now().Add(-13 * time.Hour),
+		TTL:              time.Hour,
+		Protected:        true,
+	})
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:        "runtime-idle",
+		SessionID:        "session-idle",
+		Status:           types.StatusRunning,
+		PodStatus:        types.PodStatusReady,
+		CreatedAt:        time.Now().Add(-24 * time.Hour),
+		LastActivityTime: time.Now().Add(-13 * time.Hour),
+	})
+
+	reaper.checkAndReapIdleSandboxes()
+
+	if len(mockClient.deletedRuntimes) != 1 || mockClient.deletedRuntimes[0].RuntimeID != "runtime-idle" {
+		t.Fatalf("Expected only the unprotected runtime to be reaped, got %v", mockClient.deletedRuntimes)
+	}
+	if _, err := stateMgr.GetRuntimeByID("runtime-protected"); err != nil {
+		t.Error("Protected runtime should still exist in state")
+	}
+}
+
+func TestReaper_ReapReason(t *testing.T) {
+	reaper := &Reaper{idleTimeout: 1 * time.Hour}
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		runtime        *state.RuntimeInfo
+		expectedReap   bool
+		expectedReason string
+	}{
+		{
+			name: "TTL expired while active",
+			runtime: &state.RuntimeInfo{
+				Status:           types.StatusRunning,
+				CreatedAt:        now.Add(-2 * time.Hour),
+				LastActivityTime: now,
+				TTL:              time.Hour,
+			},
+			expectedReap:   true,
+			expectedReason: "ttl_expired",
+		},
+		{
+			name: "TTL expired while paused",
+			runtime: &state.RuntimeInfo{
+				Status:           types.StatusPaused,
+				CreatedAt:        now.Add(-2 * time.Hour),
+				LastActivityTime: now,
+				TTL:              time.Hour,
+			},
+			expectedReap:   true,
+			expectedReason: "ttl_expired",
+		},
+		{
+			name: "Idle without TTL",
+			runtime: &state.RuntimeInfo{
+				Status:           types.StatusRunning,
+				CreatedAt:        now.Add(-3 * time.Hour),
+				LastActivityTime: now.Add(-2 * time.Hour),
+			},
+			expectedReap:   true,
+			expectedReason: "idle",
+		},
+		{
+			name: "Short per-sandbox idle timeout",
+			runtime: &state.RuntimeInfo{
+				Status:           types.StatusRunning,
+				CreatedAt:        now.Add(-time.Hour),
+				LastActivityTime: now.Add(-45 * time.Minute),
+				IdleTimeout:      30 * time.Minute,
+			},
+			expectedReap:   true,
+			expectedReason: "idle",
+		},
+		{
+			name: "Long per-sandbox idle timeout overrides default",
+			runtime: &state.RuntimeInfo{
+				Status:           types.StatusRunning,
+				CreatedAt:        now.Add(-24 * time.Hour),
+				LastActivityTime: now.Add(-12 * time.Hour),
+				IdleTimeout:      24 * time.Hour,
+			},
+			expectedReap: false,
+		},
+		{
+			name: "Pending runtime with expired TTL is left alone",
+			runtime: &state.RuntimeInfo{
+				Status:           types.StatusPending,
+				CreatedAt:        now.Add(-2 * time.Hour),
+				LastActivityTime: now,
+				TTL:              time.Hour,
+			},
+			expectedReap: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, reap := reaper.reapReason(tt.runtime, now)
+			if reap != tt.expectedReap {
+				t.Errorf("Expected reap=%v, got %v", tt.expectedReap, reap)
+			}
+			if reason != tt.expectedReason {
+				t.Errorf("Expected reason %q, got %q", tt.expectedReason, reason)
+			}
+		})
+	}
+}
+
+func TestReaper_GetStats(t *testing.T) {
+	cfg := &config.Config{
+		IdleTimeoutHours:    1,
+		ReaperCheckInterval: 1 * time.Minute,
+		K8sOperationTimeout: 60 * time.Second,
+	}
+	stateMgr := state.NewStateManager()
+	reaper := &Reaper{
+		stateMgr:      stateMgr,
+		k8sClient:     &mockK8sClient{},
+		config:        cfg,
+		stopChan:      make(chan struct{}),
+		idleTimeout:   1 * time.Hour,
+		checkInterval: 1 * time.Minute,
+	}
+
+	stats := reaper.GetStats()
+	if !stats.LastRunTime.IsZero() || stats.TotalRunCount != 0 {
+		t.Errorf("Expected empty stats before first run, got %+v", stats)
+	}
+
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:        "runtime-idle",
+		SessionID:        "session-idle",
+		Status:           types.StatusRunning,
+		CreatedAt:        time.Now().Add(-3 * time.Hour),
+		LastActivityTime: time.Now().Add(-2 * time.Hour),
+	})
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:        "runtime-ttl",
+		SessionID:        "session-ttl",
+		Status:           types.StatusRunning,
+		CreatedAt:        time.Now().Add(-2 * time.Hour),
+		LastActivityTime: time.Now(),
+		TTL:              time.Hour,
+	})
+
+	reaper.checkAndReapIdleSandboxes()
+	reaper.checkAndReapIdleSandboxes()
+
+	stats = reaper.GetStats()
+	if stats.LastRunTime.IsZero() {
+		t.Error("Expected LastRunTime to be set after a run")
+	}
+	if stats.TotalRunCount != 2 {
+		t.Errorf("Expected 2 runs, got %d", stats.TotalRunCount)
+	}
+	if stats.TotalReaped != 2 || stats.IdleReaped != 1 || stats.TTLReaped != 1 {
+		t.Errorf("Expected 2 reaped (1 idle, 1 ttl), got %+v", stats)
+	}
+	if len(stats.LastReapErrors) != 0 {
+		t.Errorf("Expected no reap errors, got %v", stats.LastReapErrors)
+	}
+}
+
+func TestReaper_DryRun(t *testing.T) {
+	cfg := &config.Config{
+		IdleTimeoutHours:    1,
+		ReaperCheckInterval: 1 * time.Minute,
+		K8sOperationTimeout: 60 * time.Second,
+		ReaperDryRun:        true,
+	}
+	stateMgr := state.NewStateManager()
+	mockClient := &mockK8sClient{}
+	reaper := &Reaper{
+		stateMgr:      stateMgr,
+		k8sClient:     mockClient,
+		config:        cfg,
+		stopChan:      make(chan struct{}),
+		idleTimeout:   1 * time.Hour,
+		checkInterval: 1 * time.Minute,
+	}
+
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:        "runtime-idle",
+		SessionID:        "session-idle",
+		Status:           types.StatusRunning,
+		CreatedAt:        time.Now().Add(-3 * time.Hour),
+		LastActivityTime: time.Now().Add(-2 * time.Hour),
+	})
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:        "runtime-ttl",
+		SessionID:        "session-ttl",
+		Status:           types.StatusRunning,
+		CreatedAt:        time.Now().Add(-2 * time.Hour),
+		LastActivityTime: time.Now(),
+		TTL:              time.Hour,
+	})
+	stateMgr.AddRuntime(&state.RuntimeInfo{
+		RuntimeID:        "runtime-active",
+		SessionID:        "session-active",
+		Status:           types.StatusRunning,
+		CreatedAt:        time.Now().Add(-2 * time.Hour),
+		LastActivityTime: time.Now(),
+	})
+
+	reaper.checkAndReapIdleSandboxes()
+	reaper.checkAndReapIdleSandboxes()
+
+	if len(mockClient.deletedRuntimes) != 0 {
+		t.Errorf("Expected no deletions in dry run, got %d", len(mockClient.deletedRuntimes))
+	}
+	for _, id := range []string{"runtime-idle", "runtime-ttl"} {
+		runtime, err := stateMgr.GetRuntimeByID(id)
+		if err != nil {
+			t.Errorf("Expected %s to stay in state, got %v", id, err)
+			continue
+		}
+		if runtime.Status != types.StatusRunning {
+			t.Errorf("Expected %s to stay running, got %s", id, runtime.Status)
+		}
+	}
+
+	stats := reaper.GetStats()
+	if stats.WouldReap != 2 {
+		t.Errorf("Expected 2 would-reap on the last run, got %d", stats.WouldReap)
+	}
+	if stats.TotalReaped != 0 || stats.IdleReaped != 0 || stats.TTLReaped != 0 {
+		t.Errorf("Expected nothing reaped in dry run, got %+v", stats)
+	}
+}
+
+func TestReaper_PrunesRuntimeWithMissingPod(t *testing.T) {
+	cfg := &config.Config{
+		IdleTimeoutHours:    1,
+		ReaperCheckInterval: 1 * time.Minute,
+		K8sOperationTimeout: 60 * time.Second,
+		K8sQueryTimeout:     5 * time.Second,
+	}
+	stateMgr := state.NewStateManager()
+	mockClient := &mockPodClient{existing: map[string]bool{"runtime-present": true}}
+	reaper := &Reaper{
+		stateMgr:      stateMgr,
+		k8sClient:     mockClient,
+		config:        cfg,
+		stopChan:      make(chan struct{}),
+		idleTimeout:   1 * time.Hour,
+		checkInterval: 1 * time.Minute,
+	}
+
+	for _, id := range []string{"runtime-gone", "runtime-present"} {
+		stateMgr.AddRuntime(&state.RuntimeInfo{
+			RuntimeID:        id,
+			SessionID:        "session-" + id,
+			Status:           types.StatusRunning,
+			PodName:          id,
+			CreatedAt:        time.Now().Add(-3 * time.Hour),
+			LastActivityTime: time.Now().Add(-2 * time.Hour),
+		})
+	}
+
+	reaper.checkAndReapIdleSandboxes()
+
+	for _, id := range []string{"runtime-gone", "runtime-present"} {
+		if _, err := stateMgr.GetRuntimeByID(id); err == nil {
+			t.Errorf("Expected %s to be removed from state", id)
+		}
+	}
+	if len(mockClient.deletedRuntimes) != 1 || mockClient.deletedRuntimes[0].RuntimeID != "runtime-present" {
+		t.Errorf("Expected only the runtime with a pod to be deleted, got %d deletions", len(mockClient.deletedRuntimes))
+	}
+	if history, err := stateMgr.History("runtime-gone"); err != nil || len(history) == 0 || !strings.Contains(history[0].Message, "state only") {
+		t.Errorf("Expected a state-only prune in the pruned runtime's history, got %v (err %v)", history, err)
+	}
+
+	stats := reaper.GetStats()
+	if stats.Pruned != 1 {
+		t.Errorf("Expected 1 pruned runtime, got %d", stats.Pruned)
+	}
+	if stats.TotalReaped != 1 || stats.IdleReaped != 1 {
+		t.Errorf("Expected only the runtime with a pod to count as reaped, got %+v", stats)
+	}
+}
+
+func TestReaper_RequireLowUsage(t *testing.T) {
+	idleRuntimes := func() *state.StateManager {
+		stateMgr := state.NewStateManager()
+		for _, id := range []string{"runtime-busy-cpu", "runtime-busy-mem", "runtime-quiet"} {
+			stateMgr.AddRuntime(&state.RuntimeInfo{
+				RuntimeID:        id,
+				SessionID:        "session-" + id,
+				Status:           types.StatusRunning,
+				CreatedAt:        time.Now().Add(-3 * time.Hour),
+				LastActivityTime: time.Now().Add(-2 * time.Hour),
+			})
+		}
+		return stateMgr
+	}
+	cfg := &config.Config{
+		K8sOperationTimeout:          60 * time.Second,
+		K8sQueryTimeout:              10 * time.Second,
+		ReaperRequireLowUsage:        true,
+		ReaperCPUThresholdMillicores: 100,
+		ReaperMemoryThresholdMiB:     1024,
+	}
+	deletedIDs := func(client *mockUsageClient) map[string]bool {
+		ids := make(map[string]bool)
+		for _, rt := range client.deletedRuntimes {
+			ids[rt.RuntimeID] = true
+		}
+		return ids
+	}
+
+	t.Run("High usage spares idle sandbox", func(t *testing.T) {
+		client := &mockUsageClient{
+			cpuMillis: map[string]int64{"runtime-busy-cpu": 1500, "runtime-quiet": 5},
+			memBytes:  map[string]int64{"runtime-busy-mem": 2048 * 1024 * 1024, "runtime-quiet": 100 * 1024 * 1024},
+		}
+		reaper := NewReaper(idleRuntimes(), client, cfg)
+		reaper.idleTimeout = time.Hour
+		reaper.checkAndReapIdleSandboxes()
+
+		deleted := deletedIDs(client)
+		if len(deleted) != 1 || !deleted["runtime-quiet"] {
+			t.Errorf("Expected only runtime-quiet to be reaped, got %v", deleted)
+		}
+	})
+
+	t.Run("Metrics unavailable falls back to activity only", func(t *testing.T) {
+		client := &mockUsageClient{err: errors.New("metrics.k8s.io not available")}
+		reaper := NewReaper(idleRuntimes(), client, cfg)
+		reaper.idleTimeout = time.Hour
+		reaper.checkAndReapIdleSandboxes()
+
+		if len(client.deletedRuntimes) != 3 {
+			t.Errorf("Expected all 3 idle sandboxes to be reaped, got %d", len(client.deletedRuntimes))
+		}
+	})
+
+	t.Run("Disabled ignores usage", func(t *testing.T) {
+		client := &mockUsageClient{cpuMillis: map[string]int64{"runtime-busy-cpu": 1500}}
+		disabled := *cfg
+		disabled.ReaperRequireLowUsage = false
+		reaper := NewReaper(idleRuntimes(), client, &disabled)
+		reaper.idleTimeout = time.Hour
+		reaper.checkAndReapIdleSandboxes()
+
+		if len(client.deletedRuntimes) != 3 {
+			t.Errorf("Expected all 3 idle sandboxes to be reaped, got %d", len(client.deletedRuntimes))
+		}
+	})
+}
+
+func TestReaper_IdleSignal(t *testing.T) {
+	newIdleRuntime := func() (*state.StateManager, *state.RuntimeInfo) {
+		stateMgr := state.NewStateManager()
+		info := &state.RuntimeInfo{
+			RuntimeID:        "runtime-1",
+			SessionID:        "session-1",
+			SessionAPIKey:    "session-key",
+			ServiceName:      "runtime-runtime-1",
+			Status:           types.StatusRunning,
+			CreatedAt:        time.Now().Add(-3 * time.Hour),
+			LastActivityTime: time.Now().Add(-2 * time.Hour),
+		}
+		stateMgr.AddRuntime(info)
+		return stateMgr, info
+	}
+	agentServer := func(t *testing.T, idleSeconds float64) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/server_info" || r.Header.Get("X-Session-API-Key") != "session-key" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"uptime": 10800, "idle_time": %f}`, idleSeconds)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	cfg := func(signal string) *config.Config {
+		return &config.Config{
+			IdleTimeoutHours:             1,
+			K8sOperationTimeout:          60 * time.Second,
+			K8sQueryTimeout:              10 * time.Second,
+			IdleSignal:                   signal,
+			ReaperCPUThresholdMillicores: 100,
+		}
+	}
+
+	t.Run("Agent reports recent activity", func(t *testing.T) {
+		stateMgr, info := newIdleRuntime()
+		server := agentServer(t, 60)
+		client := &mockK8sClient{}
+		reaper := NewReaper(stateMgr, client, cfg(IdleSignalAgent))
+		reaper.activity.(*agentActivitySource).baseURL = func(*state.RuntimeInfo) string { return server.URL }
+
+		reaper.checkAndReapIdleSandboxes()
+		if len(client.deletedRuntimes) != 0 {
+			t.Errorf("Expected active sandbox to be spared, got %d reaped", len(client.deletedRuntimes))
+		}
+		if time.Since(info.LastActivityTime) > 2*time.Minute {
+			t.Errorf("Expected LastActivityTime to advance to the agent's activity, got %v", info.LastActivityTime)
+		}
+	})
+
+	t.Run("Agent reports idle", func(t *testing.T) {
+		stateMgr, _ := newIdleRuntime()
+		server := agentServer(t, 7200)
+		client := &mockK8sClient{}
+		reaper := NewReaper(stateMgr, client, cfg(IdleSignalAgent))
+		reaper.activity.(*agentActivitySource).baseURL = func(*state.RuntimeInfo) string { return server.URL }
+
+		reaper.checkAndReapIdleSandboxes()
+		if len(client.deletedRuntimes) != 1 {
+			t.Errorf("Expected idle sandbox to be reaped, got %d reaped", len(client.deletedRuntimes))
+		}
+	})
+
+	t.Run("Agent unreachable falls back to proxy activity", func(t *testing.T) {
+		stateMgr, _ := newIdleRuntime()
+		client := &mockK8sClient{}
+		reaper := NewReaper(stateMgr, client, cfg(IdleSignalAgent))
+		reaper.activity.(*agentActivitySource).baseURL = func(*state.RuntimeInfo) string { return "http://127.0.0.1:1" }
+
+		reaper.checkAndReapIdleSandboxes()
+		if len(client.deletedRuntimes) != 1 {
+			t.Errorf("Expected sandbox to be reaped on proxy activity, got %d reaped", len(client.deletedRuntimes))
+		}
+	})
+
+	t.Run("Metrics signal spares busy sandbox", func(t *testing.T) {
+		stateMgr, _ := newIdleRuntime()
+		client := &mockUsageClient{cpuMillis: map[string]int64{"runtime-1": 900}}
+		reaper := NewReaper(stateMgr, client, cfg(IdleSignalMetrics))
+
+		reaper.checkAndReapIdleSandboxes()
+		if len(client.deletedRuntimes) != 0 {
+			t.Errorf("Expected busy sandbox to be spared, got %d reaped", len(client.deletedRuntimes))
+		}
+	})
+
+	t.Run("Proxy signal ignores usage", func(t *testing.T) {
+		stateMgr, _ := newIdleRuntime()
+		client := &mockUsageClient{cpuMillis: map[string]int64{"runtime-1": 900}}
+		reaper := NewReaper(stateMgr, client, cfg(IdleSignalProxy))
+
+		reaper.checkAndReapIdleSandboxes()
+		if len(client.deletedRuntimes) != 1 {
+			t.Errorf("Expected sandbox to be reaped on proxy activity, got %d reaped", len(client.deletedRuntimes))
+		}
+	})
+}
+
+func TestReaper_StillActiveUsesGivenIdleTimeout(t *testing.T) {
+	stateMgr := state.NewStateManager()
+	info := &state.RuntimeInfo{
+		RuntimeID:        "runtime-1",
+		SessionID:        "session-1",
+		SessionAPIKey:    "session-key",
+		Status:           types.StatusRunning,
+		CreatedAt:        time.Now().Add(-48 * time.Hour),
+		LastActivityTime: time.Now().Add(-40 * time.Hour),
+	}
+	stateMgr.AddRuntime(info)
+	// The agent was last active 30 hours ago
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"idle_time": 108000}`)
+	}))
+	defer server.Close()
+	reaper := NewReaper(stateMgr, &mockK8sClient{}, &config.Config{
+		IdleTimeoutHours: 72,
+		K8sQueryTimeout:  10 * time.Second,
+		IdleSignal:       IdleSignalAgent,
+	})
+	reaper.activity.(*agentActivitySource).baseURL = func(*state.RuntimeInfo) string { return server.URL }
+
+	if reaper.StillActive(info, time.Now(), 24*time.Hour) {
+		t.Error("Expected a sandbox idle for 30h not to count as active within 24h")
+	}
+	if !reaper.StillActive(info, time.Now(), 72*time.Hour) {
+		t.Error("Expected a sandbox idle for 30h to count as active within 72h")
+	}
+}
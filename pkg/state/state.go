@@ -1,6 +1,7 @@
 package state
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -22,19 +23,66 @@ type RuntimeInfo struct {
 	IngressName      string
 	RestartCount     int
 	RestartReasons   []string
-	CreatedAt        time.Time // Track when the runtime was created for cleanup purposes
-	LastActivityTime time.Time // Track last activity for idle timeout
+	Restarts         []types.RestartRecord
+	CreatedAt        time.Time     // Track when the runtime was created for cleanup purposes
+	LastActivityTime time.Time     // Track last activity for idle timeout
+	TTL              time.Duration // Maximum lifetime measured from CreatedAt, regardless of activity (0 = no limit)
+	IdleTimeout      time.Duration // Per-sandbox idle timeout overriding the reaper default (0 = use default)
+	ProxyTimeout     time.Duration // Per-sandbox PROXY_RESPONSE_HEADER_TIMEOUT override (0 = use default)
+	WorkspacePVCName string        // Existing PVC mounted as the workspace ("" = none)
+	Protected        bool          // Never removed by the idle reaper or cleanup service
+	Cordoned         bool          // Proxied traffic is refused while set; in memory only, for debugging
+	StartTraceID     uint64        // Trace of the /start request that created the runtime (0 = untraced)
+	StartSpanID      uint64        // That request's span within StartTraceID
+
+	// Resources requested by the sandbox pod, counted against MAX_TOTAL_CPU / MAX_TOTAL_MEMORY
+	CPURequestMillis   int64
+	MemoryRequestBytes int64
+
+	// The /start request the sandbox was created from, used to recreate its pod on
+	// resume; in memory only, so nil for runtimes discovered from the cluster
+	StartRequest *types.StartRequest
 
 	// Last termination info (propagated from K8s lastState.terminated)
 	LastTerminationReason   string
 	LastTerminationExitCode int
+
+	// Lifecycle events, oldest first, capped at maxHistoryEvents; in memory only
+	History []types.RuntimeEvent
 }
 
+// tombstoneRetention is how long a stopped runtime is remembered after it leaves state
+const tombstoneRetention = 24 * time.Hour
+
+// maxHistoryEvents bounds each runtime's history; the oldest events are dropped first
+const maxHistoryEvents = 100
+
 // StateManager manages runtime state
 type StateManager struct {
 	mu               sync.RWMutex
 	runtimeByID      map[string]*RuntimeInfo
 	runtimeBySession map[string]*RuntimeInfo
+
+	// Tombstones record when stopped runtimes were removed (keyed by runtime and by
+	// session ID) so lookups can tell "existed and ended" from "never existed".
+	tombstonesByID      map[string]time.Time
+	tombstonesBySession map[string]time.Time
+	// History of tombstoned runtimes, so GET /runtime/{runtime_id}/history still shows
+	// how a runtime ended. Pruned with tombstonesByID.
+	tombstonedHistory map[string][]types.RuntimeEvent
+
+	// lastReconcile is when state was last synced with Kubernetes (startup discovery
+	// or the periodic reconcile loop).
+	lastReconcile time.Time
+
+	// teardownLocks serializes destructive operations (stop, reap, cleanup) per runtime
+	teardownMu    sync.Mutex
+	teardownLocks map[string]*teardownLock
+}
+
+type teardownLock struct {
+	mu   sync.Mutex
+	refs int
 }
 
 // NewStateManager creates a new state manager
@@ -42,6 +90,12 @@ func NewStateManager() *StateManager {
 	return &StateManager{
 		runtimeByID:      make(map[string]*RuntimeInfo),
 		runtimeBySession: make(map[string]*RuntimeInfo),
+
+		tombstonesByID:      make(map[string]time.Time),
+		tombstonesBySession: make(map[string]time.Time),
+		tombstonedHistory:   make(map[string][]types.RuntimeEvent),
+
+		teardownLocks: make(map[string]*teardownLock),
 	}
 }
 
@@ -49,9 +103,74 @@ func NewStateManager() *StateManager {
 func (s *StateManager) AddRuntime(info *RuntimeInfo) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.addRuntimeLocked(info)
+}
 
+// Limits bounds the runtimes AddRuntimeWithinLimits admits. Zero fields are unlimited.
+type Limits struct {
+	MaxRuntimes    int   // Runtimes that aren't stopped (MAX_SANDBOXES)
+	MaxCPUMillis   int64 // Total CPU requested by runtimes that aren't stopped (MAX_TOTAL_CPU)
+	MaxMemoryBytes int64 // Total memory requested by runtimes that aren't stopped (MAX_TOTAL_MEMORY)
+}
+
+// Errors returned by AddRuntimeWithinLimits
+var (
+	ErrRuntimeLimitReached    = errors.New("runtime limit reached")
+	ErrResourceCeilingReached = errors.New("resource ceiling reached")
+)
+
+// AddRuntimeWithinLimits adds a new runtime unless that would exceed limits, checking
+// and adding under one lock so concurrent callers can't overshoot. Paused and pending
+// runtimes count towards the limits; stopped ones don't, so stopping a runtime frees
+// its share.
+func (s *StateManager) AddRuntimeWithinLimits(info *RuntimeInfo, limits Limits) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active, cpuMillis, memoryBytes := s.committedLocked()
+	if limits.MaxRuntimes > 0 && active >= limits.MaxRuntimes {
+		return ErrRuntimeLimitReached
+	}
+	if limits.MaxCPUMillis > 0 && cpuMillis+info.CPURequestMillis > limits.MaxCPUMillis {
+		return ErrResourceCeilingReached
+	}
+	if limits.MaxMemoryBytes > 0 && memoryBytes+info.MemoryRequestBytes > limits.MaxMemoryBytes {
+		return ErrResourceCeilingReached
+	}
+	s.addRuntimeLocked(info)
+	return nil
+}
+
+// CommittedResources returns the total CPU (millicores) and memory (bytes) requested by
+// runtimes that aren't stopped
+func (s *StateManager) CommittedResources() (cpuMillis, memoryBytes int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, cpuMillis, memoryBytes = s.committedLocked()
+	return cpuMillis, memoryBytes
+}
+
+// committedLocked counts runtimes that aren't stopped and sums their resource requests
+func (s *StateManager) committedLocked() (active int, cpuMillis, memoryBytes int64) {
+	for _, existing := range s.runtimeByID {
+		if existing.Status == types.StatusStopped {
+			continue
+		}
+		active++
+		cpuMillis += existing.CPURequestMillis
+		memoryBytes += existing.MemoryRequestBytes
+	}
+	return active, cpuMillis, memoryBytes
+}
+
+func (s *StateManager) addRuntimeLocked(info *RuntimeInfo) {
 	s.runtimeByID[info.RuntimeID] = info
 	s.runtimeBySession[info.SessionID] = info
+
+	// A live runtime supersedes any earlier stop of the same runtime or session
+	delete(s.tombstonesByID, info.RuntimeID)
+	delete(s.tombstonesBySession, info.SessionID)
+	delete(s.tombstonedHistory, info.RuntimeID)
 }
 
 // GetRuntimeByID retrieves a runtime by its ID
@@ -66,6 +185,20 @@ func (s *StateManager) GetRuntimeByID(runtimeID string) (*RuntimeInfo, error) {
 	return info, nil
 }
 
+// GetStatus returns a runtime's current status. Callers that don't hold the runtime's
+// session lock read it here rather than from a shared *RuntimeInfo, which a resume may
+// be updating concurrently.
+func (s *StateManager) GetStatus(runtimeID string) (types.RuntimeStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, exists := s.runtimeByID[runtimeID]
+	if !exists {
+		return "", fmt.Errorf("runtime not found: %s", runtimeID)
+	}
+	return info.Status, nil
+}
+
 // GetRuntimeBySessionID retrieves a runtime by its session ID
 func (s *StateManager) GetRuntimeBySessionID(sessionID string) (*RuntimeInfo, error) {
 	s.mu.RLock()
@@ -99,14 +232,42 @@ func (s *StateManager) DeleteRuntime(runtimeID string) error {
 
 	info, exists := s.runtimeByID[runtimeID]
 	if !exists {
-		return fmt.Errorf("runtime not found: %s", runtimeID)
+		// Already removed (e.g. by a concurrent stop or reap); deleting is idempotent
+		return nil
 	}
 
 	delete(s.runtimeByID, runtimeID)
-	delete(s.runtimeBySession, info.SessionID)
+	if s.runtimeBySession[info.SessionID] == info {
+		delete(s.runtimeBySession, info.SessionID)
+	}
 	return nil
 }
 
+// LockRuntime blocks until the caller holds the teardown lock for runtimeID and returns
+// the unlock func. Stop, the reaper and cleanup take it around tearing down a runtime so
+// they don't race; after acquiring it, callers should check the runtime is still in state.
+func (s *StateManager) LockRuntime(runtimeID string) func() {
+	s.teardownMu.Lock()
+	lock, ok := s.teardownLocks[runtimeID]
+	if !ok {
+		lock = &teardownLock{}
+		s.teardownLocks[runtimeID] = lock
+	}
+	lock.refs++
+	s.teardownMu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		s.teardownMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.teardownLocks, runtimeID)
+		}
+		s.teardownMu.Unlock()
+	}
+}
+
 // ListRuntimes returns all runtimes
 func (s *StateManager) ListRuntimes() []*RuntimeInfo {
 	s.mu.RLock()
@@ -146,3 +307,180 @@ func (s *StateManager) UpdateLastActivity(runtimeID string) error {
 	info.LastActivityTime = time.Now()
 	return nil
 }
+
+// TouchActivity moves a runtime's last activity time forward to t. An earlier t is
+// ignored, so a stale observation never rewinds activity recorded by the proxy.
+func (s *StateManager) TouchActivity(runtimeID string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, exists := s.runtimeByID[runtimeID]
+	if !exists {
+		return fmt.Errorf("runtime not found: %s", runtimeID)
+	}
+
+	if t.After(info.LastActivityTime) {
+		info.LastActivityTime = t
+	}
+	return nil
+}
+
+// TransitionStatus atomically moves a runtime's status from one value to another. It
+// returns false, leaving the runtime unchanged, if the runtime is gone or its status is
+// not from. Teardown paths use it to claim a runtime by moving it to StatusStopping.
+func (s *StateManager) TransitionStatus(runtimeID string, from, to types.RuntimeStatus) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, exists := s.runtimeByID[runtimeID]
+	if !exists || info.Status != from {
+		return false
+	}
+	info.Status = to
+	return true
+}
+
+// MarkResumed atomically moves a paused runtime whose pod was just recreated to
+// running, recording the new pod's committed resources and clearing the previous pod's
+// restart history. It returns false, leaving the runtime unchanged, if the runtime is
+// gone or no longer paused (e.g. a concurrent stop claimed it).
+func (s *StateManager) MarkResumed(runtimeID string, cpuMillis, memoryBytes int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, exists := s.runtimeByID[runtimeID]
+	if !exists || info.Status != types.StatusPaused {
+		return false
+	}
+	info.Status = types.StatusRunning
+	info.PodStatus = types.PodStatusPending
+	info.CPURequestMillis, info.MemoryRequestBytes = cpuMillis, memoryBytes
+	info.RestartCount = 0
+	info.RestartReasons = nil
+	info.Restarts = nil
+	info.LastTerminationReason = ""
+	info.LastTerminationExitCode = 0
+	return true
+}
+
+// SetCordoned sets or clears the runtime's cordon flag
+func (s *StateManager) SetCordoned(runtimeID string, cordoned bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, exists := s.runtimeByID[runtimeID]
+	if !exists {
+		return fmt.Errorf("runtime not found: %s", runtimeID)
+	}
+
+	info.Cordoned = cordoned
+	return nil
+}
+
+// RecordEvent appends an event to the runtime's history, dropping the oldest event once
+// maxHistoryEvents is reached. Tombstoned runtimes can still be recorded against.
+func (s *StateManager) RecordEvent(runtimeID string, eventType types.RuntimeEventType, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event := types.RuntimeEvent{Type: eventType, Time: time.Now(), Message: message}
+	if info, exists := s.runtimeByID[runtimeID]; exists {
+		info.History = appendEvent(info.History, event)
+		return nil
+	}
+	if history, exists := s.tombstonedHistory[runtimeID]; exists {
+		s.tombstonedHistory[runtimeID] = appendEvent(history, event)
+		return nil
+	}
+	return fmt.Errorf("runtime not found: %s", runtimeID)
+}
+
+func appendEvent(history []types.RuntimeEvent, event types.RuntimeEvent) []types.RuntimeEvent {
+	if len(history) >= maxHistoryEvents {
+		history = history[len(history)-maxHistoryEvents+1:]
+	}
+	return append(history, event)
+}
+
+// History returns a copy of the runtime's history, newest first. It covers runtimes in
+// state and those tombstoned within the retention window.
+func (s *StateManager) History(runtimeID string) ([]types.RuntimeEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var history []types.RuntimeEvent
+	if info, exists := s.runtimeByID[runtimeID]; exists {
+		history = info.History
+	} else if tombstoned, exists := s.tombstonedHistory[runtimeID]; exists {
+		history = tombstoned
+	} else {
+		return nil, fmt.Errorf("runtime not found: %s", runtimeID)
+	}
+
+	events := make([]types.RuntimeEvent, len(history))
+	for i, event := range history {
+		events[len(history)-1-i] = event
+	}
+	return events, nil
+}
+
+// Count returns the number of runtimes in state
+func (s *StateManager) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.runtimeByID)
+}
+
+// MarkReconciled records that state was just synced with Kubernetes
+func (s *StateManager) MarkReconciled() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastReconcile = time.Now()
+}
+
+// LastReconcileTime returns when state was last synced with Kubernetes (zero if never)
+func (s *StateManager) LastReconcileTime() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastReconcile
+}
+
+// Tombstone records that a runtime was stopped, keeping its history. Tombstones are kept
+// for 24 hours; expired ones are pruned on each call.
+func (s *StateManager) Tombstone(info *RuntimeInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, stoppedAt := range s.tombstonesByID {
+		if now.Sub(stoppedAt) > tombstoneRetention {
+			delete(s.tombstonesByID, id)
+			delete(s.tombstonedHistory, id)
+		}
+	}
+	for id, stoppedAt := range s.tombstonesBySession {
+		if now.Sub(stoppedAt) > tombstoneRetention {
+			delete(s.tombstonesBySession, id)
+		}
+	}
+
+	s.tombstonesByID[info.RuntimeID] = now
+	s.tombstonesBySession[info.SessionID] = now
+	s.tombstonedHistory[info.RuntimeID] = append([]types.RuntimeEvent(nil), info.History...)
+}
+
+// IsTombstoned reports whether runtimeID was stopped within the retention window
+func (s *StateManager) IsTombstoned(runtimeID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stoppedAt, ok := s.tombstonesByID[runtimeID]
+	return ok && time.Since(stoppedAt) <= tombstoneRetention
+}
+
+// IsSessionTombstoned reports whether the runtime for sessionID was stopped within the retention window
+func (s *StateManager) IsSessionTombstoned(sessionID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stoppedAt, ok := s.tombstonesBySession[sessionID]
+	return ok && time.Since(stoppedAt) <= tombstoneRetention
+}
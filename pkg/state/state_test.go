@@ -1,6 +1,10 @@
 package state
 
 import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -161,9 +165,12 @@ func TestDeleteRuntime(t *testing.T) {
 	})
 
 	t.Run("Delete non-existent runtime", func(t *testing.T) {
-		err := sm.DeleteRuntime("non-existent")
-		if err == nil {
-			t.Error("Expected error for non-existent runtime")
+		// A second caller (e.g. stop racing the reaper) succeeds as a no-op
+		if err := sm.DeleteRuntime("runtime-123"); err != nil {
+			t.Errorf("Expected deleting an already-deleted runtime to succeed, got %v", err)
+		}
+		if err := sm.DeleteRuntime("non-existent"); err != nil {
+			t.Errorf("Expected deleting an unknown runtime to succeed, got %v", err)
 		}
 	})
 }
@@ -287,3 +294,336 @@ func TestUpdateLastActivity(t *testing.T) {
 		}
 	})
 }
+
+func TestTouchActivity(t *testing.T) {
+	sm := NewStateManager()
+	base := time.Now().Add(-time.Hour)
+	sm.AddRuntime(&RuntimeInfo{RuntimeID: "runtime-123", SessionID: "session-456", LastActivityTime: base})
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{"Earlier time is ignored", base.Add(-time.Minute), base},
+		{"Later time moves activity forward", base.Add(time.Minute), base.Add(time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := sm.TouchActivity("runtime-123", tt.at); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			retrieved, _ := sm.GetRuntimeByID("runtime-123")
+			if !retrieved.LastActivityTime.Equal(tt.want) {
+				t.Errorf("Expected LastActivityTime %v, got %v", tt.want, retrieved.LastActivityTime)
+			}
+		})
+	}
+
+	if err := sm.TouchActivity("non-existent", time.Now()); err == nil {
+		t.Error("Expected error for non-existent runtime")
+	}
+}
+
+func TestCountAndLastReconcile(t *testing.T) {
+	sm := NewStateManager()
+
+	if sm.Count() != 0 {
+		t.Errorf("Expected count 0 for empty state, got %d", sm.Count())
+	}
+	if !sm.LastReconcileTime().IsZero() {
+		t.Error("Expected zero last reconcile time before any reconcile")
+	}
+
+	sm.AddRuntime(&RuntimeInfo{RuntimeID: "runtime-1", SessionID: "session-1"})
+	sm.AddRuntime(&RuntimeInfo{RuntimeID: "runtime-2", SessionID: "session-2"})
+	if sm.Count() != 2 {
+		t.Errorf("Expected count 2, got %d", sm.Count())
+	}
+
+	before := time.Now()
+	sm.MarkReconciled()
+	if sm.LastReconcileTime().Before(before) {
+		t.Error("Expected last reconcile time to be updated")
+	}
+}
+
+func TestTombstone(t *testing.T) {
+	sm := NewStateManager()
+	info := &RuntimeInfo{RuntimeID: "runtime-1", SessionID: "session-1"}
+
+	if sm.IsTombstoned("runtime-1") || sm.IsSessionTombstoned("session-1") {
+		t.Error("Expected no tombstone before the runtime is stopped")
+	}
+
+	sm.AddRuntime(info)
+	_ = sm.DeleteRuntime(info.RuntimeID)
+	sm.Tombstone(info)
+	if !sm.IsTombstoned("runtime-1") || !sm.IsSessionTombstoned("session-1") {
+		t.Error("Expected runtime and session to be tombstoned after stop")
+	}
+
+	// Restarting the session clears its tombstone
+	sm.AddRuntime(&RuntimeInfo{RuntimeID: "runtime-2", SessionID: "session-1"})
+	if sm.IsSessionTombstoned("session-1") {
+		t.Error("Expected session tombstone to be cleared when a new runtime is added")
+	}
+	if !sm.IsTombstoned("runtime-1") {
+		t.Error("Expected the old runtime to stay tombstoned")
+	}
+
+	// Expired tombstones are ignored and pruned
+	sm.tombstonesByID["runtime-old"] = time.Now().Add(-tombstoneRetention - time.Minute)
+	if sm.IsTombstoned("runtime-old") {
+		t.Error("Expected expired tombstone to be ignored")
+	}
+	sm.Tombstone(&RuntimeInfo{RuntimeID: "runtime-3", SessionID: "session-3"})
+	if _, ok := sm.tombstonesByID["runtime-old"]; ok {
+		t.Error("Expected expired tombstone to be pruned")
+	}
+}
+
+func TestSetCordoned(t *testing.T) {
+	sm := NewStateManager()
+	sm.AddRuntime(&RuntimeInfo{RuntimeID: "runtime-123", SessionID: "session-456"})
+
+	if err := sm.SetCordoned("runtime-123", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	retrieved, _ := sm.GetRuntimeByID("runtime-123")
+	if !retrieved.Cordoned {
+		t.Error("Expected runtime to be cordoned")
+	}
+
+	if err := sm.SetCordoned("runtime-123", false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if retrieved.Cordoned {
+		t.Error("Expected runtime to be uncordoned")
+	}
+
+	if err := sm.SetCordoned("non-existent", true); err == nil {
+		t.Error("Expected error for non-existent runtime")
+	}
+}
+
+func TestTransitionStatus(t *testing.T) {
+	sm := NewStateManager()
+	sm.AddRuntime(&RuntimeInfo{RuntimeID: "runtime-123", SessionID: "session-456", Status: types.StatusRunning})
+
+	if sm.TransitionStatus("runtime-123", types.StatusPaused, types.StatusStopping) {
+		t.Error("Expected transition from a non-matching status to fail")
+	}
+	retrieved, _ := sm.GetRuntimeByID("runtime-123")
+	if retrieved.Status != types.StatusRunning {
+		t.Errorf("Expected status to stay running after a failed transition, got %s", retrieved.Status)
+	}
+
+	if !sm.TransitionStatus("runtime-123", types.StatusRunning, types.StatusStopping) {
+		t.Fatal("Expected transition from the current status to succeed")
+	}
+	if retrieved.Status != types.StatusStopping {
+		t.Errorf("Expected status stopping, got %s", retrieved.Status)
+	}
+	if sm.TransitionStatus("runtime-123", types.StatusRunning, types.StatusStopping) {
+		t.Error("Expected a second claim to fail")
+	}
+
+	if sm.TransitionStatus("non-existent", types.StatusRunning, types.StatusStopping) {
+		t.Error("Expected transition of a non-existent runtime to fail")
+	}
+}
+
+func TestTransitionStatusConcurrent(t *testing.T) {
+	sm := NewStateManager()
+	sm.AddRuntime(&RuntimeInfo{RuntimeID: "runtime-1", SessionID: "session-1", Status: types.StatusRunning})
+
+	var wg sync.WaitGroup
+	var claimed atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if sm.TransitionStatus("runtime-1", types.StatusRunning, types.StatusStopping) {
+				claimed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := claimed.Load(); n != 1 {
+		t.Errorf("Expected exactly one claim to succeed, got %d", n)
+	}
+}
+
+func TestMarkResumed(t *testing.T) {
+	sm := NewStateManager()
+	sm.AddRuntime(&RuntimeInfo{
+		RuntimeID:    "runtime-1",
+		SessionID:    "session-1",
+		Status:       types.StatusPaused,
+		RestartCount: 3,
+		Restarts:     []types.RestartRecord{{Reason: "OOMKilled"}},
+	})
+
+	if !sm.MarkResumed("runtime-1", 500, 1024) {
+		t.Fatal("Expected a paused runtime to be marked resumed")
+	}
+	status, err := sm.GetStatus("runtime-1")
+	if err != nil || status != types.StatusRunning {
+		t.Errorf("Expected status running, got %q (err %v)", status, err)
+	}
+	retrieved, _ := sm.GetRuntimeByID("runtime-1")
+	if retrieved.PodStatus != types.PodStatusPending || retrieved.RestartCount != 0 || retrieved.Restarts != nil {
+		t.Errorf("Expected the previous pod's status and restart history to be cleared, got %+v", retrieved)
+	}
+	if cpu, mem := sm.CommittedResources(); cpu != 500 || mem != 1024 {
+		t.Errorf("Expected committed resources of the recreated pod, got %dm/%d", cpu, mem)
+	}
+
+	if sm.MarkResumed("runtime-1", 500, 1024) {
+		t.Error("Expected a runtime that is no longer paused not to be resumed again")
+	}
+	if _, err := sm.GetStatus("non-existent"); err == nil {
+		t.Error("Expected an error for a non-existent runtime")
+	}
+}
+
+func TestLockRuntime(t *testing.T) {
+	sm := NewStateManager()
+
+	unlock := sm.LockRuntime("runtime-1")
+	acquired := make(chan struct{})
+	go func() {
+		release := sm.LockRuntime("runtime-1")
+		close(acquired)
+		release()
+	}()
+
+	// A different runtime is not blocked
+	sm.LockRuntime("runtime-2")()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected second lock on the same runtime to wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	<-acquired
+
+	sm.teardownMu.Lock()
+	defer sm.teardownMu.Unlock()
+	if len(sm.teardownLocks) != 0 {
+		t.Errorf("Expected released locks to be dropped, got %d entries", len(sm.teardownLocks))
+	}
+}
+
+func TestRecordEventAndHistory(t *testing.T) {
+	sm := NewStateManager()
+	info := &RuntimeInfo{RuntimeID: "runtime-1", SessionID: "session-1"}
+	sm.AddRuntime(info)
+
+	_ = sm.RecordEvent("runtime-1", types.RuntimeEventCreated, "image test")
+	_ = sm.RecordEvent("runtime-1", types.RuntimeEventPaused, "")
+	history, err := sm.History("runtime-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(history) != 2 || history[0].Type != types.RuntimeEventPaused || history[1].Type != types.RuntimeEventCreated {
+		t.Errorf("Expected history newest first, got %v", history)
+	}
+
+	// History outlives the runtime while it is tombstoned
+	_ = sm.DeleteRuntime("runtime-1")
+	sm.Tombstone(info)
+	if err := sm.RecordEvent("runtime-1", types.RuntimeEventReaped, "cleanup: pod_failed"); err != nil {
+		t.Errorf("Expected recording against a tombstoned runtime to succeed: %v", err)
+	}
+	history, err = sm.History("runtime-1")
+	if err != nil || len(history) != 3 || history[0].Type != types.RuntimeEventReaped {
+		t.Errorf("Expected tombstoned history with the reap first, got %v (%v)", history, err)
+	}
+
+	if err := sm.RecordEvent("non-existent", types.RuntimeEventError, ""); err == nil {
+		t.Error("Expected error recording against an unknown runtime")
+	}
+	if _, err := sm.History("non-existent"); err == nil {
+		t.Error("Expected error for history of an unknown runtime")
+	}
+}
+
+func TestRecordEventBounded(t *testing.T) {
+	sm := NewStateManager()
+	sm.AddRuntime(&RuntimeInfo{RuntimeID: "runtime-1", SessionID: "session-1"})
+
+	for i := 0; i < maxHistoryEvents+10; i++ {
+		_ = sm.RecordEvent("runtime-1", types.RuntimeEventRestarted, fmt.Sprintf("restart %d", i))
+	}
+	history, _ := sm.History("runtime-1")
+	if len(history) != maxHistoryEvents {
+		t.Fatalf("Expected history capped at %d events, got %d", maxHistoryEvents, len(history))
+	}
+	if want := fmt.Sprintf("restart %d", maxHistoryEvents+9); history[0].Message != want {
+		t.Errorf("Expected newest event %q, got %q", want, history[0].Message)
+	}
+	if want := "restart 10"; history[len(history)-1].Message != want {
+		t.Errorf("Expected oldest retained event %q, got %q", want, history[len(history)-1].Message)
+	}
+}
+
+func TestAddRuntimeWithinLimits_ResourceCeiling(t *testing.T) {
+	sm := NewStateManager()
+	limits := Limits{MaxCPUMillis: 2000, MaxMemoryBytes: 4 << 30}
+	sandbox := func(id string) *RuntimeInfo {
+		return &RuntimeInfo{RuntimeID: id, SessionID: "session-" + id, Status: types.StatusRunning,
+			CPURequestMillis: 1000, MemoryRequestBytes: 1 << 30}
+	}
+
+	for _, id := range []string{"runtime-1", "runtime-2"} {
+		if err := sm.AddRuntimeWithinLimits(sandbox(id), limits); err != nil {
+			t.Fatalf("Expected %s to be admitted up to the ceiling, got %v", id, err)
+		}
+	}
+	if cpu, mem := sm.CommittedResources(); cpu != 2000 || mem != 2<<30 {
+		t.Errorf("Expected 2000m / 2Gi committed, got %dm / %d bytes", cpu, mem)
+	}
+	if err := sm.AddRuntimeWithinLimits(sandbox("runtime-3"), limits); !errors.Is(err, ErrResourceCeilingReached) {
+		t.Errorf("Expected ErrResourceCeilingReached beyond the CPU ceiling, got %v", err)
+	}
+	if _, err := sm.GetRuntimeByID("runtime-3"); err == nil {
+		t.Error("Expected rejected runtime not to be in state")
+	}
+
+	big := &RuntimeInfo{RuntimeID: "runtime-big", SessionID: "session-big", MemoryRequestBytes: 3 << 30}
+	if err := sm.AddRuntimeWithinLimits(big, Limits{MaxMemoryBytes: 4 << 30}); !errors.Is(err, ErrResourceCeilingReached) {
+		t.Errorf("Expected ErrResourceCeilingReached beyond the memory ceiling, got %v", err)
+	}
+
+	// Stopping a runtime frees its share
+	_ = sm.DeleteRuntime("runtime-1")
+	if err := sm.AddRuntimeWithinLimits(sandbox("runtime-3"), limits); err != nil {
+		t.Errorf("Expected runtime to be admitted after another was removed, got %v", err)
+	}
+	if err := sm.AddRuntimeWithinLimits(sandbox("runtime-4"), Limits{}); err != nil {
+		t.Errorf("Expected zero limits to be unlimited, got %v", err)
+	}
+}
+
+func TestAddRuntimeWithinLimits_RuntimeLimit(t *testing.T) {
+	sm := NewStateManager()
+	sm.AddRuntime(&RuntimeInfo{RuntimeID: "runtime-1", SessionID: "session-1", Status: types.StatusRunning})
+	sm.AddRuntime(&RuntimeInfo{RuntimeID: "runtime-2", SessionID: "session-2", Status: types.StatusStopped})
+
+	if err := sm.AddRuntimeWithinLimits(&RuntimeInfo{RuntimeID: "runtime-3", SessionID: "session-3"}, Limits{MaxRuntimes: 2}); err != nil {
+		t.Error("Expected runtime to be added below the limit (stopped runtimes don't count)")
+	}
+	if err := sm.AddRuntimeWithinLimits(&RuntimeInfo{RuntimeID: "runtime-4", SessionID: "session-4"}, Limits{MaxRuntimes: 2}); !errors.Is(err, ErrRuntimeLimitReached) {
+		t.Errorf("Expected runtime to be rejected at the limit, got %v", err)
+	}
+	if _, err := sm.GetRuntimeByID("runtime-4"); err == nil {
+		t.Error("Expected rejected runtime not to be in state")
+	}
+	if err := sm.AddRuntimeWithinLimits(&RuntimeInfo{RuntimeID: "runtime-5", SessionID: "session-5"}, Limits{}); err != nil {
+		t.Error("Expected a limit of 0 to be unlimited")
+	}
+}
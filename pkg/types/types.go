@@ -3,6 +3,9 @@ package types
 import (
 	"encoding/json"
 	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 // FlexibleCommand accepts command as either a JSON string or a JSON array of strings
@@ -42,6 +45,97 @@ type StartRequest struct {
 	SessionID      string            `json:"session_id"`
 	ResourceFactor float64           `json:"resource_factor,omitempty"`
 	RuntimeClass   string            `json:"runtime_class,omitempty"`
+	GPU            *GPURequest       `json:"gpu,omitempty"`
+	TTLSeconds     int               `json:"ttl_seconds,omitempty"` // max wall-clock lifetime regardless of activity (0 = no limit)
+	// IdleTimeoutMinutes overrides IDLE_TIMEOUT_HOURS for this sandbox, capped at
+	// MAX_IDLE_TIMEOUT_MINUTES (0 = use the global timeout)
+	IdleTimeoutMinutes int `json:"idle_timeout_minutes,omitempty"`
+	// ProxyTimeoutSeconds overrides PROXY_RESPONSE_HEADER_TIMEOUT for requests proxied to
+	// this sandbox (e.g. long builds), capped at MAX_PROXY_TIMEOUT (0 = use the global timeout)
+	ProxyTimeoutSeconds int `json:"proxy_timeout_seconds,omitempty"`
+	// WaitForReady makes /start respond only once the sandbox pod passes its readiness
+	// probe (same as the wait=true query parameter)
+	WaitForReady bool `json:"wait_for_ready,omitempty"`
+	// Async makes /start return 202 with an operation to poll instead of waiting for the
+	// sandbox to be created (same as the async=true query parameter)
+	Async bool `json:"async,omitempty"`
+
+	// Explicit CPU/memory quantities (e.g. "250m", "16Gi"); each one set overrides the
+	// resource_factor default for that value only
+	CPURequest    string `json:"cpu_request,omitempty"`
+	CPULimit      string `json:"cpu_limit,omitempty"`
+	MemoryRequest string `json:"memory_request,omitempty"`
+	MemoryLimit   string `json:"memory_limit,omitempty"`
+
+	// Optional per-sandbox scheduling. NodeSelector and Tolerations are merged over the
+	// SANDBOX_NODE_SELECTOR / SANDBOX_TOLERATIONS defaults; Affinity uses the Kubernetes schema.
+	NodeSelector map[string]string   `json:"node_selector,omitempty"`
+	Tolerations  []corev1.Toleration `json:"tolerations,omitempty"`
+	Affinity     *corev1.Affinity    `json:"affinity,omitempty"`
+
+	// Extra pod metadata, merged over SANDBOX_POD_LABELS / SANDBOX_POD_ANNOTATIONS.
+	// Keys the runtime API sets itself (app, runtime-id, session-id, resource-factor
+	// and the openhands.dev/ prefix) are reserved and rejected.
+	PodLabels      map[string]string `json:"pod_labels,omitempty"`
+	PodAnnotations map[string]string `json:"pod_annotations,omitempty"`
+
+	// SchedulingHint pins the sandbox near data (e.g. a zonal PV) without a full affinity spec
+	SchedulingHint *SchedulingHint `json:"scheduling_hint,omitempty"`
+
+	// WorkspacePVCName mounts an existing PersistentVolumeClaim as the workspace (at
+	// WORKSPACE_MOUNT_PATH), e.g. to resume or fork the workspace of a previous session.
+	WorkspacePVCName string `json:"workspace_pvc_name,omitempty"`
+
+	// OwnWorkspacePVC hands WorkspacePVCName over to the sandbox: /start labels the PVC
+	// with the runtime ID so it is deleted when the sandbox is stopped, reaped or cleaned
+	// up (unless DELETE_PVC_ON_STOP=false). Without it the PVC is kept.
+	OwnWorkspacePVC bool `json:"own_workspace_pvc,omitempty"`
+
+	// Protected exempts the sandbox from the idle reaper and cleanup service (e.g. for
+	// long-running demos); it is only removed by an explicit stop.
+	Protected bool `json:"protected,omitempty"`
+
+	// Volumes mounts ConfigMaps or Secrets from the runtime namespace into the sandbox
+	// container (e.g. an .npmrc, pip config or a read-only dataset)
+	Volumes []VolumeRequest `json:"volumes,omitempty"`
+
+	// InitContainer runs to completion before the sandbox container starts, sharing the
+	// workspace volume (e.g. to clone a repository or copy seed data). Its image must be
+	// in a registry listed in INIT_CONTAINER_ALLOWED_REGISTRIES.
+	InitContainer *InitContainerRequest `json:"init_container,omitempty"`
+}
+
+// InitContainerRequest describes a workspace pre-population step. A single-string
+// command runs via /bin/sh -c; an empty command runs the image's entrypoint.
+type InitContainerRequest struct {
+	Image       string            `json:"image"`
+	Command     FlexibleCommand   `json:"command,omitempty"`
+	Environment map[string]string `json:"environment,omitempty"`
+}
+
+// VolumeRequest mounts one ConfigMap or Secret (exactly one of the two is set) at
+// MountPath. SubPath mounts a single key instead of the whole source.
+type VolumeRequest struct {
+	Name      string `json:"name"`
+	ConfigMap string `json:"config_map,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+	MountPath string `json:"mount_path"`
+	SubPath   string `json:"sub_path,omitempty"`
+	ReadOnly  bool   `json:"read_only,omitempty"`
+}
+
+// SchedulingHint requires the sandbox to run in a zone and/or on nodes carrying a label.
+// Zone matches topology.kubernetes.io/zone; NodeLabel is "key=value".
+type SchedulingHint struct {
+	Zone      string `json:"zone,omitempty"`
+	NodeLabel string `json:"node_label,omitempty"`
+}
+
+// GPURequest asks for GPUs to be attached to the sandbox container.
+// ResourceName defaults to "nvidia.com/gpu" when empty.
+type GPURequest struct {
+	Count        int    `json:"count"`
+	ResourceName string `json:"resource_name,omitempty"`
 }
 
 // StopRequest represents the request to stop a runtime
@@ -63,10 +157,11 @@ type ResumeRequest struct {
 type RuntimeStatus string
 
 const (
-	StatusRunning RuntimeStatus = "running"
-	StatusPaused  RuntimeStatus = "paused"
-	StatusStopped RuntimeStatus = "stopped"
-	StatusPending RuntimeStatus = "pending"
+	StatusRunning  RuntimeStatus = "running"
+	StatusPaused   RuntimeStatus = "paused"
+	StatusStopping RuntimeStatus = "stopping"
+	StatusStopped  RuntimeStatus = "stopped"
+	StatusPending  RuntimeStatus = "pending"
 )
 
 // PodStatus represents the Kubernetes pod status
@@ -84,25 +179,124 @@ const (
 
 // RuntimeResponse represents the response from runtime operations
 type RuntimeResponse struct {
-	RuntimeID      string         `json:"runtime_id"`
-	SessionID      string         `json:"session_id"`
-	URL            string         `json:"url"`
-	VSCodeURL      string         `json:"vscode_url,omitempty"` // optional; when set (e.g. proxy mode), frontend uses this for "Open in VSCode"
-	SessionAPIKey  string         `json:"session_api_key,omitempty"`
-	Status         RuntimeStatus  `json:"status"`
-	PodStatus      PodStatus      `json:"pod_status"`
-	WorkHosts      map[string]int `json:"work_hosts,omitempty"`
-	RestartCount   int            `json:"restart_count,omitempty"`
-	RestartReasons []string       `json:"restart_reasons,omitempty"`
+	RuntimeID      string          `json:"runtime_id"`
+	SessionID      string          `json:"session_id"`
+	URL            string          `json:"url"`
+	VSCodeURL      string          `json:"vscode_url,omitempty"`   // optional; when set (e.g. proxy mode), frontend uses this for "Open in VSCode"
+	InternalURL    string          `json:"internal_url,omitempty"` // in-cluster agent server URL for callers that can skip the ingress/proxy
+	SessionAPIKey  string          `json:"session_api_key,omitempty"`
+	Status         RuntimeStatus   `json:"status"`
+	PodStatus      PodStatus       `json:"pod_status"`
+	WorkHosts      map[string]int  `json:"work_hosts,omitempty"`
+	RestartCount   int             `json:"restart_count,omitempty"`
+	RestartReasons []string        `json:"restart_reasons,omitempty"`
+	Restarts       []RestartRecord `json:"restarts,omitempty"`
+	Cordoned       bool            `json:"cordoned,omitempty"` // proxied traffic is refused with 503 until uncordoned
+
+	// StatusStale is set when the live pod status query failed or timed out, so
+	// pod_status and the restart details are the last-known values
+	StatusStale bool `json:"status_stale,omitempty"`
 
 	// Last termination details (why the container last exited, if it has restarted)
 	LastTerminationReason   string `json:"last_termination_reason,omitempty"`
 	LastTerminationExitCode int    `json:"last_termination_exit_code,omitempty"`
+
+	// PodDetail is the raw pod phase, conditions and container states, only included
+	// by GET /list?verbose=true
+	PodDetail *PodDetail `json:"pod_detail,omitempty"`
+}
+
+// PodDetail is the uncollapsed status of a sandbox pod, for debugging
+type PodDetail struct {
+	Phase      string           `json:"phase"`
+	Conditions []PodCondition   `json:"conditions,omitempty"`
+	Containers []ContainerState `json:"containers,omitempty"`
+}
+
+// PodCondition mirrors a Kubernetes pod condition (e.g. PodScheduled, Ready)
+type PodCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ContainerState is the current state of one container: "waiting" (with a reason such
+// as ImagePullBackOff), "running" or "terminated" (with reason and exit code)
+type ContainerState struct {
+	Name         string `json:"name"`
+	Init         bool   `json:"init,omitempty"`
+	State        string `json:"state"`
+	Ready        bool   `json:"ready"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message,omitempty"`
+	ExitCode     int    `json:"exit_code,omitempty"`
+	RestartCount int    `json:"restart_count"`
+}
+
+// RestartRecord describes restarts of a single sandbox container. Kubernetes only
+// retains the most recent termination, so reason, exit code and timestamp describe
+// the last restart while Count is the container's total restart count.
+type RestartRecord struct {
+	Container     string     `json:"container"`
+	Reason        string     `json:"reason,omitempty"`
+	Count         int        `json:"count"`
+	LastTimestamp *time.Time `json:"last_timestamp,omitempty"`
+	ExitCode      int        `json:"exit_code"`
+}
+
+// RuntimeEventType identifies a lifecycle event in a runtime's history
+type RuntimeEventType string
+
+const (
+	RuntimeEventCreated   RuntimeEventType = "created"
+	RuntimeEventReady     RuntimeEventType = "ready"
+	RuntimeEventPaused    RuntimeEventType = "paused"
+	RuntimeEventResumed   RuntimeEventType = "resumed"
+	RuntimeEventRestarted RuntimeEventType = "restarted"
+	RuntimeEventError     RuntimeEventType = "error"
+	RuntimeEventStopped   RuntimeEventType = "stopped"
+	RuntimeEventReaped    RuntimeEventType = "reaped"
+)
+
+// RuntimeEvent is one entry in a runtime's history
+type RuntimeEvent struct {
+	Type    RuntimeEventType `json:"type"`
+	Time    time.Time        `json:"time"`
+	Message string           `json:"message,omitempty"`
+}
+
+// HistoryResponse represents the response from GET /runtime/{runtime_id}/history
+type HistoryResponse struct {
+	RuntimeID  string         `json:"runtime_id"`
+	Events     []RuntimeEvent `json:"events"`                // Newest first
+	Total      int            `json:"total"`                 // Number of events retained, across all pages
+	NextOffset int            `json:"next_offset,omitempty"` // Offset of the next page; omitted on the last page
+}
+
+// PodEvent is a Kubernetes Event about a sandbox pod (e.g. FailedScheduling, BackOff)
+type PodEvent struct {
+	Type           string    `json:"type"` // Normal or Warning
+	Reason         string    `json:"reason"`
+	Message        string    `json:"message"`
+	Count          int32     `json:"count,omitempty"`
+	Source         string    `json:"source,omitempty"` // Reporting component, e.g. default-scheduler
+	FirstTimestamp time.Time `json:"first_timestamp"`
+	LastTimestamp  time.Time `json:"last_timestamp"`
+}
+
+// PodEventsResponse represents the response from GET /runtime/{runtime_id}/events
+type PodEventsResponse struct {
+	RuntimeID string     `json:"runtime_id"`
+	PodName   string     `json:"pod_name"`
+	Events    []PodEvent `json:"events"` // Oldest first
 }
 
 // ListResponse represents the response from list operations
 type ListResponse struct {
-	Runtimes []RuntimeResponse `json:"runtimes"`
+	Runtimes   []RuntimeResponse `json:"runtimes"`
+	Total      int               `json:"total"`                 // Number of runtimes matching the filter, across all pages
+	NextOffset int               `json:"next_offset,omitempty"` // Offset of the next page; omitted on the last page
 }
 
 // BatchSessionsResponse represents the response from batch sessions query
@@ -131,8 +325,73 @@ type BatchConversationSandbox struct {
 	ConversationIDs []string `json:"conversation_ids"`
 }
 
+// StatsResponse represents the response from the stats endpoint
+type StatsResponse struct {
+	RuntimeCount      int                  `json:"runtime_count"`
+	ActiveWebSockets  int64                `json:"active_websockets"` // WebSocket connections currently proxied
+	LastReconcileTime *time.Time           `json:"last_reconcile_time,omitempty"`
+	Cleanup           CleanupStatsResponse `json:"cleanup"`
+	Reaper            ReaperStatsResponse  `json:"reaper"`
+}
+
+// CleanupStatsResponse reports cleanup service statistics
+type CleanupStatsResponse struct {
+	Enabled           bool       `json:"enabled"`
+	LastRunTime       *time.Time `json:"last_run_time,omitempty"`
+	TotalRunCount     int        `json:"total_run_count"`
+	TotalCleaned      int        `json:"total_cleaned"`
+	FailedCleaned     int        `json:"failed_cleaned"`
+	IdleCleaned       int        `json:"idle_cleaned"`
+	DryRun            bool       `json:"dry_run"`         // CLEANUP_DRY_RUN: candidates are only logged and counted
+	WouldClean        int        `json:"would_clean"`     // Runtimes the last dry run would have cleaned up
+	Repaired          int        `json:"repaired"`        // Missing Services/Ingresses recreated (RECONCILE_RESOURCES)
+	OrphansDeleted    int        `json:"orphans_deleted"` // Runtimes whose leftover Services/Ingresses had no pod and were deleted
+	LastCleanupErrors []string   `json:"last_cleanup_errors"`
+}
+
+// ReaperStatsResponse reports idle sandbox reaper statistics
+type ReaperStatsResponse struct {
+	LastRunTime    *time.Time `json:"last_run_time,omitempty"`
+	TotalRunCount  int        `json:"total_run_count"`
+	TotalReaped    int        `json:"total_reaped"`
+	IdleReaped     int        `json:"idle_reaped"`
+	TTLReaped      int        `json:"ttl_reaped"`
+	DryRun         bool       `json:"dry_run"`    // REAPER_DRY_RUN: reap candidates are only logged and counted
+	WouldReap      int        `json:"would_reap"` // Sandboxes the last dry run would have reaped
+	Pruned         int        `json:"pruned"`     // Idle runtimes dropped from state because their pod was already gone
+	LastReapErrors []string   `json:"last_reap_errors"`
+}
+
+// OperationStatus is the state of an async /start operation
+type OperationStatus string
+
+const (
+	OperationPending   OperationStatus = "pending"
+	OperationSucceeded OperationStatus = "succeeded"
+	OperationFailed    OperationStatus = "failed"
+)
+
+// OperationResponse reports an async /start operation (GET /operations/{op_id})
+type OperationResponse struct {
+	OperationID string           `json:"operation_id"`
+	Status      OperationStatus  `json:"status"`
+	RuntimeID   string           `json:"runtime_id"`
+	Runtime     *RuntimeResponse `json:"runtime,omitempty"` // Set once the operation succeeded
+	Error       string           `json:"error,omitempty"`   // Error code, set once the operation failed
+	Message     string           `json:"message,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message,omitempty"`
 }
+
+// SandboxStartingResponse is returned by the sandbox proxy while a sandbox is still
+// starting, so clients can show progress instead of an error
+type SandboxStartingResponse struct {
+	ErrorResponse
+	EstimatedWaitSeconds int `json:"estimated_wait_seconds"`
+}
@@ -3,6 +3,7 @@ package types
 import (
 	"encoding/json"
 	"testing"
+	"time"
 )
 
 func TestRuntimeStatus(t *testing.T) {
@@ -132,6 +133,41 @@ func TestRuntimeResponse(t *testing.T) {
 	}
 }
 
+func TestRuntimeResponse_RestartsJSON(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	resp := RuntimeResponse{
+		RuntimeID:      "runtime-123",
+		RestartCount:   3,
+		RestartReasons: []string{"last:OOMKilled"},
+		Restarts: []RestartRecord{
+			{Container: "openhands-agent", Reason: "OOMKilled", Count: 3, LastTimestamp: &ts, ExitCode: 137},
+		},
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if _, ok := raw["restart_reasons"]; !ok {
+		t.Error("Expected flat restart_reasons to be kept")
+	}
+	restarts, ok := raw["restarts"].([]interface{})
+	if !ok || len(restarts) != 1 {
+		t.Fatalf("Expected 1 restart record, got %v", raw["restarts"])
+	}
+	record := restarts[0].(map[string]interface{})
+	if record["reason"] != "OOMKilled" || record["count"] != float64(3) || record["exit_code"] != float64(137) {
+		t.Errorf("Unexpected restart record: %v", record)
+	}
+	if record["last_timestamp"] != "2024-01-01T12:00:00Z" {
+		t.Errorf("Expected last_timestamp '2024-01-01T12:00:00Z', got %v", record["last_timestamp"])
+	}
+}
+
 func TestErrorResponse(t *testing.T) {
 	err := ErrorResponse{
 		Error:   "test_error",